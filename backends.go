@@ -0,0 +1,46 @@
+// Package wwiseutil describes the formats supported by the packages of this
+// module, so that GUIs and servers can list them at runtime rather than
+// hard-coding them.
+package wwiseutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+import (
+	"wwiseutil/util"
+	"wwiseutil/wem"
+)
+
+// A Backend is a component of this module that has several implementations,
+// such as a pure Go one and a faster one linking a C library through cgo, and
+// the implementation active in this build.
+type Backend struct {
+	Name           string
+	Implementation string
+}
+
+func (b *Backend) String() string {
+	return fmt.Sprintf("%s: %s", b.Name, b.Implementation)
+}
+
+// Cgo reports whether the active implementation of the backend uses cgo.
+// Builds using none can be cross compiled and linked statically.
+func (b *Backend) Cgo() bool {
+	return strings.HasPrefix(b.Implementation, "cgo")
+}
+
+// Backends returns the backends of this module: zlib compression, followed by
+// the wem decoders registered with wem.RegisterDecoder. The cgo
+// implementations are only built with the util.CgoBuildTag build tag.
+func Backends() []*Backend {
+	backends := []*Backend{{"zlib", util.ZlibImplementation()}}
+	for _, d := range wem.Decoders() {
+		backends = append(backends, &Backend{
+			Name:           fmt.Sprintf("%s decoder (0x%04X)", wem.CodecName(d.Codec), d.Codec),
+			Implementation: d.Implementation,
+		})
+	}
+	return backends
+}
@@ -0,0 +1,276 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+import (
+	"wwiseutil/util"
+)
+
+// The version of the cache format written by OpenCached. Cache files written by
+// other versions are ignored.
+const cacheVersion = 4
+
+// The extension of the cache files written by OpenCached.
+const cacheExt = ".hirc.gob"
+
+// A cachedHierarchy is the parsed form of a HIRC section, as stored in a cache
+// file. The data that objects read lazily is recorded by its location in the
+// SoundBank, rather than stored.
+type cachedHierarchy struct {
+	Version int
+	Objects []*cachedObject
+}
+
+// A cachedObject is a HIRC object stored in a cache file.
+type cachedObject struct {
+	Descriptor ObjectDescriptor
+	// The offset into the file and the length of the data of an unknown
+	// object, of the remaining data of an action, or of the remaining data of
+	// the sound structure of a sound or container.
+	Offset, Length int64
+	// The fields of the object, at most one of which is set, or none for an
+	// unknown object.
+	Sound     *cachedSound
+	Container *cachedStructure
+	Event     *cachedEvent
+	Action    *cachedAction
+}
+
+// A cachedSound holds the fields of an SfxVoiceSoundObject.
+type cachedSound struct {
+	Unknown       [5]byte
+	WemDescriptor OptionalWemDescriptor
+	Type          byte
+	Structure     cachedStructure
+	FileInfo      []uint32
+}
+
+// A cachedStructure holds the fields of a SoundStructure.
+type cachedStructure struct {
+	OverrideParentEffects byte
+	EffectCount           byte
+	Bypass                byte
+	Effects               []*Effect
+	Unknown               [10]byte
+	ParameterTypes        []byte
+	ParameterValues       [][4]byte
+}
+
+// A cachedEvent holds the fields of an EventObject.
+type cachedEvent struct {
+	ActionIds []uint32
+	VarCount  bool
+}
+
+// A cachedAction holds the fields of an ActionObject.
+type cachedAction struct {
+	ActionType uint16
+	TargetId   uint32
+}
+
+// OpenCached opens the SoundBank at path as Open does, but keeps the parsed
+// objects of its HIRC section in a cache file in cacheDir, named after the
+// SHA-256 hash of the SoundBank. Parsing the HIRC section of large SoundBanks
+// is slow, so opening the same, unchanged SoundBank again only has to hash it.
+// A cache file that cannot be read is ignored and rewritten.
+func OpenCached(path, cacheDir string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("hashing %s: %w", path, err)
+	}
+	cachePath := filepath.Join(cacheDir, hex.EncodeToString(h.Sum(nil))+cacheExt)
+
+	hirc := readHierarchyCache(cachePath)
+	bnk, err := newFile(f, hirc)
+	if err != nil && hirc != nil {
+		// The cache does not match the SoundBank after all.
+		hirc = nil
+		bnk, err = newFile(f, nil)
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	bnk.closer = f
+
+	if hirc == nil && bnk.ObjectSection != nil {
+		if err := writeHierarchyCache(cachePath, bnk.ObjectSection); err != nil {
+			bnk.Close()
+			return nil, fmt.Errorf("writing cache: %w", err)
+		}
+	}
+	return bnk, nil
+}
+
+// readHierarchyCache reads the cache file at path, returning nil if it does not
+// exist or cannot be read.
+func readHierarchyCache(path string) *cachedHierarchy {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	hirc := new(cachedHierarchy)
+	if err := gob.NewDecoder(f).Decode(hirc); err != nil || hirc.Version != cacheVersion {
+		return nil
+	}
+	return hirc
+}
+
+// writeHierarchyCache writes the objects of hrc, as they were parsed, to the
+// cache file at path. The file is written under a temporary name and then
+// renamed, so that a cache file is never partly written.
+func writeHierarchyCache(path string, hrc *ObjectHierarchySection) error {
+	hirc := &cachedHierarchy{Version: cacheVersion}
+	offset := hrc.dataOffset
+	for _, obj := range hrc.objects {
+		desc := descriptorOf(obj)
+		// The length of the descriptor includes the object ID.
+		dataOffset := offset + OBJECT_DESCRIPTOR_BYTES
+		end := dataOffset + int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+		c := &cachedObject{Descriptor: *desc, Offset: dataOffset, Length: end - dataOffset}
+		switch o := obj.(type) {
+		case *SfxVoiceSoundObject:
+			c.Sound = &cachedSound{*o.Unknown, o.WemDescriptor, o.Type,
+				cacheStructure(o.Structure), o.FileInfo}
+			c.Offset = dataOffset + SFX_UNKNOWN_BYTES + OPTIONAL_WEM_DESCRIPTOR_BYTES +
+				int64(len(o.FileInfo))*4 + 1 + o.Structure.knownBytes()
+		case *ContainerObject:
+			s := cacheStructure(o.Structure)
+			c.Container = &s
+			c.Offset = dataOffset + o.Structure.knownBytes()
+		case *EventObject:
+			c.Event = &cachedEvent{o.ActionIds, o.varCount}
+		case *ActionObject:
+			c.Action = &cachedAction{o.ActionType, o.TargetId}
+			c.Offset = dataOffset + ACTION_KNOWN_BYTES
+		}
+		c.Length = end - c.Offset
+		hirc.Objects = append(hirc.Objects, c)
+		offset = end
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(tmp).Encode(hirc); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// cacheStructure returns the fields of ss to store in a cache file.
+func cacheStructure(ss *SoundStructure) cachedStructure {
+	return cachedStructure{
+		OverrideParentEffects: ss.OverrideParentEffects,
+		EffectCount:           ss.EffectContainer.EffectCount,
+		Bypass:                ss.EffectContainer.Bypass,
+		Effects:               ss.EffectContainer.Effects,
+		Unknown:               *ss.Unknown,
+		ParameterTypes:        ss.ParameterTypes,
+		ParameterValues:       ss.ParameterValues,
+	}
+}
+
+// soundStructure returns the SoundStructure whose fields are stored in c, whose
+// remaining data is read from r.
+func (c *cachedStructure) soundStructure(r io.Reader) *SoundStructure {
+	unknown := c.Unknown
+	loops, loopCount := loopParameter(c.ParameterTypes, c.ParameterValues)
+	return &SoundStructure{
+		OverrideParentEffects: c.OverrideParentEffects,
+		EffectContainer:       &EffectContainer{c.EffectCount, c.Bypass, c.Effects},
+		Unknown:               &unknown,
+		ParameterCount:        byte(len(c.ParameterTypes)),
+		ParameterTypes:        c.ParameterTypes,
+		ParameterValues:       c.ParameterValues,
+		loops:                 loops,
+		loopCount:             loopCount,
+		RemainingReader:       r,
+	}
+}
+
+// knownBytes returns the number of bytes this SoundStructure takes before its
+// remaining data.
+func (ss *SoundStructure) knownBytes() int64 {
+	n := int64(OVERRIDE_EFFECTS_BYTES + 1)
+	if ss.EffectContainer.EffectCount > 0 {
+		n += 1 + int64(len(ss.EffectContainer.Effects))*EFFECT_BYTES
+	}
+	n += STRUCTURE_UNKNOWN_BYTES + PARAMETER_TYPE_BYTES
+	return n + int64(ss.ParameterCount)*(PARAMETER_TYPE_BYTES+PARAMETER_VALUE_BYTES)
+}
+
+// newCachedHierarchySection creates a new ObjectHierarchySection from the
+// objects of hirc, reading the data they hold lazily from sr, which must be
+// seeked to the start of the HIRC section data.
+func (hdr *SectionHeader) newCachedHierarchySection(sr util.ReadSeekerAt,
+	hirc *cachedHierarchy) (*ObjectHierarchySection, error) {
+	start, _ := sr.Seek(0, io.SeekCurrent)
+	sec := new(ObjectHierarchySection)
+	sec.Header = hdr
+	sec.loopOf = make(map[uint32]uint32)
+	sec.wemToObject = make(map[uint32]*SfxVoiceSoundObject)
+	sec.ObjectCount = uint32(len(hirc.Objects))
+	sec.dataOffset = start + OBJECT_COUNT_BYTES
+
+	var count [OBJECT_COUNT_BYTES]byte
+	if _, err := sr.ReadAt(count[:], start); err != nil {
+		return nil, err
+	}
+	if n := binary.LittleEndian.Uint32(count[:]); n != sec.ObjectCount {
+		return nil, fmt.Errorf("the cache holds %d objects, but the HIRC section %d",
+			sec.ObjectCount, n)
+	}
+
+	for _, c := range hirc.Objects {
+		desc := c.Descriptor
+		r := util.NewResettingReader(sr, c.Offset, c.Length)
+		switch {
+		case c.Sound != nil:
+			s := c.Sound
+			unknown := s.Unknown
+			sec.addSound(&SfxVoiceSoundObject{&desc, &unknown, s.WemDescriptor, s.Type,
+				s.Structure.soundStructure(r), s.FileInfo})
+		case c.Container != nil:
+			sec.objects = append(sec.objects,
+				&ContainerObject{&desc, c.Container.soundStructure(r)})
+		case c.Event != nil:
+			sec.objects = append(sec.objects,
+				&EventObject{&desc, c.Event.ActionIds, c.Event.VarCount})
+		case c.Action != nil:
+			sec.objects = append(sec.objects,
+				&ActionObject{&desc, c.Action.ActionType, c.Action.TargetId, r})
+		default:
+			sec.objects = append(sec.objects, &UnknownObject{&desc, r})
+		}
+	}
+
+	sr.Seek(int64(hdr.Length), io.SeekCurrent)
+	return sec, nil
+}
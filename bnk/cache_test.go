@@ -0,0 +1,114 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// cachePathOf returns the path of the single cache file in dir.
+func cachePathOf(t *testing.T, dir string) string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*"+cacheExt))
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("found the cache files %v (%v), want one", matches, err)
+	}
+	return matches[0]
+}
+
+// openCached opens the SoundBank at path with OpenCached and checks that it is
+// written as want, and that its objects and hierarchy are those of org.
+func openCached(t *testing.T, path, cacheDir string, org *File, want []byte) *File {
+	t.Helper()
+	bnk, err := OpenCached(path, cacheDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bnk.Close() })
+	assertWritesBank(t, bnk, want)
+	if got, want := objectsOf(bnk), objectsOf(org); !reflect.DeepEqual(got, want) {
+		t.Errorf("opened the objects %v, want %v", got, want)
+	}
+	got, err := bnk.MarshalHierarchy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := org.MarshalHierarchy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(doc) {
+		t.Errorf("the hierarchy is opened as\n%s, want\n%s", got, doc)
+	}
+	return bnk
+}
+
+func TestOpenCached(t *testing.T) {
+	for _, version := range []uint32{112, 132} {
+		data := buildBank(version, layoutOf(version), true)
+		path := writeBank(t, data)
+		org := openBank(t, data)
+		cacheDir := filepath.Join(t.TempDir(), "cache")
+
+		openCached(t, path, cacheDir, org, data)
+		cachePath := cachePathOf(t, cacheDir)
+		hirc := readHierarchyCache(cachePath)
+		if hirc == nil || len(hirc.Objects) != len(org.Objects()) {
+			t.Fatalf("version %d: the cache file holds %+v", version, hirc)
+		}
+		// The second time, the objects are read from the cache.
+		bnk := openCached(t, path, cacheDir, org, data)
+		if loop := bnk.LoopOf(0); loop != (LoopValue{true, 3}) {
+			t.Errorf("version %d: the first wem loops as %+v", version, loop)
+		}
+		if got, want := bnk.EventWems(), org.EventWems(); !reflect.DeepEqual(got, want) {
+			t.Errorf("version %d: the events reference the wems %v, want %v", version, got, want)
+		}
+
+		// A cache file that cannot be read is rewritten.
+		if err := os.WriteFile(cachePath, []byte("garbage"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		openCached(t, path, cacheDir, org, data)
+		if readHierarchyCache(cachePath) == nil {
+			t.Errorf("version %d: the unreadable cache file was not rewritten", version)
+		}
+	}
+}
+
+func TestOpenCachedMismatch(t *testing.T) {
+	cacheDir := t.TempDir()
+	path := filepath.Join(testDir, complexSoundBank)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	org, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer org.Close()
+	openCached(t, path, cacheDir, org, data)
+	complexCache, err := os.ReadFile(cachePathOf(t, cacheDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A cache file of another SoundBank stored under the name of this one is
+	// ignored and rewritten.
+	data = buildBank(132, layout132, true)
+	path = writeBank(t, data)
+	cacheDir = t.TempDir()
+	org = openBank(t, data)
+	openCached(t, path, cacheDir, org, data)
+	cachePath := cachePathOf(t, cacheDir)
+	if err := os.WriteFile(cachePath, complexCache, 0644); err != nil {
+		t.Fatal(err)
+	}
+	openCached(t, path, cacheDir, org, data)
+	if hirc := readHierarchyCache(cachePath); hirc == nil || len(hirc.Objects) != 10 {
+		t.Errorf("the cache file of another SoundBank was not rewritten")
+	}
+}
@@ -0,0 +1,284 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"strings"
+)
+
+// The ways a HIRC object can differ between two SoundBanks.
+const (
+	ObjectAdded   = "added"
+	ObjectRemoved = "removed"
+	ObjectChanged = "changed"
+)
+
+// objectTypeNames are the names of the known HIRC object types.
+var objectTypeNames = map[byte]string{
+	0x01: "State",
+	0x02: "Sound",
+	0x03: "Action",
+	0x04: "Event",
+	0x05: "Random/Sequence Container",
+	0x06: "Switch Container",
+	0x07: "Actor-Mixer",
+	0x08: "Audio Bus",
+	0x09: "Blend Container",
+	0x0A: "Music Segment",
+	0x0B: "Music Track",
+	0x0C: "Music Switch Container",
+	0x0D: "Music Playlist Container",
+	0x0E: "Attenuation",
+	0x0F: "Dialogue Event",
+	0x10: "Motion Bus",
+	0x11: "Motion FX",
+	0x12: "Effect",
+	0x13: "Source",
+	0x14: "Auxiliary Bus",
+}
+
+// ObjectTypeName returns the name of the HIRC object type t.
+func ObjectTypeName(t byte) string {
+	if name, ok := objectTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Unknown (0x%02X)", t)
+}
+
+// An ObjectChange describes how a HIRC object differs between two SoundBanks.
+type ObjectChange struct {
+	// One of ObjectAdded, ObjectRemoved or ObjectChanged.
+	Kind string
+	Id   uint32
+	Type byte
+	// The properties that differ, if the object was changed.
+	Properties []*PropertyChange
+}
+
+// A PropertyChange describes a property of a HIRC object whose value differs
+// between two SoundBanks. The value of a property that is only present in one
+// of the SoundBanks is empty in the other.
+type PropertyChange struct {
+	Name     string
+	Old, New string
+}
+
+func (c *ObjectChange) String() string {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "%-8s %-25s %d (0x%08X)\n", c.Kind, ObjectTypeName(c.Type), c.Id, c.Id)
+	for _, p := range c.Properties {
+		fmt.Fprintf(b, "         %s: %s -> %s\n", p.Name, valueOrNone(p.Old), valueOrNone(p.New))
+	}
+	return b.String()
+}
+
+func valueOrNone(v string) string {
+	if v == "" {
+		return "(none)"
+	}
+	return v
+}
+
+// DiffObjects compares the HIRC objects of two SoundBanks, and returns the
+// objects that were added to, removed from or changed in new relative to old.
+// Objects are matched by their ID. Added and changed objects are returned in
+// the order they appear in new, followed by removed objects in the order they
+// appear in old.
+func DiffObjects(old, new *File) ([]*ObjectChange, error) {
+	oldObjects, err := objectsById(old)
+	if err != nil {
+		return nil, err
+	}
+	newObjects, err := objectsById(new)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*ObjectChange
+	for _, n := range newObjects.order {
+		desc := descriptorOf(n)
+		o, ok := oldObjects.byId[desc.ObjectId]
+		if !ok {
+			changes = append(changes, &ObjectChange{ObjectAdded, desc.ObjectId, desc.Type, nil})
+			continue
+		}
+		props, err := diffProperties(o, n)
+		if err != nil {
+			return nil, fmt.Errorf("comparing object %d: %w", desc.ObjectId, err)
+		}
+		if len(props) > 0 {
+			changes = append(changes, &ObjectChange{ObjectChanged, desc.ObjectId, desc.Type, props})
+		}
+	}
+	for _, o := range oldObjects.order {
+		desc := descriptorOf(o)
+		if _, ok := newObjects.byId[desc.ObjectId]; !ok {
+			changes = append(changes, &ObjectChange{ObjectRemoved, desc.ObjectId, desc.Type, nil})
+		}
+	}
+	return changes, nil
+}
+
+// objectIndex holds the HIRC objects of a SoundBank.
+type objectIndex struct {
+	order []Object
+	byId  map[uint32]Object
+}
+
+func objectsById(bnk *File) (*objectIndex, error) {
+	idx := &objectIndex{byId: make(map[uint32]Object)}
+	if bnk.ObjectSection == nil {
+		return idx, nil
+	}
+	for _, obj := range bnk.ObjectSection.Objects() {
+		id := descriptorOf(obj).ObjectId
+		if _, ok := idx.byId[id]; ok {
+			return nil, fmt.Errorf("duplicate HIRC object ID %d", id)
+		}
+		idx.order = append(idx.order, obj)
+		idx.byId[id] = obj
+	}
+	return idx, nil
+}
+
+// descriptorOf returns the descriptor of obj.
+func descriptorOf(obj Object) *ObjectDescriptor {
+	switch o := obj.(type) {
+	case *SfxVoiceSoundObject:
+		return o.Descriptor
+	case *ContainerObject:
+		return o.Descriptor
+	case *EventObject:
+		return o.Descriptor
+	case *ActionObject:
+		return o.Descriptor
+	case *UnknownObject:
+		return o.Descriptor
+	}
+	panic(fmt.Sprintf("unexpected HIRC object %T", obj))
+}
+
+// A property is a named value of a HIRC object.
+type property struct {
+	name, value string
+}
+
+// diffProperties returns the properties that differ between o and n.
+func diffProperties(o, n Object) ([]*PropertyChange, error) {
+	oldProps, err := propertiesOf(o)
+	if err != nil {
+		return nil, err
+	}
+	newProps, err := propertiesOf(n)
+	if err != nil {
+		return nil, err
+	}
+
+	oldValues := make(map[string]string)
+	for _, p := range oldProps {
+		oldValues[p.name] = p.value
+	}
+	var changes []*PropertyChange
+	seen := make(map[string]bool)
+	for _, p := range newProps {
+		seen[p.name] = true
+		if old, ok := oldValues[p.name]; !ok || old != p.value {
+			changes = append(changes, &PropertyChange{p.name, old, p.value})
+		}
+	}
+	for _, p := range oldProps {
+		if !seen[p.name] {
+			changes = append(changes, &PropertyChange{p.name, p.value, ""})
+		}
+	}
+	return changes, nil
+}
+
+// propertiesOf returns the properties of obj that are compared when diffing.
+// The parts of an object whose meaning is unknown are compared as a whole.
+func propertiesOf(obj Object) ([]property, error) {
+	desc := descriptorOf(obj)
+	props := []property{{"type", ObjectTypeName(desc.Type)}}
+
+	switch o := obj.(type) {
+	case *SfxVoiceSoundObject:
+		props = append(props,
+			property{"wem id", fmt.Sprint(o.WemDescriptor.WemId)},
+			property{"wem length", fmt.Sprint(o.WemDescriptor.WemLength)},
+			property{"sound type", fmt.Sprintf("0x%02X", o.Type)},
+			property{"unknown", fmt.Sprintf("% X", o.Unknown[:])})
+		return appendStructureProperties(props, o.Structure)
+	case *ContainerObject:
+		return appendStructureProperties(props, o.Structure)
+	case *EventObject:
+		for i, id := range o.ActionIds {
+			props = append(props, property{fmt.Sprintf("action %d", i), fmt.Sprint(id)})
+		}
+	case *ActionObject:
+		props = append(props,
+			property{"action type", fmt.Sprintf("0x%04X", o.ActionType)},
+			property{"target id", fmt.Sprint(o.TargetId)})
+		data, err := io.ReadAll(o.RemainingReader)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, property{"remaining data", dataSummary(data)})
+	case *UnknownObject:
+		data, err := io.ReadAll(o.Reader)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, property{"data", dataSummary(data)})
+	}
+	return props, nil
+}
+
+// appendStructureProperties appends the properties of ss to props.
+func appendStructureProperties(props []property, ss *SoundStructure) ([]property, error) {
+	props = append(props,
+		property{"override parent effects", fmt.Sprint(ss.OverrideParentEffects)},
+		property{"structure unknown", fmt.Sprintf("% X", ss.Unknown[:])})
+	if ss.EffectContainer.EffectCount > 0 {
+		props = append(props, property{"effect bypass", fmt.Sprintf("0x%02X", ss.EffectContainer.Bypass)})
+	}
+	for _, e := range ss.EffectContainer.Effects {
+		props = append(props, property{fmt.Sprintf("effect %d", e.Index), fmt.Sprint(e.Id)})
+	}
+	for i, t := range ss.ParameterTypes {
+		props = append(props, property{fmt.Sprintf("parameter 0x%02X", t),
+			parameterValue(t, ss.ParameterValues[i])})
+	}
+	data, err := io.ReadAll(ss.RemainingReader)
+	if err != nil {
+		return nil, err
+	}
+	return append(props, property{"remaining data", dataSummary(data)}), nil
+}
+
+// parameterValue formats the value of a sound structure parameter of type t.
+// Loop counts are integers; other parameters are floating point numbers.
+func parameterValue(t byte, v [4]byte) string {
+	bits := binary.LittleEndian.Uint32(v[:])
+	if t == parameterLoopType {
+		if bits == InfiniteLoops {
+			return "infinite loops"
+		}
+		return fmt.Sprintf("%d loops", bits)
+	}
+	return fmt.Sprintf("%g", math.Float32frombits(bits))
+}
+
+// dataSummary describes data of unknown meaning in a way that differs when the
+// data differs.
+func dataSummary(data []byte) string {
+	const shown = 16
+	if len(data) <= shown {
+		return fmt.Sprintf("%d bytes [% X]", len(data), data)
+	}
+	return fmt.Sprintf("%d bytes [% X ...] crc32 0x%08X", len(data), data[:shown],
+		crc32.ChecksumIEEE(data))
+}
@@ -22,6 +22,10 @@ const wemAlignmentBytes = 16
 // A LoopValue identifier for looping infinite times.
 const InfiniteLoops = 0
 
+// ErrNoWems is returned when opening a SoundBank that embeds no wems, such as
+// one whose audio is all streamed.
+var ErrNoWems = errors.New("There are no wems stored within this file.")
+
 // A File represents an open Wwise SoundBank.
 type File struct {
 	closer io.Closer
@@ -32,6 +36,9 @@ type File struct {
 	IndexSection      *DataIndexSection
 	DataSection       *DataSection
 	ObjectSection     *ObjectHierarchySection
+	// The STID section, naming the SoundBanks referenced by this one, or nil if
+	// the SoundBank has none.
+	StringTableSection *StringTableSection
 }
 
 // LoopValue describes the loop parameters of a given audio object.
@@ -46,6 +53,12 @@ type LoopValue struct {
 // NewFile creates a new File for access Wwise SoundBank files. The file is
 // expected to start at position 0 in the io.ReaderAt.
 func NewFile(r io.ReaderAt) (*File, error) {
+	return newFile(r, nil)
+}
+
+// newFile is NewFile, taking the objects of the HIRC section from hirc rather
+// than parsing them if hirc is not nil.
+func newFile(r io.ReaderAt, hirc *cachedHierarchy) (*File, error) {
 	bnk := new(File)
 
 	sr := util.NewResettingReader(r, 0, math.MaxInt64)
@@ -82,12 +95,25 @@ func NewFile(r io.ReaderAt) (*File, error) {
 			bnk.DataSection = sec
 			bnk.sections = append(bnk.sections, sec)
 		case hircHeaderId:
-			sec, err := hdr.NewObjectHierarchySection(sr)
+			var sec *ObjectHierarchySection
+			var err error
+			if hirc != nil {
+				sec, err = hdr.newCachedHierarchySection(sr, hirc)
+			} else {
+				sec, err = hdr.newObjectHierarchySection(sr, bnk.layout())
+			}
 			if err != nil {
 				return nil, err
 			}
 			bnk.ObjectSection = sec
 			bnk.sections = append(bnk.sections, sec)
+		case stidHeaderId:
+			sec, err := hdr.NewStringTableSection(sr)
+			if err != nil {
+				return nil, err
+			}
+			bnk.StringTableSection = sec
+			bnk.sections = append(bnk.sections, sec)
 		default:
 			sec, err := hdr.NewUnknownSection(sr)
 			if err != nil {
@@ -98,13 +124,21 @@ func NewFile(r io.ReaderAt) (*File, error) {
 	}
 
 	if bnk.DataSection == nil || len(bnk.Wems()) == 0 {
-		return nil, errors.New("There are no wems stored within this file.")
+		return nil, ErrNoWems
+	}
+	if hdr := bnk.BankHeaderSection; hdr != nil {
+		hdr.name, _ = bnk.BankName(hdr.Descriptor.BankId)
 	}
 
 	return bnk, nil
 }
 
 // WriteTo writes the full contents of this File to the Writer specified by w.
+// Each section is written from what was read of it, updated with the changes
+// made through the File, so the sections left untouched, including those of
+// unknown types, are written byte for byte as they were read, and a File that
+// was not modified is written as an exact copy of the SoundBank it was read
+// from.
 func (bnk *File) WriteTo(w io.Writer) (written int64, err error) {
 	for _, s := range bnk.sections {
 		n, err := s.WriteTo(w)
@@ -116,6 +150,41 @@ func (bnk *File) WriteTo(w io.Writer) (written int64, err error) {
 	return
 }
 
+// Save writes the full contents of this File, as WriteTo does, to the file at
+// path. The SoundBank is written to a temporary file next to path, which only
+// replaces any file at path once fully written, so that a failed save leaves
+// it as it was. path may be the file this File was opened from, in which case
+// the File is closed before the file is replaced, as some systems require, and
+// cannot be used afterwards.
+func (bnk *File) Save(path string) error {
+	out, err := util.CreateAtomic(path)
+	if err != nil {
+		return err
+	}
+	defer out.Abort()
+	if _, err := bnk.WriteTo(out); err != nil {
+		return err
+	}
+	if bnk.isFile(path) {
+		bnk.Close()
+	}
+	return out.Commit()
+}
+
+// isFile reports whether bnk was opened from the file at path.
+func (bnk *File) isFile(path string) bool {
+	f, ok := bnk.closer.(*os.File)
+	if !ok {
+		return false
+	}
+	opened, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && os.SameFile(opened, info)
+}
+
 // Open opens the File at the specified path using os.Open and prepares it for
 // use as a Wwise SoundBank file.
 func Open(path string) (*File, error) {
@@ -160,10 +229,86 @@ func (bnk *File) ReplaceWems(rs ...*wwise.ReplacementWem) {
 	}
 }
 
+// RemoveWems removes the wems with the given IDs from the DIDX and DATA
+// sections of this File, along with their padding. The wems that follow are
+// moved back, keeping their alignment. If removeObjects is true, the sound
+// objects playing the removed wems are also removed from the HIRC section,
+// along with the actions targeting them, which are removed from the events
+// running them. Containers still list removed sounds among their children,
+// since their lists of children are not parsed, and no objects are removed
+// from SoundBanks of unsupported versions, see CheckVersion. It is an error
+// for an ID not to be one of a wem of this File, or for every wem to be
+// removed, since a SoundBank without wems cannot be opened; the File is then
+// left unchanged.
+func (bnk *File) RemoveWems(ids []uint32, removeObjects bool) error {
+	removed := make(map[uint32]bool)
+	for _, id := range ids {
+		if _, ok := bnk.IndexSection.DescriptorMap[id]; !ok {
+			return fmt.Errorf("there is no wem with ID %d", id)
+		}
+		removed[id] = true
+	}
+	if len(removed) == len(bnk.DataSection.Wems) {
+		return errors.New("every wem would be removed")
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	// Each wem and its padding end where the next wem starts, so the wems that
+	// follow a removed wem are moved back by a multiple of the alignment.
+	var wems []*wwise.Wem
+	var shift uint32
+	lastPadding := bnk.DataSection.Wems[len(bnk.DataSection.Wems)-1].Padding.Size()
+	for _, wem := range bnk.DataSection.Wems {
+		desc := wem.Descriptor
+		if removed[desc.WemId] {
+			shift += desc.Length + uint32(wem.Padding.Size())
+			delete(bnk.IndexSection.DescriptorMap, desc.WemId)
+			continue
+		}
+		desc.Offset -= shift
+		wems = append(wems, wem)
+	}
+	// The last wem is only followed by the padding that ended the section.
+	last := wems[len(wems)-1]
+	if last.Padding.Size() != lastPadding {
+		last.Padding = util.NewResettingReader(&util.InfiniteReaderAt{Value: 0}, 0, lastPadding)
+	}
+	bnk.DataSection.Wems = wems
+	bnk.DataSection.Header.Length = last.Descriptor.Offset + last.Descriptor.Length +
+		uint32(lastPadding)
+
+	idx := bnk.IndexSection
+	idx.WemIds = idx.WemIds[:0]
+	for _, wem := range wems {
+		idx.WemIds = append(idx.WemIds, wem.Descriptor.WemId)
+	}
+	idx.WemCount = len(idx.WemIds)
+	idx.Header.Length = uint32(idx.WemCount * DIDX_ENTRY_BYTES)
+
+	if removeObjects && bnk.ObjectSection != nil {
+		bnk.ObjectSection.removeSoundsOf(removed)
+	}
+	return nil
+}
+
 func (bnk *File) DataStart() uint32 {
 	return bnk.DataSection.DataStart
 }
 
+// BankName returns the name of the SoundBank with the given ID, as recorded in
+// the STID section of this File, and whether it is recorded. The STID section
+// names this SoundBank and the SoundBanks its objects refer to, such as the
+// SoundBanks Play actions load media from.
+func (bnk *File) BankName(id uint32) (string, bool) {
+	if bnk.StringTableSection == nil {
+		return "", false
+	}
+	name, ok := bnk.StringTableSection.Names[id]
+	return name, ok
+}
+
 // LoopOf returns the loop value of the wem stored in this SoundBank at index i.
 // Returns a default LoopValue{false, 0} if the index is invalid.
 func (bnk *File) LoopOf(i int) LoopValue {
@@ -270,11 +415,13 @@ func (bnk *File) String() string {
 		b.WriteString(sec.String())
 	}
 
-	tableParams := []string{"%-7", "%-15", "%-15", "%-15", "%-8", "%-12", "\n"}
+	tableParams := []string{"%-7", "%-15", "%-12", "%-15", "%-15", "%-8", "%-12", "\n"}
 	titleFmt := strings.Join(tableParams, "s|")
 	wemFmt := strings.Join(tableParams, "d|")
+	// Show the hex form of the ID as a string, padded to the column width.
+	wemFmt = strings.Replace(wemFmt, "%-12d", "0x%08X  ", 1)
 	title := fmt.Sprintf(titleFmt,
-		"Index", "Id", "Offset", "Length", "Padding", "Loop (0=Inf)")
+		"Index", "Id", "Id (hex)", "Offset", "Length", "Padding", "Loop (0=Inf)")
 	fmt.Fprint(b, title)
 	fmt.Fprintln(b, strings.Repeat("-", len(title)-1))
 
@@ -286,7 +433,7 @@ func (bnk *File) String() string {
 			loop = int(l.Value)
 		}
 
-		fmt.Fprintf(b, wemFmt, i+1, desc.WemId, desc.Offset, desc.Length,
+		fmt.Fprintf(b, wemFmt, i+1, desc.WemId, desc.WemId, desc.Offset, desc.Length,
 			wem.Padding.Size(), loop)
 	}
 
@@ -0,0 +1,199 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeBank writes data to a file of a new temporary directory and returns
+// its path.
+func writeBank(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.bnk")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// wemsOf returns the data of the wems of bnk, by ID, and checks that they are
+// aligned and that the DATA section holds them exactly.
+func wemsOf(t *testing.T, bnk *File) map[uint32][]byte {
+	t.Helper()
+	wems := make(map[uint32][]byte)
+	var length int64
+	for i, wem := range bnk.Wems() {
+		desc := wem.Descriptor
+		if desc.Offset%wemAlignmentBytes != 0 {
+			t.Errorf("wem %d is stored at offset %d", desc.WemId, desc.Offset)
+		}
+		if id := bnk.IndexSection.WemIds[i]; id != desc.WemId {
+			t.Errorf("wem %d is indexed as wem %d", desc.WemId, id)
+		}
+		data, err := io.ReadAll(wem)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wems[desc.WemId] = data
+		length = int64(desc.Offset) + int64(desc.Length) + wem.Padding.Size()
+	}
+	if got := int64(bnk.DataSection.Header.Length); got != length {
+		t.Errorf("the DATA section is %d bytes long, but its wems end at %d", got, length)
+	}
+	return wems
+}
+
+func TestSave(t *testing.T) {
+	data := buildBank(132, layout132, true)
+	path := writeBank(t, data)
+	bnk, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copied := filepath.Join(filepath.Dir(path), "copy.bnk")
+	if err := bnk.Save(copied); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(copied); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("the unchanged SoundBank is saved as %q (%v)", got, err)
+	}
+
+	// Saving over the file the SoundBank was opened from closes it.
+	bnk.ReplaceLoopOf(0, LoopValue{true, 7})
+	if err := bnk.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if bnk.closer != nil {
+		t.Error("the SoundBank saved over its file is still open")
+	}
+	saved, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer saved.Close()
+	if loop := saved.LoopOf(0); loop != (LoopValue{true, 7}) {
+		t.Errorf("the first wem of the saved SoundBank loops as %+v", loop)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 2 {
+		t.Errorf("saving left %d files in the directory, want 2", len(entries))
+	}
+}
+
+func TestRemoveWems(t *testing.T) {
+	data := buildBank(132, layout132, true)
+	for _, tt := range []struct {
+		ids           []uint32
+		removeObjects bool
+		// The objects left, by type, if objects are removed.
+		objects map[string][]uint32
+		events  map[uint32][]uint32
+	}{
+		{[]uint32{2}, true, map[string][]uint32{
+			"ContainerObject":     {500},
+			"SfxVoiceSoundObject": {1001, 1003, 1004},
+			"ActionObject":        {600, 601},
+			"EventObject":         {700, 701},
+		}, map[uint32][]uint32{700: {1, 3}, 701: {3}}},
+		{[]uint32{3, 1}, true, map[string][]uint32{
+			"ContainerObject":     {500},
+			"SfxVoiceSoundObject": {1002, 1004},
+			"ActionObject":        {600, 602},
+			"EventObject":         {700, 701},
+		}, map[uint32][]uint32{700: {2}, 701: {2}}},
+		{[]uint32{3}, false, nil, nil},
+	} {
+		path := writeBank(t, data)
+		bnk, err := Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bnk.RemoveWems(tt.ids, tt.removeObjects); err != nil {
+			t.Fatalf("removing wems %v: %v", tt.ids, err)
+		}
+		if err := bnk.Save(path); err != nil {
+			t.Fatal(err)
+		}
+		saved, err := Open(path)
+		if err != nil {
+			t.Fatalf("reopening the SoundBank without wems %v: %v", tt.ids, err)
+		}
+
+		want := make(map[uint32][]byte)
+		for id, wem := range testWems {
+			want[id] = wem
+		}
+		for _, id := range tt.ids {
+			delete(want, id)
+		}
+		if got := wemsOf(t, saved); !reflect.DeepEqual(got, want) {
+			t.Errorf("removing wems %v left the wems %q, want %q", tt.ids, got, want)
+		}
+		if tt.objects == nil {
+			if n := len(saved.Objects()); n != 10 {
+				t.Errorf("removing wems %v without their objects left %d objects", tt.ids, n)
+			}
+		} else {
+			if got := objectsOf(saved); !reflect.DeepEqual(got, tt.objects) {
+				t.Errorf("removing wems %v left the objects %v, want %v", tt.ids, got, tt.objects)
+			}
+			if got := saved.EventWems(); !reflect.DeepEqual(got, tt.events) {
+				t.Errorf("removing wems %v left events referencing %v, want %v", tt.ids, got,
+					tt.events)
+			}
+		}
+		if name, ok := saved.BankName(testBankId); !ok || name != "test" {
+			t.Errorf("the saved SoundBank is named %q", name)
+		}
+		saved.Close()
+	}
+
+	bnk := openBank(t, data)
+	if err := bnk.RemoveWems([]uint32{1, 9}, true); err == nil {
+		t.Error("removed a wem that is not in the SoundBank")
+	}
+	if err := bnk.RemoveWems([]uint32{1, 2, 3}, true); err == nil {
+		t.Error("removed every wem of the SoundBank")
+	}
+	assertWritesBank(t, bnk, data)
+}
+
+func TestRemoveWemsOfTestdata(t *testing.T) {
+	path := filepath.Join(testDir, complexSoundBank)
+	org, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer org.Close()
+	want := wemsOf(t, org)
+	first := org.Wems()[0].Descriptor.WemId
+	delete(want, first)
+
+	bnk, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bnk.Close()
+	if err := bnk.RemoveWems([]uint32{first}, true); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), complexSoundBank)
+	if err := bnk.Save(out); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer saved.Close()
+	if got := wemsOf(t, saved); !reflect.DeepEqual(got, want) {
+		t.Errorf("removing wem %d left %d wems, want %d", first, len(got), len(want))
+	}
+	if _, ok := saved.ObjectSection.wemToObject[first]; ok {
+		t.Errorf("the sound of wem %d was kept", first)
+	}
+}
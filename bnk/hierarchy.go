@@ -0,0 +1,98 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"encoding/binary"
+	"sort"
+)
+
+// Objects returns the objects of the HIRC section of this File, in the order
+// they are stored, or nil if it has no HIRC section. Sounds are
+// SfxVoiceSoundObjects, events EventObjects, actions ActionObjects, and
+// Random/Sequence, Switch and Blend containers and Actor-Mixers are
+// ContainerObjects. Objects of other types, or that could not be parsed as
+// their type, are UnknownObjects, as are all objects of SoundBanks of
+// unsupported versions, see CheckVersion.
+func (bnk *File) Objects() []Object {
+	if bnk.ObjectSection == nil {
+		return nil
+	}
+	return bnk.ObjectSection.Objects()
+}
+
+// ParentOf returns the ID of the parent of obj in the actor-mixer hierarchy,
+// the container or Actor-Mixer it belongs to, or 0 if it has none. ok is false
+// if obj is neither a sound nor a container.
+func (bnk *File) ParentOf(obj Object) (id uint32, ok bool) {
+	var ss *SoundStructure
+	switch o := obj.(type) {
+	case *SfxVoiceSoundObject:
+		ss = o.Structure
+	case *ContainerObject:
+		ss = o.Structure
+	default:
+		return 0, false
+	}
+	// The ID of the output bus precedes the ID of the parent.
+	offset := 4
+	if layout := bnk.layout(); layout == nil || layout.attachmentParams {
+		offset = 5
+	}
+	return binary.LittleEndian.Uint32(ss.Unknown[offset:]), true
+}
+
+// EventWems returns the IDs of the wems referenced by each event of this File,
+// by event ID. The wems of an event are those of the sounds targeted by its
+// actions, whatever they do, and of the sounds under the containers they
+// target, in increasing order. Only the objects of this File are followed, so
+// events targeting objects of other SoundBanks may reference no wems.
+func (bnk *File) EventWems() map[uint32][]uint32 {
+	byId := make(map[uint32]Object)
+	children := make(map[uint32][]Object)
+	for _, obj := range bnk.Objects() {
+		byId[descriptorOf(obj).ObjectId] = obj
+		if parent, ok := bnk.ParentOf(obj); ok && parent != 0 {
+			children[parent] = append(children[parent], obj)
+		}
+	}
+
+	wems := make(map[uint32][]uint32)
+	for _, obj := range bnk.Objects() {
+		event, ok := obj.(*EventObject)
+		if !ok {
+			continue
+		}
+		visited := make(map[uint32]bool)
+		seen := make(map[uint32]bool)
+		var ids []uint32
+		var visit func(obj Object)
+		visit = func(obj Object) {
+			id := descriptorOf(obj).ObjectId
+			if visited[id] {
+				return
+			}
+			visited[id] = true
+			if sound, ok := obj.(*SfxVoiceSoundObject); ok {
+				if wem := sound.WemDescriptor.WemId; !seen[wem] {
+					seen[wem] = true
+					ids = append(ids, wem)
+				}
+			}
+			for _, child := range children[id] {
+				visit(child)
+			}
+		}
+		for _, actionId := range event.ActionIds {
+			action, ok := byId[actionId].(*ActionObject)
+			if !ok {
+				continue
+			}
+			if target, ok := byId[action.TargetId]; ok {
+				visit(target)
+			}
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		wems[event.Descriptor.ObjectId] = ids
+	}
+	return wems
+}
@@ -0,0 +1,342 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// A hierarchyDocument is the HIRC section of a SoundBank as a JSON document,
+// see MarshalHierarchy.
+type hierarchyDocument struct {
+	// The SoundBank the objects belong to, for reference only.
+	BankId   uint32             `json:"bank_id"`
+	BankName string             `json:"bank_name,omitempty"`
+	Version  uint32             `json:"version"`
+	Objects  []*hierarchyObject `json:"objects"`
+}
+
+// A hierarchyObject is a HIRC object in a hierarchyDocument. Only the fields
+// that apply to its type are set.
+type hierarchyObject struct {
+	Id       uint32 `json:"id"`
+	Type     byte   `json:"type"`
+	TypeName string `json:"type_name"`
+	// The parent of a sound or container, 0 if it has none, and the sounds and
+	// containers whose parent it is.
+	Parent   *uint32  `json:"parent,omitempty"`
+	Children []uint32 `json:"children,omitempty"`
+	// The wem played by a sound, and where it expects it to be stored.
+	WemId      *uint32 `json:"wem_id,omitempty"`
+	StreamType string  `json:"stream_type,omitempty"`
+	// The effects and parameters of a sound or container.
+	Effects    []*hierarchyEffect    `json:"effects,omitempty"`
+	Parameters []*hierarchyParameter `json:"parameters,omitempty"`
+	// The actions of an event, and the wems they reference, see EventWems.
+	Actions []uint32 `json:"actions,omitempty"`
+	Wems    []uint32 `json:"wems,omitempty"`
+	// The type of an action and the object it applies to, and the SoundBank a
+	// Play action loads its media from, named if the STID section names it.
+	ActionType *uint16 `json:"action_type,omitempty"`
+	Target     *uint32 `json:"target,omitempty"`
+	BankId     *uint32 `json:"bank_id,omitempty"`
+	BankName   string  `json:"bank_name,omitempty"`
+}
+
+// A hierarchyEffect is an effect of a sound or container in a
+// hierarchyDocument.
+type hierarchyEffect struct {
+	Index byte   `json:"index"`
+	Id    uint32 `json:"id"`
+}
+
+// A hierarchyParameter is a parameter of a sound or container in a
+// hierarchyDocument. Loop counts are integers, 0 meaning infinite loops; other
+// parameters, such as volumes, are floating point numbers.
+type hierarchyParameter struct {
+	Type  byte        `json:"type"`
+	Value json.Number `json:"value"`
+}
+
+// MarshalHierarchy returns the objects of the HIRC section of bnk as an
+// indented JSON document, so that external tools can analyse them: the ID and
+// type of every object, the parent and children of sounds and containers, the
+// wem of sounds, the actions of events with the wems they reference, the type
+// and target of actions and the SoundBank of Play actions, and the effects and
+// parameters of sounds and containers. SoundBanks are named as in the STID
+// section. Objects of other types are only listed by ID and type. The
+// document lists no objects if bnk has no HIRC section. It is an
+// *UnsupportedVersionError for bnk to be of a version whose HIRC objects are
+// not parsed.
+func (bnk *File) MarshalHierarchy() ([]byte, error) {
+	if err := bnk.CheckVersion(); err != nil {
+		return nil, err
+	}
+	doc := &hierarchyDocument{Objects: []*hierarchyObject{}}
+	if hdr := bnk.BankHeaderSection; hdr != nil {
+		doc.BankId, doc.Version = hdr.Descriptor.BankId, hdr.Descriptor.Version
+		doc.BankName, _ = bnk.BankName(doc.BankId)
+	}
+
+	children := make(map[uint32][]uint32)
+	for _, obj := range bnk.Objects() {
+		if parent, ok := bnk.ParentOf(obj); ok && parent != 0 {
+			children[parent] = append(children[parent], descriptorOf(obj).ObjectId)
+		}
+	}
+	eventWems := bnk.EventWems()
+
+	for _, obj := range bnk.Objects() {
+		desc := descriptorOf(obj)
+		o := &hierarchyObject{Id: desc.ObjectId, Type: desc.Type,
+			TypeName: ObjectTypeName(desc.Type)}
+		if parent, ok := bnk.ParentOf(obj); ok {
+			o.Parent = &parent
+			o.Children = children[desc.ObjectId]
+		}
+		switch obj := obj.(type) {
+		case *SfxVoiceSoundObject:
+			wem := obj.WemDescriptor.WemId
+			o.WemId, o.StreamType = &wem, obj.StreamType().String()
+			o.Effects, o.Parameters = structureDocument(obj.Structure)
+		case *ContainerObject:
+			o.Effects, o.Parameters = structureDocument(obj.Structure)
+		case *EventObject:
+			o.Actions, o.Wems = obj.ActionIds, eventWems[desc.ObjectId]
+		case *ActionObject:
+			actionType, target := obj.ActionType, obj.TargetId
+			o.ActionType, o.Target = &actionType, &target
+			if bank, ok := obj.BankId(); ok {
+				o.BankId = &bank
+				o.BankName, _ = bnk.BankName(bank)
+			}
+		}
+		doc.Objects = append(doc.Objects, o)
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// structureDocument returns the effects and parameters of ss, as listed in a
+// hierarchyDocument.
+func structureDocument(ss *SoundStructure) ([]*hierarchyEffect, []*hierarchyParameter) {
+	var effects []*hierarchyEffect
+	for _, e := range ss.EffectContainer.Effects {
+		effects = append(effects, &hierarchyEffect{e.Index, e.Id})
+	}
+	var params []*hierarchyParameter
+	for i, t := range ss.ParameterTypes {
+		params = append(params, &hierarchyParameter{t, parameterNumber(t, ss.ParameterValues[i])})
+	}
+	return effects, params
+}
+
+// parameterNumber formats the value of a sound structure parameter of type t as
+// a JSON number, see hierarchyParameter. Floating point numbers are written
+// with the fewest digits that read back as the same value, and values that are
+// not numbers as 0.
+func parameterNumber(t byte, v [4]byte) json.Number {
+	bits := binary.LittleEndian.Uint32(v[:])
+	if t == parameterLoopType {
+		return json.Number(strconv.FormatUint(uint64(bits), 10))
+	}
+	f := float64(math.Float32frombits(bits))
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "0"
+	}
+	return json.Number(strconv.FormatFloat(f, 'g', -1, 32))
+}
+
+// UnmarshalHierarchy applies the JSON document data, written by
+// MarshalHierarchy and edited, to the HIRC objects of bnk, so that WriteTo
+// writes the SoundBank with the edited objects. Objects are matched with the
+// objects of bnk by ID and must keep their type; objects missing from the
+// document are left as they are, and it is an error for the document to hold
+// objects bnk does not. The wem, effects and parameters of sounds, the effects
+// and parameters of containers, the actions of events, and the type and target
+// of actions can be edited, and the lengths of the edited objects and of the
+// HIRC section are updated to match. Parameters whose value is unchanged keep
+// their exact bits. The parents of sounds and containers cannot be changed,
+// since the lists of children of containers are not parsed; the other fields
+// are for reference only and are ignored. bnk is left unchanged if the
+// document cannot be applied, as when it is of a version whose HIRC objects
+// are not parsed.
+func (bnk *File) UnmarshalHierarchy(data []byte) error {
+	doc := new(hierarchyDocument)
+	if err := json.Unmarshal(data, doc); err != nil {
+		return fmt.Errorf("reading HIRC document: %w", err)
+	}
+	if err := bnk.CheckVersion(); err != nil {
+		return err
+	}
+	if bnk.ObjectSection == nil && len(doc.Objects) > 0 {
+		return errors.New("the SoundBank has no HIRC section")
+	}
+	objects, err := objectsById(bnk)
+	if err != nil {
+		return err
+	}
+
+	// Check every object of the document before editing any, so that bnk is
+	// left unchanged if one cannot be applied.
+	var edits []func() int64
+	var edited []*ObjectDescriptor
+	seen := make(map[uint32]bool)
+	for _, o := range doc.Objects {
+		if o == nil {
+			return errors.New("the document holds a null object")
+		}
+		if seen[o.Id] {
+			return fmt.Errorf("object %d occurs more than once", o.Id)
+		}
+		seen[o.Id] = true
+		obj, ok := objects.byId[o.Id]
+		if !ok {
+			return fmt.Errorf("object %d is not in the SoundBank", o.Id)
+		}
+		desc := descriptorOf(obj)
+		if o.Type != desc.Type {
+			return fmt.Errorf("object %d is of type %d, not %d", o.Id, desc.Type, o.Type)
+		}
+		if parent, ok := bnk.ParentOf(obj); ok && o.Parent != nil && *o.Parent != parent {
+			return fmt.Errorf("object %d: the parent cannot be changed", o.Id)
+		}
+		edit, err := bnk.objectEdit(obj, o)
+		if err != nil {
+			return fmt.Errorf("object %d: %w", o.Id, err)
+		}
+		if edit != nil {
+			edits = append(edits, edit)
+			edited = append(edited, desc)
+		}
+	}
+
+	for i, edit := range edits {
+		delta := edit()
+		edited[i].Length = uint32(int64(edited[i].Length) + delta)
+		hdr := bnk.ObjectSection.Header
+		hdr.Length = uint32(int64(hdr.Length) + delta)
+	}
+	if bnk.ObjectSection != nil {
+		bnk.ObjectSection.indexSounds()
+	}
+	return nil
+}
+
+// objectEdit checks the fields of o, the document form of obj, returning a
+// function applying them to obj that returns by how many bytes the length of
+// obj changed, or nil if obj has no fields that can be edited.
+func (bnk *File) objectEdit(obj Object, o *hierarchyObject) (func() int64, error) {
+	switch obj := obj.(type) {
+	case *SfxVoiceSoundObject:
+		edit, err := structureEdit(obj.Structure, o)
+		if err != nil {
+			return nil, err
+		}
+		return func() int64 {
+			if o.WemId != nil && *o.WemId != obj.WemDescriptor.WemId {
+				obj.WemDescriptor.WemId = *o.WemId
+				// Embedded and prefetched sounds record the length of their wem.
+				if bnk.IndexSection != nil {
+					if desc, ok := bnk.IndexSection.DescriptorMap[*o.WemId]; ok {
+						obj.WemDescriptor.WemLength = desc.Length
+					}
+				}
+			}
+			return edit()
+		}, nil
+	case *ContainerObject:
+		return structureEdit(obj.Structure, o)
+	case *EventObject:
+		return func() int64 {
+			old := obj.dataLength()
+			obj.ActionIds = append([]uint32(nil), o.Actions...)
+			return obj.dataLength() - old
+		}, nil
+	case *ActionObject:
+		return func() int64 {
+			if o.ActionType != nil {
+				obj.ActionType = *o.ActionType
+			}
+			if o.Target != nil {
+				obj.TargetId = *o.Target
+			}
+			return 0
+		}, nil
+	}
+	return nil, nil
+}
+
+// structureEdit checks the effects and parameters of o, returning a function
+// replacing those of ss by them that returns by how many bytes the length of ss
+// changed.
+func structureEdit(ss *SoundStructure, o *hierarchyObject) (func() int64, error) {
+	if len(o.Effects) > math.MaxUint8 {
+		return nil, fmt.Errorf("%d effects are more than %d", len(o.Effects), math.MaxUint8)
+	}
+	if len(o.Parameters) > math.MaxUint8 {
+		return nil, fmt.Errorf("%d parameters are more than %d", len(o.Parameters),
+			math.MaxUint8)
+	}
+	var effects []*Effect
+	for i, e := range o.Effects {
+		if e == nil {
+			return nil, errors.New("null effect")
+		}
+		effect := &Effect{Index: e.Index, Id: e.Id}
+		if i < len(ss.EffectContainer.Effects) {
+			effect.Padding = ss.EffectContainer.Effects[i].Padding
+		}
+		effects = append(effects, effect)
+	}
+	types := make([]byte, 0, len(o.Parameters))
+	values := make([][4]byte, 0, len(o.Parameters))
+	for i, p := range o.Parameters {
+		if p == nil {
+			return nil, errors.New("null parameter")
+		}
+		v, err := parameterBits(p)
+		if err != nil {
+			return nil, err
+		}
+		// Keep the bits of values that are unchanged, which may not be read
+		// back the same from the document, such as values that are not numbers.
+		if i < len(ss.ParameterTypes) && ss.ParameterTypes[i] == p.Type &&
+			parameterNumber(p.Type, ss.ParameterValues[i]) == p.Value {
+			v = ss.ParameterValues[i]
+		}
+		types = append(types, p.Type)
+		values = append(values, v)
+	}
+
+	return func() int64 {
+		old := ss.knownBytes()
+		ss.EffectContainer.EffectCount = byte(len(effects))
+		ss.EffectContainer.Effects = effects
+		ss.ParameterCount = byte(len(types))
+		ss.ParameterTypes, ss.ParameterValues = types, values
+		return ss.knownBytes() - old
+	}, nil
+}
+
+// parameterBits returns the bits of the value of p, see hierarchyParameter.
+func parameterBits(p *hierarchyParameter) ([4]byte, error) {
+	var v [4]byte
+	if p.Type == parameterLoopType {
+		n, err := strconv.ParseUint(string(p.Value), 10, 32)
+		if err != nil {
+			return v, fmt.Errorf("parameter 0x%02X: invalid loop count %q", p.Type, p.Value)
+		}
+		binary.LittleEndian.PutUint32(v[:], uint32(n))
+		return v, nil
+	}
+	f, err := strconv.ParseFloat(string(p.Value), 32)
+	if err != nil {
+		return v, fmt.Errorf("parameter 0x%02X: invalid value %q", p.Type, p.Value)
+	}
+	binary.LittleEndian.PutUint32(v[:], math.Float32bits(float32(f)))
+	return v, nil
+}
@@ -0,0 +1,202 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// hierarchyOf returns the HIRC document of bnk, decoded, with its objects by
+// ID.
+func hierarchyOf(t *testing.T, bnk *File) (*hierarchyDocument, map[uint32]*hierarchyObject) {
+	t.Helper()
+	data, err := bnk.MarshalHierarchy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := new(hierarchyDocument)
+	if err := json.Unmarshal(data, doc); err != nil {
+		t.Fatal(err)
+	}
+	objects := make(map[uint32]*hierarchyObject)
+	for _, o := range doc.Objects {
+		objects[o.Id] = o
+	}
+	return doc, objects
+}
+
+func TestMarshalHierarchy(t *testing.T) {
+	bnk := openBank(t, buildBank(132, layout132, true))
+	doc, objects := hierarchyOf(t, bnk)
+	if doc.BankId != testBankId || doc.BankName != "test" || doc.Version != 132 ||
+		len(doc.Objects) != 10 {
+		t.Fatalf("the document is of bank %d %q of version %d and holds %d objects", doc.BankId,
+			doc.BankName, doc.Version, len(doc.Objects))
+	}
+
+	u32 := func(v uint32) *uint32 { return &v }
+	play := uint16(0x0403)
+	want := map[uint32]*hierarchyObject{
+		500: {Id: 500, Type: ranSeqCntrObjectId, TypeName: ObjectTypeName(ranSeqCntrObjectId),
+			Parent: u32(0), Children: []uint32{1001, 1002},
+			Effects: []*hierarchyEffect{{0, 0xEFFEC7}}},
+		1001: {Id: 1001, Type: soundObjectId, TypeName: ObjectTypeName(soundObjectId),
+			Parent: u32(500), WemId: u32(1), StreamType: "embedded",
+			Effects:    []*hierarchyEffect{{0, 0xEFFEC7}},
+			Parameters: []*hierarchyParameter{{0x00, "-3"}, {parameterLoopType, "3"}}},
+		1004: {Id: 1004, Type: soundObjectId, TypeName: ObjectTypeName(soundObjectId),
+			Parent: u32(0), WemId: u32(4), StreamType: "streamed",
+			Effects: []*hierarchyEffect{{0, 0xEFFEC7}}},
+		600: {Id: 600, Type: actionObjectId, TypeName: ObjectTypeName(actionObjectId),
+			ActionType: &play, Target: u32(500), BankId: u32(otherBankId), BankName: "other"},
+		700: {Id: 700, Type: eventObjectId, TypeName: ObjectTypeName(eventObjectId),
+			Actions: []uint32{600, 601}, Wems: []uint32{1, 2, 3}},
+	}
+	for id, o := range want {
+		if got := objects[id]; !reflect.DeepEqual(got, o) {
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(o)
+			t.Errorf("object %d is exported as\n%s, want\n%s", id, gotJSON, wantJSON)
+		}
+	}
+
+	// A SoundBank without an STID section names no SoundBanks.
+	doc, objects = hierarchyOf(t, openBank(t, buildBank(132, layout132, false)))
+	if doc.BankName != "" || objects[600].BankName != "" || *objects[600].BankId != otherBankId {
+		t.Errorf("the SoundBanks are named %q and %q without an STID section", doc.BankName,
+			objects[600].BankName)
+	}
+}
+
+func TestUnmarshalHierarchyUnchanged(t *testing.T) {
+	for _, version := range []uint32{112, 132} {
+		data := buildBank(version, layoutOf(version), true)
+		bnk := openBank(t, data)
+		doc, err := bnk.MarshalHierarchy()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bnk.UnmarshalHierarchy(doc); err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+		assertWritesBank(t, bnk, data)
+	}
+
+	bnk, err := Open(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bnk.Close()
+	doc, err := bnk.MarshalHierarchy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bnk.UnmarshalHierarchy(doc); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(testDir, complexSoundBank))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertWritesBank(t, bnk, data)
+}
+
+func TestUnmarshalHierarchy(t *testing.T) {
+	for _, version := range []uint32{112, 132} {
+		bnk := openBank(t, buildBank(version, layoutOf(version), true))
+		doc, objects := hierarchyOf(t, bnk)
+		// Loop the first sound 5 times, play wem 3 from the second, add an
+		// effect to the container, and run only the second action from the
+		// first event, which plays the second sound.
+		objects[1001].Parameters[1].Value = "5"
+		objects[1002].WemId = &[]uint32{3}[0]
+		objects[500].Effects = append(objects[500].Effects, &hierarchyEffect{1, 0xEFFEC8})
+		objects[700].Actions = []uint32{601}
+		objects[601].Target = &[]uint32{1002}[0]
+		edited, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bnk.UnmarshalHierarchy(edited); err != nil {
+			t.Fatalf("version %d: %v", version, err)
+		}
+
+		b := new(bytes.Buffer)
+		if _, err := bnk.WriteTo(b); err != nil {
+			t.Fatal(err)
+		}
+		applied := openBank(t, b.Bytes())
+		assertWritesBank(t, applied, b.Bytes())
+		if loop := applied.LoopOf(0); loop != (LoopValue{true, 5}) {
+			t.Errorf("version %d: the first wem loops as %+v", version, loop)
+		}
+		sound := objectById(t, applied, 1002).(*SfxVoiceSoundObject)
+		if want := (OptionalWemDescriptor{3, uint32(len(testWems[3]))}); sound.WemDescriptor != want {
+			t.Errorf("version %d: the second sound plays %+v, want %+v", version, sound.WemDescriptor,
+				want)
+		}
+		if fileInfo := len(sound.FileInfo); (fileInfo == 2) != (version <= 112) {
+			t.Errorf("version %d: the second sound kept %d fields of file info", version, fileInfo)
+		}
+		container := objectById(t, applied, 500).(*ContainerObject)
+		if n := len(container.Structure.EffectContainer.Effects); n != 2 {
+			t.Errorf("version %d: the container has %d effects", version, n)
+		}
+		wems := map[uint32][]uint32{700: {3}, 701: {3}}
+		if got := applied.EventWems(); !reflect.DeepEqual(got, wems) {
+			t.Errorf("version %d: the events reference the wems %v, want %v", version, got, wems)
+		}
+	}
+}
+
+func TestUnmarshalHierarchyErrors(t *testing.T) {
+	data := buildBank(132, layout132, true)
+	bnk := openBank(t, data)
+	for name, edit := range map[string]func(doc *hierarchyDocument, objects map[uint32]*hierarchyObject){
+		"an unknown object": func(doc *hierarchyDocument, _ map[uint32]*hierarchyObject) {
+			doc.Objects = append(doc.Objects, &hierarchyObject{Id: 9, Type: eventObjectId})
+		},
+		"a duplicate object": func(doc *hierarchyDocument, objects map[uint32]*hierarchyObject) {
+			doc.Objects = append(doc.Objects, objects[700])
+		},
+		"a changed type": func(_ *hierarchyDocument, objects map[uint32]*hierarchyObject) {
+			objects[700].Type = actionObjectId
+		},
+		"a changed parent": func(_ *hierarchyDocument, objects map[uint32]*hierarchyObject) {
+			objects[1001].Parent = &[]uint32{0}[0]
+		},
+		"an invalid loop count": func(_ *hierarchyDocument, objects map[uint32]*hierarchyObject) {
+			objects[1001].Parameters[1].Value = "-1"
+		},
+		"a null parameter": func(_ *hierarchyDocument, objects map[uint32]*hierarchyObject) {
+			objects[1001].Parameters[0] = nil
+		},
+	} {
+		doc, objects := hierarchyOf(t, bnk)
+		// An edit applied before the invalid one must not be kept.
+		objects[700].Actions = nil
+		edit(doc, objects)
+		edited, err := json.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := bnk.UnmarshalHierarchy(edited); err == nil {
+			t.Errorf("applied a document with %s", name)
+		}
+		assertWritesBank(t, bnk, data)
+	}
+	if err := bnk.UnmarshalHierarchy([]byte("{")); err == nil {
+		t.Error("applied an invalid document")
+	}
+
+	var u *UnsupportedVersionError
+	old := openBank(t, buildBank(88, layout132, true))
+	if err := old.UnmarshalHierarchy([]byte(`{"objects": []}`)); !errors.As(err, &u) {
+		t.Errorf("applying a document to an unsupported version: got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package bnk
 
 import (
 	"encoding/binary"
+	"fmt"
 	"io"
 )
 
@@ -23,12 +24,30 @@ const EFFECT_BYTES = 7
 const PARAMETER_TYPE_BYTES = 1
 const PARAMETER_VALUE_BYTES = 4
 const STRUCTURE_UNKNOWN_BYTES = 10
+const EVENT_ACTION_ID_BYTES = 4
+
+// The number of bytes used to describe the type and the target of an action.
+const ACTION_KNOWN_BYTES = 6
 
 const parameterLoopType = 0x3A
 
 // The identifier for SFX or Voice sound objects.
 const soundObjectId = 0x02
 
+// The identifiers for action and event objects.
+const actionObjectId = 0x03
+const eventObjectId = 0x04
+
+// The kind of action, in the high byte of its type, of Play actions.
+const actionPlay = 0x04
+
+// The identifiers for the container objects: Random/Sequence, Switch and Blend
+// containers, and Actor-Mixers.
+const ranSeqCntrObjectId = 0x05
+const switchCntrObjectId = 0x06
+const actorMixerObjectId = 0x07
+const layerCntrObjectId = 0x09
+
 // The wem is embedded in this sound file.
 const streamSettingEmbedded = 0x00
 
@@ -56,6 +75,11 @@ type SfxVoiceSoundObject struct {
 	Type byte
 
 	Structure *SoundStructure
+	// The ID of the file holding the wem, followed by the offset of the wem in
+	// that file unless it is streamed, which SoundBanks of versions up to 112
+	// store between the ID and the length of the wem. It is empty for the
+	// other versions, see hircLayout.
+	FileInfo []uint32
 }
 
 // A OptionalWemDescriptor provides information about where a wem is stored from
@@ -69,6 +93,39 @@ type OptionalWemDescriptor struct {
 	WemLength uint32
 }
 
+// An EventObject represents an Event object within the HIRC section, which
+// runs a list of actions when the game posts it.
+type EventObject struct {
+	Descriptor *ObjectDescriptor
+	// The IDs of the actions of this event, in the order they are run.
+	ActionIds []uint32
+	// Whether the number of actions is stored as a variable length integer, as
+	// in newer SoundBanks, rather than as a 32-bit integer.
+	varCount bool
+}
+
+// An ActionObject represents an Action object within the HIRC section, such as
+// playing or stopping a sound or container.
+type ActionObject struct {
+	Descriptor *ObjectDescriptor
+	// The kind of action in the high byte, such as 0x04 for Play, and its scope
+	// in the low byte.
+	ActionType uint16
+	// The ID of the object this action applies to.
+	TargetId uint32
+	// A reader to read the remaining data of this action.
+	RemainingReader io.Reader
+}
+
+// A ContainerObject represents a Random/Sequence, Switch or Blend container, or
+// an Actor-Mixer, within the HIRC section. Its type is that of its descriptor.
+type ContainerObject struct {
+	Descriptor *ObjectDescriptor
+	// The properties of this container. Its children are listed in the
+	// remaining data of the structure.
+	Structure *SoundStructure
+}
+
 // An UnknownObject represents an unknown object within the HIRC.
 type UnknownObject struct {
 	Descriptor *ObjectDescriptor
@@ -110,8 +167,16 @@ type Effect struct {
 }
 
 // NewSfxVoiceSoundObject creates a new SfxVoiceSoundObject, reading from sr,
-// which must be seeked to the start of the object's data.
+// which must be seeked to the start of the object's data. The object is read
+// as laid out by SoundBanks of MaxSupportedVersion.
 func (desc *ObjectDescriptor) NewSfxVoiceSoundObject(sr util.ReadSeekerAt) (*SfxVoiceSoundObject, error) {
+	return desc.newSfxVoiceSoundObject(sr, latestLayout)
+}
+
+// newSfxVoiceSoundObject is NewSfxVoiceSoundObject, reading the object as laid
+// out by SoundBanks of the versions of layout.
+func (desc *ObjectDescriptor) newSfxVoiceSoundObject(sr util.ReadSeekerAt,
+	layout *hircLayout) (*SfxVoiceSoundObject, error) {
 	// Get the offset into the file where the data portion of this object begins.
 	startOffset, _ := sr.Seek(0, io.SeekCurrent)
 	// The descriptor length includes the Object ID, which has already been
@@ -124,7 +189,24 @@ func (desc *ObjectDescriptor) NewSfxVoiceSoundObject(sr util.ReadSeekerAt) (*Sfx
 	}
 
 	wd := OptionalWemDescriptor{}
-	err = binary.Read(sr, binary.LittleEndian, &wd)
+	err = binary.Read(sr, binary.LittleEndian, &wd.WemId)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileInfo []uint32
+	if layout.soundFileId {
+		fileInfo = make([]uint32, 2)
+		if StreamType(unknown[4]) == StreamStreamed {
+			fileInfo = fileInfo[:1]
+		}
+		err = binary.Read(sr, binary.LittleEndian, fileInfo)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = binary.Read(sr, binary.LittleEndian, &wd.WemLength)
 	if err != nil {
 		return nil, err
 	}
@@ -143,7 +225,7 @@ func (desc *ObjectDescriptor) NewSfxVoiceSoundObject(sr util.ReadSeekerAt) (*Sfx
 		return nil, err
 	}
 
-	return &SfxVoiceSoundObject{desc, unknown, wd, soundType, ss}, nil
+	return &SfxVoiceSoundObject{desc, unknown, wd, soundType, ss, fileInfo}, nil
 }
 
 // WriteTo writes the full contents of this SfxVoiceSoundObject to the Writer
@@ -161,11 +243,19 @@ func (sound *SfxVoiceSoundObject) WriteTo(w io.Writer) (written int64, err error
 	}
 	written += SFX_UNKNOWN_BYTES
 
-	err = binary.Write(w, binary.LittleEndian, sound.WemDescriptor)
+	err = binary.Write(w, binary.LittleEndian, sound.WemDescriptor.WemId)
+	if err != nil {
+		return
+	}
+	err = binary.Write(w, binary.LittleEndian, sound.FileInfo)
 	if err != nil {
 		return
 	}
-	written += OPTIONAL_WEM_DESCRIPTOR_BYTES
+	err = binary.Write(w, binary.LittleEndian, sound.WemDescriptor.WemLength)
+	if err != nil {
+		return
+	}
+	written += OPTIONAL_WEM_DESCRIPTOR_BYTES + int64(len(sound.FileInfo))*4
 
 	err = binary.Write(w, binary.LittleEndian, sound.Type)
 	if err != nil {
@@ -182,6 +272,261 @@ func (sound *SfxVoiceSoundObject) WriteTo(w io.Writer) (written int64, err error
 	return written, nil
 }
 
+// newObject creates the object described by desc, of any type but a sound,
+// reading from sr, which must be seeked to the start of the object's data, as
+// laid out by SoundBanks of the versions of layout. Objects of unknown types,
+// and objects whose data does not have the layout expected of their type, are
+// read as UnknownObjects.
+func (desc *ObjectDescriptor) newObject(sr util.ReadSeekerAt, layout *hircLayout) (Object, error) {
+	startOffset, _ := sr.Seek(0, io.SeekCurrent)
+	var obj Object
+	var err error
+	switch desc.Type {
+	case eventObjectId:
+		obj, err = desc.newEventObject(sr, &layout.varActionCount)
+	case actionObjectId:
+		obj, err = desc.NewActionObject(sr)
+	case ranSeqCntrObjectId, switchCntrObjectId, actorMixerObjectId, layerCntrObjectId:
+		obj, err = desc.NewContainerObject(sr)
+	}
+	endOffset := startOffset + int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+	if currOffset, _ := sr.Seek(0, io.SeekCurrent); obj == nil || err != nil ||
+		currOffset != endOffset {
+		sr.Seek(startOffset, io.SeekStart)
+		return desc.NewUnknownObject(sr)
+	}
+	return obj, nil
+}
+
+// NewEventObject creates a new EventObject, reading from sr, which must be
+// seeked to the start of the object's data. The number of actions is read as
+// a 32-bit integer if the length of the object matches it, and as a variable
+// length integer otherwise.
+func (desc *ObjectDescriptor) NewEventObject(sr util.ReadSeekerAt) (*EventObject, error) {
+	return desc.newEventObject(sr, nil)
+}
+
+// newEventObject is NewEventObject, reading the number of actions as a
+// variable length integer if *varInt is true, and as a 32-bit integer
+// otherwise, unless varInt is nil.
+func (desc *ObjectDescriptor) newEventObject(sr util.ReadSeekerAt, varInt *bool) (*EventObject, error) {
+	dataLength := int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+	if dataLength < 1 {
+		return nil, fmt.Errorf("event %d has no data", desc.ObjectId)
+	}
+	data := make([]byte, dataLength)
+	_, err := io.ReadFull(sr, data)
+	if err != nil {
+		return nil, err
+	}
+
+	varCount := dataLength < 4
+	if varInt != nil {
+		varCount = *varInt
+	} else if !varCount {
+		count := int64(binary.LittleEndian.Uint32(data))
+		varCount = 4+count*EVENT_ACTION_ID_BYTES != dataLength
+	}
+	var count uint64
+	var n int
+	if varCount {
+		count, n = readVarInt(data)
+	} else if dataLength >= 4 {
+		count, n = uint64(binary.LittleEndian.Uint32(data)), 4
+	}
+	if n == 0 {
+		return nil, fmt.Errorf("event %d: invalid number of actions", desc.ObjectId)
+	}
+	if int64(n)+int64(count)*EVENT_ACTION_ID_BYTES != dataLength {
+		return nil, fmt.Errorf("event %d: %d actions do not fit in %d bytes",
+			desc.ObjectId, count, dataLength)
+	}
+
+	ids := make([]uint32, count)
+	for i := range ids {
+		ids[i] = binary.LittleEndian.Uint32(data[n+i*EVENT_ACTION_ID_BYTES:])
+	}
+	return &EventObject{desc, ids, varCount}, nil
+}
+
+// readVarInt reads a variable length integer from the start of b, stored as
+// groups of 7 bits, most significant first, each byte but the last having its
+// high bit set. It returns the integer and the number of bytes it takes, or 0
+// if b does not start with one.
+func readVarInt(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < len(b) && i < 10; i++ {
+		v = v<<7 | uint64(b[i]&0x7F)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return 0, 0
+}
+
+// appendVarInt appends v to b as a variable length integer, see readVarInt.
+func appendVarInt(b []byte, v uint64) []byte {
+	n := 1
+	for x := v >> 7; x > 0; x >>= 7 {
+		n++
+	}
+	for i := n - 1; i >= 0; i-- {
+		c := byte(v>>(7*uint(i))) & 0x7F
+		if i > 0 {
+			c |= 0x80
+		}
+		b = append(b, c)
+	}
+	return b
+}
+
+// WriteTo writes the full contents of this EventObject to the Writer specified
+// by w.
+func (event *EventObject) WriteTo(w io.Writer) (written int64, err error) {
+	err = binary.Write(w, binary.LittleEndian, event.Descriptor)
+	if err != nil {
+		return
+	}
+	written = OBJECT_DESCRIPTOR_BYTES
+
+	if event.varCount {
+		n, err := w.Write(appendVarInt(nil, uint64(len(event.ActionIds))))
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	} else {
+		err = binary.Write(w, binary.LittleEndian, uint32(len(event.ActionIds)))
+		if err != nil {
+			return
+		}
+		written += 4
+	}
+
+	err = binary.Write(w, binary.LittleEndian, event.ActionIds)
+	if err != nil {
+		return
+	}
+	written += int64(len(event.ActionIds)) * EVENT_ACTION_ID_BYTES
+
+	return written, nil
+}
+
+// dataLength returns the length of the data of this EventObject, excluding its
+// descriptor.
+func (event *EventObject) dataLength() int64 {
+	n := int64(4)
+	if event.varCount {
+		n = int64(len(appendVarInt(nil, uint64(len(event.ActionIds)))))
+	}
+	return n + int64(len(event.ActionIds))*EVENT_ACTION_ID_BYTES
+}
+
+// NewActionObject creates a new ActionObject, reading from sr, which must be
+// seeked to the start of the object's data.
+func (desc *ObjectDescriptor) NewActionObject(sr util.ReadSeekerAt) (*ActionObject, error) {
+	// Get the offset into the file where the data portion of this object begins.
+	startOffset, _ := sr.Seek(0, io.SeekCurrent)
+	dataLength := int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+	if dataLength < ACTION_KNOWN_BYTES {
+		return nil, fmt.Errorf("action %d is only %d bytes long", desc.ObjectId,
+			dataLength)
+	}
+
+	action := &ActionObject{Descriptor: desc}
+	err := binary.Read(sr, binary.LittleEndian, &action.ActionType)
+	if err != nil {
+		return nil, err
+	}
+	err = binary.Read(sr, binary.LittleEndian, &action.TargetId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a reader over the remaining elements in this object, then seek past
+	// it.
+	remaining := dataLength - ACTION_KNOWN_BYTES
+	action.RemainingReader = util.NewResettingReader(sr,
+		startOffset+ACTION_KNOWN_BYTES, remaining)
+	sr.Seek(remaining, io.SeekCurrent)
+	return action, nil
+}
+
+// BankId returns the ID of the SoundBank a Play action loads the media of its
+// target from, which ends the data of Play actions, and true, or false if this
+// is not a Play action.
+func (action *ActionObject) BankId() (uint32, bool) {
+	if action.ActionType>>8 != actionPlay {
+		return 0, false
+	}
+	data, err := io.ReadAll(action.RemainingReader)
+	if err != nil || len(data) < 4 {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(data[len(data)-4:]), true
+}
+
+// WriteTo writes the full contents of this ActionObject to the Writer specified
+// by w.
+func (action *ActionObject) WriteTo(w io.Writer) (written int64, err error) {
+	err = binary.Write(w, binary.LittleEndian, action.Descriptor)
+	if err != nil {
+		return
+	}
+	written = OBJECT_DESCRIPTOR_BYTES
+
+	err = binary.Write(w, binary.LittleEndian, action.ActionType)
+	if err != nil {
+		return
+	}
+	err = binary.Write(w, binary.LittleEndian, action.TargetId)
+	if err != nil {
+		return
+	}
+	written += ACTION_KNOWN_BYTES
+
+	n, err := io.Copy(w, action.RemainingReader)
+	if err != nil {
+		return written, err
+	}
+	written += n
+
+	return written, nil
+}
+
+// NewContainerObject creates a new ContainerObject, reading from sr, which must
+// be seeked to the start of the object's data.
+func (desc *ObjectDescriptor) NewContainerObject(sr util.ReadSeekerAt) (*ContainerObject, error) {
+	dataLength := int64(desc.Length) - OBJECT_DESCRIPTOR_ID_BYTES
+	ss, err := NewSoundStructure(sr, dataLength)
+	if err != nil {
+		return nil, err
+	}
+	if ss.knownBytes() > dataLength {
+		return nil, fmt.Errorf("container %d is only %d bytes long", desc.ObjectId,
+			dataLength)
+	}
+	return &ContainerObject{desc, ss}, nil
+}
+
+// WriteTo writes the full contents of this ContainerObject to the Writer
+// specified by w.
+func (ctr *ContainerObject) WriteTo(w io.Writer) (written int64, err error) {
+	err = binary.Write(w, binary.LittleEndian, ctr.Descriptor)
+	if err != nil {
+		return
+	}
+	written = OBJECT_DESCRIPTOR_BYTES
+
+	n, err := ctr.Structure.WriteTo(w)
+	if err != nil {
+		return written, err
+	}
+	written += n
+
+	return written, nil
+}
+
 // NewUnknownObject creates a new UnknownObject, reading from sr, which must
 // be seeked to the start of the unknown object's data.
 func (desc *ObjectDescriptor) NewUnknownObject(sr util.ReadSeekerAt) (*UnknownObject, error) {
@@ -243,7 +588,6 @@ func NewSoundStructure(sr util.ReadSeekerAt, length int64) (*SoundStructure, err
 
 	var types []byte
 	var values [][4]byte
-	loops, loopCount := false, uint32(0)
 
 	// Read in parameter types.
 	for i := byte(0); i < count; i++ {
@@ -262,13 +606,9 @@ func NewSoundStructure(sr util.ReadSeekerAt, length int64) (*SoundStructure, err
 		if err != nil {
 			return nil, err
 		}
-
-		// Save loop information for convinience if this sound object loops.
-		if types[i] == parameterLoopType {
-			loops, loopCount = true, binary.LittleEndian.Uint32(v[:])
-		}
 		values = append(values, v)
 	}
+	loops, loopCount := loopParameter(types, values)
 
 	// Create a reader over the remaining elements in this object, then seek past
 	// it.
@@ -280,6 +620,17 @@ func NewSoundStructure(sr util.ReadSeekerAt, length int64) (*SoundStructure, err
 		loops, loopCount, r}, nil
 }
 
+// loopParameter returns whether a sound structure with the given parameters
+// loops and, if so, the number of times it loops.
+func loopParameter(types []byte, values [][4]byte) (loops bool, loopCount uint32) {
+	for i, t := range types {
+		if t == parameterLoopType {
+			loops, loopCount = true, binary.LittleEndian.Uint32(values[i][:])
+		}
+	}
+	return loops, loopCount
+}
+
 func (ss *SoundStructure) WriteTo(w io.Writer) (written int64, err error) {
 	err = binary.Write(w, binary.LittleEndian, ss.OverrideParentEffects)
 	if err != nil {
@@ -0,0 +1,99 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+import (
+	"wwiseutil/util"
+)
+
+func TestObjectsOfTestdata(t *testing.T) {
+	for _, name := range []string{simpleSoundBank, complexSoundBank} {
+		bnk, err := Open(filepath.Join(testDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bnk.Objects()) != int(bnk.ObjectSection.ObjectCount) {
+			t.Errorf("%s: read %d objects, want %d", name, len(bnk.Objects()),
+				bnk.ObjectSection.ObjectCount)
+		}
+		sounds := 0
+		for _, obj := range bnk.Objects() {
+			desc := descriptorOf(obj)
+			b := new(bytes.Buffer)
+			if _, err := obj.WriteTo(b); err != nil {
+				t.Fatal(err)
+			}
+			if want := OBJECT_DESCRIPTOR_BYTES - OBJECT_DESCRIPTOR_ID_BYTES + int(desc.Length); b.Len() != want {
+				t.Errorf("%s: object %d of type %d is written as %d bytes, want %d", name,
+					desc.ObjectId, desc.Type, b.Len(), want)
+			}
+			if sound, ok := obj.(*SfxVoiceSoundObject); ok {
+				sounds++
+				if _, ok := bnk.IndexSection.DescriptorMap[sound.WemDescriptor.WemId]; !ok {
+					t.Errorf("%s: sound %d plays wem %d, which is not in the SoundBank", name,
+						desc.ObjectId, sound.WemDescriptor.WemId)
+				}
+			}
+		}
+		if sounds != len(bnk.Wems()) {
+			t.Errorf("%s: read %d sounds for %d wems", name, sounds, len(bnk.Wems()))
+		}
+		bnk.Close()
+	}
+}
+
+func TestNewEventObject(t *testing.T) {
+	actions := []uint32{600, 601, 602}
+	for _, l := range []*hircLayout{{}, {varActionCount: true}} {
+		event := testEvent(l, 700, actions...)
+		sr := util.NewResettingReader(bytes.NewReader(event), 0, int64(len(event)))
+		desc := new(ObjectDescriptor)
+		if err := binary.Read(sr, binary.LittleEndian, desc); err != nil {
+			t.Fatal(err)
+		}
+		// The width of the count is guessed from the length of the event.
+		obj, err := desc.NewEventObject(sr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(obj.ActionIds, actions) || obj.varCount != l.varActionCount {
+			t.Errorf("read the event %+v, want the actions %v", *obj, actions)
+		}
+		b := new(bytes.Buffer)
+		if n, err := obj.WriteTo(b); err != nil || n != int64(len(event)) || !bytes.Equal(b.Bytes(), event) {
+			t.Errorf("the event is written as %x (%v), want %x", b.Bytes(), err, event)
+		}
+	}
+
+	for _, v := range []uint64{0, 1, 0x7F, 0x80, 300, 1 << 32} {
+		b := appendVarInt([]byte{0xFF}, v)[1:]
+		if got, n := readVarInt(append(b, 0xAA)); got != v || n != len(b) {
+			t.Errorf("%d is read back as %d from %d of %d bytes", v, got, n, len(b))
+		}
+	}
+	if _, n := readVarInt([]byte{0x80, 0x80}); n != 0 {
+		t.Error("read an unterminated variable length integer")
+	}
+}
+
+func TestActionBankId(t *testing.T) {
+	bnk := openBank(t, buildBank(132, layout132, false))
+	action := objectById(t, bnk, 600).(*ActionObject)
+	for i := 0; i < 2; i++ {
+		// The remaining data of the action can be read again.
+		if id, ok := action.BankId(); !ok || id != otherBankId {
+			t.Errorf("the Play action loads its media from bank %d (%v)", id, ok)
+		}
+	}
+	action.ActionType = 0x0103
+	if id, ok := action.BankId(); ok {
+		t.Errorf("a Stop action loads its media from bank %d", id)
+	}
+}
@@ -27,6 +27,14 @@ const DIDX_ENTRY_BYTES = 12
 // The number of bytes used to describe the count of objects in the HIRC section.
 const OBJECT_COUNT_BYTES = 4
 
+// The number of bytes used to describe the string type and the count of names
+// of the STID section.
+const STID_SECTION_BYTES = 8
+
+// The number of bytes used to describe a single name within the STID section,
+// excluding the name itself: the bank ID and the length of the name.
+const STID_ENTRY_BYTES = 5
+
 // The identifier for the start of the BKHD (Bank Header) section.
 var bkhdHeaderId = [4]byte{'B', 'K', 'H', 'D'}
 
@@ -39,6 +47,9 @@ var dataHeaderId = [4]byte{'D', 'A', 'T', 'A'}
 // The identifier for the start of the HIRC section.
 var hircHeaderId = [4]byte{'H', 'I', 'R', 'C'}
 
+// The identifier for the start of the STID (String ID) section.
+var stidHeaderId = [4]byte{'S', 'T', 'I', 'D'}
+
 // Section represents a single section of a Wwise SoundBank.
 type Section interface {
 	io.WriterTo
@@ -56,6 +67,8 @@ type BankHeaderSection struct {
 	Header          *SectionHeader
 	Descriptor      BankDescriptor
 	RemainingReader io.Reader
+	// The name of this SoundBank, if the STID section names it.
+	name string
 }
 
 // A BankDescriptor provides metadata about the overall SoundBank file.
@@ -91,6 +104,8 @@ type ObjectHierarchySection struct {
 	Header      *SectionHeader
 	ObjectCount uint32
 	objects     []Object
+	// The offset into the file where the objects of this section begin.
+	dataOffset int64
 	// A convenience field for accessing the loop parameters of every wem. It maps
 	// the wem id of the loop in question to the loop value, where 0 represents
 	// infinity.
@@ -98,6 +113,21 @@ type ObjectHierarchySection struct {
 	wemToObject map[uint32]*SfxVoiceSoundObject
 }
 
+// A StringTableSection represents the STID section of a SoundBank file, which
+// holds the names of the SoundBanks referenced by the SoundBank, including its
+// own, by bank ID.
+type StringTableSection struct {
+	Header *SectionHeader
+	// The type of the strings of this section, 1 for bank names.
+	StringType uint32
+	// The IDs of the named SoundBanks, in the order they are stored.
+	BankIds []uint32
+	// The name of each SoundBank, by bank ID.
+	Names map[uint32]string
+	// A reader to read the data of this section following the names, if any.
+	RemainingReader io.Reader
+}
+
 // An UnknownSection represents an unknown section in a SoundBank file.
 type UnknownSection struct {
 	Header *SectionHeader
@@ -151,6 +181,11 @@ func (hdr *BankHeaderSection) WriteTo(w io.Writer) (written int64, err error) {
 }
 
 func (hdr *BankHeaderSection) String() string {
+	if hdr.name != "" {
+		return fmt.Sprintf("%s: len(%d) version(%d) id(%d) name(%s)\n",
+			hdr.Header.Identifier, hdr.Header.Length, hdr.Descriptor.Version,
+			hdr.Descriptor.BankId, hdr.name)
+	}
 	return fmt.Sprintf("%s: len(%d) version(%d) id(%d)\n",
 		hdr.Header.Identifier, hdr.Header.Length, hdr.Descriptor.Version,
 		hdr.Descriptor.BankId)
@@ -292,8 +327,17 @@ func (data *DataSection) String() string {
 
 // NewObjectHierarchySection creates a new ObjectHierarchySection, reading from
 // sr, which must be seeked to the start of the HIRC section data.
-// It is an error to call this method on a non-HIRC header.
+// It is an error to call this method on a non-HIRC header. The objects are
+// read as laid out by SoundBanks of MaxSupportedVersion.
 func (hdr *SectionHeader) NewObjectHierarchySection(sr util.ReadSeekerAt) (*ObjectHierarchySection, error) {
+	return hdr.newObjectHierarchySection(sr, latestLayout)
+}
+
+// newObjectHierarchySection is NewObjectHierarchySection, reading the objects
+// as laid out by SoundBanks of the versions of layout, or every object as an
+// UnknownObject if layout is nil.
+func (hdr *SectionHeader) newObjectHierarchySection(sr util.ReadSeekerAt,
+	layout *hircLayout) (*ObjectHierarchySection, error) {
 	if hdr.Identifier != hircHeaderId {
 		panic(fmt.Sprintf("Expected HIRC header but got: %s", hdr.Identifier))
 	}
@@ -308,6 +352,7 @@ func (hdr *SectionHeader) NewObjectHierarchySection(sr util.ReadSeekerAt) (*Obje
 		return nil, err
 	}
 	sec.ObjectCount = count
+	sec.dataOffset, _ = sr.Seek(0, io.SeekCurrent)
 
 	for i := uint32(0); i < sec.ObjectCount; i++ {
 		desc := new(ObjectDescriptor)
@@ -315,20 +360,22 @@ func (hdr *SectionHeader) NewObjectHierarchySection(sr util.ReadSeekerAt) (*Obje
 		if err != nil {
 			return nil, err
 		}
-		switch id := desc.Type; id {
-		case soundObjectId:
-			obj, err := desc.NewSfxVoiceSoundObject(sr)
+		switch id := desc.Type; {
+		case layout == nil:
+			obj, err := desc.NewUnknownObject(sr)
 			if err != nil {
 				return nil, err
 			}
-
-			sec.wemToObject[obj.WemDescriptor.WemId] = obj
-			if obj.Structure.loops {
-				sec.loopOf[obj.WemDescriptor.WemId] = obj.Structure.loopCount
-			}
 			sec.objects = append(sec.objects, obj)
+		case id == soundObjectId:
+			obj, err := desc.newSfxVoiceSoundObject(sr, layout)
+			if err != nil {
+				return nil, err
+			}
+
+			sec.addSound(obj)
 		default:
-			obj, err := desc.NewUnknownObject(sr)
+			obj, err := desc.newObject(sr, layout)
 			if err != nil {
 				return nil, err
 			}
@@ -339,6 +386,78 @@ func (hdr *SectionHeader) NewObjectHierarchySection(sr util.ReadSeekerAt) (*Obje
 	return sec, nil
 }
 
+// addSound appends obj to the objects of this section.
+func (hrc *ObjectHierarchySection) addSound(obj *SfxVoiceSoundObject) {
+	hrc.wemToObject[obj.WemDescriptor.WemId] = obj
+	if obj.Structure.loops {
+		hrc.loopOf[obj.WemDescriptor.WemId] = obj.Structure.loopCount
+	}
+	hrc.objects = append(hrc.objects, obj)
+}
+
+// indexSounds rebuilds the maps of the sound objects of this section, after
+// their wems or parameters were edited.
+func (hrc *ObjectHierarchySection) indexSounds() {
+	hrc.loopOf = make(map[uint32]uint32)
+	hrc.wemToObject = make(map[uint32]*SfxVoiceSoundObject)
+	for _, obj := range hrc.objects {
+		sound, ok := obj.(*SfxVoiceSoundObject)
+		if !ok {
+			continue
+		}
+		ss := sound.Structure
+		ss.loops, ss.loopCount = loopParameter(ss.ParameterTypes, ss.ParameterValues)
+		hrc.wemToObject[sound.WemDescriptor.WemId] = sound
+		if ss.loops {
+			hrc.loopOf[sound.WemDescriptor.WemId] = ss.loopCount
+		}
+	}
+}
+
+// removeSoundsOf removes the sound objects playing the wems whose IDs are in
+// wems from this section, along with the actions targeting them, which are
+// removed from the events running them.
+func (hrc *ObjectHierarchySection) removeSoundsOf(wems map[uint32]bool) {
+	removed := make(map[uint32]bool)
+	for _, obj := range hrc.objects {
+		if sound, ok := obj.(*SfxVoiceSoundObject); ok && wems[sound.WemDescriptor.WemId] {
+			removed[sound.Descriptor.ObjectId] = true
+		}
+	}
+	for _, obj := range hrc.objects {
+		if action, ok := obj.(*ActionObject); ok && removed[action.TargetId] {
+			removed[action.Descriptor.ObjectId] = true
+		}
+	}
+
+	var kept []Object
+	for _, obj := range hrc.objects {
+		desc := descriptorOf(obj)
+		if removed[desc.ObjectId] {
+			hrc.Header.Length -= OBJECT_DESCRIPTOR_BYTES - OBJECT_DESCRIPTOR_ID_BYTES +
+				desc.Length
+			continue
+		}
+		if event, ok := obj.(*EventObject); ok {
+			old := event.dataLength()
+			var actions []uint32
+			for _, id := range event.ActionIds {
+				if !removed[id] {
+					actions = append(actions, id)
+				}
+			}
+			event.ActionIds = actions
+			delta := uint32(old - event.dataLength())
+			desc.Length -= delta
+			hrc.Header.Length -= delta
+		}
+		kept = append(kept, obj)
+	}
+	hrc.objects = kept
+	hrc.ObjectCount = uint32(len(kept))
+	hrc.indexSounds()
+}
+
 // WriteTo writes the full contents of this ObjectHierarchySection to the Writer
 // specified by w.
 func (hrc *ObjectHierarchySection) WriteTo(w io.Writer) (written int64, err error) {
@@ -364,6 +483,11 @@ func (hrc *ObjectHierarchySection) WriteTo(w io.Writer) (written int64, err erro
 	return written, nil
 }
 
+// Objects returns the objects of this section, in the order they are stored.
+func (hrc *ObjectHierarchySection) Objects() []Object {
+	return hrc.objects
+}
+
 func (hrc *ObjectHierarchySection) String() string {
 	b := new(strings.Builder)
 
@@ -372,6 +496,113 @@ func (hrc *ObjectHierarchySection) String() string {
 	return b.String()
 }
 
+// NewStringTableSection creates a new StringTableSection, reading from sr,
+// which must be seeked to the start of the STID section data.
+// It is an error to call this method on a non-STID header.
+func (hdr *SectionHeader) NewStringTableSection(sr util.ReadSeekerAt) (*StringTableSection, error) {
+	if hdr.Identifier != stidHeaderId {
+		panic(fmt.Sprintf("Expected STID header but got: %s", hdr.Identifier))
+	}
+	// Get the offset into the file where the data portion of this section begins.
+	dataOffset, _ := sr.Seek(0, io.SeekCurrent)
+	sec := &StringTableSection{Header: hdr, Names: make(map[uint32]string)}
+	err := binary.Read(sr, binary.LittleEndian, &sec.StringType)
+	if err != nil {
+		return nil, err
+	}
+	var count uint32
+	err = binary.Read(sr, binary.LittleEndian, &count)
+	if err != nil {
+		return nil, err
+	}
+
+	read := int64(STID_SECTION_BYTES)
+	for i := uint32(0); i < count; i++ {
+		var entry struct {
+			BankId uint32
+			Length byte
+		}
+		err := binary.Read(sr, binary.LittleEndian, &entry)
+		if err != nil {
+			return nil, err
+		}
+		name := make([]byte, entry.Length)
+		if _, err := io.ReadFull(sr, name); err != nil {
+			return nil, err
+		}
+		if _, ok := sec.Names[entry.BankId]; ok {
+			return nil, fmt.Errorf("bank ID %d is named more than once in the STID", entry.BankId)
+		}
+		sec.BankIds = append(sec.BankIds, entry.BankId)
+		sec.Names[entry.BankId] = string(name)
+		read += STID_ENTRY_BYTES + int64(entry.Length)
+	}
+
+	remaining := int64(hdr.Length) - read
+	if remaining < 0 {
+		return nil, fmt.Errorf("the names of the STID take %d bytes, more than its %d bytes",
+			read, hdr.Length)
+	}
+	sec.RemainingReader = util.NewResettingReader(sr, dataOffset+read, remaining)
+	sr.Seek(remaining, io.SeekCurrent)
+	return sec, nil
+}
+
+// WriteTo writes the full contents of this StringTableSection to the Writer
+// specified by w.
+func (stid *StringTableSection) WriteTo(w io.Writer) (written int64, err error) {
+	err = binary.Write(w, binary.LittleEndian, stid.Header)
+	if err != nil {
+		return
+	}
+	written = int64(SECTION_HEADER_BYTES)
+
+	err = binary.Write(w, binary.LittleEndian, stid.StringType)
+	if err != nil {
+		return
+	}
+	err = binary.Write(w, binary.LittleEndian, uint32(len(stid.BankIds)))
+	if err != nil {
+		return
+	}
+	written += int64(STID_SECTION_BYTES)
+
+	for _, id := range stid.BankIds {
+		name := stid.Names[id]
+		err = binary.Write(w, binary.LittleEndian, id)
+		if err != nil {
+			return
+		}
+		err = binary.Write(w, binary.LittleEndian, byte(len(name)))
+		if err != nil {
+			return
+		}
+		n, err := io.WriteString(w, name)
+		if err != nil {
+			return written, err
+		}
+		written += int64(STID_ENTRY_BYTES + n)
+	}
+
+	n, err := io.Copy(w, stid.RemainingReader)
+	if err != nil {
+		return written, err
+	}
+	written += n
+
+	return written, nil
+}
+
+func (stid *StringTableSection) String() string {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "%s: len(%d) bank_count(%d)\n", stid.Header.Identifier,
+		stid.Header.Length, len(stid.BankIds))
+	for _, id := range stid.BankIds {
+		fmt.Fprintf(b, "STID: bank %d: %s\n", id, stid.Names[id])
+	}
+	return b.String()
+}
+
 // NewUnknownSection creates a new UnknownSection, reading from sr, which
 // must be seeked to the start of the unknown section data.
 func (hdr *SectionHeader) NewUnknownSection(sr util.ReadSeekerAt) (*UnknownSection, error) {
@@ -0,0 +1,60 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStringTableSection(t *testing.T) {
+	data := buildBank(132, layout132, true)
+	bnk := openBank(t, data)
+	stid := bnk.StringTableSection
+	if stid == nil {
+		t.Fatal("the STID section was not read")
+	}
+	if stid.StringType != 1 || len(stid.BankIds) != 2 || stid.BankIds[0] != testBankId ||
+		stid.BankIds[1] != otherBankId {
+		t.Errorf("read the STID section %+v", *stid)
+	}
+	for id, want := range map[uint32]string{testBankId: "test", otherBankId: "other"} {
+		if name, ok := bnk.BankName(id); !ok || name != want {
+			t.Errorf("bank %d is named %q (%v), want %q", id, name, ok, want)
+		}
+	}
+	if name, ok := bnk.BankName(0xDEAD); ok {
+		t.Errorf("an unknown bank is named %q", name)
+	}
+	s := bnk.String()
+	for _, want := range []string{"name(test)", "STID: bank 185273099: test",
+		"STID: bank 202116108: other"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("the SoundBank is described without %q:\n%s", want, s)
+		}
+	}
+	assertWritesBank(t, bnk, data)
+
+	// Data following the names is kept.
+	stidStart := bytes.Index(data, []byte("STID"))
+	trailing := append(append([]byte{}, data[:stidStart]...),
+		bankSection("STID", data[stidStart+8:], []byte{0xAB, 0xCD})...)
+	assertWritesBank(t, openBank(t, trailing), trailing)
+
+	unnamed := openBank(t, buildBank(132, layout132, false))
+	if name, ok := unnamed.BankName(testBankId); ok {
+		t.Errorf("a SoundBank without an STID section is named %q", name)
+	}
+
+	for name, stid := range map[string][]byte{
+		"a duplicate bank ID": bankSection("STID", le(uint32(1), uint32(2)),
+			le(uint32(testBankId), byte(1)), []byte("a"), le(uint32(testBankId), byte(1)), []byte("b")),
+		"more names than fit": append(bankSection("STID", le(uint32(1), uint32(1))),
+			append(le(uint32(testBankId), byte(4)), "test"...)...),
+	} {
+		invalid := append(append([]byte{}, data[:stidStart]...), stid...)
+		if _, err := NewFile(bytes.NewReader(invalid)); err == nil {
+			t.Errorf("read an STID section with %s", name)
+		}
+	}
+}
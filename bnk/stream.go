@@ -0,0 +1,53 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"fmt"
+)
+
+// A StreamType describes where a sound object expects the audio of its wem to
+// be stored, as recorded in the object.
+type StreamType byte
+
+const (
+	// StreamEmbedded means that the wem is embedded in the DATA section of a
+	// SoundBank, and held in memory while the SoundBank is loaded.
+	StreamEmbedded StreamType = streamSettingEmbedded
+	// StreamPrefetch means that the wem is streamed from a package or loose
+	// file, but its start is also embedded in a SoundBank so that it can play
+	// without waiting for the stream.
+	StreamPrefetch StreamType = 0x01
+	// StreamStreamed means that the wem is only streamed from a package or
+	// loose file.
+	StreamStreamed StreamType = 0x02
+)
+
+func (t StreamType) String() string {
+	switch t {
+	case StreamEmbedded:
+		return "embedded"
+	case StreamPrefetch:
+		return "prefetch"
+	case StreamStreamed:
+		return "streamed"
+	}
+	return fmt.Sprintf("unknown (0x%02X)", byte(t))
+}
+
+// StreamType returns where this sound object expects its wem to be stored.
+func (sound *SfxVoiceSoundObject) StreamType() StreamType {
+	return StreamType(sound.Unknown[4])
+}
+
+// StreamTypes returns the stream type of the wem of each sound object in this
+// SoundBank, by wem ID. It is empty if the SoundBank has no HIRC section.
+func (bnk *File) StreamTypes() map[uint32]StreamType {
+	types := make(map[uint32]StreamType)
+	if bnk.ObjectSection == nil {
+		return types
+	}
+	for id, sound := range bnk.ObjectSection.wemToObject {
+		types[id] = sound.StreamType()
+	}
+	return types
+}
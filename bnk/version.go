@@ -0,0 +1,100 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"fmt"
+)
+
+// The range of bank versions, as recorded in the BKHD section, whose HIRC
+// objects are parsed: the versions of the layouts of hircLayouts. Older and
+// newer SoundBanks lay out sounds and containers in ways none of these layouts
+// describe, so their HIRC objects are all read as UnknownObjects and written
+// back as they were read. Their wems can still be read and replaced, since the
+// DIDX and DATA sections are laid out the same in every version.
+const (
+	MinSupportedVersion = 89
+	MaxSupportedVersion = 134
+)
+
+// A hircLayout describes the fields of the HIRC objects that are parsed whose
+// layout differs between the bank versions from minVersion to maxVersion.
+type hircLayout struct {
+	minVersion, maxVersion uint32
+	// Whether sounds store the ID of the file holding their wem, and the offset
+	// of the wem in that file unless it is streamed, between the ID and the
+	// length of the wem.
+	soundFileId bool
+	// Whether sound structures store whether they override the attachment
+	// parameters of their parent before the ID of their output bus, which
+	// moves the ID of their parent by a byte, see File.ParentOf.
+	attachmentParams bool
+	// Whether events store the number of their actions as a variable length
+	// integer, rather than as a 32-bit integer.
+	varActionCount bool
+}
+
+// hircLayouts are the layouts of the HIRC objects of the supported bank
+// versions, in increasing order of version and covering every version from
+// MinSupportedVersion to MaxSupportedVersion.
+var hircLayouts = []*hircLayout{
+	{minVersion: 89, maxVersion: 89, soundFileId: true},
+	{minVersion: 90, maxVersion: 112, soundFileId: true, attachmentParams: true},
+	{minVersion: 113, maxVersion: 122, attachmentParams: true},
+	{minVersion: 123, maxVersion: 134, attachmentParams: true, varActionCount: true},
+}
+
+// The layout of the newest supported version, used to parse the objects read
+// by the exported constructors, which are not given the version of their
+// SoundBank.
+var latestLayout = hircLayouts[len(hircLayouts)-1]
+
+// layoutOf returns the layout of the HIRC objects of SoundBanks of the given
+// version, or nil if their objects are not parsed.
+func layoutOf(version uint32) *hircLayout {
+	for _, l := range hircLayouts {
+		if version >= l.minVersion && version <= l.maxVersion {
+			return l
+		}
+	}
+	return nil
+}
+
+// An UnsupportedVersionError is returned by the operations that need the
+// parsed HIRC objects of a SoundBank, when its version is not supported.
+type UnsupportedVersionError struct {
+	Version uint32
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	age := "new"
+	if e.Version < MinSupportedVersion {
+		age = "old"
+	}
+	return fmt.Sprintf("SoundBank version %d is too %s; the HIRC objects of "+
+		"versions %d to %d are supported", e.Version, age, MinSupportedVersion,
+		MaxSupportedVersion)
+}
+
+// Version returns the bank version of this File, as recorded in its BKHD
+// section, or 0 if it has none.
+func (bnk *File) Version() uint32 {
+	if bnk.BankHeaderSection == nil {
+		return 0
+	}
+	return bnk.BankHeaderSection.Descriptor.Version
+}
+
+// CheckVersion returns an *UnsupportedVersionError if the HIRC objects of this
+// File are not parsed because of its version, and nil otherwise.
+func (bnk *File) CheckVersion() error {
+	if bnk.layout() == nil {
+		return &UnsupportedVersionError{bnk.Version()}
+	}
+	return nil
+}
+
+// layout returns the layout of the HIRC objects of this File, or nil if they
+// are not parsed because of its version.
+func (bnk *File) layout() *hircLayout {
+	return layoutOf(bnk.Version())
+}
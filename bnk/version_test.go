@@ -0,0 +1,321 @@
+// Package bnk implements access to the Wwise SoundBank file format.
+package bnk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// The ID of the SoundBanks built by buildBank, and of the SoundBank the Play
+// actions of testObjects load their media from.
+const (
+	testBankId  = 0x0B0B0B0B
+	otherBankId = 0x0C0C0C0C
+)
+
+// le returns values encoded in little endian byte order, one after another.
+func le(values ...interface{}) []byte {
+	b := new(bytes.Buffer)
+	for _, v := range values {
+		if err := binary.Write(b, binary.LittleEndian, v); err != nil {
+			panic(err)
+		}
+	}
+	return b.Bytes()
+}
+
+// bankSection returns the section of a SoundBank with the given identifier
+// holding data.
+func bankSection(id string, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	return append(le([]byte(id), uint32(len(body))), body...)
+}
+
+// A testParam is a parameter of a sound structure built by testStructure.
+type testParam struct {
+	typ   byte
+	value uint32
+}
+
+// testStructure returns a sound structure laid out as by SoundBanks of the
+// versions of l, with one effect, the given parent and parameters, and some
+// remaining data.
+func testStructure(l *hircLayout, parent uint32, params ...testParam) []byte {
+	// The bit vector of the structure, and whether it overrides the
+	// attachment parameters of its parent, surround the output bus and parent.
+	unknown := le(uint32(0xB0B0B0B0), parent, byte(0))
+	if l.attachmentParams {
+		unknown = append([]byte{0}, unknown...)
+	} else {
+		unknown = append(unknown, 0)
+	}
+	b := append(le(byte(0), byte(1), byte(0), byte(0), uint32(0xEFFEC7), [2]byte{}), unknown...)
+	b = append(b, byte(len(params)))
+	for _, p := range params {
+		b = append(b, p.typ)
+	}
+	for _, p := range params {
+		b = append(b, le(p.value)...)
+	}
+	return append(b, "remaining structure data"...)
+}
+
+// testObject returns a HIRC object of the given type and ID holding data.
+func testObject(typ byte, id uint32, data ...[]byte) []byte {
+	body := bytes.Join(data, nil)
+	return append(le(typ, uint32(OBJECT_DESCRIPTOR_ID_BYTES+len(body)), id), body...)
+}
+
+// testSound returns a sound object laid out as by SoundBanks of the versions
+// of l, playing the wem with the given ID and length.
+func testSound(l *hircLayout, id, wem, length uint32, stream StreamType, parent uint32,
+	params ...testParam) []byte {
+	b := le(uint32(0x00040001), stream, wem)
+	if l.soundFileId {
+		b = append(b, le(wem+1)...)
+		if stream != StreamStreamed {
+			b = append(b, le(uint32(0x40))...)
+		}
+	}
+	b = append(b, le(length, byte(0))...)
+	return testObject(soundObjectId, id, b, testStructure(l, parent, params...))
+}
+
+// testEvent returns an event object laid out as by SoundBanks of the versions
+// of l, running the given actions.
+func testEvent(l *hircLayout, id uint32, actions ...uint32) []byte {
+	count := le(uint32(len(actions)))
+	if l.varActionCount {
+		count = appendVarInt(nil, uint64(len(actions)))
+	}
+	return testObject(eventObjectId, id, count, le(actions))
+}
+
+// testPlay returns a Play action targeting the object with the given ID, which
+// loads its media from otherBankId.
+func testPlay(id, target uint32) []byte {
+	return testObject(actionObjectId, id, le(uint16(0x0403), target, byte(0), uint32(otherBankId)))
+}
+
+// The wems of the SoundBanks built by buildBank, by ID.
+var testWems = map[uint32][]byte{
+	1: []byte("RIFF the first wem"),
+	2: []byte("RIFF the second wem, which is longer than 16 bytes"),
+	3: []byte("RIFF 3"),
+}
+
+// testObjects returns the HIRC objects of the SoundBanks built by buildBank,
+// laid out as by SoundBanks of the versions of l. The sounds of wems 1 and 2
+// belong to container 500, the first looping 3 times; event 700 plays the
+// container and the sound of wem 3, and event 701 the sounds of wems 3 and 2.
+// The sound of wem 4 is streamed and played by no event.
+func testObjects(l *hircLayout) [][]byte {
+	length := func(id uint32) uint32 { return uint32(len(testWems[id])) }
+	return [][]byte{
+		testObject(ranSeqCntrObjectId, 500, testStructure(l, 0), []byte("children")),
+		testSound(l, 1001, 1, length(1), StreamEmbedded, 500, testParam{0x00, math.Float32bits(-3)},
+			testParam{parameterLoopType, 3}),
+		testSound(l, 1002, 2, length(2), StreamEmbedded, 500),
+		testSound(l, 1003, 3, length(3), StreamEmbedded, 0),
+		testSound(l, 1004, 4, 0x1234, StreamStreamed, 0),
+		testPlay(600, 500),
+		testPlay(601, 1003),
+		testPlay(602, 1002),
+		testEvent(l, 700, 600, 601),
+		testEvent(l, 701, 601, 602),
+	}
+}
+
+// buildBank returns a SoundBank of the given version holding testWems, in
+// order of ID, and the HIRC objects of testObjects laid out as described by l,
+// followed by an STID section naming it and otherBankId if named is true.
+func buildBank(version uint32, l *hircLayout, named bool) []byte {
+	var didx, data []byte
+	for id := uint32(1); id <= uint32(len(testWems)); id++ {
+		for len(data)%wemAlignmentBytes != 0 {
+			data = append(data, 0)
+		}
+		didx = append(didx, le(id, uint32(len(data)), uint32(len(testWems[id])))...)
+		data = append(data, testWems[id]...)
+	}
+	objects := testObjects(l)
+	b := bytes.Join([][]byte{
+		bankSection("BKHD", le(version, uint32(testBankId)), []byte("language")),
+		bankSection("DIDX", didx),
+		bankSection("DATA", data),
+		bankSection("HIRC", le(uint32(len(objects))), bytes.Join(objects, nil)),
+	}, nil)
+	if named {
+		b = append(b, bankSection("STID", le(uint32(1), uint32(2)),
+			le(uint32(testBankId), byte(4)), []byte("test"),
+			le(uint32(otherBankId), byte(5)), []byte("other"))...)
+	}
+	return b
+}
+
+// openBank opens the SoundBank stored in data.
+func openBank(t *testing.T, data []byte) *File {
+	t.Helper()
+	bnk, err := NewFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bnk
+}
+
+// assertWritesBank checks that bnk is written as want.
+func assertWritesBank(t *testing.T, bnk *File, want []byte) {
+	t.Helper()
+	b := new(bytes.Buffer)
+	if n, err := bnk.WriteTo(b); err != nil || n != int64(b.Len()) {
+		t.Fatalf("wrote %d bytes, reporting %d (%v)", b.Len(), n, err)
+	}
+	if !bytes.Equal(b.Bytes(), want) {
+		t.Errorf("the SoundBank is written as\n%q, want\n%q", b.Bytes(), want)
+	}
+}
+
+// objectsOf returns the IDs of the objects of bnk, by the type they are read
+// as.
+func objectsOf(bnk *File) map[string][]uint32 {
+	ids := make(map[string][]uint32)
+	for _, obj := range bnk.Objects() {
+		typ := reflect.TypeOf(obj).Elem().Name()
+		ids[typ] = append(ids[typ], descriptorOf(obj).ObjectId)
+	}
+	return ids
+}
+
+// The layout of the HIRC objects of SoundBanks of version 132, the version of
+// simple.bnk.
+var layout132 = &hircLayout{attachmentParams: true, varActionCount: true}
+
+func TestVersionLayouts(t *testing.T) {
+	for _, tt := range []struct {
+		version uint32
+		// The layout of the objects, or nil if they are not parsed.
+		layout *hircLayout
+	}{
+		{88, nil},
+		{89, &hircLayout{soundFileId: true}},
+		{90, &hircLayout{soundFileId: true, attachmentParams: true}},
+		{112, &hircLayout{soundFileId: true, attachmentParams: true}},
+		{113, &hircLayout{attachmentParams: true}},
+		{122, &hircLayout{attachmentParams: true}},
+		{123, layout132},
+		{134, layout132},
+		{135, nil},
+	} {
+		version, l := tt.version, tt.layout
+		if l == nil {
+			// The objects of unsupported versions are kept as they are stored.
+			data := buildBank(version, layout132, false)
+			bnk := openBank(t, data)
+			assertWritesBank(t, bnk, data)
+			var u *UnsupportedVersionError
+			if err := bnk.CheckVersion(); !errors.As(err, &u) || u.Version != version {
+				t.Errorf("version %d: checking the version: got %v", version, err)
+			}
+			if _, err := bnk.MarshalHierarchy(); !errors.As(err, &u) {
+				t.Errorf("version %d: marshalling the hierarchy: got %v", version, err)
+			}
+			if ids := objectsOf(bnk); len(ids) != 1 || len(ids["UnknownObject"]) != 10 {
+				t.Errorf("version %d: read the objects %v", version, ids)
+			}
+			if loop := bnk.LoopOf(0); loop.Loops {
+				t.Errorf("version %d: the first wem loops %d times", version, loop.Value)
+			}
+			continue
+		}
+
+		got := layoutOf(version)
+		if got == nil || got.soundFileId != l.soundFileId || got.attachmentParams != l.attachmentParams ||
+			got.varActionCount != l.varActionCount {
+			t.Errorf("version %d: the objects are read as laid out by %+v, want %+v", version, got, l)
+		}
+		data := buildBank(version, l, false)
+		bnk := openBank(t, data)
+		assertWritesBank(t, bnk, data)
+		if err := bnk.CheckVersion(); err != nil {
+			t.Errorf("version %d: %v", version, err)
+		}
+		want := map[string][]uint32{
+			"ContainerObject":     {500},
+			"SfxVoiceSoundObject": {1001, 1002, 1003, 1004},
+			"ActionObject":        {600, 601, 602},
+			"EventObject":         {700, 701},
+		}
+		if ids := objectsOf(bnk); !reflect.DeepEqual(ids, want) {
+			t.Fatalf("version %d: read the objects %v, want %v", version, ids, want)
+		}
+		for _, obj := range bnk.Objects() {
+			switch obj := obj.(type) {
+			case *SfxVoiceSoundObject:
+				wem := obj.WemDescriptor.WemId
+				fileInfo := 0
+				if l.soundFileId {
+					fileInfo = 2
+					if obj.StreamType() == StreamStreamed {
+						fileInfo = 1
+					}
+				}
+				if len(obj.FileInfo) != fileInfo || (fileInfo > 0 && obj.FileInfo[0] != wem+1) {
+					t.Errorf("version %d: sound %d has the file info %v", version,
+						obj.Descriptor.ObjectId, obj.FileInfo)
+				}
+				if data, ok := testWems[wem]; ok && obj.WemDescriptor.WemLength != uint32(len(data)) {
+					t.Errorf("version %d: sound %d plays wem %+v", version, obj.Descriptor.ObjectId,
+						obj.WemDescriptor)
+				}
+			case *EventObject:
+				if obj.varCount != l.varActionCount {
+					t.Errorf("version %d: the actions of event %d are counted as a variable "+
+						"length integer: %v", version, obj.Descriptor.ObjectId, obj.varCount)
+				}
+			}
+		}
+		for id, parent := range map[uint32]uint32{500: 0, 1001: 500, 1002: 500, 1003: 0} {
+			if got, ok := bnk.ParentOf(objectById(t, bnk, id)); !ok || got != parent {
+				t.Errorf("version %d: the parent of object %d is %d (%v), want %d", version, id, got,
+					ok, parent)
+			}
+		}
+		if loop := bnk.LoopOf(0); loop != (LoopValue{true, 3}) {
+			t.Errorf("version %d: the first wem loops as %+v", version, loop)
+		}
+		wems := map[uint32][]uint32{700: {1, 2, 3}, 701: {2, 3}}
+		if got := bnk.EventWems(); !reflect.DeepEqual(got, wems) {
+			t.Errorf("version %d: the events reference the wems %v, want %v", version, got, wems)
+		}
+	}
+}
+
+// objectById returns the object of bnk with the given ID.
+func objectById(t *testing.T, bnk *File, id uint32) Object {
+	t.Helper()
+	for _, obj := range bnk.Objects() {
+		if descriptorOf(obj).ObjectId == id {
+			return obj
+		}
+	}
+	t.Fatalf("there is no object %d", id)
+	return nil
+}
+
+func TestLayoutsCoverSupportedVersions(t *testing.T) {
+	next := uint32(MinSupportedVersion)
+	for _, l := range hircLayouts {
+		if l.minVersion != next || l.maxVersion < l.minVersion {
+			t.Fatalf("the layout of versions %d to %d does not follow version %d", l.minVersion,
+				l.maxVersion, next-1)
+		}
+		next = l.maxVersion + 1
+	}
+	if next != MaxSupportedVersion+1 {
+		t.Errorf("the layouts end at version %d, want %d", next-1, MaxSupportedVersion)
+	}
+}
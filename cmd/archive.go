@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"wwiseutil/pck"
+)
+
+// openPck opens the source package at path or, with -extent, the package
+// stored in those extents of the archive at path. The archive is left open
+// until the program exits.
+func (o *options) openPck(path string) (*pck.File, error) {
+	if len(o.extents) == 0 {
+		return pck.Open(path, o.pckOpts...)
+	}
+	parent, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	a := &pck.ExtentArchive{Parent: parent, Files: map[string][]pck.Extent{path: o.extents}}
+	f, err := pck.OpenArchived(a, path, o.pckOpts...)
+	if err != nil {
+		parent.Close()
+		return nil, fmt.Errorf("opening the package in %s at %s: %w", path, o.extents.String(), err)
+	}
+	return f, nil
+}
+
+// sourceExt returns the lower case extension of the source file at path, or
+// ".pck" if it is an archive holding a package given by -extent.
+func (o *options) sourceExt(path string) string {
+	if len(o.extents) > 0 {
+		return ".pck"
+	}
+	return strings.ToLower(filepath.Ext(path))
+}
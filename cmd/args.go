@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// The maximum depth of response files referring to other response files.
+const maxResponseDepth = 8
+
+// expandResponseFiles returns args with every argument of the form @file
+// replaced by the arguments read from file, as by readResponseFile, so that
+// command lines too long for the shell can be passed in a file. Response files
+// may refer to other response files. Arguments that are the value of a
+// preceding flag of fs, such as the @ids.txt of "-id @ids.txt", are left for
+// the flag to interpret, and @@ at the start of an argument stands for a
+// literal @.
+func expandResponseFiles(fs *flag.FlagSet, args []string) ([]string, error) {
+	return expandArgs(fs, args, 0)
+}
+
+func expandArgs(fs *flag.FlagSet, args []string, depth int) ([]string, error) {
+	var expanded []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case strings.HasPrefix(arg, "@@"):
+			expanded = append(expanded, arg[1:])
+		case strings.HasPrefix(arg, "@"):
+			if depth == maxResponseDepth {
+				return nil, fmt.Errorf("%s: response files are nested too deeply", arg)
+			}
+			fileArgs, err := readResponseFile(arg[1:])
+			if err != nil {
+				return nil, err
+			}
+			fileArgs, err = expandArgs(fs, fileArgs, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, fileArgs...)
+		default:
+			expanded = append(expanded, arg)
+			if takesValue(fs, arg) && i+1 < len(args) {
+				i++
+				expanded = append(expanded, args[i])
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// takesValue reports whether arg is a flag of fs whose value is given by the
+// next argument.
+func takesValue(fs *flag.FlagSet, arg string) bool {
+	if !strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+		return false
+	}
+	f := fs.Lookup(strings.TrimLeft(arg, "-"))
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return !ok || !b.IsBoolFlag()
+}
+
+// readResponseFile reads the arguments in the response file at path. They are
+// separated by white space, and arguments holding spaces, such as paths, can
+// be enclosed in double quotes. Lines starting with # are comments.
+func readResponseFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading response file: %w", err)
+	}
+	var args []string
+	for n, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		var arg strings.Builder
+		inArg, quoted := false, false
+		for _, r := range line {
+			switch {
+			case r == '"':
+				quoted, inArg = !quoted, true
+			case unicode.IsSpace(r) && !quoted:
+				if inArg {
+					args = append(args, arg.String())
+					arg.Reset()
+					inArg = false
+				}
+			default:
+				arg.WriteRune(r)
+				inArg = true
+			}
+		}
+		if quoted {
+			return nil, fmt.Errorf("%s:%d: unterminated quote", path, n+1)
+		}
+		if inArg {
+			args = append(args, arg.String())
+		}
+	}
+	return args, nil
+}
+
+// readListFile reads the items listed in the file at path, such as IDs or
+// paths, one per line. Lines that are empty or start with # are ignored.
+func readListFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading list file: %w", err)
+	}
+	var items []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			items = append(items, line)
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+)
+
+// version is the version of the tool recorded in audit files. Release builds
+// set it with -ldflags "-X main.version=<version>".
+var version = "dev"
+
+// The extension added to the path of an output to name its audit file.
+const auditExt = ".audit.json"
+
+// An audit records how an output file was produced, so that a shipped file can
+// be traced back to the inputs and replacements it was built from.
+type audit struct {
+	Tool         string              `json:"tool"`
+	Version      string              `json:"version"`
+	Operation    string              `json:"operation"`
+	Started      time.Time           `json:"started"`
+	Finished     time.Time           `json:"finished"`
+	Input        *auditFile          `json:"input,omitempty"`
+	Replacements []*auditReplacement `json:"replacements,omitempty"`
+	Removed      []uint32            `json:"removed,omitempty"`
+	Output       *auditFile          `json:"output"`
+
+	// Whether the audit is written next to the output, see -audit.
+	writeFile bool
+	// The project history file the audit is appended to, if any, see
+	// -project.
+	project string
+}
+
+// An auditFile identifies a file by its contents. Directories, such as the
+// output of unpacking, are only identified by their path.
+type auditFile struct {
+	Path   string `json:"path"`
+	Dir    bool   `json:"dir,omitempty"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// An auditReplacement is a file that replaced or was added as an entry.
+type auditReplacement struct {
+	auditFile
+	Type string `json:"type"`
+	ID   uint32 `json:"id"`
+	New  bool   `json:"new,omitempty"`
+}
+
+// newAudit starts an audit of the operation op on the file at input, if any.
+func newAudit(op, input string) *audit {
+	a := &audit{
+		Tool:      "wwiseutil_SDDE",
+		Version:   version,
+		Operation: op,
+		Started:   time.Now().UTC(),
+	}
+	if input != "" {
+		a.Input = &auditFile{Path: input}
+	}
+	return a
+}
+
+// addReplacement records that the file at path replaced, or if isNew was added
+// as, the entry of type typ with the given ID.
+func (a *audit) addReplacement(path, typ string, id uint32, isNew bool) {
+	a.Replacements = append(a.Replacements, &auditReplacement{
+		auditFile: auditFile{Path: path},
+		Type:      typ,
+		ID:        id,
+		New:       isNew,
+	})
+}
+
+// writeFor finishes the audit of an operation that produced the file at output,
+// hashing every file it refers to, and writes it next to output and appends it
+// to the project history, as requested.
+func (a *audit) writeFor(output string) error {
+	a.Finished = time.Now().UTC()
+	a.Output = &auditFile{Path: output}
+	files := []*auditFile{a.Output}
+	if a.Input != nil {
+		files = append(files, a.Input)
+	}
+	for _, r := range a.Replacements {
+		files = append(files, &r.auditFile)
+	}
+	for _, f := range files {
+		if err := f.hash(); err != nil {
+			return err
+		}
+	}
+
+	if a.project != "" {
+		if err := appendHistory(a.project, a); err != nil {
+			return err
+		}
+	}
+	if !a.writeFile {
+		return nil
+	}
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(output+auditExt, append(data, '\n'), 0644)
+}
+
+// hash fills in the size and SHA-256 hash of f from its contents.
+func (f *auditFile) hash() error {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return fmt.Errorf("hashing %s: %w", f.Path, err)
+	}
+	defer file.Close()
+	if fi, err := file.Stat(); err == nil && fi.IsDir() {
+		f.Dir = true
+		return nil
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, file)
+	if err != nil {
+		return fmt.Errorf("hashing %s: %w", f.Path, err)
+	}
+	f.Size = n
+	f.SHA256 = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// finishAudit writes a, if auditing is enabled, for the operation that
+// produced output. Failing to write an audit file does not undo the operation,
+// so it is reported as a warning.
+func finishAudit(a *audit, output string) {
+	if a == nil {
+		return
+	}
+	if err := a.writeFor(output); err != nil {
+		log.Printf("Warning: could not write audit file: %v", err)
+		return
+	}
+	if a.writeFile {
+		log.Printf("Audit trail written to: %s", output+auditExt)
+	}
+	if a.project != "" {
+		log.Printf("Recorded in the history of: %s", a.project)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"wwiseutil/pck"
+)
+
+// handleBuild builds a new package at outputFile from the bnk and wem folders
+// of dir. If templateFile is not empty, the header of the package at
+// templateFile is used for the new package.
+func handleBuild(dir, templateFile, outputFile string, opts *options) {
+	a := opts.startAudit("build", templateFile)
+	buildOpts := opts.pckOpts
+	if opts.progress {
+		buildOpts = append(buildOpts, pck.WithProgress(newProgressPrinter("Wrote")))
+	}
+	if templateFile != "" {
+		t, err := pck.Open(templateFile, opts.pckOpts...)
+		if err != nil {
+			log.Fatalf("Error opening template PCK file: %v", err)
+		}
+		defer t.Close()
+		log.Printf("Using the header of %s (%s)", templateFile, t.Format)
+		buildOpts = append(buildOpts, pck.WithTemplate(t))
+	}
+
+	outFile, err := os.Create(outputFile)
+	if err != nil {
+		log.Fatalf("Error creating output file: %v", err)
+	}
+	defer outFile.Close()
+
+	log.Printf("Building PCK file from: %s", dir)
+	bytesWritten, err := pck.Build(dir, outFile, buildOpts...)
+	if err != nil {
+		log.Fatalf("Error building PCK file: %v", err)
+	}
+
+	log.Println("Build completed successfully!")
+	log.Printf("Output file written to: %s", outputFile)
+	log.Printf("Wrote %d bytes in total", bytesWritten)
+	finishAudit(a, outputFile)
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+
+	"wwiseutil/pck"
+)
+
+// parseKey parses a key given as hexadecimal, or as @file for a key file whose
+// bytes are the key.
+func parseKey(s string) ([]byte, error) {
+	if strings.HasPrefix(s, "@") {
+		return os.ReadFile(s[1:])
+	}
+	return hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(s), "0x"))
+}
+
+// newCipher returns the cipher named name ("xor" or "aes-ctr") with the given
+// key and IV, given as by parseKey.
+func newCipher(name, key, iv string) (pck.Cipher, error) {
+	k, err := parseKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -key: %v", err)
+	}
+	switch name {
+	case "xor":
+		return pck.NewXORCipher(k)
+	case "aes-ctr":
+		v, err := parseKey(iv)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -iv: %v", err)
+		}
+		return pck.NewAESCTRCipher(k, v)
+	}
+	return nil, fmt.Errorf("unknown cipher %q. Use xor or aes-ctr", name)
+}
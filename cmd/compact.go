@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log"
+
+	"wwiseutil/pck"
+	"wwiseutil/util"
+)
+
+// handleSlack reports the wasted space of the package at inputFile.
+func handleSlack(inputFile string, opts *options) {
+	f, err := opts.openPck(inputFile)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer f.Close()
+
+	r, err := f.Slack()
+	if err != nil {
+		log.Fatalf("Error analysing PCK file: %v", err)
+	}
+	log.Println(r)
+}
+
+// handleCompact writes the package at inputFile to outputFile without the gaps
+// between its entries.
+func handleCompact(inputFile, outputFile string, opts *options) {
+	a := opts.startAudit("compact", inputFile)
+	f, err := opts.openPck(inputFile)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer f.Close()
+
+	compactOpts := opts.pckOpts
+	if opts.progress {
+		compactOpts = append(compactOpts, pck.WithProgress(newProgressPrinter("Wrote")))
+	}
+	if opts.backup {
+		if err := backupOriginal(outputFile); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+	outFile := opts.createOutput(inputFile, outputFile)
+	written, saved, err := f.Compact(outFile, compactOpts...)
+	if err != nil {
+		outFile.Abort()
+		log.Fatalf("Error compacting PCK file: %v", err)
+	}
+	f.Close()
+	commitOutput(outFile)
+	log.Printf("Output file written to: %s", outputFile)
+	if saved >= 0 {
+		log.Printf("Wrote %d bytes in total, saving %s", written, util.FormatByteSize(saved))
+	} else {
+		log.Printf("Wrote %d bytes in total, %s more than the source file", written, util.FormatByteSize(-saved))
+	}
+	finishAudit(a, outputFile)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"wwiseutil"
+	"wwiseutil/util"
+	"wwiseutil/wem"
+)
+
+// The sample rate of the WAVE files of a dataset, unless given by -decode.
+const datasetSampleRate = 48000
+
+// The name of the metadata file of a dataset.
+const datasetMetadata = "metadata.csv"
+
+// The columns of the metadata file of a dataset.
+var datasetColumns = []string{"id", "name", "duration", "language", "package", "file", "speaker", "text"}
+
+// A datasetEntry is a wem exported to a dataset.
+type datasetEntry struct {
+	id       uint32
+	language string
+	data     io.Reader
+}
+
+// handleDataset exports the decodable wems of inputFile to outputDir as mono
+// 16 bit WAVE files of a uniform sample rate, and appends a row per wem to the
+// metadata CSV file of the dataset, recording its ID, its name in names, its
+// duration in seconds, its language, the package it came from and its subtitle
+// in subtitlesFile, if any. Running it on several packages with the same
+// outputDir builds a single dataset.
+func handleDataset(inputFile, outputDir, namesFile, subtitlesFile string, opts *options) {
+	opts.checkOperation(wwiseutil.OpDecode, inputFile)
+	var names util.Names
+	if namesFile != "" {
+		var err error
+		if names, err = util.ReadNames(namesFile); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+		log.Printf("Read %d name(s) from %s", len(names), namesFile)
+	}
+	subs := readSubtitles(subtitlesFile)
+	rate := opts.decodeRate
+	if rate == 0 {
+		rate = datasetSampleRate
+	}
+
+	var entries []datasetEntry
+	switch ext := opts.sourceExt(inputFile); ext {
+	case ".pck", ".npck":
+		f, err := opts.openPck(inputFile)
+		if err != nil {
+			log.Fatalf("Error opening PCK file: %v", err)
+		}
+		defer f.Close()
+		for _, w := range f.Wems {
+			language, _ := f.LanguageOf(w.Index)
+			entries = append(entries, datasetEntry{w.Index.ID, language, w.Open()})
+		}
+	case ".bnk", ".nbnk":
+		f, err := openBnk(inputFile, opts)
+		if err != nil {
+			log.Fatalf("Error opening BNK file: %v", err)
+		}
+		defer f.Close()
+		for _, w := range f.Wems() {
+			entries = append(entries, datasetEntry{w.Descriptor.WemId, "", w.Reader})
+		}
+	default:
+		log.Fatalf("Unsupported file type: %s", ext)
+	}
+
+	wavDir := filepath.Join(outputDir, "wav")
+	if err := os.MkdirAll(wavDir, 0755); err != nil {
+		log.Fatalf("Error creating output directory: %v", err)
+	}
+	metadata, err := openMetadata(filepath.Join(outputDir, datasetMetadata))
+	if err != nil {
+		log.Fatalf("Error opening %s: %v", datasetMetadata, err)
+	}
+	defer metadata.Close()
+	w := csv.NewWriter(metadata)
+
+	exported := 0
+	skipped := make(map[string]int)
+	for _, e := range entries {
+		if len(opts.ids) > 0 && !opts.ids.contains(e.id) {
+			continue
+		}
+		pcm, codec, err := decodeWem(e.data)
+		if errors.Is(err, wem.ErrUnsupportedCodec) {
+			skipped[codec]++
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: skipping wem %s: %v", util.FormatID(e.id), err)
+			continue
+		}
+		pcm = pcm.Resample(rate).Downmix()
+
+		sub := subs.Find(e.id, names[e.id])
+		file := subtitleFileName(e.id, sub, "wav")
+		out, err := os.Create(filepath.Join(wavDir, file))
+		if err != nil {
+			log.Fatalf("Error creating %s: %v", file, err)
+		}
+		_, err = pcm.WriteWAV(out)
+		if cerr := out.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			log.Fatalf("Error writing %s: %v", file, err)
+		}
+		speaker, text := subtitleColumns(sub)
+		w.Write([]string{
+			strconv.FormatUint(uint64(e.id), 10),
+			names[e.id],
+			strconv.FormatFloat(pcm.Duration().Seconds(), 'f', 3, 64),
+			e.language,
+			filepath.Base(inputFile),
+			"wav/" + file,
+			speaker,
+			text,
+		})
+		exported++
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Fatalf("Error writing %s: %v", datasetMetadata, err)
+	}
+
+	for codec, n := range skipped {
+		log.Printf("Skipped %d wem(s) encoded with %s, which cannot be decoded.", n, codec)
+	}
+	log.Printf("Exported %d wem(s) as %d Hz mono WAVs to: %s", exported, rate, outputDir)
+}
+
+// openMetadata opens the metadata file of a dataset at path for appending,
+// creating it with a header row if it does not exist.
+func openMetadata(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err == nil && info.Size() == 0 {
+		w := csv.NewWriter(f)
+		w.Write(datasetColumns)
+		w.Flush()
+		err = w.Error()
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"wwiseutil/bnk"
+	"wwiseutil/pck"
+)
+
+// handleDiff reports how otherFile differs from inputFile: the HIRC objects of
+// SoundBanks, or the entries of packages.
+func handleDiff(inputFile, otherFile string, opts *options) {
+	kind := func(path string) string {
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".bnk", ".nbnk":
+			return "bnk"
+		case ".pck", ".npck":
+			return "pck"
+		}
+		return ""
+	}
+	switch k := kind(inputFile); {
+	case k == "" || k != kind(otherFile):
+		log.Fatalf("Comparing is only supported between two .bnk files or two .pck files")
+	case k == "pck":
+		handlePckDiff(inputFile, otherFile, opts)
+		return
+	}
+
+	oldBnk, err := openBnk(inputFile, opts)
+	if err != nil {
+		log.Fatalf("Error opening BNK file: %v", err)
+	}
+	defer oldBnk.Close()
+	newBnk, err := openBnk(otherFile, opts)
+	if err != nil {
+		log.Fatalf("Error opening BNK file: %v", err)
+	}
+	defer newBnk.Close()
+
+	changes, err := bnk.DiffObjects(oldBnk, newBnk)
+	if err != nil {
+		log.Fatalf("Error comparing BNK files: %v", err)
+	}
+	if len(changes) == 0 {
+		log.Println("The HIRC objects of both files are identical.")
+		return
+	}
+
+	counts := make(map[string]int)
+	b := new(strings.Builder)
+	for _, c := range changes {
+		counts[c.Kind]++
+		b.WriteString(c.String())
+	}
+	log.Print(b.String())
+	log.Printf("%d object(s) added, %d removed, %d changed.",
+		counts[bnk.ObjectAdded], counts[bnk.ObjectRemoved], counts[bnk.ObjectChanged])
+}
+
+// handlePckDiff reports the entries that were added to, removed from or changed
+// in the package at otherFile relative to the one at inputFile.
+func handlePckDiff(inputFile, otherFile string, opts *options) {
+	oldPck, err := pck.Open(inputFile, opts.pckOpts...)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer oldPck.Close()
+	newPck, err := pck.Open(otherFile, opts.pckOpts...)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer newPck.Close()
+
+	changes, err := pck.Diff(oldPck, newPck)
+	if err != nil {
+		log.Fatalf("Error comparing PCK files: %v", err)
+	}
+	if len(changes) == 0 {
+		log.Println("The entries of both files are identical.")
+		return
+	}
+
+	counts := make(map[string]int)
+	b := new(strings.Builder)
+	for _, c := range changes {
+		counts[c.Kind]++
+		fmt.Fprintln(b, c)
+	}
+	log.Print(b.String())
+	log.Printf("%d entries added, %d removed, %d changed.",
+		counts[pck.EntryAdded], counts[pck.EntryRemoved], counts[pck.EntryChanged])
+}
+
+// openBnk opens the SoundBank at path, through the cache of parsed objects if
+// one is given by -cache, warning if its HIRC objects cannot be parsed because
+// of its version.
+func openBnk(path string, opts *options) (*bnk.File, error) {
+	var f *bnk.File
+	var err error
+	if opts.cacheDir != "" {
+		f, err = bnk.OpenCached(path, opts.cacheDir)
+	} else {
+		f, err = bnk.Open(path)
+	}
+	if err == nil {
+		if err := f.CheckVersion(); err != nil {
+			log.Printf("Warning: %s: %v. Its HIRC objects are kept as they are.", path, err)
+		}
+	}
+	return f, err
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"wwiseutil/pck"
+	"wwiseutil/util"
+)
+
+// idList is a flag.Value holding a list of Wwise IDs. Each use of the flag may
+// give a comma separated list of IDs, in decimal or 0x-prefixed hexadecimal,
+// or @file for a file listing them one or more per line.
+type idList []uint32
+
+func (l *idList) String() string {
+	if l == nil {
+		return ""
+	}
+	var ids []string
+	for _, id := range *l {
+		ids = append(ids, util.FormatID(id))
+	}
+	return strings.Join(ids, ", ")
+}
+
+func (l *idList) Set(s string) error {
+	if strings.HasPrefix(s, "@") {
+		lines, err := readListFile(s[1:])
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if err := l.Set(line); err != nil {
+				return fmt.Errorf("%s: %w", s[1:], err)
+			}
+		}
+		return nil
+	}
+	for _, field := range strings.Split(s, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		id, err := util.ParseID(field)
+		if err != nil {
+			return err
+		}
+		*l = append(*l, id)
+	}
+	return nil
+}
+
+// contains reports whether id is in l.
+func (l idList) contains(id uint32) bool {
+	for _, i := range l {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// remapList is a flag.Value holding the ID remappings given by each use of the
+// flag as from:to pairs, with IDs as idList accepts them, separated by commas,
+// or @file for a file listing them one per line.
+type remapList map[uint32]uint32
+
+func (l *remapList) String() string {
+	if l == nil {
+		return ""
+	}
+	var ids []uint32
+	for id := range *l {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	var pairs []string
+	for _, id := range ids {
+		pairs = append(pairs, util.FormatID(id)+" to "+util.FormatID((*l)[id]))
+	}
+	return strings.Join(pairs, ", ")
+}
+
+func (l *remapList) Set(s string) error {
+	if strings.HasPrefix(s, "@") {
+		lines, err := readListFile(s[1:])
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if err := l.Set(line); err != nil {
+				return fmt.Errorf("%s: %w", s[1:], err)
+			}
+		}
+		return nil
+	}
+	if *l == nil {
+		*l = make(remapList)
+	}
+	for _, field := range strings.Split(s, ",") {
+		if strings.TrimSpace(field) == "" {
+			continue
+		}
+		from, to, ok := cut(field, ":")
+		if !ok {
+			return fmt.Errorf("invalid remapping %q, want from:to", field)
+		}
+		id, err := util.ParseID(from)
+		if err != nil {
+			return err
+		}
+		if (*l)[id], err = util.ParseID(to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pathList is a flag.Value holding the paths given by each use of the flag.
+// @file gives the paths listed in a file, one per line.
+type pathList []string
+
+func (l *pathList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ", ")
+}
+
+func (l *pathList) Set(s string) error {
+	if strings.HasPrefix(s, "@") {
+		lines, err := readListFile(s[1:])
+		if err != nil {
+			return err
+		}
+		*l = append(*l, lines...)
+		return nil
+	}
+	*l = append(*l, s)
+	return nil
+}
+
+// extentList is a flag.Value holding the extents given by each use of the
+// flag as offset:length, in decimal or 0x-prefixed hexadecimal, or @file for a
+// file listing them one per line.
+type extentList []pck.Extent
+
+func (l *extentList) String() string {
+	if l == nil {
+		return ""
+	}
+	var extents []string
+	for _, e := range *l {
+		extents = append(extents, fmt.Sprintf("%d:%d", e.Offset, e.Length))
+	}
+	return strings.Join(extents, ", ")
+}
+
+func (l *extentList) Set(s string) error {
+	if strings.HasPrefix(s, "@") {
+		lines, err := readListFile(s[1:])
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			if err := l.Set(line); err != nil {
+				return fmt.Errorf("%s: %w", s[1:], err)
+			}
+		}
+		return nil
+	}
+	offset, length, ok := cut(s, ":")
+	if !ok {
+		return fmt.Errorf("invalid extent %q, want offset:length", s)
+	}
+	o, err := strconv.ParseInt(strings.TrimSpace(offset), 0, 64)
+	if err != nil || o < 0 {
+		return fmt.Errorf("invalid offset %q", offset)
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(length), 0, 64)
+	if err != nil || n < 0 {
+		return fmt.Errorf("invalid length %q", length)
+	}
+	*l = append(*l, pck.Extent{Offset: o, Length: n})
+	return nil
+}
+
+// parseDuplicatePolicy parses the policy given as "error", "first" or "last".
+func parseDuplicatePolicy(s string) (pck.DuplicatePolicy, error) {
+	for _, p := range []pck.DuplicatePolicy{pck.DuplicateError, pck.DuplicateKeepFirst, pck.DuplicateKeepLast} {
+		if s == p.String() {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown policy %q", s)
+}
+
+// parseWatermark parses the watermark given as the name of a mod, optionally
+// followed by a colon and its version, e.g. "My Mod:2". The version defaults
+// to 0.
+func parseWatermark(s string) (*pck.Watermark, error) {
+	name, version := s, uint64(0)
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		v, err := strconv.ParseUint(s[i+1:], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version %q", s[i+1:])
+		}
+		name, version = s[:i], v
+	}
+	if name == "" {
+		return nil, fmt.Errorf("the mod name is empty")
+	}
+	return pck.NewWatermark(name, uint32(version)), nil
+}
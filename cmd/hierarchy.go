@@ -0,0 +1,73 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// handleExportHierarchy writes the HIRC objects of the SoundBank at inputFile
+// to outputFile as a JSON document.
+func handleExportHierarchy(inputFile, outputFile string, opts *options) {
+	switch strings.ToLower(filepath.Ext(inputFile)) {
+	case ".bnk", ".nbnk":
+	default:
+		log.Fatalf("Error: -hirc is only supported for .bnk files.")
+	}
+	f, err := openBnk(inputFile, opts)
+	if err != nil {
+		log.Fatalf("Error opening BNK file: %v", err)
+	}
+	defer f.Close()
+
+	data, err := f.MarshalHierarchy()
+	if err != nil {
+		log.Fatalf("Error encoding HIRC objects: %v", err)
+	}
+	if err := os.WriteFile(outputFile, append(data, '\n'), 0644); err != nil {
+		log.Fatalf("Error writing HIRC objects: %v", err)
+	}
+	log.Printf("%d HIRC objects written to: %s", len(f.Objects()), outputFile)
+}
+
+// handleImportHierarchy rewrites the SoundBank at inputFile to outputFile with
+// the HIRC objects of the JSON document at hircFile, as written by
+// handleExportHierarchy and edited.
+func handleImportHierarchy(hircFile, inputFile, outputFile string, opts *options) {
+	switch strings.ToLower(filepath.Ext(inputFile)) {
+	case ".bnk", ".nbnk":
+	default:
+		log.Fatalf("Error: -applyhirc is only supported for .bnk files.")
+	}
+	a := opts.startAudit("hirc", inputFile)
+	data, err := os.ReadFile(hircFile)
+	if err != nil {
+		log.Fatalf("Error reading HIRC objects: %v", err)
+	}
+	f, err := openBnk(inputFile, opts)
+	if err != nil {
+		log.Fatalf("Error opening BNK file: %v", err)
+	}
+	defer f.Close()
+	if err := f.UnmarshalHierarchy(data); err != nil {
+		log.Fatalf("Error applying %s: %v", hircFile, err)
+	}
+
+	if opts.backup {
+		if err := backupOriginal(outputFile); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+	out := opts.createOutput(inputFile, outputFile)
+	n, err := f.WriteTo(out)
+	if err != nil {
+		out.Abort()
+		log.Fatalf("Error writing BNK file: %v", err)
+	}
+	f.Close()
+	commitOutput(out)
+	log.Printf("Output file written to: %s", outputFile)
+	log.Printf("Wrote %d bytes in total", n)
+	finishAudit(a, outputFile)
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// appendHistory appends a to the project history file at path, as a line of
+// JSON, creating the file if it does not exist.
+func appendHistory(path string, a *audit) error {
+	data, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(data, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// readHistory reads the operations recorded in the project history file at
+// path, oldest first.
+func readHistory(path string) ([]*audit, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var history []*audit
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 64<<20)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+		a := new(audit)
+		if err := json.Unmarshal(sc.Bytes(), a); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		history = append(history, a)
+	}
+	return history, sc.Err()
+}
+
+// handleHistory lists the operations recorded in the project history file at
+// path. If file is not empty, only the operations that read or produced it are
+// listed, to trace how it was made.
+func handleHistory(path, file string, opts *options) {
+	history, err := readHistory(path)
+	if err != nil {
+		log.Fatalf("Error reading history: %v", err)
+	}
+
+	b := new(strings.Builder)
+	listed := 0
+	for _, a := range history {
+		if file != "" && !a.involves(file) {
+			continue
+		}
+		listed++
+		fmt.Fprintf(b, "\n%s %-9s", a.Finished.Local().Format("2006-01-02 15:04:05"), a.Operation)
+		if a.Input != nil && a.Input.Path != "" {
+			fmt.Fprintf(b, " %s ->", a.Input.Path)
+		}
+		fmt.Fprintf(b, " %s", a.Output.describe())
+		if n := len(a.Replacements); n > 0 {
+			fmt.Fprintf(b, ", %d replacement(s)", n)
+		}
+		if n := len(a.Removed); n > 0 {
+			fmt.Fprintf(b, ", %d removed", n)
+		}
+		if !opts.verbose {
+			continue
+		}
+		if a.Input != nil && a.Input.Path != "" {
+			fmt.Fprintf(b, "\n    input  %s", a.Input.describe())
+		}
+		for _, r := range a.Replacements {
+			verb := "replaced"
+			if r.New {
+				verb = "added"
+			}
+			fmt.Fprintf(b, "\n    %s %s ID %d with %s", verb, r.Type, r.ID, r.describe())
+		}
+		fmt.Fprintf(b, "\n    tool   %s %s", a.Tool, a.Version)
+	}
+	log.Print(b.String())
+	log.Printf("%d of %d recorded operation(s) listed.", listed, len(history))
+}
+
+// involves reports whether the operation recorded by a read or produced the
+// file at path.
+func (a *audit) involves(path string) bool {
+	files := []*auditFile{a.Output}
+	if a.Input != nil {
+		files = append(files, a.Input)
+	}
+	for _, r := range a.Replacements {
+		files = append(files, &r.auditFile)
+	}
+	for _, f := range files {
+		if f != nil && sameFile(f.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// sameFile reports whether the paths a and b refer to the same file.
+func sameFile(a, b string) bool {
+	if absA, err := filepath.Abs(a); err == nil {
+		a = absA
+	}
+	if absB, err := filepath.Abs(b); err == nil {
+		b = absB
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
+
+// describe returns the path of f with the start of its hash, if it has one.
+func (f *auditFile) describe() string {
+	if f == nil {
+		return "(none)"
+	}
+	if f.SHA256 == "" {
+		return f.Path
+	}
+	return fmt.Sprintf("%s (sha256 %.16s)", f.Path, f.SHA256)
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"wwiseutil/pck"
+)
+
+// handleExportIndex writes the header and index tables of the package at
+// inputFile to outputFile as a JSON document.
+func handleExportIndex(inputFile, outputFile string, opts *options) {
+	f, err := opts.openPck(inputFile)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer f.Close()
+
+	data, err := f.MarshalIndex()
+	if err != nil {
+		log.Fatalf("Error encoding index: %v", err)
+	}
+	if err := os.WriteFile(outputFile, append(data, '\n'), 0644); err != nil {
+		log.Fatalf("Error writing index: %v", err)
+	}
+	log.Printf("Index of %d bnk and %d wem entries written to: %s",
+		len(f.BnkIndexes), len(f.WemIndexes), outputFile)
+}
+
+// handleSaveHeader writes the raw header of the package at inputFile to
+// outputFile, for -header to inject into the packages written for the same
+// game.
+func handleSaveHeader(inputFile, outputFile string, opts *options) {
+	f, err := opts.openPck(inputFile)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer f.Close()
+
+	raw := f.Header.Raw()
+	if err := os.WriteFile(outputFile, raw, 0644); err != nil {
+		log.Fatalf("Error writing header: %v", err)
+	}
+	log.Printf("Header of %d bytes written to: %s", len(raw), outputFile)
+}
+
+// handleImportIndex rewrites the package at inputFile to outputFile with the
+// header and index tables of the JSON document at indexFile, as written by
+// handleExportIndex and possibly edited.
+func handleImportIndex(indexFile, inputFile, outputFile string, opts *options) {
+	a := opts.startAudit("index", inputFile)
+	data, err := os.ReadFile(indexFile)
+	if err != nil {
+		log.Fatalf("Error reading index: %v", err)
+	}
+	f, err := pck.Open(inputFile, opts.pckOpts...)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer f.Close()
+	if err := f.UnmarshalIndex(data); err != nil {
+		log.Fatalf("Error applying %s: %v", indexFile, err)
+	}
+
+	if opts.backup {
+		if err := backupOriginal(outputFile); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+	out := opts.createOutput(inputFile, outputFile)
+	n, err := f.WriteTo(out)
+	if err != nil {
+		out.Abort()
+		log.Fatalf("Error writing PCK file: %v", err)
+	}
+	f.Close()
+	commitOutput(out)
+	log.Printf("Output file written to: %s", outputFile)
+	log.Printf("Wrote %d bytes in total", n)
+	finishAudit(a, outputFile)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"wwiseutil/pck"
+	"wwiseutil/util"
+)
+
+// handleLookup reports which of the packages in dir hold the entries with the
+// given IDs and, if outputDir is not empty, unpacks those entries to it, in a
+// folder per package.
+func handleLookup(dir string, ids []uint32, outputDir string, opts *options) {
+	log.Printf("Indexing PCK files in: %s", dir)
+	p, err := pck.OpenProjectDir(dir, opts.pckOpts...)
+	if err != nil {
+		log.Fatalf("Error indexing directory: %v", err)
+	}
+	defer p.Close()
+	for path, err := range p.Skipped {
+		log.Printf("Warning: skipped %s: %v", path, err)
+	}
+	log.Printf("Indexed %d PCK file(s).", len(p.Packages))
+
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "\n%-25s | %-5s | %-15s | %-10s | %s\n", "ID", "Table", "Offset", "Length", "Package")
+	found := 0
+	for _, id := range ids {
+		entries := p.Lookup(id)
+		if len(entries) == 0 {
+			fmt.Fprintf(b, "%-25s | %-5s | %-15s | %-10s | %s\n", util.FormatID(id), "-", "-", "-", "not found")
+			continue
+		}
+		found++
+		for _, e := range entries {
+			fmt.Fprintf(b, "%-25s | %-5s | %-15d | %-10d | %s\n", util.FormatID(id), e.Table,
+				e.Entry.Index.Offset, e.Entry.Index.Length, e.Package.Name)
+		}
+	}
+	log.Print(b.String())
+	log.Printf("Found %d of %d ID(s).", found, len(ids))
+
+	if outputDir == "" || found == 0 {
+		return
+	}
+	unpackOpts := []pck.Option{pck.WithIDs(ids...), pck.WithWorkers(opts.workers)}
+	if opts.byLanguage {
+		unpackOpts = append(unpackOpts, pck.SplitLanguages())
+	}
+	if err := warnTruncated(p.UnpackToContext(opts.ctx, outputDir, unpackOpts...)); err != nil {
+		log.Fatalf("Error unpacking: %v", err)
+	}
+	log.Printf("Successfully unpacked the entries found to: %s", outputDir)
+}
@@ -1,21 +1,82 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"io/fs"
+	"io"
 	"log"
+	"math"
 	"os"
+	"os/signal"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
-	"wwiseutil/bnk"
+	"wwiseutil"
 	"wwiseutil/pck"
+	"wwiseutil/util"
 	"wwiseutil/wwise"
 )
 
+// options holds the command line flags that affect how an operation is run.
+type options struct {
+	verbose bool
+	// The checksum of every entry shown in the verbose listing of a .pck, one
+	// of the pck.Checksum constants, or "" for none.
+	checksum string
+	// Whether skeletons describe their package with its entry data zeroed.
+	zeroPayloads bool
+	// Whether to proceed despite problems that would otherwise stop an
+	// operation.
+	force bool
+	// The path to a manifest describing replacement files, if any.
+	manifest string
+	// The IDs of the entries to operate on, or empty for all entries.
+	ids idList
+	// The IDs of the entries to remove when replacing.
+	remove idList
+	// Whether removing wems from a .bnk also removes the objects playing them.
+	removeObjects bool
+	// The IDs the entries are remapped to when replacing, keyed by their
+	// original IDs.
+	remap remapList
+	// The extents of the source file holding the package to read, if it is an
+	// archive holding a package, or empty.
+	extents extentList
+	// Whether an audit file is written next to every output.
+	audit bool
+	// The project history file every operation is recorded in, if any.
+	project string
+	// Whether unpacked entries are split into a folder per language.
+	byLanguage bool
+	// Whether the output file may be the source file.
+	overwrite bool
+	// Whether an existing output file is backed up before it is overwritten.
+	backup bool
+	// Whether a .pck is patched in place rather than written to a new output.
+	inPlace bool
+	// Whether a .pck is patched by appending the replacement data to it, see
+	// -append.
+	appendData bool
+	// Whether replacing only reports the layout of the output, see -dryrun.
+	dryRun bool
+	// The number of entries of a .pck unpacked at once.
+	workers int
+	// Whether the progress of long operations on a .pck is shown.
+	progress bool
+	// The directory the parsed objects of .bnk files are cached in, if any.
+	cacheDir string
+	// The sample rate unpacked wems are decoded to WAV at, or 0 if they are
+	// not decoded.
+	decodeRate int
+	// The options used when opening and repacking .pck files.
+	pckOpts []pck.Option
+	// The context of long operations, done when the user interrupts them.
+	ctx context.Context
+}
+
 func main() {
 	log.SetFlags(0)
 
@@ -27,8 +88,93 @@ func main() {
 	flag.StringVar(&outputFlag, "output", "", "Output directory for unpacking or output file for repacking.")
 	flag.StringVar(&targetFlag, "t", "", "(shorthand for -target)")
 	flag.StringVar(&targetFlag, "target", "", "Directory containing replacement files.")
-
-	var unpackFlag, replaceFlag, verboseFlag bool
+	var bwlimitFlag, manifestFlag, sheetFlag, diffFlag, buildFlag, alignFlag, watermarkFlag, minimizeFlag, cacheFlag, makePatchFlag, applyPatchFlag, datasetFlag, namesFlag, subtitlesFlag, splitFlag string
+	var checksumFlag, cipherFlag, keyFlag, ivFlag string
+	var entryCipherFlag bool
+	flag.StringVar(&cipherFlag, "cipher", "", "Read a .pck encrypted with this cipher, xor or aes-ctr, with -key, and encrypt the .pck files written from it the same way. The whole file is encrypted, unless -entrycipher is given.")
+	flag.StringVar(&keyFlag, "key", "", "The key of -cipher, in hexadecimal, or @file for a key file holding the key's bytes.")
+	flag.StringVar(&ivFlag, "iv", "", "The 16 byte initial counter of -cipher aes-ctr, given as -key is.")
+	flag.BoolVar(&entryCipherFlag, "entrycipher", false, "With -cipher, only the data of each entry is encrypted, starting over at each entry, and the header is not.")
+	flag.StringVar(&checksumFlag, "checksum", "", "With -v, add a column of this checksum of every entry to the listing of a .pck: sha256, md5 or crc32, the fastest. Compare the listings of two versions of a game to see which entries changed.")
+	var skeletonFlag, rehydrateFlag, onDupFlag, indexFlag, applyIndexFlag, projectFlag, dataAlignFlag, saveHeaderFlag, headerFlag, hircFlag, applyHircFlag string
+	flag.StringVar(&makePatchFlag, "mkpatch", "", "Write a patch turning the source .pck into this modified .pck to -output. The patch only holds the data that is not already in the source file.")
+	flag.StringVar(&applyPatchFlag, "applypatch", "", "Apply this patch, made by -mkpatch, to the source .pck, writing the modified .pck to -output.")
+	flag.StringVar(&rehydrateFlag, "rehydrate", "", "Rebuild the .pck described by this skeleton .json at -output, taking the audio from the source .pck and, if -target is given, the mod files in it.")
+	flag.StringVar(&projectFlag, "project", "", "Append a record of every unpack, repack, replacement and build, with the hashes of its files, to this project history file. List it with -history.")
+	flag.StringVar(&indexFlag, "index", "", "Write the header and index tables of the source .pck to this .json path, for inspecting or editing them.")
+	flag.StringVar(&applyIndexFlag, "applyindex", "", "Rewrite the source .pck to -output with the header and index tables of this .json file, written by -index and possibly edited.")
+	flag.StringVar(&saveHeaderFlag, "saveheader", "", "Write the raw header of the source .pck to this file, to be given to -header.")
+	flag.StringVar(&headerFlag, "header", "", "When replacing in, building or merging a .pck, write the raw header in this file, saved by -saveheader from a package of the game, in place of the header of the .pck written. For games whose header is not understood.")
+	flag.StringVar(&skeletonFlag, "skeleton", "", "Write the skeleton of the source .pck to this .json path: its header, index tables and the hashes of its entries, without any audio data.")
+	flag.StringVar(&splitFlag, "split", "", "Split the source .pck into volumes of at most this many bytes, e.g. 4G, each a complete .pck, named after -output followed by their number.")
+	flag.StringVar(&minimizeFlag, "minimize", "", "Write a minimized copy of the source .pck to this path for bug reports, keeping its header and index tables but only the first few bytes of each entry.")
+	flag.StringVar(&watermarkFlag, "watermark", "", "When replacing in or building a .pck, mark it as modded by the mod with this name, optionally followed by :version, e.g. \"My Mod:2\".")
+	flag.StringVar(&alignFlag, "align", "", "When replacing in or building a .pck, start entry data on multiples of this many bytes, e.g. 2048 or 2K. By default the alignment of the source file is kept.")
+	flag.StringVar(&dataAlignFlag, "dataalign", "", "When replacing in or building a .pck, pad the index tables so entry data starts on a multiple of this many bytes, e.g. 2K, or \"keep\" to keep the data start alignment of the source file. By default data starts directly after the index tables.")
+	flag.StringVar(&buildFlag, "build", "", "Build a new .pck at -output from the bnk and wem folders of this directory, with files named by ID. If -filepath is given, its header is used as a template.")
+	flag.StringVar(&bwlimitFlag, "bwlimit", "", "Limit the rate of I/O on behalf of a .pck file, in bytes per second, shared by reading it, reading replacement files and writing output and unpacked files. Accepts K, M and G suffixes, e.g. 20M.")
+	flag.StringVar(&diffFlag, "diff", "", "Compare the source .bnk or .pck with this file of the same type, reporting the HIRC objects or the entries that were added, removed or changed.")
+	flag.StringVar(&hircFlag, "hirc", "", "Write the HIRC objects of the source .bnk to this .json path, with their IDs, types, parents and children, wems, actions and parameters, for analysis in other tools.")
+	flag.StringVar(&applyHircFlag, "applyhirc", "", "Rewrite the source .bnk to -output with the HIRC objects of this .json file, written by -hirc and edited: the wems, effects and parameters of sounds and containers, the actions of events and the type and target of actions.")
+	flag.StringVar(&datasetFlag, "dataset", "", "Export every decodable wem of the source file to this directory as mono 16-bit .wav files, with a metadata.csv of their IDs, names, durations, languages and source files. Repeat with other sources to add them to the same dataset.")
+	flag.StringVar(&namesFlag, "names", "", "Name wems exported by -dataset after this SoundbanksInfo.xml or .json file generated by Wwise, or a CSV file of ID and name pairs.")
+	flag.StringVar(&subtitlesFlag, "subtitles", "", "Show the subtitles in this JSON or CSV file, keyed by voice line or event ID or name, next to the wems they belong to in the reports of -dataset and -streams, and in the names of the files exported by -dataset.")
+	flag.StringVar(&sheetFlag, "sheet", "", "Write a contact sheet previewing every decodable wem in the source file to this path, as a FLAC file, or as a WAVE file if the path ends in .wav. Only PCM and IMA ADPCM wems can be previewed; the others are listed as skipped.")
+	flag.StringVar(&cacheFlag, "cache", "", "Keep the parsed HIRC objects of .bnk files in this directory, so that opening the same unchanged .bnk again is faster.")
+	flag.StringVar(&onDupFlag, "ondup", "", "When merging, what to do with entries found in more than one .pck: error, first (keep the first) or last (keep the last); by default the merge stops with an error. When replacing in a .pck, what to do with entries whose ID occurs more than once in it, and with several files replacing the same entry; by default all duplicated entries are kept and the last file is used.")
+	flag.StringVar(&manifestFlag, "manifest", "", "A CSV file mapping replacement file paths, relative to -target, to the entries they replace.")
+
+	var workersFlag, decodeFlag int
+	flag.IntVar(&decodeFlag, "decode", 0, "When unpacking, write the wems that can be decoded as 16-bit PCM .wav files at this sample rate, 44100 or 48000, instead of .wem files. Only PCM and IMA ADPCM wems are decoded; wems of other codecs, such as Vorbis, are unpacked as .wem files with a warning.")
+	flag.IntVar(&workersFlag, "workers", 1, "When unpacking a .pck, write this many entries at once. Higher values can speed up unpacking to fast drives.")
+
+	var mergeFlag, variantFlag, convertFlag pathList
+	flag.Var(&variantFlag, "variant", "Replace the files of -target in the package of a platform, given as name=source.pck,output.pck. May be repeated to rebuild the packages of several platforms, such as PC and console, in one run.")
+	flag.Var(&convertFlag, "convert", "Convert replacement wems for a -variant with a command, given as name=command, in which {in} and {out} stand for the replacement file and the converted file, e.g. \"ps5=at9tool -e {in} {out}\".")
+	flag.Var(&mergeFlag, "merge", "Merge the entries of this .pck into the source .pck, writing the merged .pck to -output. May be repeated to merge several files, such as DLC packages, or given as @file for a file listing them one per line.")
+
+	var extentFlag extentList
+	flag.Var(&extentFlag, "extent", "Read the source .pck from inside the -filepath archive, where it is stored at offset:length, in bytes, e.g. 0x4000:52000. May be repeated for a package stored in several chunks, in order. Only for operations that read the package, such as -unpack or -validate.")
+
+	var idFlag, removeFlag, lookupFlag idList
+	flag.Var(&lookupFlag, "lookup", "Treat -filepath as a directory and report which of the .pck files in it hold the entries with these IDs, unpacking them to -output if given. Accepts IDs as -id does.")
+	flag.Var(&idFlag, "id", "Only unpack the entries with these IDs. Accepts decimal or 0x-prefixed hex IDs, separated by commas, or @file for a file listing them; may be repeated.")
+	flag.Var(&removeFlag, "remove", "When replacing in a .pck or .bnk, remove the entries or wems with these IDs. Accepts IDs as -id does.")
+	var remapFlag remapList
+	flag.Var(&remapFlag, "remap", "When replacing in a .pck, give entries new IDs, keeping their data, as from:to pairs of IDs separated by commas, e.g. 123:456, or @file for a file listing them; may be repeated. Replacement files are still named by the original IDs.")
+
+	var unpackFlag, replaceFlag, verboseFlag, forceFlag, scanFlag, safeFlag, keepOrderFlag, auditFlag, byLangFlag bool
+	var validateFlag, statusFlag, revertFlag, backupFlag, inPlaceFlag, progressFlag, verifyOutputFlag, streamsFlag, mmapFlag bool
+	var overwriteFlag, zeroPayloadsFlag, dryRunFlag, decompressFlag, appendFlag, removeObjectsFlag bool
+	flag.BoolVar(&dryRunFlag, "dryrun", false, "When replacing in a .pck, only report the index tables, offsets and size of the output file, without writing it. -output is not required.")
+	flag.BoolVar(&zeroPayloadsFlag, "zeropayloads", false, "With -skeleton, describe the source .pck with the data of every entry zeroed, keeping only its header and index tables, e.g. to share the layout of a game's package as a test fixture.")
+	flag.BoolVar(&overwriteFlag, "overwrite", false, "Allow the output file to be the source file, which is replaced once the output is fully written.")
+	var historyFlag, sortIndexFlag, checkSortedFlag, withHashFlag, dropGapsFlag, slackFlag, compactFlag bool
+	flag.BoolVar(&slackFlag, "slack", false, "Report the bytes of the source .pck that hold no entry data: gaps between entries, orphaned data and the space compacting it would save.")
+	flag.BoolVar(&compactFlag, "compact", false, "Write the source .pck to -output tightly packed, without the gaps between its entries.")
+	flag.BoolVar(&dropGapsFlag, "dropgaps", false, "When replacing in a .pck, leave out the bytes between entries of the source file, such as slack left by the tool that built it, rather than copying them.")
+	flag.BoolVar(&withHashFlag, "withhash", false, "With -v, add a column of the SHA-256 hash of every entry to the listing of a .pck, hashing -workers entries at once. The same as -checksum sha256.")
+	flag.BoolVar(&sortIndexFlag, "sortindex", false, "When replacing in, merging or building a .pck, sort its BNK and WEM index tables by ID, for engines that look entries up by binary search.")
+	flag.BoolVar(&checkSortedFlag, "checksorted", false, "Refuse to open a .pck whose BNK or WEM index table is not sorted by ID.")
+	flag.BoolVar(&historyFlag, "history", false, "List the operations recorded in the -project history file, or only those that read or produced -filepath if given. Use -v to list their files and hashes.")
+	flag.BoolVar(&decompressFlag, "decompress", false, "Detect the entries of the source .pck stored compressed with zlib or LZ4, and unpack them decompressed. Replacements of compressed entries are compressed the same way.")
+	flag.BoolVar(&mmapFlag, "mmap", false, "Map the source .pck into memory instead of reading it piece by piece, which can speed up unpacking very large files.")
+	flag.BoolVar(&progressFlag, "progress", false, "Show the progress of unpacking, replacing in or building a .pck.")
+	flag.BoolVar(&inPlaceFlag, "inplace", false, "When replacing in a .pck, patch the source file in place instead of writing -output. Only possible when every replacement is no larger than the entry it replaces.")
+	flag.BoolVar(&appendFlag, "append", false, "When replacing in a .pck, patch the source file by appending the replacement data to its end and rewriting only its index tables, instead of writing -output. Replacements may be of any size, and the replaced data is left unused in the file; -compact reclaims it.")
+	flag.BoolVar(&validateFlag, "validate", false, "Check the header and index tables of the source .pck for problems, such as entries out of bounds or overlapping, or duplicated IDs.")
+	flag.BoolVar(&streamsFlag, "streams", false, "Report whether each wem of the source .pck is held in memory, prefetched or streamed, according to where its data is stored and the SoundBanks referencing it.")
+	flag.BoolVar(&statusFlag, "status", false, "Report whether the source .pck is vanilla or modded.")
+	flag.BoolVar(&revertFlag, "revert", false, "Restore the vanilla version of the source file, from the backup kept by -backup or the original recorded by -audit.")
+	flag.BoolVar(&backupFlag, "backup", false, "When replacing, keep a copy of the output file, if it exists, before first overwriting it, so that it can be restored with -revert.")
+	flag.BoolVar(&byLangFlag, "bylang", false, "When unpacking a .pck, place the entries of each language in a folder named after the language.")
+	flag.BoolVar(&auditFlag, "audit", false, "Write a .audit.json file next to each output, recording the tool version, the hashes of the input and replacement files, and timestamps.")
+	flag.BoolVar(&verifyOutputFlag, "verify-output", false, "When replacing in a .pck, read the written file back and check that it holds the replaced entries, to catch truncated or corrupted writes.")
+	flag.BoolVar(&keepOrderFlag, "keeporder", false, "When replacing in a .pck, store entry data in the same order as the source file rather than in index order.")
+	flag.BoolVar(&safeFlag, "safe", false, "When replacing in a .pck, keep entry data starting at the same offset as in the source file, for games that expect it there.")
+	flag.BoolVar(&scanFlag, "scan", false, "Treat -filepath as a directory and report the WEM IDs that appear in more than one of the .pck files in it.")
+	flag.BoolVar(&removeObjectsFlag, "removeobjects", false, "With -remove on a .bnk, also remove the sounds playing the removed wems from its HIRC section, along with the actions targeting them.")
+	flag.BoolVar(&forceFlag, "force", false, "Proceed even if replacement files look like the wrong type for the entries they replace.")
 	flag.BoolVar(&unpackFlag, "u", false, "(shorthand for -unpack)")
 	flag.BoolVar(&unpackFlag, "unpack", false, "Unpack a .bnk or .pck into separate files.")
 	flag.BoolVar(&replaceFlag, "r", false, "(shorthand for -replace)")
@@ -36,54 +182,298 @@ func main() {
 	flag.BoolVar(&verboseFlag, "v", false, "(shorthand for -verbose)")
 	flag.BoolVar(&verboseFlag, "verbose", false, "Show additional information about the parsed file.")
 
-	flag.Parse()
+	args, err := expandResponseFiles(flag.CommandLine, os.Args[1:])
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	flag.CommandLine.Parse(args)
 
-	if filepathFlag == "" {
+	if filepathFlag == "" && buildFlag == "" && len(variantFlag) == 0 && !historyFlag {
 		log.Println("Error: -filepath (-f) is a required argument.")
 		flag.Usage()
 		return
 	}
 
-	if unpackFlag {
+	if len(extentFlag) > 0 && (replaceFlag || len(variantFlag) > 0 || len(mergeFlag) > 0 || diffFlag != "" ||
+		makePatchFlag != "" || applyPatchFlag != "" || rehydrateFlag != "" || applyIndexFlag != "" || applyHircFlag != "" ||
+		minimizeFlag != "" || scanFlag || len(lookupFlag) > 0 || statusFlag || revertFlag) {
+		log.Fatalf("Error: -extent can only be used with operations that read the source package, such as -unpack or -validate.")
+	}
+
+	opts := &options{verbose: verboseFlag, checksum: checksumFlag, zeroPayloads: zeroPayloadsFlag, force: forceFlag, manifest: manifestFlag, ids: idFlag, remove: removeFlag, removeObjects: removeObjectsFlag, remap: remapFlag, extents: extentFlag, overwrite: overwriteFlag,
+		audit: auditFlag, project: projectFlag, byLanguage: byLangFlag, backup: backupFlag, inPlace: inPlaceFlag, appendData: appendFlag, dryRun: dryRunFlag,
+		workers: workersFlag, progress: progressFlag, cacheDir: cacheFlag}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	opts.ctx = ctx
+	if withHashFlag && checksumFlag == "" {
+		opts.checksum = pck.ChecksumSHA256
+	}
+	switch opts.checksum {
+	case "", pck.ChecksumSHA256, pck.ChecksumMD5, pck.ChecksumCRC32:
+	default:
+		log.Fatalf("Error: invalid -checksum: %s. Use sha256, md5 or crc32.", opts.checksum)
+	}
+	if cipherFlag != "" {
+		c, err := newCipher(cipherFlag, keyFlag, ivFlag)
+		if err != nil {
+			log.Fatalf("Error: invalid -cipher: %v", err)
+		}
+		if entryCipherFlag {
+			opts.pckOpts = append(opts.pckOpts, pck.WithEntryCipher(c))
+		} else {
+			opts.pckOpts = append(opts.pckOpts, pck.WithCipher(c))
+		}
+	} else if keyFlag != "" || ivFlag != "" || entryCipherFlag {
+		log.Fatalf("Error: -key, -iv and -entrycipher can only be used with -cipher.")
+	}
+	if bwlimitFlag != "" {
+		limit, err := util.ParseByteSize(bwlimitFlag)
+		if err != nil {
+			log.Fatalf("Error: invalid -bwlimit: %v", err)
+		}
+		opts.pckOpts = append(opts.pckOpts, pck.WithRateLimit(limit))
+	}
+	if alignFlag != "" {
+		align, err := util.ParseByteSize(alignFlag)
+		if err != nil || align < 1 || align > math.MaxUint32 {
+			log.Fatalf("Error: invalid -align: %s", alignFlag)
+		}
+		opts.pckOpts = append(opts.pckOpts, pck.WithAlignment(uint32(align)))
+	}
+	if dataAlignFlag == "keep" {
+		opts.pckOpts = append(opts.pckOpts, pck.PreserveDataAlignment())
+	} else if dataAlignFlag != "" {
+		align, err := util.ParseByteSize(dataAlignFlag)
+		if err != nil || align < 1 || align > math.MaxUint32 {
+			log.Fatalf("Error: invalid -dataalign: %s", dataAlignFlag)
+		}
+		opts.pckOpts = append(opts.pckOpts, pck.WithDataAlignment(uint32(align)))
+	}
+	if watermarkFlag != "" {
+		w, err := parseWatermark(watermarkFlag)
+		if err != nil {
+			log.Fatalf("Error: invalid -watermark: %v", err)
+		}
+		opts.pckOpts = append(opts.pckOpts, pck.WithWatermark(w))
+	}
+	if headerFlag != "" {
+		raw, err := os.ReadFile(headerFlag)
+		if err != nil {
+			log.Fatalf("Error reading -header: %v", err)
+		}
+		if len(raw) < 8 {
+			log.Fatalf("Error: invalid -header: %s is %d bytes long, too short to be a header", headerFlag, len(raw))
+		}
+		opts.pckOpts = append(opts.pckOpts, pck.WithRawHeader(raw))
+	}
+	if workersFlag < 1 {
+		log.Fatalf("Error: invalid -workers: %d", workersFlag)
+	}
+	if decodeFlag != 0 && decodeFlag != 44100 && decodeFlag != 48000 {
+		log.Fatalf("Error: invalid -decode: %d. Use 44100 or 48000.", decodeFlag)
+	}
+	opts.decodeRate = decodeFlag
+	if safeFlag {
+		opts.pckOpts = append(opts.pckOpts, pck.PreserveDataStart())
+	}
+	if keepOrderFlag {
+		opts.pckOpts = append(opts.pckOpts, pck.PreserveDataOrder())
+	}
+	if dropGapsFlag {
+		opts.pckOpts = append(opts.pckOpts, pck.DropGaps())
+	}
+	if sortIndexFlag {
+		opts.pckOpts = append(opts.pckOpts, pck.SortIndexes())
+	}
+	if checkSortedFlag {
+		opts.pckOpts = append(opts.pckOpts, pck.RequireSortedIndexes())
+	}
+	if mmapFlag {
+		opts.pckOpts = append(opts.pckOpts, pck.WithMmap())
+	}
+	if decompressFlag {
+		opts.pckOpts = append(opts.pckOpts, pck.Decompress())
+	}
+	if overwriteFlag {
+		opts.pckOpts = append(opts.pckOpts, pck.AllowOverwrite())
+	}
+	if onDupFlag != "" {
+		policy, err := parseDuplicatePolicy(onDupFlag)
+		if err != nil {
+			log.Fatalf("Error: invalid -ondup: %v", err)
+		}
+		opts.pckOpts = append(opts.pckOpts, pck.OnDuplicate(policy))
+	}
+	opts.pckOpts = append(opts.pckOpts, pck.WithDuplicateReport(func(d *pck.DuplicateChoice) {
+		log.Printf("Warning: %s", d)
+	}))
+	if verifyOutputFlag {
+		opts.pckOpts = append(opts.pckOpts, pck.VerifyOutput())
+	}
+
+	if historyFlag {
+		if projectFlag == "" {
+			log.Println("Error: -project is required for listing the history.")
+			flag.Usage()
+			return
+		}
+		handleHistory(projectFlag, filepathFlag, opts)
+	} else if unpackFlag {
 		if outputFlag == "" {
 			log.Println("Error: -output (-o) is required for unpacking.")
 			flag.Usage()
 			return
 		}
-		handleUnpack(filepathFlag, outputFlag, verboseFlag)
+		handleUnpack(filepathFlag, outputFlag, opts)
 	} else if replaceFlag {
-		if outputFlag == "" {
+		if inPlaceFlag && appendFlag {
+			log.Fatalf("Error: -inplace cannot be combined with -append.")
+		}
+		if inPlaceFlag || appendFlag {
+			if outputFlag != "" && outputFlag != filepathFlag {
+				log.Println("Warning: -output is ignored with -inplace and -append.")
+			}
+			outputFlag = filepathFlag
+		}
+		if dryRunFlag && (inPlaceFlag || appendFlag) {
+			log.Fatalf("Error: -dryrun cannot be combined with -inplace or -append.")
+		}
+		if outputFlag == "" && !dryRunFlag {
 			log.Println("Error: -output (-o) is required for replacing.")
 			flag.Usage()
 			return
 		}
-		if targetFlag == "" {
-			log.Println("Error: -target (-t) is required for replacing.")
+		if targetFlag == "" && len(removeFlag) == 0 && len(remapFlag) == 0 {
+			log.Println("Error: -target (-t) is required for replacing, unless only removing or remapping entries.")
+			flag.Usage()
+			return
+		}
+		handleReplace(filepathFlag, outputFlag, targetFlag, opts)
+	} else if len(variantFlag) > 0 {
+		if targetFlag == "" && len(removeFlag) == 0 && len(remapFlag) == 0 {
+			log.Println("Error: -target (-t) is required for replacing, unless only removing or remapping entries.")
+			flag.Usage()
+			return
+		}
+		handleVariants(targetFlag, variantFlag, convertFlag, opts)
+	} else if len(mergeFlag) > 0 {
+		if outputFlag == "" {
+			log.Println("Error: -output (-o) is required for merging.")
+			flag.Usage()
+			return
+		}
+		handleMerge(filepathFlag, mergeFlag, outputFlag, opts)
+	} else if datasetFlag != "" {
+		handleDataset(filepathFlag, datasetFlag, namesFlag, subtitlesFlag, opts)
+	} else if sheetFlag != "" {
+		handleContactSheet(filepathFlag, sheetFlag, opts)
+	} else if diffFlag != "" {
+		handleDiff(filepathFlag, diffFlag, opts)
+	} else if hircFlag != "" {
+		handleExportHierarchy(filepathFlag, hircFlag, opts)
+	} else if applyHircFlag != "" {
+		if outputFlag == "" {
+			log.Println("Error: -output (-o) is required for applying HIRC objects.")
+			flag.Usage()
+			return
+		}
+		handleImportHierarchy(applyHircFlag, filepathFlag, outputFlag, opts)
+	} else if scanFlag {
+		handleScan(filepathFlag, opts)
+	} else if len(lookupFlag) > 0 {
+		handleLookup(filepathFlag, lookupFlag, outputFlag, opts)
+	} else if validateFlag {
+		handleValidate(filepathFlag, opts)
+	} else if streamsFlag {
+		handleStreams(filepathFlag, subtitlesFlag, opts)
+	} else if statusFlag {
+		handleStatus(filepathFlag, opts)
+	} else if revertFlag {
+		handleRevert(filepathFlag)
+	} else if makePatchFlag != "" || applyPatchFlag != "" {
+		if outputFlag == "" {
+			log.Println("Error: -output (-o) is required for patching.")
+			flag.Usage()
+			return
+		}
+		if makePatchFlag != "" {
+			handleMakePatch(filepathFlag, makePatchFlag, outputFlag, opts)
+		} else {
+			handleApplyPatch(applyPatchFlag, filepathFlag, outputFlag, opts)
+		}
+	} else if rehydrateFlag != "" {
+		if outputFlag == "" {
+			log.Println("Error: -output (-o) is required for rehydrating.")
+			flag.Usage()
+			return
+		}
+		handleRehydrate(rehydrateFlag, filepathFlag, targetFlag, outputFlag, opts)
+	} else if indexFlag != "" {
+		handleExportIndex(filepathFlag, indexFlag, opts)
+	} else if saveHeaderFlag != "" {
+		handleSaveHeader(filepathFlag, saveHeaderFlag, opts)
+	} else if applyIndexFlag != "" {
+		if outputFlag == "" {
+			log.Println("Error: -output (-o) is required for applying an index.")
+			flag.Usage()
+			return
+		}
+		handleImportIndex(applyIndexFlag, filepathFlag, outputFlag, opts)
+	} else if skeletonFlag != "" {
+		handleSkeleton(filepathFlag, skeletonFlag, opts)
+	} else if splitFlag != "" {
+		if outputFlag == "" {
+			log.Println("Error: -output (-o) is required for splitting.")
+			flag.Usage()
+			return
+		}
+		maxSize, err := util.ParseByteSize(splitFlag)
+		if err != nil || maxSize <= 0 {
+			log.Fatalf("Error: invalid -split: %s", splitFlag)
+		}
+		handleSplit(filepathFlag, outputFlag, maxSize, opts)
+	} else if slackFlag {
+		handleSlack(filepathFlag, opts)
+	} else if compactFlag {
+		if outputFlag == "" {
+			log.Println("Error: -output (-o) is required for compacting.")
 			flag.Usage()
 			return
 		}
-		handleReplace(filepathFlag, outputFlag, targetFlag, verboseFlag)
+		handleCompact(filepathFlag, outputFlag, opts)
+	} else if minimizeFlag != "" {
+		handleMinimize(filepathFlag, minimizeFlag, opts)
+	} else if buildFlag != "" {
+		if outputFlag == "" {
+			log.Println("Error: -output (-o) is required for building.")
+			flag.Usage()
+			return
+		}
+		handleBuild(buildFlag, filepathFlag, outputFlag, opts)
 	} else {
-		log.Println("No operation specified. Use -unpack or -replace.")
+		log.Println("No operation specified. Use -unpack, -replace, -variant, -merge, -sheet, -dataset, -diff, -hirc, -applyhirc, -scan, -lookup, -build, -split, -slack, -compact, -minimize, -index, -applyindex, -saveheader, -skeleton, -rehydrate, -mkpatch, -applypatch, -validate, -streams, -status, -revert or -history.")
 		flag.Usage()
 	}
 }
 
-func handleUnpack(inputFile, outputDir string, verbose bool) {
-	ext := strings.ToLower(filepath.Ext(inputFile))
+func handleUnpack(inputFile, outputDir string, opts *options) {
+	opts.checkOperation(wwiseutil.OpUnpack, inputFile)
+	a := opts.startAudit("unpack", inputFile)
+	ext := opts.sourceExt(inputFile)
 
 	switch ext {
 	case ".pck", ".npck":
 		log.Printf("Unpacking PCK file: %s", inputFile)
-		f, err := pck.Open(inputFile)
+		f, err := opts.openPck(inputFile)
 		if err != nil {
 			log.Fatalf("Error opening PCK file: %v", err)
 		}
 		defer f.Close()
 
-		if verbose {
+		if opts.verbose {
 			timestamp := time.Now().Format(time.RFC3339Nano)
-			verboseOutput := f.String()
+			verboseOutput := opts.describePck(f)
 			finalOutput := fmt.Sprintf("Log generated at: %s\n\n%s", timestamp, verboseOutput)
 
 			log.Println(finalOutput)
@@ -99,20 +489,45 @@ func handleUnpack(inputFile, outputDir string, verbose bool) {
 			}
 		}
 
-		if err := f.UnpackTo(outputDir); err != nil {
+		var unpackOpts []pck.Option
+		if len(opts.ids) > 0 {
+			log.Printf("Only unpacking entries with ID: %s", opts.ids.String())
+			unpackOpts = append(unpackOpts, pck.WithIDs(opts.ids...))
+		}
+		if opts.byLanguage {
+			unpackOpts = append(unpackOpts, pck.SplitLanguages())
+		}
+		unpackOpts = append(unpackOpts, pck.WithWorkers(opts.workers))
+		undecoded := 0
+		if opts.decodeRate > 0 {
+			unpackOpts = append(unpackOpts, pck.DecodeWems(opts.decodeRate),
+				pck.WithUndecodedReport(func(f *pck.EmbeddedFile, err error) {
+					warnUndecoded(f.Index.ID, err)
+					undecoded++
+				}))
+		}
+		if opts.progress {
+			unpackOpts = append(unpackOpts, pck.WithProgress(newProgressPrinter("Unpacked")))
+		}
+		err = warnTruncated(f.UnpackToContext(opts.ctx, outputDir, unpackOpts...))
+		if errors.Is(err, context.Canceled) {
+			log.Fatalf("Unpacking interrupted. The files unpacked so far are left in: %s", outputDir)
+		}
+		if err != nil {
 			log.Fatalf("Error unpacking PCK file: %v", err)
 		}
+		summarizeUndecoded(undecoded)
 		log.Printf("Successfully unpacked files to: %s", outputDir)
 
 	case ".bnk", ".nbnk":
 		log.Printf("Unpacking BNK file: %s", inputFile)
-		f, err := bnk.Open(inputFile)
+		f, err := openBnk(inputFile, opts)
 		if err != nil {
 			log.Fatalf("Error opening BNK file: %v", err)
 		}
 		defer f.Close()
 
-		if verbose {
+		if opts.verbose {
 			log.Println(f.String())
 		}
 
@@ -120,51 +535,150 @@ func handleUnpack(inputFile, outputDir string, verbose bool) {
 			log.Fatalf("Error creating output directory: %v", err)
 		}
 
-		for _, wem := range f.Wems() {
-			wemName := fmt.Sprintf("%d.wem", wem.Descriptor.WemId)
-			outPath := filepath.Join(outputDir, wemName)
-			outFile, err := os.Create(outPath)
-			if err != nil {
-				log.Printf("Failed to create file %s: %v", outPath, err)
+		undecoded := 0
+		for _, w := range f.Wems() {
+			id := w.Descriptor.WemId
+			if len(opts.ids) > 0 && !opts.ids.contains(id) {
 				continue
 			}
-			_, err = outFile.ReadFrom(wem.Reader)
-			outFile.Close()
+			data, err := io.ReadAll(w.Reader)
 			if err != nil {
-				log.Printf("Failed to write wem %s: %v", wemName, err)
+				log.Printf("Failed to read wem %d: %v", id, err)
+				continue
+			}
+			name, out := fmt.Sprintf("%d.wem", id), data
+			if opts.decodeRate > 0 {
+				if wav, err := decodeToWAV(data, opts.decodeRate); err == nil {
+					name, out = fmt.Sprintf("%d.wav", id), wav
+				} else {
+					warnUndecoded(id, err)
+					undecoded++
+				}
+			}
+			if err := os.WriteFile(filepath.Join(outputDir, name), out, 0644); err != nil {
+				log.Printf("Failed to write wem %s: %v", name, err)
 			}
 		}
+		summarizeUndecoded(undecoded)
 		log.Printf("Successfully unpacked WEM files to: %s", outputDir)
 
 	default:
 		log.Fatalf("Unsupported file type: %s", ext)
 	}
+	finishAudit(a, outputDir)
 }
 
-func handleReplace(inputFile, outputFile, targetDir string, verbose bool) {
-	ext := strings.ToLower(filepath.Ext(inputFile))
-	switch ext {
+func handleReplace(inputFile, outputFile, targetDir string, opts *options) {
+	if opts.inPlace || opts.appendData {
+		opts.checkOperation(wwiseutil.OpPatch, inputFile)
+	} else {
+		opts.checkOperation(wwiseutil.OpReplace, inputFile)
+	}
+	switch strings.ToLower(filepath.Ext(inputFile)) {
 	case ".pck", ".npck":
-		handlePckReplace(inputFile, outputFile, targetDir, verbose)
+		handlePckReplace(inputFile, outputFile, targetDir, opts)
 	case ".bnk", ".nbnk":
-		handleBnkReplace(inputFile, outputFile, targetDir, verbose)
-	default:
-		log.Fatalf("Replacing is only supported for .pck and .bnk formats.")
+		if opts.dryRun {
+			log.Fatalf("Error: -dryrun is only supported when replacing in a .pck.")
+		}
+		if len(opts.remap) > 0 {
+			log.Fatalf("Error: -remap is only supported when replacing in a .pck.")
+		}
+		handleBnkReplace(inputFile, outputFile, targetDir, opts)
+	}
+}
+
+// warnTruncated logs which entries of a truncated package were only partly
+// unpacked if err is a *pck.TruncatedError, returning nil so that what could be
+// salvaged is kept. Other errors are returned as they are.
+func warnTruncated(err error) error {
+	var t *pck.TruncatedError
+	if !errors.As(err, &t) {
+		return err
+	}
+	for _, f := range t.Entries {
+		if n := f.Open().Size(); n > 0 {
+			log.Printf("Warning: %s is truncated; only %d of its %d bytes were unpacked.", f.Name, n, f.Index.Length)
+		} else {
+			log.Printf("Warning: %s starts past the end of the package; it was not unpacked.", f.Name)
+		}
+	}
+	log.Printf("Warning: the package is cut short; %d damaged entries were salvaged as far as possible.",
+		len(t.Entries))
+	return nil
+}
+
+// warnUndecoded warns that the wem with the given ID was unpacked as it is
+// under -decode, because it could not be decoded for the reason err.
+func warnUndecoded(id uint32, err error) {
+	log.Printf("Warning: wem %s could not be decoded and was unpacked as a .wem file: %v",
+		util.FormatID(id), err)
+}
+
+// summarizeUndecoded reports the number of wems unpacked as they are under
+// -decode, if any.
+func summarizeUndecoded(n int) {
+	if n > 0 {
+		log.Printf("%d wem(s) could not be decoded and were unpacked as they are. Only PCM and "+
+			"IMA ADPCM wems are decoded.", n)
 	}
 }
 
-func handlePckReplace(inputFile, outputFile, targetDir string, verbose bool) {
+// checkOperation exits with the reason op is not supported on the source file
+// at path, if it is not. Packages in archives given by -extent are not
+// checked, since they are always packages.
+func (o *options) checkOperation(op, path string) {
+	if len(o.extents) > 0 {
+		return
+	}
+	if err := wwiseutil.CheckOperation(op, path); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+}
+
+// describePck returns the verbose listing of f, with the checksum of every
+// entry if -checksum or -withhash is given.
+func (o *options) describePck(f *pck.File) string {
+	if o.checksum == "" {
+		return f.String()
+	}
+	s, err := f.StringWithChecksums(o.checksum, o.workers)
+	if err != nil {
+		log.Fatalf("Error hashing entries: %v", err)
+	}
+	return s
+}
+
+// startAudit starts an audit of the operation op on input, or returns nil if
+// neither audit files nor a project history are requested.
+func (o *options) startAudit(op, input string) *audit {
+	if !o.audit && o.project == "" {
+		return nil
+	}
+	a := newAudit(op, input)
+	a.writeFile, a.project = o.audit, o.project
+	return a
+}
+
+func handlePckReplace(inputFile, outputFile, targetDir string, opts *options) {
+	var a *audit
+	if opts.inPlace || opts.appendData {
+		// The input is overwritten, so only the patched file can be recorded.
+		a = opts.startAudit("patch", "")
+	} else {
+		a = opts.startAudit("replace", inputFile)
+	}
 	// Open the source PCK to get the ID mappings from indexes
-	srcPck, err := pck.Open(inputFile)
+	srcPck, err := pck.Open(inputFile, opts.pckOpts...)
 	if err != nil {
 		log.Fatalf("Error opening source PCK: %v", err)
 	}
 	defer srcPck.Close()
 
-	if verbose {
+	if opts.verbose {
 		log.Println("Source file structure:")
 		timestamp := time.Now().Format(time.RFC3339Nano)
-		verboseOutput := srcPck.String()
+		verboseOutput := opts.describePck(srcPck)
 		finalOutput := fmt.Sprintf("Log generated at: %s\n\n%s", timestamp, verboseOutput)
 
 		log.Println(finalOutput)
@@ -181,12 +695,23 @@ func handlePckReplace(inputFile, outputFile, targetDir string, verbose bool) {
 	}
 
 	// Find replacement files
-	replacements, err := findPckReplacementFiles(targetDir, srcPck)
-	if err != nil {
-		log.Fatalf("Error finding replacement files: %v", err)
+	var replacements, additions []*pck.ReplacementFile
+	if targetDir != "" {
+		if opts.manifest != "" {
+			replacements, err = readManifest(opts.manifest, targetDir, srcPck)
+		} else {
+			replacements, err = findPckReplacementFiles(targetDir, srcPck)
+		}
+		if err != nil {
+			log.Fatalf("Error finding replacement files: %v", err)
+		}
+		additions, err = findNewEntryFiles(targetDir)
+		if err != nil {
+			log.Fatalf("Error finding new entry files: %v", err)
+		}
 	}
 
-	if len(replacements) == 0 {
+	if len(replacements) == 0 && len(additions) == 0 && len(opts.remove) == 0 && len(opts.remap) == 0 {
 		log.Println("No valid replacement files found in target directory. Nothing to do.")
 		return
 	}
@@ -195,187 +720,194 @@ func handlePckReplace(inputFile, outputFile, targetDir string, verbose bool) {
 	for _, r := range replacements {
 		replacementNames = append(replacementNames, filepath.Base(r.Path))
 	}
-
-	log.Printf("Using %d replacement file(s): %s", len(replacements), strings.Join(replacementNames, ", "))
-
-	bytesWritten, err := pck.Repack(inputFile, outputFile, replacements)
-	if err != nil {
-		log.Fatalf("Error during repack: %v", err)
+	if len(replacements) > 0 {
+		log.Printf("Using %d replacement file(s): %s", len(replacements), strings.Join(replacementNames, ", "))
+	}
+	for _, a := range additions {
+		log.Printf("Adding %s as new %s ID %s", a.Path, strings.ToUpper(a.Type), util.FormatID(a.ID))
+	}
+	replacements = append(replacements, additions...)
+	for _, r := range replacements {
+		if fi, err := os.Stat(r.Path); err == nil && fi.Size() == 0 && !r.New {
+			log.Printf("%s is empty; %s ID %s will be emptied, disabling it.",
+				r.Path, strings.ToUpper(r.Type), util.FormatID(r.ID))
+		}
 	}
 
-	log.Println("Repack completed successfully!")
-	log.Printf("Output file written to: %s", outputFile)
-	log.Printf("Wrote %d bytes in total", bytesWritten)
-}
-
-func findPckReplacementFiles(targetDir string, srcPck *pck.File) ([]*pck.ReplacementFile, error) {
-	var replacements []*pck.ReplacementFile
-
-	// Scan for BNK replacements
-	bnkTargetDir := filepath.Join(targetDir, "bnk")
-	if _, err := os.Stat(bnkTargetDir); !os.IsNotExist(err) {
-		err := filepath.WalkDir(bnkTargetDir, func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
-				return err
-			}
-			if !d.IsDir() {
-				base := filepath.Base(path)
-				ext := filepath.Ext(base)
-				indexStr := strings.TrimSuffix(base, ext)
-				index, err := strconv.Atoi(indexStr)
-				if err != nil {
-					log.Printf("Warning: could not parse index from filename %s, skipping.", base)
-					return nil
-				}
+	pckOpts := opts.pckOpts
+	if len(opts.remove) > 0 {
+		log.Printf("Removing entries with ID: %s", opts.remove.String())
+		pckOpts = append(pckOpts, pck.RemoveIDs(opts.remove...))
+	}
+	if len(opts.remap) > 0 {
+		log.Printf("Remapping entries: %s", opts.remap.String())
+		pckOpts = append(pckOpts, pck.RemapIDs(opts.remap))
+	}
+	if n := checkReplacementTypes(replacements); n > 0 {
+		if !opts.force {
+			log.Fatalf("Refusing to repack: %d replacement file(s) look like the wrong type. "+
+				"Use -force to repack anyway.", n)
+		}
+		pckOpts = append(pckOpts, pck.AllowTypeMismatch())
+	}
+	if opts.dryRun {
+		previewReplace(inputFile, replacements, pckOpts)
+		return
+	}
 
-				if index < 1 || index > len(srcPck.BnkIndexes) {
-					log.Printf("Warning: index %d from filename %s is out of bounds for BNK files (1-%d), skipping.", index, base, len(srcPck.BnkIndexes))
-					return nil
-				}
-				// Convert 1-based user index to 0-based slice index
-				id := srcPck.BnkIndexes[index-1].ID
-				replacements = append(replacements, &pck.ReplacementFile{ID: id, Path: path, Type: "bnk"})
-			}
-			return nil
-		})
-		if err != nil {
-			return nil, fmt.Errorf("error scanning bnk target directory: %w", err)
+	if opts.backup {
+		if err := backupOriginal(outputFile); err != nil {
+			log.Fatalf("Error: %v", err)
 		}
 	}
+	if opts.progress {
+		pckOpts = append(pckOpts, pck.WithProgress(newProgressPrinter("Wrote")))
+	}
+	if opts.inPlace {
+		bytesWritten, err := pck.Patch(inputFile, replacements, pckOpts...)
+		if errors.Is(err, pck.ErrDoesNotFit) {
+			log.Fatalf("Error: cannot patch in place: %v. Replace with -append instead, or without -inplace to rewrite the file.", err)
+		}
+		if errors.Is(err, pck.ErrVerifyFailed) {
+			log.Fatalf("Error: %v. The patched file is broken; restore it before using it.", err)
+		}
+		if err != nil {
+			log.Fatalf("Error during patch: %v", err)
+		}
+		log.Println("Patch completed successfully!")
+		log.Printf("Patched %s in place, writing %d bytes", inputFile, bytesWritten)
+	} else if opts.appendData {
+		bytesWritten, err := pck.PatchAppend(inputFile, replacements, pckOpts...)
+		if errors.Is(err, pck.ErrDoesNotFit) {
+			log.Fatalf("Error: cannot patch by appending: %v. Replace without -append to rewrite the file.", err)
+		}
+		if errors.Is(err, pck.ErrOffsetOverflow) {
+			log.Fatalf("Error: %v. Replace without -append to rewrite the file.", err)
+		}
+		if errors.Is(err, pck.ErrVerifyFailed) {
+			log.Fatalf("Error: %v. The patched file is broken; restore it before using it.", err)
+		}
+		if err != nil {
+			log.Fatalf("Error during patch: %v", err)
+		}
+		log.Println("Patch completed successfully!")
+		log.Printf("Patched %s by appending to it, writing %d bytes", inputFile, bytesWritten)
+	} else {
+		bytesWritten, err := pck.RepackContext(opts.ctx, inputFile, outputFile, replacements, pckOpts...)
+		if errors.Is(err, context.Canceled) {
+			log.Fatalf("Repack interrupted. The output file was left as it was.")
+		}
+		if errors.Is(err, pck.ErrOffsetOverflow) {
+			log.Fatalf("Error: %v. -split writes a .pck as volumes.", err)
+		}
+		if errors.Is(err, pck.ErrOverwrite) {
+			log.Fatalf("Error: %v. Use -overwrite to replace it, or -inplace to patch it.", err)
+		}
+		if errors.Is(err, pck.ErrVerifyFailed) {
+			log.Fatalf("Error: %v. The output file was not written; check the free disk space and repack again.", err)
+		}
+		if err != nil {
+			log.Fatalf("Error during repack: %v", err)
+		}
 
-	// Scan for WEM replacements
-	wemTargetDir := filepath.Join(targetDir, "wem")
-	if _, err := os.Stat(wemTargetDir); !os.IsNotExist(err) {
-		err := filepath.WalkDir(wemTargetDir, func(path string, d fs.DirEntry, err error) error {
-			if err != nil {
-				return err
-			}
-			if !d.IsDir() {
-				base := filepath.Base(path)
-				ext := filepath.Ext(base)
-				indexStr := strings.TrimSuffix(base, ext)
-				index, err := strconv.Atoi(indexStr)
-				if err != nil {
-					log.Printf("Warning: could not parse index from filename %s, skipping.", base)
-					return nil
-				}
+		log.Println("Repack completed successfully!")
+		log.Printf("Output file written to: %s", outputFile)
+		log.Printf("Wrote %d bytes in total", bytesWritten)
+	}
 
-				if index < 1 || index > len(srcPck.WemIndexes) {
-					log.Printf("Warning: index %d from filename %s is out of bounds for WEM files (1-%d), skipping.", index, base, len(srcPck.WemIndexes))
-					return nil
-				}
-				// Convert 1-based user index to 0-based slice index
-				id := srcPck.WemIndexes[index-1].ID
-				replacements = append(replacements, &pck.ReplacementFile{ID: id, Path: path, Type: "wem"})
-			}
-			return nil
-		})
-		if err != nil {
-			return nil, fmt.Errorf("error scanning wem target directory: %w", err)
+	if a != nil {
+		for _, r := range replacements {
+			a.addReplacement(r.Path, r.Type, r.ID, r.New)
 		}
+		a.Removed = opts.remove
+		finishAudit(a, outputFile)
 	}
+}
 
-	return replacements, nil
+// previewReplace reports the layout of the package that repacking inputFile
+// with replacements would write, without writing it.
+func previewReplace(inputFile string, replacements []*pck.ReplacementFile, pckOpts []pck.Option) {
+	l, err := pck.PreviewRepack(inputFile, replacements, pckOpts...)
+	if err != nil {
+		log.Fatalf("Error during dry run: %v", err)
+	}
+	log.Printf("Predicted layout of the repacked file:\n%s", l)
+	var size int64
+	if fi, err := os.Stat(inputFile); err == nil {
+		size = fi.Size()
+	}
+	log.Printf("Dry run: the repacked file would be %d bytes (%+d bytes); nothing was written.", l.Size, l.Size-size)
 }
 
-func handleBnkReplace(inputFile, outputFile, targetDir string, verbose bool) {
-	srcBnk, err := bnk.Open(inputFile)
+func handleBnkReplace(inputFile, outputFile, targetDir string, opts *options) {
+	a := opts.startAudit("replace", inputFile)
+	srcBnk, err := openBnk(inputFile, opts)
 	if err != nil {
 		log.Fatalf("Error opening source BNK: %v", err)
 	}
 	defer srcBnk.Close()
 
-	if verbose {
+	if opts.verbose {
 		log.Println("Source file structure:")
 		log.Println(srcBnk.String())
 	}
 
-	replacements, err := findBnkReplacementFiles(targetDir, srcBnk)
-	if err != nil {
-		log.Fatalf("Error finding replacement files: %v", err)
+	var replacements []*wwise.ReplacementWem
+	if targetDir != "" {
+		replacements, err = findBnkReplacementFiles(targetDir, srcBnk)
+		if err != nil {
+			log.Fatalf("Error finding replacement files: %v", err)
+		}
 	}
 
-	if len(replacements) == 0 {
+	if len(replacements) == 0 && len(opts.remove) == 0 {
 		log.Println("No valid replacement files found in target directory. Nothing to do.")
 		return
 	}
 
 	var replacementNames []string
+	var replacementIds []uint32
+	wems := srcBnk.Wems()
 	for _, r := range replacements {
 		replacementNames = append(replacementNames, filepath.Base(r.Wem.(*os.File).Name()))
+		replacementIds = append(replacementIds, wems[r.WemIndex].Descriptor.WemId)
 	}
 
-	log.Printf("Using %d replacement file(s): %s", len(replacements), strings.Join(replacementNames, ", "))
+	if len(replacements) > 0 {
+		log.Printf("Using %d replacement file(s): %s", len(replacements), strings.Join(replacementNames, ", "))
+	}
 
 	srcBnk.ReplaceWems(replacements...)
-
-	outFile, err := os.Create(outputFile)
-	if err != nil {
-		log.Fatalf("Error creating output file: %v", err)
+	if len(opts.remove) > 0 {
+		log.Printf("Removing wems with ID: %s", opts.remove.String())
+		if err := srcBnk.RemoveWems(opts.remove, opts.removeObjects); err != nil {
+			log.Fatalf("Error removing wems: %v", err)
+		}
 	}
-	defer outFile.Close()
 
+	if opts.backup {
+		if err := backupOriginal(outputFile); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+	outFile := opts.createOutput(inputFile, outputFile)
 	bytesWritten, err := srcBnk.WriteTo(outFile)
 	if err != nil {
+		outFile.Abort()
 		log.Fatalf("Error writing to output file: %v", err)
 	}
+	srcBnk.Close()
+	commitOutput(outFile)
 
 	log.Println("Repack completed successfully!")
 	log.Printf("Output file written to: %s", outputFile)
 	log.Printf("Wrote %d bytes in total", bytesWritten)
-}
-
-func findBnkReplacementFiles(targetDir string, srcBnk *bnk.File) ([]*wwise.ReplacementWem, error) {
-	var replacements []*wwise.ReplacementWem
-
-	err := filepath.WalkDir(targetDir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-		if !d.IsDir() {
-			base := filepath.Base(path)
-			ext := filepath.Ext(base)
-			if strings.ToLower(ext) != ".wem" {
-				return nil
-			}
-
-			indexStr := strings.TrimSuffix(base, ext)
-			wemIndex, err := strconv.Atoi(indexStr)
-			if err != nil {
-				log.Printf("Warning: could not parse index from filename %s, skipping.", base)
-				return nil
-			}
-
-			if wemIndex < 0 || wemIndex >= len(srcBnk.Wems()) {
-				log.Printf("Warning: index %d from filename %s is out of bounds for the BNK, skipping.", wemIndex, base)
-				return nil
-			}
-
-			file, err := os.Open(path)
-			if err != nil {
-				log.Printf("Warning: could not open replacement file %s: %v", path, err)
-				return nil
-			}
 
-			fi, err := file.Stat()
-			if err != nil {
-				log.Printf("Warning: could not get file info for %s: %v", path, err)
-				file.Close()
-				return nil
-			}
-
-			replacements = append(replacements, &wwise.ReplacementWem{
-				Wem:      file,
-				WemIndex: wemIndex,
-				Length:   fi.Size(),
-			})
+	if a != nil {
+		for i, r := range replacements {
+			a.addReplacement(r.Wem.(*os.File).Name(), "wem", replacementIds[i], false)
 		}
-		return nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("error scanning target directory: %w", err)
+		a.Removed = opts.remove
+		finishAudit(a, outputFile)
 	}
-
-	return replacements, nil
 }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log"
+
+	"wwiseutil/pck"
+)
+
+// handleMerge merges the packages at inputFile and mergeFiles, in that order,
+// into a new package at outputFile, resolving duplicated entries as given by
+// -ondup.
+func handleMerge(inputFile string, mergeFiles []string, outputFile string, opts *options) {
+	a := opts.startAudit("merge", inputFile)
+	var sources []*pck.File
+	for _, path := range append([]string{inputFile}, mergeFiles...) {
+		f, err := pck.Open(path, opts.pckOpts...)
+		if err != nil {
+			log.Fatalf("Error opening PCK file %s: %v", path, err)
+		}
+		defer f.Close()
+		log.Printf("Merging %s: %d bnk and %d wem entries", path, len(f.BnkIndexes), len(f.WemIndexes))
+		sources = append(sources, f)
+	}
+
+	mergeOpts := opts.pckOpts
+	if opts.progress {
+		mergeOpts = append(mergeOpts, pck.WithProgress(newProgressPrinter("Wrote")))
+	}
+	if opts.backup {
+		if err := backupOriginal(outputFile); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+	outFile := opts.createOutput(inputFile, outputFile)
+	bytesWritten, err := pck.Merge(outFile, sources, mergeOpts...)
+	if err != nil {
+		outFile.Abort()
+		log.Fatalf("Error merging PCK files: %v", err)
+	}
+	for _, f := range sources {
+		f.Close()
+	}
+	commitOutput(outFile)
+	log.Println("Merge completed successfully!")
+	log.Printf("Output file written to: %s", outputFile)
+	log.Printf("Wrote %d bytes in total", bytesWritten)
+	finishAudit(a, outputFile)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"wwiseutil/pck"
+)
+
+// handleMinimize writes a minimized copy of the package at inputFile to
+// outputFile, keeping its header and index tables but only the first few bytes
+// of each entry, for attaching to bug reports.
+func handleMinimize(inputFile, outputFile string, opts *options) {
+	in, err := os.Open(inputFile)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+
+	out, err := os.Create(outputFile)
+	if err != nil {
+		log.Fatalf("Error creating output file: %v", err)
+	}
+	defer out.Close()
+
+	n, err := pck.Minimize(in, info.Size(), out, pck.DefaultMinimizeBytes, opts.pckOpts...)
+	if err != nil {
+		log.Fatalf("Error minimizing PCK file: %v", err)
+	}
+	log.Printf("Wrote a minimized copy of %s (%d bytes, down from %d) to: %s",
+		inputFile, n, info.Size(), outputFile)
+	log.Printf("Only the first %d bytes of each entry were kept. Check that the copy still shows the problem before sharing it.",
+		pck.DefaultMinimizeBytes)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"wwiseutil/util"
+)
+
+// createOutput creates the file an operation on the package at inputFile
+// writes to outputFile. It is written to a temporary file, which only replaces
+// outputFile once committed with commitOutput, so that a failed operation
+// leaves outputFile as it was. Unless -overwrite is given, it is an error for
+// outputFile to be inputFile.
+func (o *options) createOutput(inputFile, outputFile string) *util.AtomicFile {
+	if !o.overwrite && isSource(inputFile, outputFile) {
+		log.Fatalf("Error: the output file %s is the source file. Use -overwrite to replace it.", outputFile)
+	}
+	out, err := util.CreateAtomic(outputFile)
+	if err != nil {
+		log.Fatalf("Error creating output file: %v", err)
+	}
+	return out
+}
+
+// commitOutput replaces the output file with out, once fully written.
+func commitOutput(out *util.AtomicFile) {
+	if err := out.Commit(); err != nil {
+		log.Fatalf("Error writing output file: %v", err)
+	}
+}
+
+// isSource reports whether outputFile is the existing file at inputFile.
+func isSource(inputFile, outputFile string) bool {
+	in, err := os.Stat(inputFile)
+	if err != nil {
+		return false
+	}
+	out, err := os.Stat(outputFile)
+	return err == nil && os.SameFile(in, out)
+}
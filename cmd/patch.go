@@ -0,0 +1,72 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"wwiseutil/pck"
+	"wwiseutil/util"
+)
+
+// handleMakePatch writes a patch turning the package at inputFile into the
+// package at modifiedFile to outputFile.
+func handleMakePatch(inputFile, modifiedFile, outputFile string, opts *options) {
+	orig, err := pck.Open(inputFile, opts.pckOpts...)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer orig.Close()
+	modified, err := pck.Open(modifiedFile, opts.pckOpts...)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer modified.Close()
+
+	out, err := os.Create(outputFile)
+	if err != nil {
+		log.Fatalf("Error creating output file: %v", err)
+	}
+	defer out.Close()
+
+	log.Printf("Comparing %s with %s...", modifiedFile, inputFile)
+	n, err := pck.CreatePatch(out, orig, modified)
+	if err != nil {
+		log.Fatalf("Error creating patch: %v", err)
+	}
+	log.Printf("Patch of %s written to: %s", util.FormatByteSize(n), outputFile)
+}
+
+// handleApplyPatch rebuilds the package described by the patch at patchFile
+// from the package at inputFile, writing it to outputFile.
+func handleApplyPatch(patchFile, inputFile, outputFile string, opts *options) {
+	a := opts.startAudit("patch", inputFile)
+	patch, err := os.Open(patchFile)
+	if err != nil {
+		log.Fatalf("Error opening patch: %v", err)
+	}
+	defer patch.Close()
+	f, err := pck.Open(inputFile, opts.pckOpts...)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer f.Close()
+
+	if opts.backup {
+		if err := backupOriginal(outputFile); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+	out := opts.createOutput(inputFile, outputFile)
+	log.Printf("Applying %s to: %s", patchFile, inputFile)
+	n, err := pck.ApplyPatch(out, f, patch)
+	if err != nil {
+		out.Abort()
+		log.Fatalf("Error applying patch: %v", err)
+	}
+	f.Close()
+	commitOutput(out)
+	log.Println("Patch applied successfully!")
+	log.Printf("Output file written to: %s", outputFile)
+	log.Printf("Wrote %d bytes in total", n)
+	finishAudit(a, outputFile)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"wwiseutil/pck"
+	"wwiseutil/util"
+)
+
+// newProgressPrinter returns a pck.ProgressFunc that shows the progress of an
+// operation, described by verb, on a single line of standard error. The line
+// is only redrawn when the percentage of entries done changes.
+func newProgressPrinter(verb string) pck.ProgressFunc {
+	last, finished := -1, false
+	return func(p pck.Progress) {
+		percent := 100
+		if p.EntriesTotal > 0 {
+			percent = p.EntriesDone * 100 / p.EntriesTotal
+		}
+		done := p.EntriesDone == p.EntriesTotal
+		if finished || (percent == last && !done) {
+			return
+		}
+		last = percent
+		fmt.Fprintf(os.Stderr, "\r%s %d/%d entries (%d%%), %s written", verb,
+			p.EntriesDone, p.EntriesTotal, percent, util.FormatByteSize(p.BytesWritten))
+		if done && p.EntriesTotal > 0 {
+			fmt.Fprintln(os.Stderr)
+			finished = true
+		}
+	}
+}
@@ -0,0 +1,495 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"wwiseutil/bnk"
+	"wwiseutil/pck"
+	"wwiseutil/util"
+	"wwiseutil/wwise"
+)
+
+// ignoredFiles are the names, in lower case, of files created by operating
+// systems and file browsers that are never replacement files.
+var ignoredFiles = map[string]bool{
+	"thumbs.db":   true,
+	"ehthumbs.db": true,
+	"desktop.ini": true,
+}
+
+// isIgnored reports whether the file or directory with the given base name
+// should be skipped when scanning for replacement files. Hidden files, such as
+// .DS_Store or the ._ files macOS leaves on foreign file systems, are skipped.
+func isIgnored(name string) bool {
+	return strings.HasPrefix(name, ".") || ignoredFiles[strings.ToLower(name)]
+}
+
+// findPckReplacementFiles scans the bnk and wem subdirectories of targetDir,
+// including any nested directories, for files named by the 1-based index or
+// the ID of the entry they replace, see entryID. Files to add as new entries
+// are kept apart from these, see findNewEntryFiles.
+func findPckReplacementFiles(targetDir string, srcPck *pck.File) ([]*pck.ReplacementFile, error) {
+	bnks, err := scanTableDir(targetDir, "bnk", srcPck.BnkIndexes)
+	if err != nil {
+		return nil, err
+	}
+	wems, err := scanTableDir(targetDir, "wem", srcPck.WemIndexes)
+	if err != nil {
+		return nil, err
+	}
+	return append(bnks, wems...), nil
+}
+
+// scanTableDir scans the typ subdirectory of targetDir for replacements of the
+// entries in indexes. It is not an error for the subdirectory not to exist.
+func scanTableDir(targetDir, typ string, indexes []*pck.FileIndex) ([]*pck.ReplacementFile, error) {
+	// Files replacing the same entry are all returned; Repack resolves them as
+	// given by -ondup.
+	var replacements []*pck.ReplacementFile
+
+	dir := filepath.Join(targetDir, typ)
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && isIgnored(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		base := d.Name()
+		id, err := entryID(strings.TrimSuffix(base, filepath.Ext(base)), indexes)
+		if err != nil {
+			log.Printf("Warning: %s: %v for %s files (indexes 1-%d), skipping.",
+				base, err, strings.ToUpper(typ), len(indexes))
+			return nil
+		}
+		replacements = append(replacements, &pck.ReplacementFile{ID: id, Path: path, Type: typ})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error scanning %s target directory: %w", typ, err)
+	}
+	return replacements, nil
+}
+
+// entryID returns the ID of the entry of indexes that a replacement file named
+// name, without its extension, replaces. A decimal name from 1 to the number
+// of indexes is the 1-based index of the entry, as shown by -v; any other name
+// is the ID of the entry, in decimal or 0x-prefixed hexadecimal, as written by
+// -unpack. Since IDs are hashes, they are practically never small enough to be
+// mistaken for an index.
+func entryID(name string, indexes []*pck.FileIndex) (uint32, error) {
+	if index, err := strconv.Atoi(name); err == nil && index >= 1 && index <= len(indexes) {
+		return indexes[index-1].ID, nil
+	}
+	id, err := util.ParseID(name)
+	if err != nil {
+		return 0, errors.New("name is not an index or ID")
+	}
+	if !hasEntry(indexes, id) {
+		return 0, fmt.Errorf("no entry has index or ID %s", name)
+	}
+	return id, nil
+}
+
+// hasEntry reports whether any of indexes has the given ID.
+func hasEntry(indexes []*pck.FileIndex, id uint32) bool {
+	for _, idx := range indexes {
+		if idx.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// The subdirectory of the target directory holding files to add as new
+// entries, in bnk and wem subdirectories of its own.
+const newEntriesDir = "new"
+
+// findNewEntryFiles scans the new/bnk and new/wem subdirectories of targetDir
+// for files to add as new entries, named by the ID of the entry to add in
+// decimal or 0x-prefixed hexadecimal. Files whose name is not an ID are
+// skipped with a warning.
+func findNewEntryFiles(targetDir string) ([]*pck.ReplacementFile, error) {
+	var additions []*pck.ReplacementFile
+	for _, typ := range []string{"bnk", "wem"} {
+		dir := filepath.Join(targetDir, newEntriesDir, typ)
+		entries, err := os.ReadDir(dir)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error scanning new %s directory: %w", typ, err)
+		}
+		for _, e := range entries {
+			if e.IsDir() || isIgnored(e.Name()) {
+				continue
+			}
+			base := e.Name()
+			id, err := util.ParseID(strings.TrimSuffix(base, filepath.Ext(base)))
+			if err != nil {
+				log.Printf("Warning: could not parse ID from filename %s, skipping.", base)
+				continue
+			}
+			additions = append(additions, &pck.ReplacementFile{
+				ID:   id,
+				Path: filepath.Join(dir, base),
+				Type: typ,
+				New:  true,
+			})
+		}
+	}
+	return additions, nil
+}
+
+// readManifest reads the replacement files listed in the CSV manifest at path.
+// The manifest starts with a header row naming its columns, which must include
+// "path", "type" and either "index" or "id":
+//
+//	path,type,index
+//	music/intro.wem,wem,5
+//
+// Paths are relative to targetDir and use forward slashes on every platform.
+// Indexes are 1-based, as shown in the verbose output. IDs are written in
+// decimal or 0x-prefixed hexadecimal; if a manifest has both columns, a row
+// whose ID is empty uses its index. Lines starting with # are ignored.
+//
+// The optional "entry_type", "unknown1", "language" and "align" columns
+// override fields of the index of a row's entry, see overrideOf. The optional
+// "offset" column makes a row's file replace only the bytes of its entry
+// starting at that offset, accepting the suffixes of -align, rather than the
+// whole entry.
+func readManifest(path, targetDir string, srcPck *pck.File) ([]*pck.ReplacementFile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening manifest: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.Comment = '#'
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading manifest header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"path", "type"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("manifest is missing the %q column", name)
+		}
+	}
+	indexCol, hasIndex := columns["index"]
+	idCol, hasID := columns["id"]
+	if !hasIndex && !hasID {
+		return nil, errors.New(`manifest is missing the "index" or "id" column`)
+	}
+
+	var replacements []*pck.ReplacementFile
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading manifest: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+
+		relPath := row[columns["path"]]
+		typ := strings.ToLower(row[columns["type"]])
+		var indexes []*pck.FileIndex
+		switch typ {
+		case "bnk":
+			indexes = srcPck.BnkIndexes
+		case "wem":
+			indexes = srcPck.WemIndexes
+		default:
+			return nil, fmt.Errorf("manifest line %d: unknown type %q", line, typ)
+		}
+		var id uint32
+		if hasID && row[idCol] != "" {
+			if id, err = util.ParseID(row[idCol]); err != nil {
+				return nil, fmt.Errorf("manifest line %d: %w", line, err)
+			}
+			if !hasEntry(indexes, id) {
+				return nil, fmt.Errorf("manifest line %d: no %s entry has ID %s",
+					line, typ, util.FormatID(id))
+			}
+		} else if !hasIndex {
+			return nil, fmt.Errorf("manifest line %d: no ID given", line)
+		} else {
+			index, err := strconv.Atoi(row[indexCol])
+			if err != nil || index < 1 || index > len(indexes) {
+				return nil, fmt.Errorf("manifest line %d: invalid %s index %q (1-%d)",
+					line, typ, row[indexCol], len(indexes))
+			}
+			id = indexes[index-1].ID
+		}
+
+		fullPath := filepath.Join(targetDir, filepath.FromSlash(relPath))
+		if _, err := os.Stat(fullPath); err != nil {
+			return nil, fmt.Errorf("manifest line %d: %w", line, err)
+		}
+		override, err := overrideOf(row, columns, srcPck)
+		if err != nil {
+			return nil, fmt.Errorf("manifest line %d: %w", line, err)
+		}
+		var offset *int64
+		if i, ok := columns["offset"]; ok && i < len(row) && strings.TrimSpace(row[i]) != "" {
+			n, err := util.ParseByteSize(strings.TrimSpace(row[i]))
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("manifest line %d: invalid offset %q", line, row[i])
+			}
+			offset = &n
+		}
+		replacements = append(replacements, &pck.ReplacementFile{
+			ID:       id,
+			Path:     fullPath,
+			Type:     typ,
+			Override: override,
+			Offset:   offset,
+		})
+	}
+	return replacements, nil
+}
+
+// overrideOf returns the overrides of the fields of an entry's index given by
+// a manifest row, or nil if the row overrides none. Empty cells override
+// nothing. "entry_type" and "unknown1" set the Type and Unknown1 fields, as
+// shown by -v, in decimal or 0x-prefixed hexadecimal; "language" sets the
+// language of the entry by ID or by its name in the language map of srcPck;
+// and "align" starts the data of the entry on a multiple of the given number
+// of bytes, accepting the suffixes of -align.
+func overrideOf(row []string, columns map[string]int, srcPck *pck.File) (*pck.IndexOverride, error) {
+	cell := func(name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	var o pck.IndexOverride
+	set := false
+	for _, field := range []struct {
+		column string
+		dst    **uint32
+	}{{"entry_type", &o.Type}, {"unknown1", &o.Unknown1}} {
+		if v := cell(field.column); v != "" {
+			n, err := util.ParseID(v)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", field.column, err)
+			}
+			*field.dst = &n
+			set = true
+		}
+	}
+	if v := cell("language"); v != "" {
+		id, err := languageID(v, srcPck)
+		if err != nil {
+			return nil, err
+		}
+		o.Unknown2 = &id
+		set = true
+	}
+	if v := cell("align"); v != "" {
+		align, err := util.ParseByteSize(v)
+		if err != nil || align < 1 || align > math.MaxUint32 {
+			return nil, fmt.Errorf("invalid align %q", v)
+		}
+		o.Alignment = uint32(align)
+		set = true
+	}
+	if !set {
+		return nil, nil
+	}
+	return &o, nil
+}
+
+// languageID returns the ID of the language of srcPck named name, in any case,
+// or given by name as a number.
+func languageID(name string, srcPck *pck.File) (uint32, error) {
+	for _, l := range srcPck.Languages {
+		if strings.EqualFold(l.Name, name) {
+			return l.ID, nil
+		}
+	}
+	id, err := util.ParseID(name)
+	if err != nil {
+		return 0, fmt.Errorf("unknown language %q", name)
+	}
+	return id, nil
+}
+
+// findBnkReplacementFiles scans targetDir for .wem files replacing the wems of
+// srcBnk, named by their index or ID as bnkWemIndex reads them. If several
+// files replace the same wem, the last one found is used.
+func findBnkReplacementFiles(targetDir string, srcBnk *bnk.File) ([]*wwise.ReplacementWem, error) {
+	var replacements []*wwise.ReplacementWem
+	// The position in replacements of the file replacing each wem.
+	replaced := make(map[int]int)
+
+	err := filepath.WalkDir(targetDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != targetDir && isIgnored(d.Name()) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.IsDir() {
+			base := filepath.Base(path)
+			ext := filepath.Ext(base)
+			if strings.ToLower(ext) != ".wem" {
+				return nil
+			}
+
+			wemIndex, err := bnkWemIndex(strings.TrimSuffix(base, ext), srcBnk.Wems())
+			if errors.Is(err, errAmbiguousWem) {
+				log.Printf("Error: %s: %v; name it by its 0x-prefixed hexadecimal ID to replace "+
+					"the wem with that ID, skipping.", base, err)
+				return nil
+			}
+			if err != nil {
+				log.Printf("Warning: %s: %v for the wems of the BNK (indexes 0-%d), skipping.",
+					base, err, len(srcBnk.Wems())-1)
+				return nil
+			}
+
+			file, err := os.Open(path)
+			if err != nil {
+				log.Printf("Warning: could not open replacement file %s: %v", path, err)
+				return nil
+			}
+
+			fi, err := file.Stat()
+			if err != nil {
+				log.Printf("Warning: could not get file info for %s: %v", path, err)
+				file.Close()
+				return nil
+			}
+
+			var mismatch *pck.TypeMismatchError
+			err = pck.CheckReplacementType("wem", file, fi.Size())
+			if errors.As(err, &mismatch) {
+				log.Printf("WARNING: %s looks like a %s file, not a wem, skipping.", path, mismatch.Kind)
+				file.Close()
+				return nil
+			}
+
+			r := &wwise.ReplacementWem{
+				Wem:      file,
+				WemIndex: wemIndex,
+				Length:   fi.Size(),
+			}
+			if i, ok := replaced[wemIndex]; ok {
+				prev := replacements[i].Wem.(*os.File)
+				log.Printf("Warning: %s and %s both replace the wem at index %d; using %s.",
+					prev.Name(), path, wemIndex, path)
+				prev.Close()
+				replacements[i] = r
+				return nil
+			}
+			replaced[wemIndex] = len(replacements)
+			replacements = append(replacements, r)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("error scanning target directory: %w", err)
+	}
+
+	return replacements, nil
+}
+
+// errAmbiguousWem is returned by bnkWemIndex for a name that is both the index
+// of a wem and the ID of another.
+var errAmbiguousWem = errors.New("name is both the index of a wem and the ID of another")
+
+// bnkWemIndex returns the 0-based index into wems of the wem that a replacement
+// file named name, without its extension, replaces. Unlike the entries of a
+// package, see entryID, the wems of a SoundBank have always been replaced by
+// their 0-based index in the DIDX section: a decimal name from 0 to the number
+// of wems minus one is that index. Any other name is the ID of the wem, in
+// decimal or 0x-prefixed hexadecimal, as written by -unpack. A decimal name
+// that is also the ID of another wem results in errAmbiguousWem.
+func bnkWemIndex(name string, wems []*wwise.Wem) (int, error) {
+	id, idErr := util.ParseID(name)
+	byID := -1
+	if idErr == nil {
+		for i, wem := range wems {
+			if wem.Descriptor.WemId == id {
+				byID = i
+				break
+			}
+		}
+	}
+	if index, err := strconv.Atoi(name); err == nil && index >= 0 && index < len(wems) {
+		if byID >= 0 && byID != index {
+			return 0, errAmbiguousWem
+		}
+		return index, nil
+	}
+	if byID >= 0 {
+		return byID, nil
+	}
+	if idErr != nil {
+		return 0, errors.New("name is not an index or ID")
+	}
+	return 0, fmt.Errorf("no wem has index or ID %s", name)
+}
+
+// checkReplacementTypes warns about every replacement file that looks like the
+// wrong type for the entry it replaces, returning the number of such files.
+func checkReplacementTypes(replacements []*pck.ReplacementFile) int {
+	mismatches := 0
+	for _, r := range replacements {
+		if r.Offset != nil {
+			// Partial replacements are fragments of their entry.
+			continue
+		}
+		f, err := os.Open(r.Path)
+		if err != nil {
+			// Repack will report the problem when it reads the file.
+			continue
+		}
+		fi, err := f.Stat()
+		if err == nil {
+			err = pck.CheckReplacementType(r.Type, f, fi.Size())
+		}
+		f.Close()
+
+		var mismatch *pck.TypeMismatchError
+		if errors.As(err, &mismatch) {
+			log.Printf("WARNING: %s is in the %s folder but looks like a %s file. "+
+				"Check that it was not placed in the wrong folder.", r.Path, r.Type, mismatch.Kind)
+			mismatches++
+		}
+	}
+	return mismatches
+}
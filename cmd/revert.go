@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"wwiseutil/pck"
+)
+
+// The extension added to the path of a file to name the copy of it kept by
+// -backup before it is first overwritten.
+const backupExt = ".vanilla"
+
+// backupOriginal copies the file at path, if it exists, to path+backupExt
+// before it is overwritten. An existing backup is kept, since it holds the
+// file as it was before it was first modified.
+func backupOriginal(path string) error {
+	backup := path + backupExt
+	if _, err := os.Stat(backup); err == nil {
+		return nil
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if err := copyFile(path, backup); err != nil {
+		return fmt.Errorf("backing up %s: %w", path, err)
+	}
+	log.Printf("Backed up the original file to: %s", backup)
+	return nil
+}
+
+// copyFile copies the file at src to dst, replacing dst if it exists.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// readAudit reads the audit file of the output at path, if it has one.
+func readAudit(path string) (*audit, error) {
+	data, err := os.ReadFile(path + auditExt)
+	if err != nil {
+		return nil, err
+	}
+	a := new(audit)
+	if err := json.Unmarshal(data, a); err != nil {
+		return nil, fmt.Errorf("reading audit file: %w", err)
+	}
+	return a, nil
+}
+
+// matches reports whether the file at path has the size and hash recorded in
+// f.
+func (f *auditFile) matches(path string) bool {
+	actual := &auditFile{Path: path}
+	return actual.hash() == nil && actual.Size == f.Size && actual.SHA256 == f.SHA256
+}
+
+// handleStatus reports whether the package at path is vanilla or modded, from
+// its watermark, its audit file and the fingerprints of known releases.
+func handleStatus(path string, opts *options) {
+	f, err := pck.Open(path, opts.pckOpts...)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer f.Close()
+
+	modded := false
+	if w, ok := f.Watermark(); ok {
+		log.Printf("Modded: watermarked by %s", w)
+		modded = true
+	}
+	if a, err := readAudit(path); err == nil && a.Output != nil && a.Output.matches(path) {
+		log.Printf("Modded: produced by %s %s on %s (see %s)", a.Tool, a.Version,
+			a.Finished.Format("2006-01-02 15:04:05 MST"), path+auditExt)
+		modded = true
+	}
+	if label, ok := f.Identify(); ok && !modded {
+		log.Printf("Vanilla: %s", label)
+		return
+	}
+	if !modded {
+		log.Printf("Unknown: %s has no watermark or audit file, and does not match a known release. "+
+			"Its fingerprint is %s.", path, f.Fingerprint())
+	}
+	if _, err := os.Stat(path + backupExt); err == nil {
+		log.Printf("A backup of the original file is available; use -revert to restore it.")
+	}
+}
+
+// handleRevert restores the vanilla version of the file at path, from the
+// backup kept by -backup or else from the input recorded in its audit file,
+// provided that input still has the recorded hash.
+func handleRevert(path string) {
+	source := path + backupExt
+	if _, err := os.Stat(source); err != nil {
+		a, err := readAudit(path)
+		if err != nil {
+			log.Fatalf("Error: %s has neither a backup nor an audit file to revert from.", path)
+		}
+		if a.Input == nil || !a.Input.matches(a.Input.Path) {
+			log.Fatalf("Error: the original file recorded in %s is missing or has changed "+
+				"since %s was produced.", path+auditExt, path)
+		}
+		source = a.Input.Path
+	}
+
+	if err := copyFile(source, path); err != nil {
+		log.Fatalf("Error restoring %s: %v", path, err)
+	}
+	// The audit file described the modded file, which no longer exists.
+	if err := os.Remove(path + auditExt); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: could not remove %s: %v", path+auditExt, err)
+	}
+	log.Printf("Restored %s from %s", path, source)
+}
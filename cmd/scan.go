@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"wwiseutil/pck"
+	"wwiseutil/util"
+)
+
+// handleScan reports the WEM IDs that appear in more than one of the packages
+// in dir.
+func handleScan(dir string, opts *options) {
+	log.Printf("Scanning PCK files in: %s", dir)
+	scan, err := pck.ScanDir(dir, opts.pckOpts...)
+	if err != nil {
+		log.Fatalf("Error scanning directory: %v", err)
+	}
+	for path, err := range scan.Skipped {
+		log.Printf("Warning: skipped %s: %v", path, err)
+	}
+	log.Printf("Scanned %d PCK file(s).", len(scan.Packages))
+
+	dups := scan.Duplicates()
+	if len(dups) == 0 {
+		log.Println("No WEM ID appears more than once.")
+		return
+	}
+
+	b := new(strings.Builder)
+	var total int64
+	fmt.Fprintf(b, "\n--- Duplicated WEM IDs ---\n")
+	fmt.Fprintf(b, "%-25s | %-6s | %-16s | %s\n", "ID", "Copies", "Duplicated Bytes", "Packages")
+	for _, d := range dups {
+		var packages []string
+		for _, e := range d.Entries {
+			packages = append(packages, e.Package)
+		}
+		fmt.Fprintf(b, "%-25s | %-6d | %-16d | %s\n", util.FormatID(d.ID), len(d.Entries),
+			d.DuplicatedBytes, strings.Join(packages, ", "))
+		total += d.DuplicatedBytes
+	}
+
+	fmt.Fprintf(b, "\n--- Packages With WEM IDs In Common ---\n")
+	fmt.Fprintf(b, "%-6s | %-16s | %s\n", "IDs", "Bytes", "Packages")
+	for _, o := range scan.Overlaps() {
+		fmt.Fprintf(b, "%-6d | %-16d | %s, %s\n", o.IDs, o.Bytes, o.A, o.B)
+	}
+	log.Print(b.String())
+	log.Printf("%d WEM ID(s) appear more than once, taking %d duplicated bytes in total.",
+		len(dups), total)
+}
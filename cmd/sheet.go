@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"wwiseutil"
+	"wwiseutil/util"
+	"wwiseutil/wem"
+)
+
+// The sample rate of contact sheets, and the length of each preview in them.
+const (
+	sheetSampleRate = 22050
+	sheetPreview    = 3 * time.Second
+)
+
+// sheetEntry is a wem to be previewed in a contact sheet.
+type sheetEntry struct {
+	id   uint32
+	data io.Reader
+}
+
+// handleContactSheet writes a contact sheet previewing every decodable wem in
+// inputFile to outputFile, as a WAVE file if outputFile ends in .wav and as a
+// FLAC file otherwise.
+func handleContactSheet(inputFile, outputFile string, opts *options) {
+	opts.checkOperation(wwiseutil.OpDecode, inputFile)
+	a := opts.startAudit("sheet", inputFile)
+	var entries []sheetEntry
+	ext := opts.sourceExt(inputFile)
+	switch ext {
+	case ".pck", ".npck":
+		f, err := opts.openPck(inputFile)
+		if err != nil {
+			log.Fatalf("Error opening PCK file: %v", err)
+		}
+		defer f.Close()
+		for _, w := range f.Wems {
+			data, err := w.Bytes()
+			if err != nil {
+				log.Fatalf("Error reading %s: %v", w.Name, err)
+			}
+			entries = append(entries, sheetEntry{w.Index.ID, bytes.NewReader(data)})
+		}
+	case ".bnk", ".nbnk":
+		f, err := openBnk(inputFile, opts)
+		if err != nil {
+			log.Fatalf("Error opening BNK file: %v", err)
+		}
+		defer f.Close()
+		for _, w := range f.Wems() {
+			entries = append(entries, sheetEntry{w.Descriptor.WemId, w.Reader})
+		}
+	default:
+		log.Fatalf("Unsupported file type: %s", ext)
+	}
+
+	sheet := wem.NewContactSheet(sheetSampleRate, sheetPreview)
+	skipped := make(map[string]int)
+	invalid := 0
+	for _, e := range entries {
+		if len(opts.ids) > 0 && !opts.ids.contains(e.id) {
+			continue
+		}
+		pcm, codec, err := decodeWem(e.data)
+		if errors.Is(err, wem.ErrUnsupportedCodec) {
+			log.Printf("Skipping wem %s: it is encoded with %s, which cannot be decoded.",
+				util.FormatID(e.id), codec)
+			skipped[codec]++
+			continue
+		}
+		if err == nil {
+			err = sheet.Add(util.FormatID(e.id), pcm)
+		}
+		if err != nil {
+			log.Printf("Warning: skipping wem %s: %v", util.FormatID(e.id), err)
+			invalid++
+		}
+	}
+	for codec, n := range skipped {
+		log.Printf("Skipped %d wem(s) encoded with %s, which cannot be decoded.", n, codec)
+	}
+	if invalid > 0 {
+		log.Printf("Skipped %d wem(s) that could not be previewed.", invalid)
+	}
+	if len(sheet.Cues) == 0 {
+		log.Println("No decodable wems found; only PCM and IMA ADPCM wems can be previewed. " +
+			"Nothing to do.")
+		return
+	}
+
+	outFile, err := os.Create(outputFile)
+	if err != nil {
+		log.Fatalf("Error creating output file: %v", err)
+	}
+	defer outFile.Close()
+	write := sheet.WriteFLAC
+	if strings.EqualFold(filepath.Ext(outputFile), ".wav") {
+		write = sheet.WriteTo
+	}
+	if _, err := write(outFile); err != nil {
+		log.Fatalf("Error writing contact sheet: %v", err)
+	}
+
+	log.Printf("Wrote a contact sheet of %d wem(s) to: %s", len(sheet.Cues), outputFile)
+	for _, c := range sheet.Cues {
+		log.Printf("%s  %s", formatTimestamp(c.Start), c.Label)
+	}
+	finishAudit(a, outputFile)
+}
+
+// decodeWem parses and decodes the wem read from r, also returning the name
+// of the codec it is encoded with.
+func decodeWem(r io.Reader) (*wem.PCM, string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	f, err := wem.NewFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, "", err
+	}
+	pcm, err := f.Decode()
+	return pcm, f.CodecName(), err
+}
+
+// decodeToWAV decodes the wem data to a 16 bit PCM WAVE file with the given
+// sample rate.
+func decodeToWAV(data []byte, sampleRate int) ([]byte, error) {
+	f, err := wem.NewFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	if _, err := f.WriteWAV(&b, sampleRate); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// formatTimestamp formats d as minutes, seconds and milliseconds.
+func formatTimestamp(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d.%03d", int(d.Minutes()), int(d.Seconds())%60,
+		int(d.Milliseconds())%1000)
+}
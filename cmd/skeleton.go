@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"wwiseutil/pck"
+)
+
+// handleSkeleton writes the skeleton of the package at inputFile, its
+// structure and the hashes of its entries without their data, to outputFile.
+// With -zeropayloads, the hashes are those of zeroed data.
+func handleSkeleton(inputFile, outputFile string, opts *options) {
+	f, err := opts.openPck(inputFile)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer f.Close()
+
+	log.Printf("Hashing the entries of %s...", inputFile)
+	s, err := f.Skeleton()
+	if err != nil {
+		log.Fatalf("Error reading PCK file: %v", err)
+	}
+	if opts.zeroPayloads {
+		s.ZeroPayloads()
+	}
+
+	out, err := os.Create(outputFile)
+	if err != nil {
+		log.Fatalf("Error creating output file: %v", err)
+	}
+	defer out.Close()
+	if err := pck.WriteSkeleton(out, s); err != nil {
+		log.Fatalf("Error writing skeleton: %v", err)
+	}
+	log.Printf("Skeleton of %d entries written to: %s", len(s.Entries), outputFile)
+}
+
+// handleRehydrate rebuilds the package described by the skeleton at
+// skeletonFile into outputFile, taking the data of its entries from the
+// package at inputFile and from the files in modDir, if given.
+func handleRehydrate(skeletonFile, inputFile, modDir, outputFile string, opts *options) {
+	a := opts.startAudit("rehydrate", inputFile)
+	sf, err := os.Open(skeletonFile)
+	if err != nil {
+		log.Fatalf("Error opening skeleton: %v", err)
+	}
+	s, err := pck.ReadSkeleton(sf)
+	sf.Close()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+
+	f, err := pck.Open(inputFile, opts.pckOpts...)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer f.Close()
+
+	var files []string
+	if modDir != "" {
+		err := filepath.WalkDir(modDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if path != modDir && isIgnored(d.Name()) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !d.IsDir() {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatalf("Error scanning mod files: %v", err)
+		}
+		log.Printf("Using %d mod file(s) from: %s", len(files), modDir)
+	}
+
+	if opts.backup {
+		if err := backupOriginal(outputFile); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+	out := opts.createOutput(inputFile, outputFile)
+	log.Printf("Rehydrating %s (%d entries) from: %s", skeletonFile, len(s.Entries), inputFile)
+	n, err := s.Rehydrate(out, []*pck.File{f}, files)
+	if err != nil {
+		out.Abort()
+		log.Fatalf("Error rehydrating: %v", err)
+	}
+	f.Close()
+	commitOutput(out)
+	log.Println("Rehydration completed successfully!")
+	log.Printf("Output file written to: %s", outputFile)
+	log.Printf("Wrote %d bytes in total", n)
+	finishAudit(a, outputFile)
+}
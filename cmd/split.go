@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"wwiseutil/util"
+)
+
+// handleSplit splits the package at inputFile into volumes of no more than
+// maxSize bytes, named after outputFile followed by their number, e.g.
+// audio_1.pck and audio_2.pck for audio.pck.
+func handleSplit(inputFile, outputFile string, maxSize int64, opts *options) {
+	f, err := opts.openPck(inputFile)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer f.Close()
+
+	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+		log.Fatalf("Error creating output directory: %v", err)
+	}
+	ext := filepath.Ext(outputFile)
+	base := strings.TrimSuffix(outputFile, ext)
+	log.Printf("Splitting %s into volumes of at most %s", inputFile, util.FormatByteSize(maxSize))
+	n, err := f.Split(maxSize, func(volume int) (io.WriteCloser, error) {
+		path := fmt.Sprintf("%s_%d%s", base, volume, ext)
+		out, err := os.Create(path)
+		if err == nil {
+			log.Printf("Writing volume %d to: %s", volume, path)
+		}
+		return out, err
+	}, opts.pckOpts...)
+	if err != nil {
+		log.Fatalf("Error splitting PCK file: %v", err)
+	}
+	log.Printf("Split %s into %d volume(s)", inputFile, n)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"wwiseutil/bnk"
+	"wwiseutil/util"
+)
+
+// The ways a wem of a package can be stored, as classified by handleStreams.
+const (
+	storageInMemory = "in-memory"
+	storagePrefetch = "prefetch"
+	storageStreamed = "streamed"
+)
+
+// A wemStorage describes how a wem of a package is stored and referenced.
+type wemStorage struct {
+	id uint32
+	// One of the storage constants, from where the wem is found: only in the
+	// DATA section of SoundBanks, both there and in the wem table, or only in
+	// the wem table.
+	class string
+	// The stream types declared by the sound objects referencing the wem, by
+	// the ID of their SoundBank.
+	declared map[uint32]bnk.StreamType
+}
+
+// handleStreams reports whether each wem of the package at path is held in
+// memory, prefetched or streamed, from where its data is found and from the
+// sound objects of the SoundBanks in the package that reference it. Wems with
+// a subtitle in subtitlesFile, if any, are listed with its text.
+func handleStreams(path, subtitlesFile string, opts *options) {
+	subs := readSubtitles(subtitlesFile)
+	f, err := opts.openPck(path)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer f.Close()
+
+	wems := make(map[uint32]*wemStorage)
+	storage := func(id uint32) *wemStorage {
+		s, ok := wems[id]
+		if !ok {
+			s = &wemStorage{id: id, declared: make(map[uint32]bnk.StreamType)}
+			wems[id] = s
+		}
+		return s
+	}
+	for _, idx := range f.WemIndexes {
+		storage(idx.ID).class = storageStreamed
+	}
+	for _, ef := range f.Bnks {
+		r, err := f.ExtractBnk(ef.Index.ID)
+		if err != nil {
+			log.Fatalf("Error reading BNK ID %s: %v", util.FormatID(ef.Index.ID), err)
+		}
+		b, err := bnk.NewFile(r)
+		if err != nil {
+			log.Printf("Warning: skipping BNK ID %s, which cannot be parsed: %v",
+				util.FormatID(ef.Index.ID), err)
+			continue
+		}
+		for _, w := range b.Wems() {
+			s := storage(w.Descriptor.WemId)
+			if s.class == storageStreamed {
+				s.class = storagePrefetch
+			} else if s.class == "" {
+				s.class = storageInMemory
+			}
+		}
+		for id, t := range b.StreamTypes() {
+			storage(id).declared[ef.Index.ID] = t
+		}
+	}
+
+	ids := make([]uint32, 0, len(wems))
+	for id, s := range wems {
+		// Sounds whose wem is in neither place belong to other packages.
+		if s.class != "" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	counts := make(map[string]int)
+	b := new(strings.Builder)
+	for _, id := range ids {
+		s := wems[id]
+		counts[s.class]++
+		fmt.Fprintf(b, "\nwem %-24s %-10s %s", util.FormatID(id), s.class, s.references())
+		if sub := subs.Find(id, ""); sub != nil {
+			fmt.Fprintf(b, " %q", sub.Text)
+		}
+	}
+	log.Print(b.String())
+	log.Printf("%d in-memory, %d prefetched and %d streamed wem(s).",
+		counts[storageInMemory], counts[storagePrefetch], counts[storageStreamed])
+	if counts[storageInMemory] > 0 || counts[storagePrefetch] > 0 {
+		log.Println("In-memory wems are stored in their SoundBanks and must be replaced there. " +
+			"The start of prefetched wems is also stored in their SoundBanks, so replacing " +
+			"them in the package alone may cause a glitch when they start playing.")
+	}
+}
+
+// references describes the sound objects referencing the wem and the stream
+// types they declare.
+func (s *wemStorage) references() string {
+	if len(s.declared) == 0 {
+		return "(not referenced by the SoundBanks of this package)"
+	}
+	banks := make([]uint32, 0, len(s.declared))
+	for id := range s.declared {
+		banks = append(banks, id)
+	}
+	sort.Slice(banks, func(i, j int) bool { return banks[i] < banks[j] })
+	refs := make([]string, len(banks))
+	for i, id := range banks {
+		refs[i] = fmt.Sprintf("%s by BNK %d", s.declared[id], id)
+	}
+	return "declared " + strings.Join(refs, ", ")
+}
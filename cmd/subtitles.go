@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+
+	"wwiseutil/util"
+)
+
+// The maximum number of characters of a subtitle included in a file name.
+const maxSubtitleNameLength = 48
+
+// readSubtitles reads the subtitles of the file at path, or returns nil if
+// path is empty.
+func readSubtitles(path string) util.Subtitles {
+	if path == "" {
+		return nil
+	}
+	subs, err := util.ReadSubtitles(path)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	log.Printf("Read %d subtitle(s) from %s", len(subs), path)
+	return subs
+}
+
+// subtitleFileName returns the name of the file of the voice line with the
+// given ID and subtitle, which may be nil, with the extension ext. The name
+// starts with the ID, followed by the start of the text of the subtitle with
+// every run of characters other than letters and digits replaced by an
+// underscore, so that it is valid on every file system.
+func subtitleFileName(id uint32, s *util.Subtitle, ext string) string {
+	if s == nil {
+		return fmt.Sprintf("%d.%s", id, ext)
+	}
+	b := new(strings.Builder)
+	n, sep := 0, true
+	for _, r := range s.Text {
+		if n == maxSubtitleNameLength {
+			break
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			n, sep = n+1, false
+		} else if !sep {
+			b.WriteByte('_')
+			n, sep = n+1, true
+		}
+	}
+	text := strings.TrimSuffix(b.String(), "_")
+	if text == "" {
+		return fmt.Sprintf("%d.%s", id, ext)
+	}
+	return fmt.Sprintf("%d_%s.%s", id, text, ext)
+}
+
+// subtitleColumns returns the speaker and the text of s, which may be nil, as
+// written to reports.
+func subtitleColumns(s *util.Subtitle) (speaker, text string) {
+	if s == nil {
+		return "", ""
+	}
+	return s.Speaker, s.Text
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"log"
+	"os"
+)
+
+// handleValidate reports the inconsistencies found in the header and index
+// tables of the package at path, and whether it is reproduced byte for byte
+// when rewritten, exiting with a non-zero status if there are any problems.
+func handleValidate(path string, opts *options) {
+	f, err := opts.openPck(path)
+	if err != nil {
+		log.Fatalf("Error opening PCK file: %v", err)
+	}
+	defer f.Close()
+
+	problems, err := f.Validate()
+	if err != nil {
+		log.Fatalf("Error validating PCK file: %v", err)
+	}
+	for _, p := range problems {
+		log.Printf("Problem: %s", p)
+	}
+	failed := len(problems)
+	if err := f.VerifyRoundTrip(); err != nil {
+		log.Printf("Problem: the package cannot be rewritten byte for byte: %v", err)
+		failed++
+	}
+	if failed == 0 {
+		log.Printf("No problems found in %s.", path)
+		return
+	}
+	log.Printf("Found %d problem(s) in %s.", failed, path)
+	f.Close()
+	os.Exit(1)
+}
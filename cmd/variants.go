@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"wwiseutil/pck"
+)
+
+// parseVariant parses a platform variant given as its name, followed by an
+// equals sign, the path to its package and, after a comma, the path its
+// rebuilt package is written to, e.g. "ps5=ps5/audio.pck,out/ps5/audio.pck".
+func parseVariant(s string) (*pck.Variant, error) {
+	name, paths, ok := cut(s, "=")
+	input, output, ok2 := cut(paths, ",")
+	if !ok || !ok2 || name == "" || input == "" || output == "" {
+		return nil, fmt.Errorf("%q is not of the form name=source.pck,output.pck", s)
+	}
+	return &pck.Variant{Name: name, Input: input, Output: output}, nil
+}
+
+// cut slices s around the first instance of sep, as strings.Cut does.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// commandConverter returns a pck.ConvertFunc converting wem replacement files
+// by running command, in which {in} and {out} are replaced by the path to the
+// replacement file and the path the converted file is to be written to, in a
+// folder of dir named after the variant. Other replacement files are used as
+// they are.
+func commandConverter(command, dir string) pck.ConvertFunc {
+	args := strings.Fields(command)
+	return func(v *pck.Variant, r *pck.ReplacementFile) (*pck.ReplacementFile, error) {
+		if r.Type != "wem" {
+			return r, nil
+		}
+		if r.Offset != nil {
+			return nil, errors.New("partial replacements cannot be converted")
+		}
+		out := filepath.Join(dir, v.Name, filepath.Base(r.Path))
+		if err := os.MkdirAll(filepath.Dir(out), 0755); err != nil {
+			return nil, err
+		}
+		cmdArgs := make([]string, len(args))
+		for i, a := range args {
+			cmdArgs[i] = strings.NewReplacer("{in}", r.Path, "{out}", out).Replace(a)
+		}
+		cmd := exec.Command(cmdArgs[0], cmdArgs[1:]...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			return nil, fmt.Errorf("running %s: %v\n%s", args[0], err, output)
+		}
+		converted := *r
+		converted.Path, converted.Data = out, nil
+		return &converted, nil
+	}
+}
+
+// handleVariants rebuilds the package of each of the platform variants given
+// by variantFlags with the replacement files of targetDir, converting them for
+// the platforms given a conversion command by convertFlags, e.g.
+// "ps5=at9tool -e {in} {out}".
+func handleVariants(targetDir string, variantFlags, convertFlags []string, opts *options) {
+	var variants []*pck.Variant
+	byName := make(map[string]*pck.Variant)
+	for _, s := range variantFlags {
+		v, err := parseVariant(s)
+		if err != nil {
+			log.Fatalf("Error: invalid -variant: %v", err)
+		}
+		if byName[v.Name] != nil {
+			log.Fatalf("Error: invalid -variant: %s is given more than once", v.Name)
+		}
+		variants = append(variants, v)
+		byName[v.Name] = v
+	}
+
+	commands := make(map[*pck.Variant]string)
+	for _, s := range convertFlags {
+		name, command, ok := cut(s, "=")
+		v := byName[name]
+		if !ok || strings.TrimSpace(command) == "" {
+			log.Fatalf("Error: invalid -convert: %q is not of the form name=command", s)
+		}
+		if v == nil {
+			log.Fatalf("Error: invalid -convert: no -variant is named %s", name)
+		}
+		commands[v] = command
+	}
+
+	// Replacement files named by index refer to the entries of the first
+	// variant.
+	srcPck, err := pck.Open(variants[0].Input, opts.pckOpts...)
+	if err != nil {
+		log.Fatalf("Error opening source PCK: %v", err)
+	}
+	var replacements, additions []*pck.ReplacementFile
+	if targetDir != "" {
+		if opts.manifest != "" {
+			replacements, err = readManifest(opts.manifest, targetDir, srcPck)
+		} else {
+			replacements, err = findPckReplacementFiles(targetDir, srcPck)
+		}
+		if err != nil {
+			log.Fatalf("Error finding replacement files: %v", err)
+		}
+		if additions, err = findNewEntryFiles(targetDir); err != nil {
+			log.Fatalf("Error finding new entry files: %v", err)
+		}
+		replacements = append(replacements, additions...)
+	}
+	srcPck.Close()
+	if len(replacements) == 0 && len(opts.remove) == 0 && len(opts.remap) == 0 {
+		log.Println("No valid replacement files found in target directory. Nothing to do.")
+		return
+	}
+	log.Printf("Using %d replacement file(s) for %d variant(s)", len(replacements), len(variants))
+
+	pckOpts := opts.pckOpts
+	if len(opts.remove) > 0 {
+		log.Printf("Removing entries with ID: %s", opts.remove.String())
+		pckOpts = append(pckOpts, pck.RemoveIDs(opts.remove...))
+	}
+	if len(opts.remap) > 0 {
+		log.Printf("Remapping entries: %s", opts.remap.String())
+		pckOpts = append(pckOpts, pck.RemapIDs(opts.remap))
+	}
+	if n := checkReplacementTypes(replacements); n > 0 {
+		if !opts.force {
+			log.Fatalf("Refusing to repack: %d replacement file(s) look like the wrong type. "+
+				"Use -force to repack anyway.", n)
+		}
+		pckOpts = append(pckOpts, pck.AllowTypeMismatch())
+	}
+	audits := make([]*audit, len(variants))
+	for i, v := range variants {
+		audits[i] = opts.startAudit("replace", v.Input)
+		if opts.backup {
+			if err := backupOriginal(v.Output); err != nil {
+				log.Fatalf("Error: %v", err)
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(v.Output), 0755); err != nil {
+			log.Fatalf("Error creating output directory: %v", err)
+		}
+	}
+
+	tmp, err := os.MkdirTemp("", "wwiseutil-variants")
+	if err != nil {
+		log.Fatalf("Error creating temporary directory: %v", err)
+	}
+	for v, command := range commands {
+		v.Convert = commandConverter(command, tmp)
+	}
+	written, err := pck.RepackVariants(opts.ctx, variants, replacements, pckOpts...)
+	os.RemoveAll(tmp)
+	for i, n := range written {
+		log.Printf("Wrote %s (%d bytes) to: %s", variants[i].Name, n, variants[i].Output)
+		if a := audits[i]; a != nil {
+			for _, r := range replacements {
+				a.addReplacement(r.Path, r.Type, r.ID, r.New)
+			}
+			a.Removed = opts.remove
+			finishAudit(a, variants[i].Output)
+		}
+	}
+	if errors.Is(err, context.Canceled) {
+		log.Fatalf("Repack interrupted. The partly written output file was removed.")
+	}
+	if errors.Is(err, pck.ErrVerifyFailed) {
+		log.Fatalf("Error: %v. The output file is broken; check the free disk space and repack again.", err)
+	}
+	if err != nil {
+		log.Fatalf("Error during repack: %v", err)
+	}
+	log.Printf("Repacked all %d variant(s) successfully!", len(variants))
+}
@@ -0,0 +1,127 @@
+// Package wwiseutil describes the formats supported by the packages of this
+// module, so that GUIs and servers can list them at runtime rather than
+// hard-coding them.
+package wwiseutil
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+import (
+	"wwiseutil/pck"
+	"wwiseutil/wem"
+)
+
+// The kinds of Format.
+const (
+	// KindContainer is a file format holding other files, such as a package
+	// or a SoundBank.
+	KindContainer = "container"
+	// KindCodec is a codec the audio of a wem can be encoded with.
+	KindCodec = "codec"
+	// KindProfile is a registered pck.Profile, describing the packages of a
+	// particular game.
+	KindProfile = "profile"
+)
+
+// Capabilities is a set of operations supported on a Format.
+type Capabilities uint
+
+const (
+	// CanRead means that files of the format can be opened and their contents
+	// listed and extracted.
+	CanRead Capabilities = 1 << iota
+	// CanWrite means that files of the format can be modified and written,
+	// such as by replacing their contents.
+	CanWrite
+	// CanConvert means that files of the format can be converted to a common
+	// format, such as wem audio decoded to WAV.
+	CanConvert
+)
+
+func (c Capabilities) String() string {
+	var names []string
+	for _, n := range []struct {
+		c    Capabilities
+		name string
+	}{{CanRead, "read"}, {CanWrite, "write"}, {CanConvert, "convert"}} {
+		if c&n.c != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ", ")
+}
+
+// A Format describes a format supported by this module.
+type Format struct {
+	// One of KindContainer, KindCodec or KindProfile.
+	Kind string
+	Name string
+	// The extensions of the files of the format, such as ".pck", if any.
+	Extensions []string
+	// The format tag of a codec, see wem.Codecs.
+	Codec        uint16
+	Capabilities Capabilities
+	// Details about the format, such as the layout described by a profile, or
+	// "" if there are none.
+	Description string
+}
+
+func (f *Format) String() string {
+	s := fmt.Sprintf("%s %q (%s)", f.Kind, f.Name, f.Capabilities)
+	if f.Description != "" {
+		s += ": " + f.Description
+	}
+	return s
+}
+
+// Formats returns the container formats, the wem codecs and the game profiles
+// currently supported, in that order. Profiles registered with
+// pck.RegisterProfile are included.
+func Formats() []*Format {
+	pckExts := []string{".pck", ".npck"}
+	formats := []*Format{
+		{Kind: KindContainer, Name: pck.FormatHybrid.String(), Extensions: pckExts,
+			Capabilities: CanRead | CanWrite},
+		{Kind: KindContainer, Name: pck.FormatHybrid64.String(), Extensions: pckExts,
+			Capabilities: CanRead | CanWrite},
+		{Kind: KindContainer, Name: pck.FormatStandard.String(), Extensions: pckExts,
+			Capabilities: CanRead | CanWrite},
+		{Kind: KindContainer, Name: "SoundBank", Extensions: []string{".bnk", ".nbnk"},
+			Capabilities: CanRead | CanWrite},
+	}
+
+	for _, tag := range wem.Codecs() {
+		f := &Format{Kind: KindCodec, Name: wem.CodecName(tag), Extensions: []string{".wem"},
+			Codec: tag, Capabilities: CanRead}
+		if wem.CanDecode(tag) {
+			f.Capabilities |= CanConvert
+			f.Description = "decoded to WAV at 8, 16 and 24 bits"
+			if tag == wem.CodecADPCM {
+				f.Description = "decoded to WAV from 4 bit Wwise IMA ADPCM"
+			}
+		}
+		formats = append(formats, f)
+	}
+
+	for _, p := range pck.Profiles() {
+		order := p.ByteOrder
+		if order == nil {
+			order = binary.LittleEndian
+		}
+		formats = append(formats, &Format{
+			Kind:         KindProfile,
+			Name:         p.Name,
+			Extensions:   pckExts,
+			Capabilities: CanRead | CanWrite,
+			Description: fmt.Sprintf("%d byte Unknown header section, %d byte index entries, %s",
+				p.UnknownSize, p.EntrySize, order),
+		})
+	}
+	return formats
+}
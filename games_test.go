@@ -0,0 +1,232 @@
+// Package wwiseutil describes the formats supported by the packages of this
+// module, so that GUIs and servers can list them at runtime rather than
+// hard-coding them.
+package wwiseutil
+
+// End-to-end tests against package layouts. Each directory under
+// testdata/games holds the skeletons of packages, named after the package
+// followed by .json, written with -skeleton -zeropayloads: the header and
+// index tables of the package, byte for byte, with the data of every entry
+// zeroed. Rehydrating a skeleton rebuilds the package with its layout and
+// quirks intact, which is all the tests need, without shipping any audio.
+//
+// The wwiseutil directory holds the little endian standard packages the pck
+// tests are run on. The synthetic directory holds small packages built with
+// pck.Builder, one for each other Format and byte order, as no package of a
+// real game of those layouts is available. Skeletons of the packages of real
+// games belong in a directory of their own.
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+import (
+	"wwiseutil/pck"
+	"wwiseutil/util"
+)
+
+const gamesDir = "testdata/games"
+
+// A gameFixture is a package rebuilt from its skeleton.
+type gameFixture struct {
+	name string // The directory of the skeleton followed by the package's name.
+	path string
+	data []byte
+}
+
+// loadGameFixtures rehydrates every skeleton under gamesDir into a directory
+// of the test, keeping the names of the packages, which profiles are matched
+// by.
+func loadGameFixtures(t *testing.T) []*gameFixture {
+	paths, err := filepath.Glob(filepath.Join(gamesDir, "*", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no skeletons found in %s", gamesDir)
+	}
+
+	var fixtures []*gameFixture
+	for _, path := range paths {
+		game := filepath.Base(filepath.Dir(path))
+		name := strings.TrimSuffix(filepath.Base(path), ".json")
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s, err := pck.ReadSkeleton(f)
+		f.Close()
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		buf := new(bytes.Buffer)
+		if _, err := s.Rehydrate(buf, nil, nil); err != nil {
+			t.Fatalf("rehydrating %s: %v", path, err)
+		}
+
+		dir := filepath.Join(t.TempDir(), game)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		out := filepath.Join(dir, name)
+		if err := os.WriteFile(out, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		fixtures = append(fixtures, &gameFixture{game + "/" + name, out, buf.Bytes()})
+	}
+	return fixtures
+}
+
+func TestGameFixturesCoverEveryLayout(t *testing.T) {
+	type layout struct {
+		format pck.Format
+		order  binary.ByteOrder
+	}
+	found := make(map[layout]bool)
+	for _, g := range loadGameFixtures(t) {
+		f, err := pck.OpenReader(bytes.NewReader(g.data), int64(len(g.data)))
+		if err != nil {
+			t.Fatalf("%s: %v", g.name, err)
+		}
+		found[layout{f.Format, f.ByteOrder}] = true
+		f.Close()
+	}
+	for _, format := range []pck.Format{pck.FormatHybrid, pck.FormatHybrid64, pck.FormatStandard} {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			if !found[layout{format, order}] {
+				t.Errorf("no skeleton of a %s package in %v", format, order)
+			}
+		}
+	}
+}
+
+func TestGameFixturesOpenAndValidate(t *testing.T) {
+	for _, g := range loadGameFixtures(t) {
+		for _, op := range []string{OpUnpack, OpReplace, OpPatch} {
+			if err := CheckOperation(op, g.path); err != nil {
+				t.Errorf("%s: %v", g.name, err)
+			}
+		}
+		f, err := pck.Open(g.path)
+		if err != nil {
+			t.Errorf("%s: %v", g.name, err)
+			continue
+		}
+		problems, err := f.Validate()
+		if err != nil {
+			t.Errorf("%s: %v", g.name, err)
+		}
+		for _, p := range problems {
+			t.Errorf("%s: %s", g.name, p)
+		}
+		f.Close()
+	}
+}
+
+func TestGameFixturesUnchangedRepackIsEqual(t *testing.T) {
+	for _, g := range loadGameFixtures(t) {
+		out := g.path + ".out"
+		if _, err := pck.Repack(g.path, out, nil); err != nil {
+			t.Errorf("%s: %v", g.name, err)
+			continue
+		}
+		data, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, g.data) {
+			t.Errorf("%s: repacking without changes wrote %d bytes that differ from the %d "+
+				"bytes of the package", g.name, len(data), len(g.data))
+		}
+	}
+}
+
+func TestGameFixturesUnpack(t *testing.T) {
+	util.SkipIfShort(t)
+
+	for _, g := range loadGameFixtures(t) {
+		f, err := pck.Open(g.path)
+		if err != nil {
+			t.Errorf("%s: %v", g.name, err)
+			continue
+		}
+		dir := g.path + ".unpacked"
+		if err := f.UnpackTo(dir); err != nil {
+			t.Errorf("%s: %v", g.name, err)
+		}
+		// Entries of the same ID in several languages are unpacked to the same
+		// file, so only the number of distinct files is checked.
+		want := make(map[string]bool)
+		for _, files := range [][]*pck.EmbeddedFile{f.Bnks, f.Wems} {
+			for _, e := range files {
+				want[e.Name] = true
+			}
+		}
+		got := 0
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				got++
+			}
+			return nil
+		})
+		if got != len(want) {
+			t.Errorf("%s: unpacked %d files, want %d", g.name, got, len(want))
+		}
+		f.Close()
+	}
+}
+
+func TestGameFixturesReplaceAndReread(t *testing.T) {
+	for _, g := range loadGameFixtures(t) {
+		orig, err := pck.Open(g.path)
+		if err != nil {
+			t.Errorf("%s: %v", g.name, err)
+			continue
+		}
+		if len(orig.Wems) == 0 {
+			orig.Close()
+			continue
+		}
+		target := orig.Wems[len(orig.Wems)/2].Index
+		data := bytes.Repeat([]byte{0xA5}, int(target.Length)+333)
+		out := g.path + ".replaced"
+		r := []*pck.ReplacementFile{{ID: target.ID, Data: data, Type: "wem"}}
+		if _, err := pck.Repack(g.path, out, r, pck.AllowTypeMismatch(), pck.VerifyOutput()); err != nil {
+			t.Errorf("%s: replacing wem ID %d: %v", g.name, target.ID, err)
+			orig.Close()
+			continue
+		}
+
+		replaced, err := pck.Open(out)
+		if err != nil {
+			t.Errorf("%s: reopening: %v", g.name, err)
+			orig.Close()
+			continue
+		}
+		if len(replaced.Bnks) != len(orig.Bnks) || len(replaced.Wems) != len(orig.Wems) {
+			t.Errorf("%s: replaced package holds %d bnks and %d wems, want %d and %d", g.name,
+				len(replaced.Bnks), len(replaced.Wems), len(orig.Bnks), len(orig.Wems))
+		}
+		problems, err := replaced.Validate()
+		if err != nil {
+			t.Errorf("%s: %v", g.name, err)
+		}
+		for _, p := range problems {
+			t.Errorf("%s: replaced package: %s", g.name, p)
+		}
+		for _, w := range replaced.Wems {
+			if w.Index.ID != target.ID {
+				continue
+			}
+			if got, err := w.Bytes(); err != nil || !bytes.Equal(got, data) {
+				t.Errorf("%s: wem ID %d does not hold the replacement data (%v)", g.name, target.ID, err)
+			}
+		}
+		replaced.Close()
+		orig.Close()
+	}
+}
@@ -0,0 +1,138 @@
+// Package wwiseutil describes the formats supported by the packages of this
+// module, so that GUIs and servers can list them at runtime rather than
+// hard-coding them.
+package wwiseutil
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The operations checked by CheckOperation.
+const (
+	// OpUnpack extracts the files held by a package or SoundBank.
+	OpUnpack = "unpack"
+	// OpReplace writes a copy of a package or SoundBank with some of its files
+	// replaced.
+	OpReplace = "replace"
+	// OpPatch replaces files of a package or SoundBank by overwriting it in
+	// place.
+	OpPatch = "patch"
+	// OpDecode decodes the wems held by a package or SoundBank to WAV. Wems
+	// in codecs that cannot be decoded, see wem.CanDecode, are skipped or
+	// kept as they are rather than failing the operation.
+	OpDecode = "decode"
+)
+
+// The capabilities each operation needs.
+var opCapabilities = map[string]Capabilities{
+	OpUnpack:  CanRead,
+	OpReplace: CanRead | CanWrite,
+	OpPatch:   CanRead | CanWrite,
+	OpDecode:  CanRead | CanConvert,
+}
+
+// ErrUnsupportedOperation is wrapped by the errors of CheckOperation for
+// operations that are not supported on a file.
+var ErrUnsupportedOperation = errors.New("unsupported operation")
+
+// An UnsupportedOperationError reports an operation that is not supported on
+// a file, what the file lacks and why, so that GUIs and servers can tell users
+// what they can do instead.
+type UnsupportedOperationError struct {
+	// One of the operations of CheckOperation, such as OpReplace.
+	Op   string
+	Path string
+	// The format of the file, such as "SoundBank", as listed by Formats.
+	Format string
+	// The capabilities the operation needs that the file lacks.
+	Missing Capabilities
+	// Why the operation is not supported, and what can be done instead.
+	Reason string
+}
+
+func (e *UnsupportedOperationError) Error() string {
+	return fmt.Sprintf("cannot %s %s (%s): %s", e.Op, filepath.Base(e.Path), e.Format, e.Reason)
+}
+
+// Is reports whether target is ErrUnsupportedOperation.
+func (e *UnsupportedOperationError) Is(target error) bool {
+	return target == ErrUnsupportedOperation
+}
+
+// CheckOperation reports whether op is supported on the file at path, a
+// package or SoundBank, returning an *UnsupportedOperationError if it is not.
+// The format of the file is told by its extension, as elsewhere in this
+// module, and SoundBanks are inspected for embedded wems.
+func CheckOperation(op, path string) error {
+	need, ok := opCapabilities[op]
+	if !ok {
+		return fmt.Errorf("unknown operation %q", op)
+	}
+	unsupported := func(format string, missing Capabilities, reason string) error {
+		return &UnsupportedOperationError{Op: op, Path: path, Format: format, Missing: missing, Reason: reason}
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pck", ".npck":
+		return nil
+	case ".bnk", ".nbnk":
+		if op == OpPatch {
+			return unsupported("SoundBank", CanWrite, "SoundBanks cannot be patched in place, "+
+				"since their sections are rewritten; replace their wems into a new file instead")
+		}
+		hasWems, err := bnkHasWems(path)
+		if err != nil {
+			return err
+		}
+		if !hasWems {
+			return unsupported("SoundBank", need, "the SoundBank embeds no wems; its audio is "+
+				"streamed from a package or from loose .wem files, which hold the files to "+
+				"work on instead")
+		}
+		return nil
+	case ".wem":
+		return unsupported("wem", need, "a wem holds a single sound rather than other files; "+
+			"work on the package or SoundBank holding it instead")
+	}
+	return unsupported("unknown", need, "only packages (.pck, .npck) and SoundBanks (.bnk, .nbnk) "+
+		"are supported")
+}
+
+// bnkHasWems reports whether the SoundBank at path embeds any wems, by reading
+// the headers of its sections.
+func bnkHasWems(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	var indexed, data bool
+	for {
+		var hdr struct {
+			Identifier [4]byte
+			Length     uint32
+		}
+		if err := binary.Read(f, binary.LittleEndian, &hdr); err == io.EOF {
+			break
+		} else if err != nil {
+			return false, fmt.Errorf("reading %s: %w", path, err)
+		}
+		switch string(hdr.Identifier[:]) {
+		case "DIDX":
+			indexed = hdr.Length > 0
+		case "DATA":
+			data = true
+		}
+		if _, err := f.Seek(int64(hdr.Length), io.SeekCurrent); err != nil {
+			return false, err
+		}
+	}
+	return indexed && data, nil
+}
@@ -0,0 +1,62 @@
+// Package wwiseutil describes the formats supported by the packages of this
+// module, so that GUIs and servers can list them at runtime rather than
+// hard-coding them.
+package wwiseutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckOperation(t *testing.T) {
+	dir := t.TempDir()
+	// A SoundBank without wems: a header section and a hierarchy section.
+	streamed := filepath.Join(dir, "streamed.bnk")
+	bank := []byte("BKHD\x04\x00\x00\x00\x86\x00\x00\x00HIRC\x04\x00\x00\x00\x00\x00\x00\x00")
+	if err := os.WriteFile(streamed, bank, 0644); err != nil {
+		t.Fatal(err)
+	}
+	bnk := filepath.Join("bnk", "testdata", "simple.bnk")
+
+	for _, tt := range []struct {
+		op, path string
+		// The capabilities reported missing, or 0 if the operation is
+		// supported.
+		missing Capabilities
+	}{
+		{OpReplace, "sound.pck", 0},
+		{OpPatch, "SOUND.NPCK", 0},
+		{OpDecode, bnk, 0},
+		{OpReplace, bnk, 0},
+		{OpPatch, bnk, CanWrite},
+		{OpUnpack, streamed, CanRead},
+		{OpReplace, streamed, CanRead | CanWrite},
+		{OpDecode, "1234.wem", CanRead | CanConvert},
+		{OpUnpack, "sound.zip", CanRead},
+	} {
+		err := CheckOperation(tt.op, tt.path)
+		if tt.missing == 0 {
+			if err != nil {
+				t.Errorf("%s %s: %v", tt.op, tt.path, err)
+			}
+			continue
+		}
+		var u *UnsupportedOperationError
+		if !errors.As(err, &u) || !errors.Is(err, ErrUnsupportedOperation) {
+			t.Errorf("%s %s: got %v, want an UnsupportedOperationError", tt.op, tt.path, err)
+			continue
+		}
+		if u.Op != tt.op || u.Path != tt.path || u.Missing != tt.missing || u.Reason == "" {
+			t.Errorf("%s %s: got %+v, missing %v", tt.op, tt.path, *u, tt.missing)
+		}
+	}
+
+	if err := CheckOperation("delete", "sound.pck"); err == nil || errors.Is(err, ErrUnsupportedOperation) {
+		t.Errorf("checking an unknown operation: got %v", err)
+	}
+	if err := CheckOperation(OpUnpack, filepath.Join(dir, "missing.bnk")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("checking a missing SoundBank: got %v", err)
+	}
+}
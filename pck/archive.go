@@ -0,0 +1,104 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+)
+
+// An Archive is a parent archive holding packages, such as the archive format
+// of a game, that packages can be opened from by OpenArchived without being
+// extracted first. An implementation for a particular archive format looks
+// the file up in its table of contents, and returns a reader over its data.
+type Archive interface {
+	// OpenFile returns a reader over the contents of the file at name in the
+	// archive, and its size in bytes.
+	OpenFile(name string) (io.ReaderAt, int64, error)
+}
+
+// OpenArchived opens the package stored as the file at name in a, as
+// OpenReader does. Registered profiles are matched against name. Offsets of
+// the package, such as those of EmbeddedFile.Span, are relative to the start
+// of the file rather than of the archive.
+func OpenArchived(a Archive, name string, opts ...Option) (*File, error) {
+	r, size, err := a.OpenFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s in archive: %w", name, err)
+	}
+	return open(name, readerAtFile{io.NewSectionReader(r, 0, size)}, newOptions(opts))
+}
+
+// An Extent is a range of bytes of a file.
+type Extent struct {
+	Offset, Length int64
+}
+
+// An ExtentArchive is an Archive whose table of contents is given by the
+// caller: each of its files is stored as one or more extents of Parent, in
+// order, as in the chunked virtual file systems of many games. It serves any
+// archive whose files are stored uncompressed, and as an example of an
+// Archive.
+type ExtentArchive struct {
+	Parent io.ReaderAt
+	// The extents of the files of the archive, by name.
+	Files map[string][]Extent
+}
+
+// OpenFile returns a reader over the extents of the file at name.
+func (a *ExtentArchive) OpenFile(name string) (io.ReaderAt, int64, error) {
+	extents, ok := a.Files[name]
+	if !ok {
+		return nil, 0, fmt.Errorf("%s: %w", name, fs.ErrNotExist)
+	}
+	r := &extentReader{parent: a.Parent, extents: extents}
+	for _, e := range extents {
+		if e.Offset < 0 || e.Length < 0 {
+			return nil, 0, fmt.Errorf("%s: invalid extent of %d bytes at offset %d", name, e.Length, e.Offset)
+		}
+		r.size += e.Length
+	}
+	return r, r.size, nil
+}
+
+// extentReader is an io.ReaderAt over the extents of a parent file, read as
+// if they were stored one after the other.
+type extentReader struct {
+	parent  io.ReaderAt
+	extents []Extent
+	size    int64
+}
+
+func (r *extentReader) ReadAt(p []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	n := 0
+	for _, e := range r.extents {
+		if len(p) == 0 {
+			break
+		}
+		if off >= e.Length {
+			off -= e.Length
+			continue
+		}
+		want := e.Length - off
+		if want > int64(len(p)) {
+			want = int64(len(p))
+		}
+		m, err := r.parent.ReadAt(p[:want], e.Offset+off)
+		n += m
+		if int64(m) < want {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return n, err
+		}
+		p, off = p[m:], 0
+	}
+	if len(p) > 0 {
+		return n, io.EOF
+	}
+	return n, nil
+}
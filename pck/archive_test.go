@@ -0,0 +1,71 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/fs"
+	"testing"
+	"testing/iotest"
+)
+
+// scatter stores data in three extents of a new parent file, in reverse order
+// and separated by junk, and returns the parent and the extents.
+func scatter(data []byte) ([]byte, []Extent) {
+	third := len(data) / 3
+	chunks := [][]byte{data[:third], data[third : 2*third], data[2*third:]}
+	parent := []byte("junk")
+	extents := make([]Extent, len(chunks))
+	for i := len(chunks) - 1; i >= 0; i-- {
+		extents[i] = Extent{int64(len(parent)), int64(len(chunks[i]))}
+		parent = append(append(parent, chunks[i]...), "junk"...)
+	}
+	return parent, extents
+}
+
+func TestOpenArchived(t *testing.T) {
+	entries := testEntries()
+	data := buildPackage(testBnks, testWems)
+	parent, extents := scatter(data)
+	a := &ExtentArchive{Parent: bytes.NewReader(parent), Files: map[string][]Extent{
+		"sound/test.pck": extents,
+		"empty":          nil,
+	}}
+	r, size, err := a.OpenFile("sound/test.pck")
+	if err != nil || size != int64(len(data)) {
+		t.Fatalf("opened a file of %d bytes (%v)", size, err)
+	}
+	if err := iotest.TestReader(io.NewSectionReader(r, 0, size), data); err != nil {
+		t.Error(err)
+	}
+
+	f, err := OpenArchived(a, "sound/test.pck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertHolds(t, "the archived package", f, entries)
+	assertWritesBytes(t, f, data)
+	f.Close()
+
+	if _, err := OpenArchived(a, "missing.pck"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("opening a missing file: got %v", err)
+	}
+	if _, err := OpenArchived(a, "empty"); err == nil {
+		t.Error("an empty file was opened as a package")
+	}
+
+	a = &ExtentArchive{Parent: bytes.NewReader(nil), Files: map[string][]Extent{"bad": {{-1, 4}}}}
+	if _, _, err := a.OpenFile("bad"); err == nil {
+		t.Error("a file with an extent at a negative offset was opened")
+	}
+	// Extents past the end of the parent are reported when read.
+	a.Files["short"] = []Extent{{0, 4}}
+	r, _, err = a.OpenFile("short")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadAt(make([]byte, 4), 0); err != io.ErrUnexpectedEOF {
+		t.Errorf("reading an extent past the end of the parent: got %v", err)
+	}
+}
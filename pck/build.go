@@ -0,0 +1,233 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+import (
+	"wwiseutil/util"
+)
+
+// The name of the only language in the language map of packages built without
+// a template. Entries of such packages use its ID, 0.
+const defaultLanguage = "sfx"
+
+// The package version written to the header of packages built without a
+// template.
+const defaultVersion = 1
+
+// Build writes a new package to w holding the files in the bnk and wem
+// subdirectories of dir, as written by UnpackTo. Each file is named by the ID
+// of its entry, in decimal or 0x-prefixed hexadecimal, followed by any
+// extension. Hidden files are ignored.
+//
+// The header, format and byte order of the package are taken from the package
+// given by WithTemplate, whose entries are not copied, or else from the
+// profile given by WithProfile. Without either, a little endian FormatHybrid
+// package is built.
+func Build(dir string, w io.Writer, opts ...Option) (int64, error) {
+	b := NewBuilder(opts...)
+	var files []*lazyFile
+	defer func() { closeFiles(files) }()
+	for _, typ := range []string{"bnk", "wem"} {
+		entries, err := os.ReadDir(filepath.Join(dir, typ))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		for _, e := range entries {
+			if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+				continue
+			}
+			name := e.Name()
+			id, err := util.ParseID(strings.TrimSuffix(name, filepath.Ext(name)))
+			if err != nil {
+				return 0, fmt.Errorf("%s file %s is not named by an ID", typ, name)
+			}
+			info, err := e.Info()
+			if err != nil {
+				return 0, err
+			}
+			f := &lazyFile{path: filepath.Join(dir, typ, name), size: info.Size()}
+			files = append(files, f)
+			if err := b.add(typ, id, f, f.size); err != nil {
+				return 0, fmt.Errorf("adding %s: %w", f.path, err)
+			}
+		}
+	}
+	return b.WriteTo(w)
+}
+
+// A Builder assembles a new package entry by entry, from data held anywhere,
+// such as in memory, rather than from files on disk as Build does. As with
+// Build, the index tables of the package are ordered by ID. The data of the
+// entries is only read once the package is written, so it must stay readable
+// until then.
+type Builder struct {
+	opts    []Option
+	profile *Profile
+	entries []*builderEntry
+	// The IDs of the entries added so far, by type.
+	ids map[string]map[uint32]bool
+}
+
+// A builderEntry is an entry added to a Builder.
+type builderEntry struct {
+	typ  string
+	id   uint32
+	data io.ReaderAt
+	size int64
+}
+
+// NewBuilder returns a Builder of an empty package. The header, format and
+// byte order of the package are given by the options as for Build, which also
+// affect how it is written as they affect a Session, such as SortIndexes.
+func NewBuilder(opts ...Option) *Builder {
+	return &Builder{
+		opts: opts,
+		ids:  map[string]map[uint32]bool{"bnk": {}, "wem": {}},
+	}
+}
+
+// SetHeader makes the package be built with the header, format, byte order and
+// alignment described by p, in place of those given by the options of
+// NewBuilder, including a template. It is an error for p to describe an
+// unsupported layout.
+func (b *Builder) SetHeader(p *Profile) error {
+	if _, err := p.format(); err != nil {
+		return err
+	}
+	b.profile = p
+	return nil
+}
+
+// AddBnk adds a SoundBank with the given ID to the package, holding the first
+// size bytes of r. It is an error for the package to already hold a SoundBank
+// with that ID.
+func (b *Builder) AddBnk(id uint32, r io.ReaderAt, size int64) error {
+	return b.add("bnk", id, r, size)
+}
+
+// AddWem adds a wem with the given ID to the package, holding the first size
+// bytes of r, as AddBnk does.
+func (b *Builder) AddWem(id uint32, r io.ReaderAt, size int64) error {
+	return b.add("wem", id, r, size)
+}
+
+// add adds an entry of type typ to the package.
+func (b *Builder) add(typ string, id uint32, r io.ReaderAt, size int64) error {
+	if b.ids[typ][id] {
+		return fmt.Errorf("a %s entry with ID %d already exists", typ, id)
+	}
+	if size < 0 {
+		return fmt.Errorf("%s ID %d has a negative size of %d bytes", typ, id, size)
+	}
+	b.ids[typ][id] = true
+	b.entries = append(b.entries, &builderEntry{typ, id, r, size})
+	return nil
+}
+
+// WriteTo writes the package to w, returning the number of bytes written. It
+// may be called again, for instance after adding more entries, to write the
+// package again.
+func (b *Builder) WriteTo(w io.Writer) (int64, error) {
+	opts := b.opts
+	if b.profile != nil {
+		opts = append(append([]Option(nil), opts...), WithTemplate(nil), WithProfile(b.profile))
+	}
+	base, err := buildBase(newOptions(opts))
+	if err != nil {
+		return 0, err
+	}
+	session := base.NewSession(opts...)
+	for _, e := range b.entries {
+		if err := session.Add(e.typ, e.id, e.data, e.size); err != nil {
+			return 0, err
+		}
+	}
+	return session.WriteTo(w)
+}
+
+// buildBase returns an empty File to build a package from, as described by o.
+func buildBase(o *options) (*File, error) {
+	base := &File{Format: FormatHybrid, ByteOrder: binary.LittleEndian}
+	if t := o.template; t != nil {
+		hdr := *t.Header
+		// Entries are not copied, so neither is the length of their tables.
+		hdr.Unknown = t.NewSession().updateTableLengths(make([][]*FileIndex, 3))
+		base.Format, base.ByteOrder, base.Header = t.Format, t.ByteOrder, &hdr
+		base.Alignment = t.Alignment
+	} else {
+		if p := o.profile; p != nil {
+			format, err := p.format()
+			if err != nil {
+				return nil, err
+			}
+			base.Format, base.ByteOrder, base.Alignment = format, p.byteOrder(), p.Alignment
+		}
+		base.Header = &Header{
+			Identifier: [4]byte{'A', 'K', 'P', 'K'},
+			Unknown:    newUnknown(base.Format, base.ByteOrder),
+			order:      base.ByteOrder,
+		}
+	}
+	if o.alignment != 0 {
+		base.Alignment = o.alignment
+	}
+	return base, nil
+}
+
+// newUnknown returns the Unknown header section of an empty package of the
+// given format and byte order, whose language map holds defaultLanguage.
+func newUnknown(format Format, o binary.ByteOrder) []byte {
+	languages := languageMap(o, defaultLanguage)
+	tables := format.tables()
+	unknown := make([]byte, 8+4*len(tables), 8+4*len(tables)+len(languages))
+	o.PutUint32(unknown[0:], defaultVersion)
+	o.PutUint32(unknown[languageMapLengthOffset:], uint32(len(languages)))
+	for i, c := range tables {
+		o.PutUint32(unknown[tableLengthsOffset+4*i:], indexTableSize(c, 0))
+	}
+	return append(unknown, languages...)
+}
+
+// lazyFile is an io.ReaderAt over the file at path that only keeps the file
+// open while it is being read, so that packages of thousands of files can be
+// built without running out of file descriptors. The file is closed once its
+// last byte has been read.
+type lazyFile struct {
+	path string
+	size int64
+	f    *os.File
+}
+
+func (l *lazyFile) ReadAt(p []byte, off int64) (int, error) {
+	if l.f == nil {
+		f, err := os.Open(l.path)
+		if err != nil {
+			return 0, err
+		}
+		l.f = f
+	}
+	n, err := l.f.ReadAt(p, off)
+	if err != nil || off+int64(n) >= l.size {
+		l.close()
+	}
+	return n, err
+}
+
+// close closes the file, if it is open.
+func (l *lazyFile) close() {
+	if l.f != nil {
+		l.f.Close()
+		l.f = nil
+	}
+}
@@ -0,0 +1,127 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// assertHolds checks that f holds exactly the entries of want, by type and
+// ID, with their indexes ordered by ID.
+func assertHolds(t *testing.T, name string, f *File, want map[string]map[uint32][]byte) {
+	t.Helper()
+	for typ, files := range map[string][]*EmbeddedFile{"bnk": f.Bnks, "wem": f.Wems} {
+		if len(files) != len(want[typ]) {
+			t.Errorf("%s: holds %d %s entries, want %d", name, len(files), typ, len(want[typ]))
+		}
+		for i, e := range files {
+			if i > 0 && files[i-1].Index.ID >= e.Index.ID {
+				t.Errorf("%s: %s ID %d is indexed after ID %d", name, typ, e.Index.ID,
+					files[i-1].Index.ID)
+			}
+			if got, err := e.Bytes(); err != nil || !bytes.Equal(got, want[typ][e.Index.ID]) {
+				t.Errorf("%s: %s ID %d holds %q (%v)", name, typ, e.Index.ID, got, err)
+			}
+		}
+	}
+}
+
+func TestBuilderWithTemplate(t *testing.T) {
+	for _, p := range buildTestPackages(t) {
+		f := p.open(t)
+		b := NewBuilder(WithTemplate(f))
+		want := map[string]map[uint32][]byte{"bnk": {}, "wem": {}}
+		for _, id := range []uint32{7, 3} {
+			data := []byte(fmt.Sprintf("RIFF wem %d built from the %s package", id, p.name))
+			want["wem"][id] = data
+			if err := b.AddWem(id, bytes.NewReader(data), int64(len(data))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := b.AddWem(7, bytes.NewReader(nil), 0); err == nil {
+			t.Errorf("%s: wem ID 7 was added twice", p.name)
+		}
+		// The same ID may be used in each table.
+		data := []byte("BKHD bank 7")
+		want["bnk"][7] = data
+		if err := b.AddBnk(7, bytes.NewReader(data), int64(len(data))); err != nil {
+			t.Errorf("%s: %v", p.name, err)
+		}
+
+		buf := new(bytes.Buffer)
+		if _, err := b.WriteTo(buf); err != nil {
+			t.Fatalf("%s: %v", p.name, err)
+		}
+		built := (&testPackage{name: p.name, data: buf.Bytes()}).open(t)
+		if built.Format != f.Format || built.ByteOrder != f.ByteOrder {
+			t.Errorf("%s: built a %s package in %v, not as the template", p.name, built.Format,
+				built.ByteOrder)
+		}
+		assertHolds(t, p.name, built, want)
+		built.Close()
+
+		// The package can be written again, once more entries are added.
+		data = []byte("RIFF wem 5")
+		want["wem"][5] = data
+		if err := b.AddWem(5, bytes.NewReader(data), int64(len(data))); err != nil {
+			t.Fatal(err)
+		}
+		buf.Reset()
+		if _, err := b.WriteTo(buf); err != nil {
+			t.Fatalf("%s: writing again: %v", p.name, err)
+		}
+		built = (&testPackage{name: p.name, data: buf.Bytes()}).open(t)
+		assertHolds(t, p.name+", written again", built, want)
+		built.Close()
+		f.Close()
+	}
+}
+
+func TestBuilderSetHeader(t *testing.T) {
+	data := []byte("RIFF")
+	for _, p := range testLayouts {
+		b := NewBuilder()
+		if err := b.AddWem(1, bytes.NewReader(data), int64(len(data))); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.SetHeader(p); err != nil {
+			t.Fatalf("%s: %v", p.Name, err)
+		}
+		buf := new(bytes.Buffer)
+		if _, err := b.WriteTo(buf); err != nil {
+			t.Fatalf("%s: %v", p.Name, err)
+		}
+		f := (&testPackage{name: p.Name, data: buf.Bytes()}).open(t)
+		if want, _ := p.format(); f.Format != want || f.ByteOrder != p.byteOrder() {
+			t.Errorf("%s: built a %s package in %v", p.Name, f.Format, f.ByteOrder)
+		}
+		assertHolds(t, p.Name, f, map[string]map[uint32][]byte{"wem": {1: data}})
+		f.Close()
+	}
+	if err := NewBuilder().SetHeader(&Profile{Name: "odd", EntrySize: 21}); err == nil {
+		t.Error("a profile with 21 byte index entries was accepted")
+	}
+}
+
+func TestLazyFileOnlyStaysOpenWhileRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	l := &lazyFile{path: path, size: 10}
+	b := make([]byte, 4)
+	for _, off := range []int64{0, 6, 2} {
+		if n, err := l.ReadAt(b, off); n != 4 || err != nil {
+			t.Fatalf("reading at %d: read %d bytes (%v)", off, n, err)
+		}
+		// The file is closed once its last byte is read, and opened again
+		// when it is read once more.
+		if open := l.f != nil; open != (off != 6) {
+			t.Errorf("after reading at %d, the file is open: %v", off, open)
+		}
+	}
+	l.close()
+}
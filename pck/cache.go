@@ -0,0 +1,69 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"container/list"
+	"sync"
+)
+
+// dataCache is a least recently used cache of entry data, bounded by the total
+// number of bytes it holds. It is safe for concurrent use.
+type dataCache struct {
+	mu       sync.Mutex
+	maxBytes int64
+	size     int64
+	// The cached entries, from most to least recently used.
+	lru     *list.List
+	entries map[cacheKey]*list.Element
+}
+
+// cacheKey identifies a range of bytes within a package.
+type cacheKey struct {
+	offset, length int64
+}
+
+type cacheEntry struct {
+	key  cacheKey
+	data []byte
+}
+
+func newDataCache(maxBytes int64) *dataCache {
+	return &dataCache{
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[cacheKey]*list.Element),
+	}
+}
+
+// get returns the data cached for k, marking it as recently used.
+func (c *dataCache) get(k cacheKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[k]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*cacheEntry).data, true
+}
+
+// add caches data for k, evicting the least recently used data until the cache
+// is within its size limit. Data larger than the limit is not cached.
+func (c *dataCache) add(k cacheKey, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if int64(len(data)) > c.maxBytes {
+		return
+	}
+	if e, ok := c.entries[k]; ok {
+		c.lru.MoveToFront(e)
+		return
+	}
+	c.entries[k] = c.lru.PushFront(&cacheEntry{k, data})
+	c.size += int64(len(data))
+	for c.size > c.maxBytes {
+		oldest := c.lru.Remove(c.lru.Back()).(*cacheEntry)
+		delete(c.entries, oldest.key)
+		c.size -= int64(len(oldest.data))
+	}
+}
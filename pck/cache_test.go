@@ -0,0 +1,70 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// countingReaderAt is an io.ReaderAt counting the bytes read from it.
+type countingReaderAt struct {
+	r    io.ReaderAt
+	read int64
+}
+
+func (c *countingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := c.r.ReadAt(p, off)
+	atomic.AddInt64(&c.read, int64(n))
+	return n, err
+}
+
+func TestDataCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newDataCache(10)
+	a, b, d := cacheKey{0, 4}, cacheKey{4, 4}, cacheKey{8, 4}
+	c.add(a, []byte("aaaa"))
+	c.add(b, []byte("bbbb"))
+	c.get(a)
+	// Adding d evicts b, the least recently used.
+	c.add(d, []byte("dddd"))
+	for k, want := range map[cacheKey]bool{a: true, b: false, d: true} {
+		if _, ok := c.get(k); ok != want {
+			t.Errorf("data of %+v cached: %v, want %v", k, ok, want)
+		}
+	}
+	if c.size != 8 {
+		t.Errorf("the cache holds %d bytes, want 8", c.size)
+	}
+	c.add(cacheKey{12, 11}, make([]byte, 11))
+	if _, ok := c.get(cacheKey{12, 11}); ok || c.size != 8 {
+		t.Errorf("data larger than the cache was cached")
+	}
+}
+
+func TestWithCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.pck")
+	if err := os.WriteFile(path, buildPackage(testBnks, testWems), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Open(path, WithCache(1<<20))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := f.Wems[1]
+	for i := 0; i < 3; i++ {
+		if _, ok := w.cached(); (i == 0) == ok {
+			t.Errorf("before read %d, the data of wem ID %d cached: %v", i+1, w.Index.ID, ok)
+		}
+		got, err := w.Bytes()
+		if err != nil || !bytes.Equal(got, testWems[1]) {
+			t.Fatalf("wem ID %d holds %q (%v)", w.Index.ID, got, err)
+		}
+	}
+	if _, ok := f.Wems[0].cached(); ok {
+		t.Error("the data of a wem that was never read is cached")
+	}
+}
@@ -0,0 +1,243 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A Cipher decrypts and encrypts the bytes of a package some games store
+// encrypted, see WithCipher and WithEntryCipher. It is a stream cipher whose
+// key stream only depends on the position of each byte, so that any part of
+// a package can be read on its own, and for which encrypting is the same as
+// decrypting.
+type Cipher interface {
+	// XORKeyStreamAt XORs each byte of src with the byte of the key stream at
+	// its position, src[0] being at position off, and stores the result in
+	// dst. dst and src must overlap entirely or not at all.
+	XORKeyStreamAt(dst, src []byte, off int64)
+}
+
+// xorCipher is a Cipher XORing each byte with a repeated key.
+type xorCipher []byte
+
+// NewXORCipher returns a Cipher XORing each byte with the byte of key at its
+// position, the key being repeated as needed, as done by games that merely
+// obfuscate their packages.
+func NewXORCipher(key []byte) (Cipher, error) {
+	if len(key) == 0 {
+		return nil, errors.New("the XOR key is empty")
+	}
+	return xorCipher(append([]byte(nil), key...)), nil
+}
+
+func (c xorCipher) XORKeyStreamAt(dst, src []byte, off int64) {
+	k := int(off % int64(len(c)))
+	for i, b := range src {
+		dst[i] = b ^ c[k]
+		if k++; k == len(c) {
+			k = 0
+		}
+	}
+}
+
+// aesCTRCipher is a Cipher encrypting with AES in counter mode.
+type aesCTRCipher struct {
+	block cipher.Block
+	iv    [aes.BlockSize]byte
+}
+
+// NewAESCTRCipher returns a Cipher encrypting with AES in counter mode, as by
+// crypto/cipher.NewCTR, with the given 16, 24 or 32 byte key and the 16 byte
+// initial counter iv. The counter is incremented once per 16 bytes of the
+// package, as a big endian number.
+func NewAESCTRCipher(key, iv []byte) (Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, fmt.Errorf("the IV is %d bytes long, not %d", len(iv), aes.BlockSize)
+	}
+	c := &aesCTRCipher{block: block}
+	copy(c.iv[:], iv)
+	return c, nil
+}
+
+func (c *aesCTRCipher) XORKeyStreamAt(dst, src []byte, off int64) {
+	// Start the stream at the counter of the block holding off, skipping the
+	// bytes of that block before off.
+	counter := c.iv
+	n := uint64(off / aes.BlockSize)
+	for i := aes.BlockSize - 1; i >= 0 && n > 0; i-- {
+		sum := uint64(counter[i]) + n&0xFF
+		counter[i] = byte(sum)
+		n = n>>8 + sum>>8
+	}
+	stream := cipher.NewCTR(c.block, counter[:])
+	var skip [aes.BlockSize]byte
+	stream.XORKeyStream(skip[:off%aes.BlockSize], skip[:off%aes.BlockSize])
+	stream.XORKeyStream(dst, src)
+}
+
+// cipherFile is a readerAtSeeker over a package encrypted as a whole, see
+// WithCipher, decrypting what is read from it.
+type cipherFile struct {
+	readerAtSeeker
+	cipher Cipher
+}
+
+func (f *cipherFile) Read(p []byte) (int, error) {
+	off, err := f.readerAtSeeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	n, err := f.readerAtSeeker.Read(p)
+	f.cipher.XORKeyStreamAt(p[:n], p[:n], off)
+	return n, err
+}
+
+func (f *cipherFile) ReadAt(p []byte, off int64) (int, error) {
+	n, err := f.readerAtSeeker.ReadAt(p, off)
+	f.cipher.XORKeyStreamAt(p[:n], p[:n], off)
+	return n, err
+}
+
+// entryCipherFile is a readerAtSeeker over a package whose entries are
+// encrypted, see WithEntryCipher, decrypting the data of the entries read from
+// it. The rest of the package is read as it is.
+type entryCipherFile struct {
+	readerAtSeeker
+	cipher Cipher
+	// The data of the entries, by increasing offset and without overlaps.
+	spans []span
+}
+
+func (f *entryCipherFile) Read(p []byte) (int, error) {
+	off, err := f.readerAtSeeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	n, err := f.readerAtSeeker.Read(p)
+	f.decrypt(p[:n], off)
+	return n, err
+}
+
+func (f *entryCipherFile) ReadAt(p []byte, off int64) (int, error) {
+	n, err := f.readerAtSeeker.ReadAt(p, off)
+	f.decrypt(p[:n], off)
+	return n, err
+}
+
+// decrypt decrypts the bytes of b, read at offset off, that belong to the data
+// of an entry, with the key stream starting over at the start of each entry.
+func (f *entryCipherFile) decrypt(b []byte, off int64) {
+	end := off + int64(len(b))
+	i := sort.Search(len(f.spans), func(i int) bool {
+		return int64(f.spans[i].offset)+int64(f.spans[i].length) > off
+	})
+	for ; i < len(f.spans) && int64(f.spans[i].offset) < end; i++ {
+		s := f.spans[i]
+		lo, hi := int64(s.offset), int64(s.offset)+int64(s.length)
+		if lo < off {
+			lo = off
+		}
+		if hi > end {
+			hi = end
+		}
+		part := b[lo-off : hi-off]
+		f.cipher.XORKeyStreamAt(part, part, lo-int64(s.offset))
+	}
+}
+
+// decryptEntries makes pck read the data of its entries through c, for
+// packages whose entries are encrypted, see WithEntryCipher. The data of an
+// entry overlapping the data of an entry stored before it is decrypted as
+// part of the first one.
+func (pck *File) decryptEntries(c Cipher) {
+	var spans []span
+	for _, indexes := range pck.indexTables() {
+		for _, idx := range indexes {
+			if idx.Length > 0 {
+				spans = append(spans, span{idx.Offset, idx.Length})
+			}
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].offset < spans[j].offset })
+	kept := spans[:0]
+	var end uint64
+	for _, s := range spans {
+		if s.offset >= end {
+			kept = append(kept, s)
+			end = s.offset + uint64(s.length)
+		}
+	}
+
+	pck.entryCipher = c
+	pck.reader = &entryCipherFile{pck.reader, c, kept}
+	pck.embedFiles(pck.reader)
+}
+
+// cipherWriter is an io.Writer encrypting what is written to it with a Cipher
+// before writing it to w, the first byte written being at position offset.
+type cipherWriter struct {
+	w      io.Writer
+	cipher Cipher
+	offset int64
+	buf    []byte
+}
+
+func (c *cipherWriter) Write(p []byte) (int, error) {
+	if cap(c.buf) < len(p) {
+		c.buf = make([]byte, len(p))
+	}
+	buf := c.buf[:len(p)]
+	c.cipher.XORKeyStreamAt(buf, p, c.offset)
+	n, err := c.w.Write(buf)
+	c.offset += int64(n)
+	return n, err
+}
+
+// cipherWriterAt is an io.WriterAt encrypting what is written to it with a
+// Cipher before writing it to w.
+type cipherWriterAt struct {
+	w      io.WriterAt
+	cipher Cipher
+}
+
+func (c *cipherWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	buf := make([]byte, len(p))
+	c.cipher.XORKeyStreamAt(buf, p, off)
+	return c.w.WriteAt(buf, off)
+}
+
+// encryptPackage returns w, encrypting what is written to it, from the start
+// of a package, as pck is encrypted if it was opened with WithCipher.
+func (pck *File) encryptPackage(w io.Writer) io.Writer {
+	if pck.cipher == nil {
+		return w
+	}
+	return &cipherWriter{w: w, cipher: pck.cipher}
+}
+
+// encryptPackageAt is encryptPackage for writing a package in place.
+func (pck *File) encryptPackageAt(w io.WriterAt) io.WriterAt {
+	if pck.cipher == nil {
+		return w
+	}
+	return &cipherWriterAt{w, pck.cipher}
+}
+
+// encryptEntry returns w, encrypting what is written to it, from the start of
+// the data of an entry, as the entries of pck are encrypted if it was opened
+// with WithEntryCipher.
+func (pck *File) encryptEntry(w io.Writer) io.Writer {
+	if pck.entryCipher == nil {
+		return w
+	}
+	return &cipherWriter{w: w, cipher: pck.entryCipher}
+}
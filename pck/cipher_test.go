@@ -0,0 +1,188 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+)
+
+// decodeHex decodes s, a hexadecimal test vector.
+func decodeHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// TestAESCTRCipherVector checks the AES-128 CTR vector of NIST SP 800-38A,
+// F.5.1, whose counter wraps its last byte after the first block.
+func TestAESCTRCipherVector(t *testing.T) {
+	key := decodeHex(t, "2b7e151628aed2a6abf7158809cf4f3c")
+	iv := decodeHex(t, "f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+	plain := decodeHex(t, "6bc1bee22e409f96e93d7e117393172a"+"ae2d8a571e03ac9c9eb76fac45af8e51"+
+		"30c81c46a35ce411e5fbc1191a0a52ef"+"f69f2445df4f9b17ad2b417be66c3710")
+	want := decodeHex(t, "874d6191b620e3261bef6864990db6ce"+"9806f66b7970fdff8617187bb9fffdff"+
+		"5ae4df3edbd5d35e5b4f09020db03eab"+"1e031dda2fbe03d1792170a0f3009cee")
+
+	c, err := NewAESCTRCipher(key, iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, len(plain))
+	c.XORKeyStreamAt(got, plain, 0)
+	if !bytes.Equal(got, want) {
+		t.Errorf("encrypted\n%x\nwant\n%x", got, want)
+	}
+	// Each part of the stream can be encrypted on its own.
+	for _, off := range []int{1, 15, 16, 17, 33, 63} {
+		part := make([]byte, len(plain)-off)
+		c.XORKeyStreamAt(part, plain[off:], int64(off))
+		if !bytes.Equal(part, want[off:]) {
+			t.Errorf("encrypted from offset %d\n%x\nwant\n%x", off, part, want[off:])
+		}
+	}
+}
+
+func TestAESCTRCipherCounterCarries(t *testing.T) {
+	iv := bytes.Repeat([]byte{0xFF}, 16)
+	iv[0] = 0
+	c, err := NewAESCTRCipher(make([]byte, 16), iv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The counter of block 0x1_0001 carries into every byte but the first.
+	const off = 0x10001*16 + 5
+	stream := make([]byte, off+40)
+	c.XORKeyStreamAt(stream, stream, 0)
+	part := make([]byte, 40)
+	c.XORKeyStreamAt(part, part, off)
+	if !bytes.Equal(part, stream[off:]) {
+		t.Errorf("the key stream at offset %d differs from that of the whole stream", off)
+	}
+}
+
+func TestXORCipher(t *testing.T) {
+	c, err := NewXORCipher([]byte{0x01, 0x02, 0x03})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := []byte{0x10, 0x20, 0x30, 0x40, 0x50}
+	got := make([]byte, len(src))
+	c.XORKeyStreamAt(got, src, 0)
+	if want := []byte{0x11, 0x22, 0x33, 0x41, 0x52}; !bytes.Equal(got, want) {
+		t.Errorf("encrypted %x, want %x", got, want)
+	}
+	c.XORKeyStreamAt(got, src, 4)
+	if want := []byte{0x12, 0x23, 0x31, 0x42, 0x53}; !bytes.Equal(got, want) {
+		t.Errorf("encrypted from offset 4 %x, want %x", got, want)
+	}
+	// Encrypting twice decrypts.
+	c.XORKeyStreamAt(got, got, 4)
+	if !bytes.Equal(got, src) {
+		t.Errorf("decrypted %x, want %x", got, src)
+	}
+}
+
+func TestNewCipherErrors(t *testing.T) {
+	if _, err := NewXORCipher(nil); err == nil {
+		t.Error("an empty XOR key was accepted")
+	}
+	if _, err := NewAESCTRCipher(make([]byte, 15), make([]byte, 16)); err == nil {
+		t.Error("a 15 byte AES key was accepted")
+	}
+	if _, err := NewAESCTRCipher(make([]byte, 16), make([]byte, 8)); err == nil {
+		t.Error("an 8 byte IV was accepted")
+	}
+}
+
+// testCiphers returns an AES-CTR and an XOR cipher.
+func testCiphers(t *testing.T) []Cipher {
+	key := []byte("0123456789abcdef")
+	aesCTR, err := NewAESCTRCipher(key, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xor, err := NewXORCipher(key[:5])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []Cipher{aesCTR, xor}
+}
+
+func TestWithCipher(t *testing.T) {
+	entries := testEntries()
+	data := buildPackage(testBnks, testWems)
+	for _, c := range testCiphers(t) {
+		encrypted := make([]byte, len(data))
+		c.XORKeyStreamAt(encrypted, data, 0)
+		f, err := OpenReader(bytes.NewReader(encrypted), int64(len(encrypted)), WithCipher(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, w := range f.Wems {
+			if got, err := io.ReadAll(w.Open()); err != nil || !bytes.Equal(got, entries["wem"][w.Index.ID]) {
+				t.Errorf("wem ID %d is not decrypted (%v)", w.Index.ID, err)
+			}
+		}
+		if written := sessionBytes(t, f.NewSession()); !bytes.Equal(written, encrypted) {
+			t.Error("repacking without changes did not encrypt the package as it was")
+		}
+		f.Close()
+	}
+}
+
+func TestWithEntryCipher(t *testing.T) {
+	entries := testEntries()
+	for _, c := range testCiphers(t) {
+		f, data := openTestPackage(t)
+		encrypted := append([]byte(nil), data...)
+		for _, files := range [][]*EmbeddedFile{f.Bnks, f.Wems} {
+			for _, e := range files {
+				b := encrypted[e.Index.Offset : e.Index.Offset+uint64(e.Index.Length)]
+				c.XORKeyStreamAt(b, b, 0)
+			}
+		}
+		f.Close()
+
+		f, err := OpenReader(bytes.NewReader(encrypted), int64(len(encrypted)), WithEntryCipher(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for typ, files := range map[string][]*EmbeddedFile{"bnk": f.Bnks, "wem": f.Wems} {
+			for _, e := range files {
+				if got, err := e.Bytes(); err != nil || !bytes.Equal(got, entries[typ][e.Index.ID]) {
+					t.Errorf("%s ID %d is not decrypted (%v)", typ, e.Index.ID, err)
+				}
+			}
+		}
+		// A replacement is encrypted as the entry it replaces.
+		replacement := []byte("RIFF replaced")
+		s := f.NewSession()
+		if err := s.Replace("wem", 2, bytes.NewReader(replacement), int64(len(replacement))); err != nil {
+			t.Fatal(err)
+		}
+		written := sessionBytes(t, s)
+		rewritten, err := OpenReader(bytes.NewReader(written), int64(len(written)), WithEntryCipher(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, w := range rewritten.Wems {
+			if w.Index.ID != 2 {
+				continue
+			}
+			stored := written[w.Index.Offset : w.Index.Offset+uint64(w.Index.Length)]
+			if bytes.Equal(stored, replacement) {
+				t.Error("the replacement was stored unencrypted")
+			}
+			if got, err := w.Bytes(); err != nil || !bytes.Equal(got, replacement) {
+				t.Errorf("the replacement reads %q (%v)", got, err)
+			}
+		}
+		rewritten.Close()
+		f.Close()
+	}
+}
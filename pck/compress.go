@@ -0,0 +1,418 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"sync"
+)
+
+import (
+	"wwiseutil/util"
+)
+
+// The compressions of entries detected by Decompress.
+const (
+	CompressionZlib = "zlib"
+	// The LZ4 frame format, see https://github.com/lz4/lz4/blob/dev/doc/lz4_Frame_format.md.
+	CompressionLZ4 = "lz4"
+)
+
+// The magic number LZ4 frames start with, little endian.
+const lz4FrameMagic = 0x184D2204
+
+// sniffCompression returns the compression the data in r, of the given size,
+// looks compressed with by its first bytes, or "" if it looks uncompressed.
+// zlib streams have no magic number, only a header checksum, so data that
+// sniffs as zlib may still fail to decompress.
+func sniffCompression(r io.ReaderAt, size int64) string {
+	var b [4]byte
+	if size < int64(len(b)) {
+		return ""
+	}
+	if _, err := r.ReadAt(b[:], 0); err != nil {
+		return ""
+	}
+	if binary.LittleEndian.Uint32(b[:]) == lz4FrameMagic {
+		return CompressionLZ4
+	}
+	// A deflate stream with a window of at most 32 KB and no dictionary.
+	if b[0]&0x0F == 8 && b[0]>>4 <= 7 && b[1]&0x20 == 0 && (uint16(b[0])<<8|uint16(b[1]))%31 == 0 {
+		return CompressionZlib
+	}
+	return ""
+}
+
+// decompress returns the decompressed data of r, compressed with compression.
+func decompress(compression string, r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	switch compression {
+	case CompressionZlib:
+		return util.Decompress(data, 0)
+	case CompressionLZ4:
+		return lz4DecompressFrame(data)
+	}
+	return nil, fmt.Errorf("unknown compression %q", compression)
+}
+
+// compress returns data compressed with compression.
+func compress(compression string, data []byte) ([]byte, error) {
+	switch compression {
+	case CompressionZlib:
+		return util.Compress(data, util.DefaultCompression)
+	case CompressionLZ4:
+		return lz4CompressFrame(data), nil
+	}
+	return nil, fmt.Errorf("unknown compression %q", compression)
+}
+
+// decompressedReaderAt is an io.ReaderAt over the decompressed data of an
+// entry, which is only decompressed once it is first read, and then kept in
+// memory.
+type decompressedReaderAt struct {
+	compression string
+	stored      *io.SectionReader
+	once        sync.Once
+	data        []byte
+	err         error
+}
+
+func (d *decompressedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	d.once.Do(func() {
+		d.data, d.err = decompress(d.compression, io.NewSectionReader(d.stored, 0, d.stored.Size()))
+	})
+	if d.err != nil {
+		return 0, d.err
+	}
+	return bytes.NewReader(d.data).ReadAt(p, off)
+}
+
+// detectCompression finds the entries of pck whose data is compressed, see
+// Decompress, and makes their data read decompressed. An entry is only taken
+// for compressed if its data decompresses without error, which it is once
+// here to find its decompressed size.
+func (pck *File) detectCompression() {
+	for _, files := range [][]*EmbeddedFile{pck.Bnks, pck.Wems} {
+		for _, f := range files {
+			c := sniffCompression(f.section, f.section.Size())
+			if c == "" {
+				continue
+			}
+			n, err := decompressedSize(c, io.NewSectionReader(f.section, 0, f.section.Size()))
+			if err != nil {
+				continue
+			}
+			f.compression, f.stored = c, f.section
+			f.section = io.NewSectionReader(&decompressedReaderAt{compression: c, stored: f.stored}, 0, n)
+			f.reader = util.NewResettingReader(f.section, 0, n)
+			f.Reader = f.reader
+		}
+	}
+}
+
+// decompressedSize returns the size of the decompressed data of r, compressed
+// with compression.
+func decompressedSize(compression string, r io.Reader) (int64, error) {
+	data, err := decompress(compression, r)
+	return int64(len(data)), err
+}
+
+// compressionOf returns the compression of the data of the entry of type typ
+// with the given ID, as by EmbeddedFile.Compression.
+func (pck *File) compressionOf(typ string, id uint32) string {
+	files := pck.Bnks
+	if typ == "wem" {
+		files = pck.Wems
+	}
+	for _, f := range files {
+		if f.Index.ID == id {
+			return f.compression
+		}
+	}
+	return ""
+}
+
+// compressReplacement returns the first length bytes of data compressed with
+// compression, and their length.
+func compressReplacement(compression string, data io.ReaderAt, length int64) (io.ReaderAt, int64, error) {
+	b, err := io.ReadAll(io.NewSectionReader(data, 0, length))
+	if err != nil {
+		return nil, 0, err
+	}
+	if b, err = compress(compression, b); err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(b), int64(len(b)), nil
+}
+
+// Compression returns the compression the data of this file is stored with,
+// CompressionZlib or CompressionLZ4, or "" if it is stored uncompressed or the
+// package was not opened with Decompress. The data of a compressed file is
+// read decompressed, while Index.Length is the length of its stored data.
+func (f *EmbeddedFile) Compression() string {
+	return f.compression
+}
+
+var errLZ4Corrupt = errors.New("corrupt LZ4 data")
+
+// lz4DecompressFrame decompresses the LZ4 frames of src, one after the other.
+// Block and content checksums are skipped rather than checked.
+func lz4DecompressFrame(src []byte) ([]byte, error) {
+	var out []byte
+	for len(src) > 0 {
+		if len(src) < 7 || binary.LittleEndian.Uint32(src) != lz4FrameMagic {
+			return nil, errLZ4Corrupt
+		}
+		flg := src[4]
+		if flg>>6 != 1 {
+			return nil, fmt.Errorf("unsupported LZ4 frame version %d", flg>>6)
+		}
+		if flg&0x01 != 0 {
+			return nil, errors.New("LZ4 frames with a dictionary are not supported")
+		}
+		i := 6
+		if flg&0x08 != 0 {
+			i += 8
+		}
+		i++ // The header checksum.
+		frameStart := len(out)
+		for {
+			if i+4 > len(src) {
+				return nil, errLZ4Corrupt
+			}
+			size := binary.LittleEndian.Uint32(src[i:])
+			i += 4
+			if size == 0 {
+				break
+			}
+			n := int(size &^ (1 << 31))
+			if i+n > len(src) {
+				return nil, errLZ4Corrupt
+			}
+			var err error
+			if size&(1<<31) != 0 {
+				out = append(out, src[i:i+n]...)
+			} else if out, err = lz4DecompressBlock(out, frameStart, src[i:i+n]); err != nil {
+				return nil, err
+			}
+			i += n
+			if flg&0x10 != 0 {
+				i += 4
+			}
+		}
+		if flg&0x04 != 0 {
+			i += 4
+		}
+		if i > len(src) {
+			return nil, errLZ4Corrupt
+		}
+		src = src[i:]
+	}
+	return out, nil
+}
+
+// lz4DecompressBlock appends the decompressed data of the LZ4 block src to
+// out, whose data from start on may be referenced by the block, as the blocks
+// of a frame may reference the previous ones.
+func lz4DecompressBlock(out []byte, start int, src []byte) ([]byte, error) {
+	i := 0
+	for i < len(src) {
+		token := src[i]
+		i++
+		n, ok := lz4Length(src, &i, int(token>>4))
+		if !ok || i+n > len(src) {
+			return nil, errLZ4Corrupt
+		}
+		out = append(out, src[i:i+n]...)
+		i += n
+		if i == len(src) {
+			break
+		}
+		if i+2 > len(src) {
+			return nil, errLZ4Corrupt
+		}
+		offset := int(binary.LittleEndian.Uint16(src[i:]))
+		i += 2
+		m, ok := lz4Length(src, &i, int(token&0x0F))
+		if !ok || offset == 0 || offset > len(out)-start {
+			return nil, errLZ4Corrupt
+		}
+		// The match may overlap the bytes it produces, so it is copied a byte
+		// at a time.
+		for from := len(out) - offset; m+4 > 0; m-- {
+			out = append(out, out[from])
+			from++
+		}
+	}
+	return out, nil
+}
+
+// lz4Length returns the length held in the 4 bits n of a token, extended by
+// the bytes of src at *i if n is 15.
+func lz4Length(src []byte, i *int, n int) (int, bool) {
+	if n != 15 {
+		return n, true
+	}
+	for {
+		if *i >= len(src) {
+			return 0, false
+		}
+		b := src[*i]
+		*i++
+		n += int(b)
+		if b != 255 {
+			return n, true
+		}
+	}
+}
+
+// The size of the blocks of the LZ4 frames written by lz4CompressFrame, the
+// smallest the format allows.
+const lz4BlockSize = 64 << 10
+
+// lz4CompressFrame compresses src as an LZ4 frame of independent blocks,
+// recording its content size.
+func lz4CompressFrame(src []byte) []byte {
+	out := make([]byte, 4, 19+len(src)+len(src)/255+16)
+	binary.LittleEndian.PutUint32(out, lz4FrameMagic)
+	// Version 1, independent blocks and a content size; blocks of 64 KB.
+	descriptor := []byte{0x68, 0x40, 0, 0, 0, 0, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint64(descriptor[2:], uint64(len(src)))
+	out = append(out, descriptor...)
+	out = append(out, byte(xxh32(descriptor, 0)>>8))
+
+	var size [4]byte
+	for len(src) > 0 {
+		n := len(src)
+		if n > lz4BlockSize {
+			n = lz4BlockSize
+		}
+		block := lz4CompressBlock(src[:n])
+		if len(block) >= n {
+			binary.LittleEndian.PutUint32(size[:], uint32(n)|1<<31)
+			block = src[:n]
+		} else {
+			binary.LittleEndian.PutUint32(size[:], uint32(len(block)))
+		}
+		out = append(append(out, size[:]...), block...)
+		src = src[n:]
+	}
+	return append(out, 0, 0, 0, 0)
+}
+
+// lz4CompressBlock compresses src as a single LZ4 block, finding matches
+// greedily through a table of the last position of every hashed 4 bytes.
+func lz4CompressBlock(src []byte) []byte {
+	var out []byte
+	var table [1 << 14]int32
+	anchor := 0
+	// The last match must start 12 bytes before the end of the block, and the
+	// last 5 bytes must be literals.
+	limit := len(src) - 12
+	for i := 0; i < limit; {
+		seq := binary.LittleEndian.Uint32(src[i:])
+		h := (seq * 2654435761) >> 18
+		ref := int(table[h]) - 1
+		table[h] = int32(i + 1)
+		if ref < 0 || i-ref > 0xFFFF || binary.LittleEndian.Uint32(src[ref:]) != seq {
+			i++
+			continue
+		}
+		m := 4
+		for i+m < len(src)-5 && src[ref+m] == src[i+m] {
+			m++
+		}
+		out = lz4AppendSequence(out, src[anchor:i], i-ref, m)
+		i += m
+		anchor = i
+	}
+	return lz4AppendSequence(out, src[anchor:], 0, 0)
+}
+
+// lz4AppendSequence appends to out the sequence of the given literals
+// followed by a match of matchLen bytes at offset, or by nothing if matchLen
+// is 0, as the last sequence of a block is.
+func lz4AppendSequence(out, literals []byte, offset, matchLen int) []byte {
+	token := len(literals)
+	if token > 15 {
+		token = 15
+	}
+	m := 0
+	if matchLen > 0 {
+		if m = matchLen - 4; m > 15 {
+			m = 15
+		}
+	}
+	out = append(out, byte(token<<4|m))
+	out = lz4AppendLength(out, len(literals))
+	out = append(out, literals...)
+	if matchLen == 0 {
+		return out
+	}
+	out = append(out, byte(offset), byte(offset>>8))
+	return lz4AppendLength(out, matchLen-4)
+}
+
+// lz4AppendLength appends the bytes extending the length n, whose first 15
+// are held in a token.
+func lz4AppendLength(out []byte, n int) []byte {
+	if n < 15 {
+		return out
+	}
+	for n -= 15; n >= 255; n -= 255 {
+		out = append(out, 255)
+	}
+	return append(out, byte(n))
+}
+
+// The primes of xxHash32.
+const (
+	xxPrime1 uint32 = 2654435761
+	xxPrime2 uint32 = 2246822519
+	xxPrime3 uint32 = 3266489917
+	xxPrime4 uint32 = 668265263
+	xxPrime5 uint32 = 374761393
+)
+
+// xxh32 returns the xxHash32 of b with the given seed, which LZ4 frames use as
+// their checksum.
+func xxh32(b []byte, seed uint32) uint32 {
+	round := func(acc, input uint32) uint32 {
+		return bits.RotateLeft32(acc+input*xxPrime2, 13) * xxPrime1
+	}
+	var h uint32
+	i := 0
+	if len(b) >= 16 {
+		v1, v2, v3, v4 := seed+xxPrime1+xxPrime2, seed+xxPrime2, seed, seed-xxPrime1
+		for ; i+16 <= len(b); i += 16 {
+			v1 = round(v1, binary.LittleEndian.Uint32(b[i:]))
+			v2 = round(v2, binary.LittleEndian.Uint32(b[i+4:]))
+			v3 = round(v3, binary.LittleEndian.Uint32(b[i+8:]))
+			v4 = round(v4, binary.LittleEndian.Uint32(b[i+12:]))
+		}
+		h = bits.RotateLeft32(v1, 1) + bits.RotateLeft32(v2, 7) + bits.RotateLeft32(v3, 12) +
+			bits.RotateLeft32(v4, 18)
+	} else {
+		h = seed + xxPrime5
+	}
+	h += uint32(len(b))
+	for ; i+4 <= len(b); i += 4 {
+		h = bits.RotateLeft32(h+binary.LittleEndian.Uint32(b[i:])*xxPrime3, 17) * xxPrime4
+	}
+	for ; i < len(b); i++ {
+		h = bits.RotateLeft32(h+uint32(b[i])*xxPrime5, 11) * xxPrime1
+	}
+	h ^= h >> 15
+	h *= xxPrime2
+	h ^= h >> 13
+	h *= xxPrime3
+	h ^= h >> 16
+	return h
+}
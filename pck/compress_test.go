@@ -0,0 +1,162 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+import (
+	"wwiseutil/util"
+)
+
+// An LZ4 frame holding lz4Plain, as written by the lz4 command.
+var lz4Frame = []byte{
+	0x04, 0x22, 0x4d, 0x18, 0x64, 0x40, 0xa7, 0x14, 0x00, 0x00, 0x00, 0xaf,
+	0x52, 0x49, 0x46, 0x46, 0x20, 0x68, 0x65, 0x6c, 0x6c, 0x6f, 0x06, 0x00,
+	0x0c, 0x50, 0x68, 0x65, 0x6c, 0x6c, 0x6f, 0x00, 0x00, 0x00, 0x00, 0x70,
+	0xc6, 0x08, 0x4b,
+}
+
+const lz4Plain = "RIFF hello hello hello hello hello hello hello"
+
+func TestXXH32(t *testing.T) {
+	// The content checksums of the frames the lz4 command writes.
+	for _, tt := range []struct {
+		in   string
+		want uint32
+	}{
+		{"", 0x02CC5D05},
+		{"a", 0x550D7456},
+		{"abc", 0x32D153FF},
+		{"Nobody inspects the spammish repetition", 0xE2293B2F},
+		{"0123456789abcdef0123456789abcdef01234", 0xADACA4AA},
+		{lz4Plain, 0x4B08C670},
+	} {
+		if got := xxh32([]byte(tt.in), 0); got != tt.want {
+			t.Errorf("xxh32(%q) = 0x%08X, want 0x%08X", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestLZ4DecompressFrame(t *testing.T) {
+	got, err := lz4DecompressFrame(lz4Frame)
+	if err != nil || string(got) != lz4Plain {
+		t.Errorf("decompressed %q (%v), want %q", got, err, lz4Plain)
+	}
+	// Frames are decompressed one after the other.
+	got, err = lz4DecompressFrame(append(append([]byte(nil), lz4Frame...), lz4Frame...))
+	if err != nil || string(got) != lz4Plain+lz4Plain {
+		t.Errorf("decompressed two frames to %q (%v)", got, err)
+	}
+	for _, n := range []int{3, 7, 20, len(lz4Frame) - 8} {
+		if _, err := lz4DecompressFrame(lz4Frame[:n]); err == nil {
+			t.Errorf("a frame cut after %d bytes was decompressed", n)
+		}
+	}
+}
+
+func TestLZ4CompressRoundTrip(t *testing.T) {
+	random := make([]byte, 100<<10)
+	rand.New(rand.NewSource(1)).Read(random)
+	for name, data := range map[string][]byte{
+		"empty":          nil,
+		"short":          []byte("RIFF"),
+		"repeated":       []byte(lz4Plain),
+		"long run":       bytes.Repeat([]byte{0x55}, 3*lz4BlockSize+17),
+		"incompressible": random,
+	} {
+		frame := lz4CompressFrame(data)
+		got, err := lz4DecompressFrame(frame)
+		if err != nil || !bytes.Equal(got, data) {
+			t.Errorf("%s: %d bytes decompress to %d bytes (%v)", name, len(data), len(got), err)
+		}
+		// The header checksum covers the frame descriptor.
+		if want := byte(xxh32(frame[4:14], 0) >> 8); frame[14] != want {
+			t.Errorf("%s: header checksum 0x%02X, want 0x%02X", name, frame[14], want)
+		}
+	}
+	if n := len(lz4CompressFrame(bytes.Repeat([]byte("hello "), 1000))); n > 200 {
+		t.Errorf("6000 repeated bytes compress to %d bytes", n)
+	}
+}
+
+func TestSniffCompression(t *testing.T) {
+	z, err := util.Compress([]byte(lz4Plain), util.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		data []byte
+		want string
+	}{
+		{lz4Frame, CompressionLZ4},
+		{z, CompressionZlib},
+		{[]byte("RIFF\x00\x00\x00\x00WAVE"), ""},
+		{[]byte("BKHD"), ""},
+		{[]byte{0x78}, ""},
+	} {
+		if got := sniffCompression(bytes.NewReader(tt.data), int64(len(tt.data))); got != tt.want {
+			t.Errorf("sniffCompression(%x) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestDecompressOption(t *testing.T) {
+	for _, p := range buildTestPackages(t) {
+		f := p.open(t)
+		zlibPlain := []byte("RIFF " + p.name)
+		z, err := util.Compress(zlibPlain, util.DefaultCompression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := f.NewSession()
+		s.Replace("wem", 300, bytes.NewReader(lz4Frame), int64(len(lz4Frame)))
+		s.Replace("wem", 100, bytes.NewReader(z), int64(len(z)))
+		data := sessionBytes(t, s)
+		f.Close()
+
+		f, err = OpenReader(bytes.NewReader(data), int64(len(data)), Decompress())
+		if err != nil {
+			t.Fatalf("%s: %v", p.name, err)
+		}
+		for _, want := range []struct {
+			id          uint32
+			compression string
+			data        []byte
+		}{{300, CompressionLZ4, []byte(lz4Plain)}, {100, CompressionZlib, zlibPlain},
+			{200, "", p.entries["wem"][200]}} {
+			w := mustFind(t, f, "wem", want.id)
+			if w.Compression() != want.compression {
+				t.Errorf("%s: wem ID %d is compressed with %q, not %q", p.name, want.id,
+					w.Compression(), want.compression)
+			}
+			if got, err := io.ReadAll(w.Open()); err != nil || !bytes.Equal(got, want.data) {
+				t.Errorf("%s: wem ID %d reads %q (%v), not %q", p.name, want.id, got, err, want.data)
+			}
+		}
+
+		// Replacements of compressed entries are compressed, and the other
+		// entries keep their stored data.
+		replaced := []byte("RIFF replaced " + p.name)
+		s = f.NewSession()
+		if err := s.Replace("wem", 100, bytes.NewReader(replaced), int64(len(replaced))); err != nil {
+			t.Fatalf("%s: %v", p.name, err)
+		}
+		if err := s.ReplaceRange("wem", 300, 0, bytes.NewReader(replaced), 4); err == nil {
+			t.Errorf("%s: part of a compressed wem was replaced", p.name)
+		}
+		rewritten, _ := writeSession(t, s)
+		f.Close()
+		if got, _ := mustFind(t, rewritten, "wem", 300).Bytes(); !bytes.Equal(got, lz4Frame) {
+			t.Errorf("%s: the stored data of wem ID 300 was not kept", p.name)
+		}
+		stored, _ := mustFind(t, rewritten, "wem", 100).Bytes()
+		if got, err := util.Decompress(stored, 0); err != nil || !bytes.Equal(got, replaced) {
+			t.Errorf("%s: wem ID 100 decompresses to %q (%v), not %q", p.name, got, err, replaced)
+		}
+		rewritten.Close()
+	}
+}
@@ -0,0 +1,53 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"context"
+	"io"
+)
+
+// contextWriter returns w, failing with ctx's error once ctx is done. w is
+// returned as is if ctx can never be done, to keep the fast paths of io.Copy.
+func contextWriter(ctx context.Context, w io.Writer) io.Writer {
+	if ctx.Done() == nil {
+		return w
+	}
+	return &ctxWriter{ctx, w}
+}
+
+// contextReader returns r, failing with ctx's error once ctx is done, as
+// contextWriter does for writers.
+func contextReader(ctx context.Context, r io.Reader) io.Reader {
+	if ctx.Done() == nil {
+		return r
+	}
+	return &ctxReader{ctx, r}
+}
+
+// A ctxWriter is an io.Writer that fails with the error of its context once
+// the context is done, so that long copies stop between chunks.
+type ctxWriter struct {
+	ctx context.Context
+	w   io.Writer
+}
+
+func (c *ctxWriter) Write(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.w.Write(p)
+}
+
+// A ctxReader is an io.Reader that fails with the error of its context once
+// the context is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,88 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// A cancelingWriter cancels its context once n bytes are written to it.
+type cancelingWriter struct {
+	n      int
+	cancel context.CancelFunc
+}
+
+func (c *cancelingWriter) Write(p []byte) (int, error) {
+	if c.n -= len(p); c.n <= 0 {
+		c.cancel()
+	}
+	return len(p), nil
+}
+
+func TestUnpackToContextStops(t *testing.T) {
+	entries := testEntries()
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dir := t.TempDir()
+	if err := f.UnpackToContext(ctx, dir); !errors.Is(err, context.Canceled) {
+		t.Errorf("unpacking with a canceled context: got %v", err)
+	}
+	if files := readTree(t, dir); len(files) != 0 {
+		t.Errorf("unpacked %d files with a canceled context", len(files))
+	}
+
+	// Canceling after two entries leaves those entries, whole.
+	ctx, cancel = context.WithCancel(context.Background())
+	progress := WithProgress(func(p Progress) {
+		if p.EntriesDone == 2 {
+			cancel()
+		}
+	})
+	dir = t.TempDir()
+	if err := f.UnpackToContext(ctx, dir, progress); !errors.Is(err, context.Canceled) {
+		t.Errorf("unpacking canceled after two entries: got %v", err)
+	}
+	files := readTree(t, dir)
+	if len(files) != 2 {
+		t.Errorf("unpacked %d files before being canceled, want 2", len(files))
+	}
+	for name, data := range files {
+		typ, base := path.Split(name)
+		id, _ := strconv.ParseUint(strings.TrimSuffix(base, path.Ext(base)), 10, 32)
+		if want := entries[strings.TrimSuffix(typ, "/")][uint32(id)]; data != string(want) {
+			t.Errorf("%s holds %q, want %q", name, data, want)
+		}
+	}
+}
+
+func TestWriteToContextStops(t *testing.T) {
+	f, data := openTestPackage(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &cancelingWriter{n: len(data) / 2, cancel: cancel}
+	if n, err := f.WriteToContext(ctx, w); !errors.Is(err, context.Canceled) || n >= int64(len(data)) {
+		t.Errorf("writing canceled halfway wrote %d of %d bytes (%v)", n, len(data), err)
+	}
+	if _, err := f.NewSession().WriteToContext(ctx, io.Discard); !errors.Is(err, context.Canceled) {
+		t.Errorf("writing a session with a canceled context: got %v", err)
+	}
+	f.Close()
+
+	// A canceled repack leaves no output behind, not even a temporary file.
+	dir := t.TempDir()
+	out := filepath.Join(dir, "repacked.pck")
+	if _, err := RepackContext(ctx, writeTestPackage(t, data), out, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("repacking with a canceled context: got %v", err)
+	}
+	if entries, err := os.ReadDir(dir); err != nil || len(entries) != 0 {
+		t.Errorf("a canceled repack left %d files (%v)", len(entries), err)
+	}
+}
@@ -0,0 +1,101 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+import (
+	"wwiseutil/wem"
+)
+
+// testWem returns a mono wem encoded with the codec with the given format tag,
+// with 16 bit samples at 48000 Hz.
+func testWem(codec uint16, samples ...int16) []byte {
+	data := new(bytes.Buffer)
+	binary.Write(data, binary.LittleEndian, samples)
+	format := new(bytes.Buffer)
+	binary.Write(format, binary.LittleEndian, wem.Format{Codec: codec, Channels: 1,
+		SampleRate: 48000, BytesPerSec: 96000, BlockAlign: 2, BitsPerSample: 16})
+	b := new(bytes.Buffer)
+	b.WriteString("RIFF")
+	binary.Write(b, binary.LittleEndian, uint32(4+8+format.Len()+8+data.Len()))
+	b.WriteString("WAVEfmt ")
+	binary.Write(b, binary.LittleEndian, uint32(format.Len()))
+	b.Write(format.Bytes())
+	b.WriteString("data")
+	binary.Write(b, binary.LittleEndian, uint32(data.Len()))
+	b.Write(data.Bytes())
+	return b.Bytes()
+}
+
+func TestUnpackDecodeWems(t *testing.T) {
+	wems := map[uint32][]byte{
+		1: testWem(wem.CodecPCM, 0, 1000, -1000, 32767),
+		2: testWem(wem.CodecVorbis, 1, 2, 3, 4),
+		3: testWem(wem.CodecADPCM, 5, 6),
+		4: []byte("RIFF, but not a wem"),
+	}
+	b := NewBuilder()
+	for id := uint32(1); id <= 4; id++ {
+		if err := b.AddWem(id, bytes.NewReader(wems[id]), int64(len(wems[id]))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf := new(bytes.Buffer)
+	if _, err := b.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	f, err := OpenReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{1, 4} {
+		dir := t.TempDir()
+		undecoded := make(map[uint32]error)
+		err := f.UnpackTo(dir, DecodeWems(44100), WithWorkers(workers),
+			WithUndecodedReport(func(f *EmbeddedFile, err error) {
+				undecoded[f.Index.ID] = err
+			}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := readTree(t, dir)
+		if len(files) != 4 || files["wem/2.wem"] != string(wems[2]) ||
+			files["wem/3.wem"] != string(wems[3]) || files["wem/4.wem"] != string(wems[4]) {
+			t.Errorf("%d workers unpacked the files %q", workers, files)
+		}
+		wav, err := wem.NewFile(bytes.NewReader([]byte(files["wem/1.wav"])), int64(len(files["wem/1.wav"])))
+		if err != nil || wav.Codec != wem.CodecPCM || wav.SampleRate != 44100 {
+			t.Errorf("%d workers decoded the PCM wem to %+v (%v)", workers, wav, err)
+		}
+
+		if len(undecoded) != 3 || undecoded[1] != nil {
+			t.Errorf("%d workers reported the undecoded wems %v", workers, undecoded)
+		}
+		for _, id := range []uint32{2, 3} {
+			if !errors.Is(undecoded[id], wem.ErrUnsupportedCodec) {
+				t.Errorf("%d workers reported wem %d as undecoded because: %v", workers, id,
+					undecoded[id])
+			}
+		}
+		if err := undecoded[4]; err == nil || errors.Is(err, wem.ErrUnsupportedCodec) {
+			t.Errorf("%d workers reported the invalid wem as undecoded because: %v", workers, err)
+		}
+	}
+
+	// Without a report, the undecoded wems are still unpacked.
+	dir := t.TempDir()
+	if err := f.UnpackTo(dir, DecodeWems(48000)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "wem", "2.wem")); err != nil {
+		t.Error(err)
+	}
+}
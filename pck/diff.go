@@ -0,0 +1,152 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The ways an entry can differ between two packages.
+const (
+	EntryAdded   = "added"
+	EntryRemoved = "removed"
+	EntryChanged = "changed"
+)
+
+// An EntryChange describes how an entry differs between two packages.
+type EntryChange struct {
+	// One of EntryAdded, EntryRemoved or EntryChanged.
+	Kind string
+	Type string // "bnk", "wem" or "externals"
+	ID   uint32
+	// The index of the entry in the old and the new package, or nil if it is
+	// not in that package.
+	Old, New *FileIndex
+	// The SHA-256 hashes of the data of a changed entry in the old and the new
+	// package, in hexadecimal. They are only computed for entries whose length
+	// did not change, and are empty otherwise.
+	OldHash, NewHash string
+}
+
+func (c *EntryChange) String() string {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "%-8s %-9s %d (0x%08X)", c.Kind, c.Type, c.ID, c.ID)
+	switch {
+	case c.Old == nil:
+		fmt.Fprintf(b, " %d bytes", c.New.Length)
+	case c.New == nil:
+		fmt.Fprintf(b, " %d bytes", c.Old.Length)
+	case c.Old.Length != c.New.Length:
+		fmt.Fprintf(b, " %d -> %d bytes", c.Old.Length, c.New.Length)
+	default:
+		fmt.Fprintf(b, " %d bytes, sha256 %.16s -> %.16s", c.New.Length, c.OldHash, c.NewHash)
+	}
+	return b.String()
+}
+
+// Diff compares the entries of two packages, such as a package before and
+// after a game patch, and returns the entries that were added to, removed from
+// or changed in new relative to old. Entries are matched by their type and ID
+// and, in standard packages, their language. An entry is changed if its length
+// or the hash of its data differs; the other fields of its index are not
+// compared, since they change whenever entries are moved. Added and changed
+// entries are returned in the order they appear in new, followed by removed
+// entries in the order they appear in old.
+func Diff(old, new *File) ([]*EntryChange, error) {
+	oldEntries := old.entriesByKey()
+	oldHashes, newHashes := make(map[span]string), make(map[span]string)
+
+	var changes []*EntryChange
+	seen := make(map[*FileIndex]bool)
+	for _, e := range new.diffEntries() {
+		candidates := oldEntries[e.key]
+		if len(candidates) == 0 {
+			changes = append(changes, &EntryChange{Kind: EntryAdded, Type: e.key.typ, ID: e.idx.ID, New: e.idx})
+			continue
+		}
+		o := candidates[0]
+		oldEntries[e.key] = candidates[1:]
+		seen[o] = true
+
+		c := &EntryChange{Kind: EntryChanged, Type: e.key.typ, ID: e.idx.ID, Old: o, New: e.idx}
+		if o.Length == e.idx.Length {
+			var err error
+			if c.OldHash, err = old.hashEntry(o, oldHashes); err != nil {
+				return nil, fmt.Errorf("hashing %s ID %d: %w", e.key.typ, o.ID, err)
+			}
+			if c.NewHash, err = new.hashEntry(e.idx, newHashes); err != nil {
+				return nil, fmt.Errorf("hashing %s ID %d: %w", e.key.typ, e.idx.ID, err)
+			}
+			if c.OldHash == c.NewHash {
+				continue
+			}
+		}
+		changes = append(changes, c)
+	}
+	for _, e := range old.diffEntries() {
+		if !seen[e.idx] {
+			changes = append(changes, &EntryChange{Kind: EntryRemoved, Type: e.key.typ, ID: e.idx.ID, Old: e.idx})
+		}
+	}
+	return changes, nil
+}
+
+// A diffKey identifies the entries of two packages that Diff compares.
+type diffKey struct {
+	typ      string
+	id       uint32
+	language uint32
+}
+
+// A diffEntry is an entry of a package compared by Diff.
+type diffEntry struct {
+	key diffKey
+	idx *FileIndex
+}
+
+// A span is the location of the data of an entry in a package.
+type span struct {
+	offset uint64
+	length uint32
+}
+
+// diffEntries returns the entries of pck in the order of its index tables.
+func (pck *File) diffEntries() []*diffEntry {
+	var entries []*diffEntry
+	for i, indexes := range pck.indexTables() {
+		for _, idx := range indexes {
+			key := diffKey{typ: tableNames[i], id: idx.ID}
+			if pck.Format == FormatStandard {
+				key.language = idx.LanguageID()
+			}
+			entries = append(entries, &diffEntry{key, idx})
+		}
+	}
+	return entries
+}
+
+// entriesByKey returns the indexes of the entries of pck by their diffKey, in
+// the order of the index tables.
+func (pck *File) entriesByKey() map[diffKey][]*FileIndex {
+	m := make(map[diffKey][]*FileIndex)
+	for _, e := range pck.diffEntries() {
+		m[e.key] = append(m[e.key], e.idx)
+	}
+	return m
+}
+
+// hashEntry returns the hash of the data of idx, an entry of pck. Hashes are
+// kept in hashes, so that data shared by several entries is only read once.
+func (pck *File) hashEntry(idx *FileIndex, hashes map[span]string) (string, error) {
+	key := span{idx.Offset, idx.Length}
+	if hash, ok := hashes[key]; ok {
+		return hash, nil
+	}
+	hash, err := hashData(io.NewSectionReader(pck.reader, int64(idx.Offset), int64(idx.Length)))
+	if err != nil {
+		return "", err
+	}
+	hashes[key] = hash
+	return hash, nil
+}
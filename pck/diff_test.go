@@ -0,0 +1,75 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	entries := testEntries()
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	s := f.NewSession()
+	same := bytes.ToLower(entries["wem"][2])
+	longer := append(append([]byte(nil), entries["wem"][3]...), "more"...)
+	for id, data := range map[uint32][]byte{2: same, 3: longer} {
+		if err := s.Replace("wem", id, bytes.NewReader(data), int64(len(data))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Remove("bnk", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("wem", 5, bytes.NewReader([]byte("RIFF")), 4); err != nil {
+		t.Fatal(err)
+	}
+	m, _ := writeSession(t, s)
+	defer m.Close()
+
+	changes, err := Diff(f, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Added and changed entries come in the order of the new package.
+	kinds := map[uint32]string{5: EntryAdded, 2: EntryChanged, 3: EntryChanged}
+	var want []string
+	for _, w := range m.Wems {
+		if kind, ok := kinds[w.Index.ID]; ok {
+			want = append(want, fmt.Sprintf("%s wem %d", kind, w.Index.ID))
+		}
+	}
+	want = append(want, "removed bnk 1")
+	var got []string
+	for _, c := range changes {
+		got = append(got, fmt.Sprintf("%s %s %d", c.Kind, c.Type, c.ID))
+		switch {
+		case c.Kind == EntryChanged && c.ID == 2:
+			if c.OldHash == "" || c.OldHash == c.NewHash {
+				t.Errorf("wem ID 2 changed from hash %q to %q", c.OldHash, c.NewHash)
+			}
+			if !strings.Contains(c.String(), "sha256") {
+				t.Errorf("the change of wem ID 2 reads %q", c)
+			}
+		case c.Kind == EntryChanged && c.ID == 3:
+			if c.OldHash != "" || c.NewHash != "" {
+				t.Errorf("the data of wem ID 3 was hashed although its length changed")
+			}
+			want := fmt.Sprintf("%d -> %d bytes", len(entries["wem"][3]), len(longer))
+			if !strings.Contains(c.String(), want) {
+				t.Errorf("the change of wem ID 3 reads %q", c)
+			}
+		case c.Kind == EntryRemoved && c.New != nil, c.Kind == EntryAdded && c.Old != nil:
+			t.Errorf("%s has both indexes", c)
+		}
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("diff is %q, want %q", got, want)
+	}
+
+	if changes, err := Diff(f, f); err != nil || len(changes) != 0 {
+		t.Errorf("a package differs from itself by %v (%v)", changes, err)
+	}
+}
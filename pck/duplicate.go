@@ -0,0 +1,249 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A DuplicatePolicy decides which of several entries or replacement files of
+// the same type and ID is used, such as by Merge for entries found in several
+// packages.
+type DuplicatePolicy int
+
+const (
+	// DuplicateError makes the operation fail with an error wrapping
+	// ErrDuplicateID on the first duplicate. It is the default policy of Merge.
+	DuplicateError DuplicatePolicy = iota
+	// DuplicateKeepFirst keeps the first of the duplicates.
+	DuplicateKeepFirst
+	// DuplicateKeepLast keeps the last of the duplicates, as when later
+	// packages or replacement files patch earlier ones.
+	DuplicateKeepLast
+)
+
+func (p DuplicatePolicy) String() string {
+	switch p {
+	case DuplicateError:
+		return "error"
+	case DuplicateKeepFirst:
+		return "first"
+	case DuplicateKeepLast:
+		return "last"
+	}
+	return fmt.Sprintf("DuplicatePolicy(%d)", int(p))
+}
+
+// OnDuplicate sets the policy applied to duplicated entries: by Merge, to the
+// entries found in several of the packages it merges, and by Repack and Patch,
+// to the entries of the package sharing a type and ID (and, in standard
+// packages, a language) and to the replacement files replacing the same entry.
+// Without it, Repack and Patch keep every duplicated entry of the package and
+// use the last replacement file of an entry, as they always have.
+func OnDuplicate(p DuplicatePolicy) Option {
+	return func(o *options) {
+		o.onDuplicate = p
+		o.resolveDuplicates = true
+	}
+}
+
+// WithDuplicateReport sets a function called with every set of duplicates
+// found by Merge, Repack or Patch, reporting which of them was used.
+func WithDuplicateReport(fn func(d *DuplicateChoice)) Option {
+	return func(o *options) {
+		o.duplicateReport = fn
+	}
+}
+
+// A DuplicateChoice describes a set of duplicated entries or replacement
+// files, and which of them was used.
+type DuplicateChoice struct {
+	Type string // "bnk" or "wem"
+	ID   uint32
+	// Where each duplicate comes from: the paths of replacement files or of
+	// merged packages, or "index N" for the 1-based indexes of the entries of
+	// a package.
+	Sources []string
+	// The index in Sources of the duplicate used, or -1 if all of them are
+	// kept.
+	Kept int
+}
+
+func (d *DuplicateChoice) String() string {
+	what := fmt.Sprintf("%s ID %d is duplicated in %s", d.Type, d.ID, strings.Join(d.Sources, ", "))
+	if d.Kept < 0 {
+		return what + "; keeping all of them"
+	}
+	return what + "; using " + d.Sources[d.Kept]
+}
+
+// keptDuplicate returns the index in d.Sources of the duplicate kept by
+// policy, or an error wrapping ErrDuplicateID describing d for DuplicateError.
+func keptDuplicate(policy DuplicatePolicy, d *DuplicateChoice) (int, error) {
+	switch policy {
+	case DuplicateKeepFirst:
+		return 0, nil
+	case DuplicateKeepLast:
+		return len(d.Sources) - 1, nil
+	}
+	return -1, fmt.Errorf("%w: %s ID %d is duplicated in %s", ErrDuplicateID, d.Type, d.ID,
+		strings.Join(d.Sources, ", "))
+}
+
+// ResolveDuplicates finds the entries of the original File sharing a type and
+// an ID and, in standard packages, a language, and resolves each set of them
+// according to policy: DuplicateKeepFirst and DuplicateKeepLast keep only the
+// first or the last of them in the written package, while DuplicateError
+// returns an error wrapping ErrDuplicateID. It returns how each set was
+// resolved, up to the error.
+func (s *Session) ResolveDuplicates(policy DuplicatePolicy) ([]*DuplicateChoice, error) {
+	var choices []*DuplicateChoice
+	for _, set := range s.src.duplicateSets() {
+		kept, err := keptDuplicate(policy, set.choice)
+		if err != nil {
+			return choices, err
+		}
+		set.choice.Kept = kept
+		for i, idx := range set.indexes {
+			if i != kept {
+				s.dropped[idx] = true
+			}
+		}
+		choices = append(choices, set.choice)
+	}
+	return choices, nil
+}
+
+// A duplicateSet is a set of entries of a package sharing a type and an ID
+// and, in standard packages, a language.
+type duplicateSet struct {
+	indexes []*FileIndex
+	// The choice describing the set, keeping all of its entries.
+	choice *DuplicateChoice
+}
+
+// duplicateSets returns the sets of duplicated bnk and wem entries of pck, in
+// the order of the index tables.
+func (pck *File) duplicateSets() []*duplicateSet {
+	var sets []*duplicateSet
+	for _, typ := range []string{"bnk", "wem"} {
+		indexes, _ := pck.indexesOf(typ)
+		for _, positions := range pck.duplicates(indexes) {
+			set := &duplicateSet{choice: &DuplicateChoice{Type: typ, ID: indexes[positions[0]].ID, Kept: -1}}
+			for _, i := range positions {
+				set.indexes = append(set.indexes, indexes[i])
+				set.choice.Sources = append(set.choice.Sources, fmt.Sprintf("index %d", i+1))
+			}
+			sets = append(sets, set)
+		}
+	}
+	return sets
+}
+
+// duplicates returns the positions in indexes of the entries of pck sharing an
+// ID and, in standard packages, a language, as one set per ID in the order
+// the sets start.
+func (pck *File) duplicates(indexes []*FileIndex) [][]int {
+	type key struct{ id, language uint32 }
+	positions := make(map[key][]int)
+	var keys []key
+	for i, idx := range indexes {
+		k := key{id: idx.ID}
+		if pck.Format == FormatStandard {
+			k.language = idx.LanguageID()
+		}
+		if _, ok := positions[k]; !ok {
+			keys = append(keys, k)
+		}
+		positions[k] = append(positions[k], i)
+	}
+	var sets [][]int
+	for _, k := range keys {
+		if len(positions[k]) > 1 {
+			sets = append(sets, positions[k])
+		}
+	}
+	return sets
+}
+
+// dedupeReplacements resolves replacement files replacing, or adding, the same
+// entry in full according to o, reporting each set of them to the duplicate
+// report of o. Without OnDuplicate, the last of them is used. Partial
+// replacements are left as they are, since several ranges of an entry can be
+// replaced.
+func dedupeReplacements(replacements []*ReplacementFile, o *options) ([]*ReplacementFile, error) {
+	type key struct {
+		typ string
+		id  uint32
+		new bool
+	}
+	positions := make(map[key][]int)
+	for i, r := range replacements {
+		if r.Offset == nil {
+			k := key{r.Type, r.ID, r.New}
+			positions[k] = append(positions[k], i)
+		}
+	}
+	skipped := make(map[int]bool)
+	for i, r := range replacements {
+		set := positions[key{r.Type, r.ID, r.New}]
+		if r.Offset != nil || len(set) < 2 || set[0] != i {
+			continue
+		}
+		d := &DuplicateChoice{Type: r.Type, ID: r.ID}
+		for _, j := range set {
+			d.Sources = append(d.Sources, replacements[j].Path)
+		}
+		d.Kept = len(set) - 1
+		if o.resolveDuplicates {
+			kept, err := keptDuplicate(o.onDuplicate, d)
+			if err != nil {
+				return nil, err
+			}
+			d.Kept = kept
+		}
+		for k, j := range set {
+			if k != d.Kept {
+				skipped[j] = true
+			}
+		}
+		o.reportDuplicate(d)
+	}
+	if len(skipped) == 0 {
+		return replacements, nil
+	}
+	var kept []*ReplacementFile
+	for i, r := range replacements {
+		if !skipped[i] {
+			kept = append(kept, r)
+		}
+	}
+	return kept, nil
+}
+
+// reportDuplicate passes d to the duplicate report of o, if any.
+func (o *options) reportDuplicate(d *DuplicateChoice) {
+	if o.duplicateReport != nil {
+		o.duplicateReport(d)
+	}
+}
+
+// applyDuplicatePolicy resolves the duplicated entries of the original File
+// with the policy given by OnDuplicate, reporting each set of them to the
+// duplicate report of o. Without OnDuplicate, every duplicate is kept.
+func (s *Session) applyDuplicatePolicy(o *options) error {
+	if o.resolveDuplicates {
+		choices, err := s.ResolveDuplicates(o.onDuplicate)
+		for _, d := range choices {
+			o.reportDuplicate(d)
+		}
+		return err
+	}
+	if o.duplicateReport == nil {
+		return nil
+	}
+	for _, set := range s.src.duplicateSets() {
+		o.reportDuplicate(set.choice)
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// wemIndexPos returns the position of the index entry of the wem at index i in
+// the package returned by openTestPackage.
+func wemIndexPos(i int) int {
+	return 8 + testUnknownSize + 4 + 24*len(testBnks) + 4 + 24*i
+}
+
+// withWemID returns the data of the package returned by openTestPackage with
+// the ID of its wem at index i changed to id, leaving the table in its order.
+func withWemID(i int, id uint32) []byte {
+	return editWemIndex(i, func(idx *FileIndex) { idx.ID = id })
+}
+
+// editWemIndex returns the data of the package returned by openTestPackage with
+// the index entry of its wem at index i changed by edit.
+func editWemIndex(i int, edit func(idx *FileIndex)) []byte {
+	data := buildPackage(testBnks, testWems)
+	b := data[wemIndexPos(i):]
+	idx := hybridEntry.decode(b, binary.LittleEndian)
+	edit(idx)
+	hybridEntry.encode(b, binary.LittleEndian, idx)
+	return data
+}
+
+func TestResolveDuplicates(t *testing.T) {
+	entries := testEntries()
+	f := openMemory(t, withWemID(1, 2))
+	defer f.Close()
+	if n := len(f.Wems); n != len(entries["wem"]) {
+		t.Fatalf("%d wems were read, want %d", n, len(entries["wem"]))
+	}
+
+	if _, err := f.NewSession().ResolveDuplicates(DuplicateError); !errors.Is(err, ErrDuplicateID) {
+		t.Errorf("resolving duplicates as errors: got %v", err)
+	}
+	kept := map[DuplicatePolicy][]byte{DuplicateKeepFirst: testWems[0], DuplicateKeepLast: testWems[1]}
+	for _, policy := range []DuplicatePolicy{DuplicateKeepFirst, DuplicateKeepLast} {
+		s := f.NewSession()
+		choices, err := s.ResolveDuplicates(policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(choices) != 1 || choices[0].ID != 2 || len(choices[0].Sources) != 2 {
+			t.Errorf("resolved the duplicates %v", choices)
+		}
+		resolved, _ := writeSession(t, s)
+		want := map[string]map[uint32][]byte{"bnk": entries["bnk"], "wem": {2: kept[policy]}}
+		assertHolds(t, "keeping the "+policy.String()+" duplicate", resolved, want)
+		resolved.Close()
+	}
+}
+
+func TestRepackDuplicateReplacements(t *testing.T) {
+	path := writeTestPackage(t, buildPackage(testBnks, testWems))
+	dir := t.TempDir()
+	var r []*ReplacementFile
+	for _, name := range []string{"first.wem", "second.wem"} {
+		file := filepath.Join(dir, name)
+		if err := os.WriteFile(file, []byte("RIFF "+name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		r = append(r, &ReplacementFile{ID: 2, Path: file, Type: "wem"})
+	}
+
+	out := filepath.Join(t.TempDir(), "repacked.pck")
+	for _, tt := range []struct {
+		opts []Option
+		want string
+	}{
+		// Without OnDuplicate, the last replacement file is used.
+		{nil, "second.wem"},
+		{[]Option{OnDuplicate(DuplicateKeepLast)}, "second.wem"},
+		{[]Option{OnDuplicate(DuplicateKeepFirst)}, "first.wem"},
+	} {
+		var reports []*DuplicateChoice
+		report := func(d *DuplicateChoice) { reports = append(reports, d) }
+		opts := append(tt.opts, WithDuplicateReport(report))
+		if _, err := Repack(path, out, r, opts...); err != nil {
+			t.Fatal(err)
+		}
+		f, err := Open(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := mustFind(t, f, "wem", 2).Bytes(); string(got) != "RIFF "+tt.want {
+			t.Errorf("replaced wem ID 2 with %q, want the data of %s", got, tt.want)
+		}
+		f.Close()
+		if len(reports) != 1 || filepath.Base(reports[0].Sources[reports[0].Kept]) != tt.want {
+			t.Errorf("reported the duplicates %v, want %s to be used", reports, tt.want)
+		}
+	}
+	if _, err := Repack(path, out, r, OnDuplicate(DuplicateError)); !errors.Is(err, ErrDuplicateID) {
+		t.Errorf("repacking duplicated replacement files: got %v, want ErrDuplicateID", err)
+	}
+
+	// Duplicated entries of the package are kept unless OnDuplicate is given.
+	dup := filepath.Join(t.TempDir(), "dup.pck")
+	if err := os.WriteFile(dup, withWemID(1, 2), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		opts  []Option
+		count int
+	}{{nil, 2}, {[]Option{OnDuplicate(DuplicateKeepFirst)}, 1}} {
+		if _, err := Repack(dup, out, nil, tt.opts...); err != nil {
+			t.Fatal(err)
+		}
+		f, err := Open(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		for _, w := range f.Wems {
+			if w.Index.ID == 2 {
+				count++
+			}
+		}
+		if count != tt.count {
+			t.Errorf("repacked with %d options, wem ID 2 is held %d times, want %d", len(tt.opts),
+				count, tt.count)
+		}
+		f.Close()
+	}
+	if _, err := Repack(dup, out, nil, OnDuplicate(DuplicateError)); !errors.Is(err, ErrDuplicateID) {
+		t.Errorf("repacking a package with duplicated entries: got %v, want ErrDuplicateID", err)
+	}
+}
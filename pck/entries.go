@@ -0,0 +1,126 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// An EntryIterator steps through the entries of a package, table by table in
+// the order they are stored, as returned by File.Entries and ReadEntries. Like
+// a bufio.Scanner, it is advanced with Next until Next returns false, after
+// which Err reports the error that stopped it, if any:
+//
+//	it := f.Entries()
+//	for it.Next() {
+//		e := it.Entry()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type EntryIterator struct {
+	next  func() (*Entry, error)
+	entry *Entry
+	err   error
+}
+
+// Next advances the iterator to the next entry, which is then returned by
+// Entry. It returns false once there are no entries left, or reading an entry
+// failed.
+func (it *EntryIterator) Next() bool {
+	if it.err != nil || it.next == nil {
+		return false
+	}
+	it.entry, it.err = it.next()
+	if it.entry == nil {
+		it.next = nil
+		if it.err == io.EOF {
+			it.err = nil
+		}
+		return false
+	}
+	return true
+}
+
+// Entry returns the entry the iterator is at, after a call to Next that
+// returned true.
+func (it *EntryIterator) Entry() *Entry {
+	return it.entry
+}
+
+// Err returns the error that stopped the iterator, or nil if it went through
+// every entry.
+func (it *EntryIterator) Err() error {
+	return it.err
+}
+
+// Entries returns an iterator over the entries of the index tables of pck,
+// including externals, with the type of each entry: "bnk", "wem" or
+// "externals". The indexes yielded are those of pck, and must not be modified.
+func (pck *File) Entries() *EntryIterator {
+	tables := pck.indexTables()
+	table, i := 0, 0
+	return &EntryIterator{next: func() (*Entry, error) {
+		for table < len(tables) && i == len(tables[table]) {
+			table, i = table+1, 0
+		}
+		if table == len(tables) {
+			return nil, io.EOF
+		}
+		i++
+		return &Entry{tableNames[table], tables[table][i-1]}, nil
+	}}
+}
+
+// ReadEntries returns an iterator over the entries of the index tables of the
+// package stored in r, as File.Entries does, reading the index tables one
+// entry at a time as the iterator advances, rather than all at once as Open
+// does. The memory it uses does not depend on the number of entries, so
+// packages with hundreds of thousands of entries can be listed or filtered in
+// constant memory. The layout of the package is detected from its header as by
+// Open; of the options, only WithByteOrder and WithProfile apply.
+func ReadEntries(r io.ReaderAt, opts ...Option) (*EntryIterator, error) {
+	o := newOptions(opts)
+	var format Format
+	var order binary.ByteOrder
+	var unknownSize int
+	if p := o.profile; p != nil {
+		var err error
+		if format, err = p.format(); err != nil {
+			return nil, err
+		}
+		order, unknownSize = p.byteOrder(), p.UnknownSize
+	} else {
+		var err error
+		if format, order, unknownSize, err = detectLayout(r, o.byteOrder); err != nil {
+			return nil, err
+		}
+	}
+
+	br := bufio.NewReader(io.NewSectionReader(r, 8+int64(unknownSize), math.MaxInt64))
+	codecs := format.tables()
+	table := -1
+	var left, read uint32
+	var b []byte
+	return &EntryIterator{next: func() (*Entry, error) {
+		for left == 0 {
+			if table++; table == len(codecs) {
+				return nil, io.EOF
+			}
+			if err := binary.Read(br, order, &left); err != nil {
+				return nil, fmt.Errorf("reading %s table: reading count: %w", tableNames[table], err)
+			}
+			read, b = 0, make([]byte, codecs[table].size)
+		}
+		c := codecs[table]
+		if _, err := io.ReadFull(br, b); err != nil {
+			return nil, fmt.Errorf("reading %s table: reading index %d: %w", tableNames[table], read, err)
+		}
+		left, read = left-1, read+1
+		return &Entry{tableNames[table], c.decode(b, order)}, nil
+	}}, nil
+}
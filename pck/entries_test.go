@@ -0,0 +1,65 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"testing"
+)
+
+// collectEntries returns the entries it goes through.
+func collectEntries(it *EntryIterator) []*Entry {
+	var entries []*Entry
+	for it.Next() {
+		entries = append(entries, it.Entry())
+	}
+	return entries
+}
+
+func TestEntries(t *testing.T) {
+	for i, p := range buildTestPackages(t) {
+		f := p.open(t)
+		var want []Entry
+		for table, indexes := range f.indexTables() {
+			for _, idx := range indexes {
+				want = append(want, Entry{tableNames[table], idx})
+			}
+		}
+		read, err := ReadEntries(bytes.NewReader(p.data))
+		if err != nil {
+			t.Fatalf("%s: %v", p.name, err)
+		}
+		for name, it := range map[string]*EntryIterator{"Entries": f.Entries(), "ReadEntries": read} {
+			got := collectEntries(it)
+			if err := it.Err(); err != nil || len(got) != len(want) {
+				t.Errorf("%s: %s went through %d entries (%v), want %d", p.name, name, len(got), err,
+					len(want))
+				continue
+			}
+			for i, e := range got {
+				if e.Type != want[i].Type || *e.Index != *want[i].Index {
+					t.Errorf("%s: %s: entry %d is %s %+v, want %s %+v", p.name, name, i, e.Type, *e.Index,
+						want[i].Type, *want[i].Index)
+				}
+			}
+			if it.Next() {
+				t.Errorf("%s: %s: Next returned true after the last entry", p.name, name)
+			}
+		}
+		f.Close()
+
+		// The iterator stops with an error where the index tables are cut
+		// short, after the entries before the cut. The header no longer adds
+		// up, so the layout is given.
+		cut := p.data[:8+int(f.Header.HeaderAndIndexesLength)-1]
+		it, err := ReadEntries(bytes.NewReader(cut), WithProfile(testLayouts[i]))
+		if err != nil {
+			t.Fatalf("%s: %v", p.name, err)
+		}
+		// The last byte belongs to the last entry, or to the count of an empty
+		// table after it.
+		if got := collectEntries(it); it.Err() == nil || len(got) < len(want)-1 {
+			t.Errorf("%s: reading cut index tables went through %d entries (%v), want at least %d "+
+				"and an error", p.name, len(got), it.Err(), len(want)-1)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// ErrDoesNotFit is wrapped by the errors of Session.WriteInPlace,
+// Session.WriteAppend, Patch and PatchAppend when the pending changes cannot
+// be written without rewriting the package.
+var ErrDoesNotFit = errors.New("changes do not fit in place")
+
+// ErrNotFound is wrapped by the errors of ExtractBnk, ExtractWem and ExtractTo
+// when the package holds no entry with the requested ID.
+var ErrNotFound = errors.New("no such entry")
+
+// ErrVerifyFailed is wrapped by the errors of Session.Verify, and of Repack and
+// Patch with VerifyOutput, when the written package does not hold the changes
+// it should.
+var ErrVerifyFailed = errors.New("verifying the written package failed")
+
+// ErrDuplicateID is wrapped by the errors of Merge, Repack, Patch and
+// Session.ResolveDuplicates when duplicated entries are found and the
+// DuplicatePolicy is DuplicateError.
+var ErrDuplicateID = errors.New("duplicate ID")
+
+// ErrOverwrite is wrapped by the errors of Repack when the output file is the
+// package being repacked, unless AllowOverwrite is given.
+var ErrOverwrite = errors.New("the output file is the source package")
+
+// ErrUnsorted is wrapped by the errors of File.CheckIndexOrder, and of Open
+// with RequireSortedIndexes, when an index table is not sorted by ID.
+var ErrUnsorted = errors.New("index table is not sorted by ID")
+
+// ErrOffsetOverflow is wrapped by the errors of sessions, and so of Repack,
+// Merge and Build, when an entry of the package they would write starts past
+// the largest offset its index entry can hold, or is longer than an entry can
+// be, rather than the offset or length being truncated. Split writes packages
+// too large for their format as several volumes.
+var ErrOffsetOverflow = errors.New("the package is too large for its index entries")
+
+// ErrFormat is wrapped by the errors of opening a package whose header and
+// index tables are inconsistent, such as an index table counting more entries
+// than the header and index tables can hold.
+var ErrFormat = errors.New("not a valid package")
+
+// ErrTruncated is wrapped by the errors of UnpackTo, through a TruncatedError,
+// and of the writers of packages, when the data of an entry extends past the
+// end of its package, see EmbeddedFile.Truncated.
+var ErrTruncated = errors.New("entry data is truncated")
+
+// A TruncatedError is returned by UnpackTo once it has unpacked every entry it
+// could, if some of the entries were truncated: the part of their data within
+// the package was unpacked, but the rest of it is missing.
+type TruncatedError struct {
+	Entries []*EmbeddedFile
+}
+
+func (e *TruncatedError) Error() string {
+	names := make([]string, len(e.Entries))
+	for i, f := range e.Entries {
+		names[i] = f.Name
+	}
+	return fmt.Sprintf("%d truncated entries were only partly unpacked: %s",
+		len(e.Entries), strings.Join(names, ", "))
+}
+
+func (e *TruncatedError) Unwrap() error { return ErrTruncated }
+
+// The number of bytes from the start of a file recorded by an OpenError.
+const openErrorHeaderBytes = 16
+
+// An OpenError is returned by Open for a file whose layout cannot be detected
+// and that matches no registered Profile. It records the start of the file and
+// a hint at what the file is or how it could be opened.
+type OpenError struct {
+	Path string
+	// Up to the first 16 bytes of the file.
+	Header []byte
+	// The identifier the file starts with, as a quoted string.
+	Magic string
+	// A suggestion of what the file is or how it might be opened, or "" if
+	// there is none.
+	Hint string
+	// The error detecting the layout of the file.
+	Err error
+}
+
+func (e *OpenError) Error() string {
+	name := "stream"
+	if e.Path != "" {
+		name = filepath.Base(e.Path)
+	}
+	msg := fmt.Sprintf("unsupported pck file: %s - unknown header size: %v "+
+		"(header bytes: % X, magic: %s)", name, e.Err, e.Header, e.Magic)
+	if e.Hint != "" {
+		msg += "; " + e.Hint
+	}
+	return msg
+}
+
+func (e *OpenError) Unwrap() error { return e.Err }
+
+// newOpenError returns the OpenError of the file at path, stored in r and of
+// size bytes, whose layout could not be detected because of err. order is the
+// byte order given by WithByteOrder, if any.
+func newOpenError(path string, r io.ReaderAt, size int64, order binary.ByteOrder, err error) *OpenError {
+	header := make([]byte, openErrorHeaderBytes)
+	n, _ := r.ReadAt(header, 0)
+	header = header[:n]
+	magic := header
+	if len(magic) > 4 {
+		magic = magic[:4]
+	}
+	e := &OpenError{Path: path, Header: header, Magic: fmt.Sprintf("%q", magic), Err: err}
+
+	switch string(magic) {
+	case "AKPK":
+		e.Hint = akpkHint(r, size, order)
+	case "BKHD":
+		e.Hint = "looks like a SoundBank rather than a package; give it a .bnk extension to open it as one"
+	case "RIFF", "RIFX":
+		e.Hint = "looks like a wem or other RIFF audio file rather than a package"
+	default:
+		if n < 8 {
+			e.Hint = fmt.Sprintf("the file is only %d bytes long, too short to be a package", size)
+		} else {
+			e.Hint = `not a Wwise File Package, which starts with "AKPK"`
+		}
+	}
+	return e
+}
+
+// akpkHint returns the hint of an OpenError for a file of size bytes stored in
+// r that starts like a package, but whose layout could not be detected in
+// byte order order, or in the detected byte order if order is nil.
+func akpkHint(r io.ReaderAt, size int64, order binary.ByteOrder) string {
+	if order != nil {
+		if _, _, err := detectFormat(r, otherOrder(order)); err == nil {
+			return "looks like a package of the other byte order; open it without overriding the byte order"
+		}
+	} else if order, _ = DetectByteOrder(r); order == nil {
+		return "the file is too short to hold a package header"
+	}
+	var b [4]byte
+	if _, err := r.ReadAt(b[:], 4); err == nil {
+		if length := int64(order.Uint32(b[:])); 8+length > size {
+			return fmt.Sprintf("the header and indexes are %d bytes long, but the file is only "+
+				"%d bytes; it may be truncated", length, size)
+		}
+	}
+	return "looks like a package, but its section sizes match neither the hybrid nor the " +
+		"standard layout; its layout can be given by a Profile, see RegisterProfile"
+}
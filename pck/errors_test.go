@@ -0,0 +1,145 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestOpenErrorHints(t *testing.T) {
+	data := buildPackage(testBnks, testWems)
+	inconsistent := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(inconsistent[4:], binary.LittleEndian.Uint32(inconsistent[4:])+1)
+	for _, tt := range []struct {
+		name string
+		data []byte
+		opts []Option
+		hint string
+	}{
+		{"SoundBank", []byte("BKHD\x20\x00\x00\x00 a bank of some kind"), nil, "SoundBank"},
+		{"wem", []byte("RIFF\x20\x00\x00\x00WAVEfmt "), nil, "RIFF audio"},
+		{"short file", []byte("AK"), nil, "only 2 bytes"},
+		{"other file", []byte("PK\x03\x04 a zip archive"), nil, `starts with "AKPK"`},
+		{"truncated package", data[:40], nil, "truncated"},
+		{"inconsistent package", inconsistent, nil, "Profile"},
+		{"other byte order", data, []Option{WithByteOrder(binary.BigEndian)}, "other byte order"},
+	} {
+		_, err := Open(writeTestPackage(t, tt.data), tt.opts...)
+		var oe *OpenError
+		if !errors.As(err, &oe) {
+			t.Errorf("%s: got %v, want an *OpenError", tt.name, err)
+			continue
+		}
+		if !strings.Contains(oe.Hint, tt.hint) || !strings.Contains(err.Error(), oe.Hint) {
+			t.Errorf("%s: the hint %q does not mention %q", tt.name, oe.Hint, tt.hint)
+		}
+		n := len(tt.data)
+		if n > openErrorHeaderBytes {
+			n = openErrorHeaderBytes
+		}
+		if !bytes.Equal(oe.Header, tt.data[:n]) {
+			t.Errorf("%s: recorded the header % X", tt.name, oe.Header)
+		}
+	}
+}
+
+// zeroReaderAt is an io.ReaderAt of endless zeros.
+type zeroReaderAt struct{}
+
+func (zeroReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestOffsetOverflow(t *testing.T) {
+	f, _ := openTestPackage(t)
+	defer f.Close()
+
+	s := f.NewSession()
+	if err := s.Replace("wem", 2, zeroReaderAt{}, math.MaxUint32+1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.WriteTo(io.Discard); !errors.Is(err, ErrOffsetOverflow) {
+		t.Errorf("writing an entry longer than 4 GB: got %v, want ErrOffsetOverflow", err)
+	}
+
+	// Two entries of 3 GB push the offset of the entry after them past 4 GB,
+	// which only the 64-bit variant of the hybrid format can hold.
+	s = f.NewSession()
+	for typ, id := range map[string]uint32{"bnk": 1, "wem": 2} {
+		if err := s.Replace(typ, id, zeroReaderAt{}, 3<<30); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.checkLimits(s.Preview()); !errors.Is(err, ErrOffsetOverflow) {
+		t.Errorf("placing an entry past 4 GB: got %v, want ErrOffsetOverflow", err)
+	}
+}
+
+func TestTruncatedEntriesAreSalvaged(t *testing.T) {
+	for _, p := range buildTestPackages(t) {
+		f := p.open(t)
+		var byOffset []*EmbeddedFile
+		for _, files := range [][]*EmbeddedFile{f.Bnks, f.Wems} {
+			byOffset = append(byOffset, files...)
+		}
+		sort.Slice(byOffset, func(i, j int) bool { return byOffset[i].Index.Offset < byOffset[j].Index.Offset })
+		f.Close()
+		// Cut the package in the middle of the entry before last, so that the
+		// last one starts past its end.
+		cutShort, past := byOffset[len(byOffset)-2], byOffset[len(byOffset)-1]
+		cut := int(cutShort.Index.Offset) + int(cutShort.Index.Length)/2
+		data := p.data[:cut]
+
+		f = (&testPackage{name: p.name, data: data}).open(t)
+		var truncated []uint32
+		for _, e := range f.Truncated() {
+			truncated = append(truncated, e.Index.ID)
+		}
+		if want := []uint32{cutShort.Index.ID, past.Index.ID}; fmt.Sprint(truncated) != fmt.Sprint(want) {
+			t.Errorf("%s: truncated entries %v, want %v", p.name, truncated, want)
+		}
+		dir := t.TempDir()
+		err := f.UnpackTo(dir)
+		var te *TruncatedError
+		if !errors.As(err, &te) || !errors.Is(err, ErrTruncated) || len(te.Entries) != 2 {
+			t.Fatalf("%s: unpacking: got %v, want a *TruncatedError of 2 entries", p.name, err)
+		}
+		for typ, files := range map[string][]*EmbeddedFile{"bnk": f.Bnks, "wem": f.Wems} {
+			for _, e := range files {
+				path := filepath.Join(dir, typ, fmt.Sprintf("%d.%s", e.Index.ID, typ))
+				got, err := os.ReadFile(path)
+				switch e.Index.ID {
+				case cutShort.Index.ID:
+					if want := data[cutShort.Index.Offset:]; err != nil || !bytes.Equal(got, want) {
+						t.Errorf("%s: salvaged %d bytes of %s (%v), want %d", p.name, len(got), e.Name, err,
+							len(want))
+					}
+				case past.Index.ID:
+					if !os.IsNotExist(err) {
+						t.Errorf("%s: %s holds no data, but was unpacked (%v)", p.name, e.Name, err)
+					}
+				default:
+					if err != nil || !bytes.Equal(got, p.entries[typ][e.Index.ID]) {
+						t.Errorf("%s: intact entry %s was unpacked as %q (%v)", p.name, e.Name, got, err)
+					}
+				}
+			}
+		}
+		if _, err := f.NewSession().WriteTo(io.Discard); !errors.Is(err, ErrTruncated) {
+			t.Errorf("%s: repacking the truncated package: got %v, want ErrTruncated", p.name, err)
+		}
+		f.Close()
+	}
+}
@@ -0,0 +1,90 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"fmt"
+	"io"
+)
+
+// ExtractBnk returns a reader over the data of the SoundBank with the given
+// ID, without unpacking the rest of the package. In standard packages, where
+// a SoundBank can be stored once per language, the first one in the index
+// table is returned. It is an error wrapping ErrNotFound for the package to
+// hold no such SoundBank.
+func (pck *File) ExtractBnk(id uint32) (*io.SectionReader, error) {
+	return pck.extract("bnk", id)
+}
+
+// ExtractWem returns a reader over the data of the wem with the given ID, as
+// ExtractBnk does for SoundBanks.
+func (pck *File) ExtractWem(id uint32) (*io.SectionReader, error) {
+	return pck.extract("wem", id)
+}
+
+// ExtractTo writes the data of the entry of type typ ("bnk" or "wem") with the
+// given ID to w, as ExtractBnk and ExtractWem find it.
+func (pck *File) ExtractTo(w io.Writer, typ string, id uint32) (int64, error) {
+	r, err := pck.extract(typ, id)
+	if err != nil {
+		return 0, err
+	}
+	return io.Copy(w, r)
+}
+
+// Span returns the location of the data of f in its package: the offset of
+// its first byte from the start of the package file, and its length. Programs
+// that map the package into memory themselves can slice the data of f out of
+// the mapping with it, without going through the readers of this package. For
+// packages opened with OpenReader, the offset is relative to the start of the
+// io.ReaderAt.
+func (f *EmbeddedFile) Span() (offset, length int64) {
+	return int64(f.Index.Offset), int64(f.Index.Length)
+}
+
+// EntrySpan returns the location of the data of the entry of type typ ("bnk",
+// "wem" or "externals") with the given ID, as EmbeddedFile.Span does. In
+// standard packages, the first entry with the ID in the index table is used.
+// It is an error wrapping ErrNotFound for the package to hold no such entry.
+func (pck *File) EntrySpan(typ string, id uint32) (offset, length int64, err error) {
+	f, err := pck.find(typ, id)
+	if err != nil {
+		return 0, 0, err
+	}
+	offset, length = f.Span()
+	return offset, length, nil
+}
+
+// extract returns a reader over the data of the first entry of type typ with
+// the given ID.
+func (pck *File) extract(typ string, id uint32) (*io.SectionReader, error) {
+	if typ == "externals" {
+		return nil, fmt.Errorf("unknown entry type %q", typ)
+	}
+	f, err := pck.find(typ, id)
+	if err != nil {
+		return nil, err
+	}
+	return f.Open(), nil
+}
+
+// find returns the first entry of type typ ("bnk", "wem" or "externals") with
+// the given ID.
+func (pck *File) find(typ string, id uint32) (*EmbeddedFile, error) {
+	var files []*EmbeddedFile
+	switch typ {
+	case "bnk":
+		files = pck.Bnks
+	case "wem":
+		files = pck.Wems
+	case "externals":
+		files = pck.Externals
+	default:
+		return nil, fmt.Errorf("unknown entry type %q", typ)
+	}
+	for _, f := range files {
+		if f.Index.ID == id {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s ID %d", ErrNotFound, typ, id)
+}
@@ -0,0 +1,66 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	entries := testEntries()
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	for typ, extract := range map[string]func(uint32) (*io.SectionReader, error){
+		"bnk": f.ExtractBnk, "wem": f.ExtractWem} {
+		for id, want := range entries[typ] {
+			r, err := extract(id)
+			if err != nil {
+				t.Fatalf("extracting %s ID %d: %v", typ, id, err)
+			}
+			if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, want) {
+				t.Errorf("extracted %q (%v) from %s ID %d", got, err, typ, id)
+			}
+			buf := new(bytes.Buffer)
+			if n, err := f.ExtractTo(buf, typ, id); err != nil || n != int64(len(want)) ||
+				!bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("ExtractTo wrote %d bytes (%v) of %s ID %d", n, err, typ, id)
+			}
+		}
+		// The IDs of one table are not looked up in the other.
+		if _, err := extract(12345); !errors.Is(err, ErrNotFound) {
+			t.Errorf("extracting a missing %s: got %v, want ErrNotFound", typ, err)
+		}
+	}
+	if _, err := f.ExtractBnk(2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("extracting wem ID 2 as a bnk: got %v, want ErrNotFound", err)
+	}
+	for _, typ := range []string{"externals", "wav"} {
+		if _, err := f.ExtractTo(io.Discard, typ, 2); err == nil || errors.Is(err, ErrNotFound) {
+			t.Errorf("extracting a %s entry: got %v, want an unknown type", typ, err)
+		}
+	}
+}
+
+func TestSpan(t *testing.T) {
+	entries := testEntries()
+	f, data := openTestPackage(t)
+	defer f.Close()
+	for typ, files := range map[string][]*EmbeddedFile{"bnk": f.Bnks, "wem": f.Wems} {
+		for _, e := range files {
+			offset, length := e.Span()
+			if got := data[offset : offset+length]; !bytes.Equal(got, entries[typ][e.Index.ID]) {
+				t.Errorf("the span of %s ID %d holds %q", typ, e.Index.ID, got)
+			}
+			o, l, err := f.EntrySpan(typ, e.Index.ID)
+			if err != nil || o != offset || l != length {
+				t.Errorf("EntrySpan of %s ID %d is %d+%d (%v), want %d+%d", typ, e.Index.ID,
+					o, l, err, offset, length)
+			}
+		}
+	}
+	if _, _, err := f.EntrySpan("bnk", 100); !errors.Is(err, ErrNotFound) {
+		t.Errorf("the span of a missing entry: got %v, want ErrNotFound", err)
+	}
+}
@@ -3,24 +3,81 @@ package pck
 
 import (
 	"bufio"
+	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+)
+
+import (
+	"wwiseutil/util"
+	"wwiseutil/wem"
 )
 
 // A File represents an open Wwise File Package.
 // This version is modified to support a special PCK format that contains both BNK and WEM files.
+// Standard packages are also supported; their banks and streamed files are
+// accessed as BNK and WEM files respectively.
+//
+// A File is safe for concurrent use by multiple goroutines reading it, such as
+// by unpacking, hashing or repacking it while other goroutines read its
+// entries with EmbeddedFile.Open, as long as none of them modifies it. The
+// package is only ever read at given offsets, never by seeking it.
 type File struct {
-	closer     io.Closer
-	reader     readerAtSeeker
+	closer io.Closer
+	reader readerAtSeeker
+	Format Format
+	// The byte order of the header and index tables. Packages for older
+	// consoles, such as the PS3 and Xbox 360, are big endian.
+	ByteOrder  binary.ByteOrder
 	Header     *Header
 	BnkIndexes []*FileIndex
 	WemIndexes []*FileIndex
 	Bnks       []*EmbeddedFile
 	Wems       []*EmbeddedFile
+	// The externals table of a standard package. Externals are preserved when
+	// the package is written, but cannot be unpacked or replaced.
+	ExternalIndexes []*FileIndex
+	Externals       []*EmbeddedFile
+	// The alignment of entry data when the package is rewritten. 0 or 1 lays
+	// entries back-to-back. It is detected from the offsets of the entries when
+	// the package is read, see Open.
+	Alignment uint32
+	// The alignment of the start of the entry data, which some packages pad
+	// the index tables to. It is detected from the offset of the first entry
+	// when the package is read, and is 1 if the data starts directly after the
+	// index tables. Rewritten packages only keep it if given
+	// PreserveDataAlignment.
+	DataAlignment uint32
+	// The languages of the language map in the header, if it could be decoded.
+	Languages []*Language
+	// Whether WriteTo reproduces the layout of the original package, see
+	// Strict.
+	strict bool
+	// The gaps between the data of the entries, see Gaps.
+	gaps []*Gap
+	// Whether the data of the entries of a hybrid package is aligned to their
+	// block sizes, see detectBlockAlignment.
+	blockAligned bool
+	// The size of the package, and the error finding it, found once when the
+	// package is opened, see fileSize.
+	size    int64
+	sizeErr error
+	// The ciphers the package or its entries are encrypted with, see
+	// WithCipher and WithEntryCipher.
+	cipher      Cipher
+	entryCipher Cipher
+	// The throttle limiting the rate of I/O on behalf of the package, if it
+	// was opened with WithRateLimit.
+	throttle *util.Throttle
 }
 
 // Header represents a single Wwise File Package header.
@@ -28,24 +85,52 @@ type File struct {
 type Header struct {
 	Identifier             [4]byte
 	HeaderAndIndexesLength uint32 // Length from this field's end to the end of all indexes.
-	Unknown                []byte   // Variable length unknown section, determined by filename
+	Unknown                []byte // Variable length unknown section, see DetectFormat and File.HeaderFields
+	// The byte order of the package the header was read from, see Raw.
+	order binary.ByteOrder
 }
 
 // FileIndex represents the 24-byte structure for both BNK and WEM file indexes.
+// The index entries of standard packages are converted to this structure, see
+// FormatStandard. The fields keep the names they were given before their
+// meaning was known; the methods of FileIndex give them their real names.
 type FileIndex struct {
-	ID       uint32
-	Type     uint32
-	Length   uint32
-	Unknown1 uint32
-	Offset   uint32 // Absolute offset from the beginning of the file
-	Unknown2 uint32
+	ID uint32 `json:"id"`
+	// The block size of the entry, see BlockSize.
+	Type   uint32 `json:"type"`
+	Length uint32 `json:"length"`
+	// The high half of the 64 bit ID of an entry of the externals table of a
+	// standard package, see ExternalID. It is 0 in the other tables, and in
+	// every hybrid package seen so far.
+	Unknown1 uint32 `json:"unknown1"`
+	Offset   uint64 `json:"offset"` // Absolute offset from the beginning of the file
+	// The language ID of the entry, see LanguageID.
+	Unknown2 uint32 `json:"unknown2"`
 }
 
 // EmbeddedFile represents a file (BNK or WEM) stored within the PCK.
 type EmbeddedFile struct {
-	Index  *FileIndex
+	Index *FileIndex
+	// A reader over the data of the file, which returns to the start of the
+	// data once read to the end, so that it can be read again. It is shared by
+	// every user of the file, so it must not be read by several goroutines at
+	// once; Open returns a reader of their own.
 	Reader io.Reader
 	Name   string
+	// The section of the package holding this file's data.
+	section *io.SectionReader
+	// The Reader the file was created with, see data.
+	reader io.Reader
+	// The cache of entry data shared by the files of a package, if any.
+	cache *dataCache
+	// The compression of the data of the file, and the section of the
+	// package holding its compressed data, if it is compressed, see
+	// Compression.
+	compression string
+	stored      *io.SectionReader
+	// Whether the data of the file extends past the end of the package, see
+	// Truncated.
+	truncated bool
 }
 
 // readerAtSeeker is an interface that groups io.ReaderAt and io.ReadSeeker.
@@ -60,102 +145,270 @@ type readerAtSeeker interface {
 // NewFile creates a new File for accessing the special Wwise File Package format.
 // It requires the size of the 'Unknown' header field to be determined beforehand.
 func NewFile(r readerAtSeeker, unknownSize int) (*File, error) {
+	return newFile(r, FormatHybrid, binary.LittleEndian, unknownSize)
+}
+
+// NewStandardFile creates a new File for accessing a standard Wwise File
+// Package, as written by the Wwise authoring tool.
+func NewStandardFile(r readerAtSeeker) (*File, error) {
+	o, err := DetectByteOrder(r)
+	if err != nil {
+		return nil, err
+	}
+	unknownSize, err := detectStandardUnknownSize(r, o)
+	if err != nil {
+		return nil, err
+	}
+	return newFile(r, FormatStandard, o, unknownSize)
+}
+
+// newFile creates a new File for accessing a package of the given format and
+// byte order.
+func newFile(r readerAtSeeker, format Format, o binary.ByteOrder, unknownSize int) (*File, error) {
 	pck := new(File)
 	pck.closer = r
 	pck.reader = r
+	pck.Format = format
+	pck.ByteOrder = o
 
-	// Read Header
-	hdr := new(Header)
-	if err := binary.Read(r, binary.LittleEndian, &hdr.Identifier); err != nil {
+	pck.size, pck.sizeErr = r.Seek(0, io.SeekEnd)
+
+	// Read Header, from the start of the package whatever the position of r.
+	hr := io.NewSectionReader(r, 0, math.MaxInt64)
+	hdr := &Header{order: o}
+	if err := binary.Read(hr, o, &hdr.Identifier); err != nil {
 		return nil, fmt.Errorf("reading header identifier: %w", err)
 	}
-	if err := binary.Read(r, binary.LittleEndian, &hdr.HeaderAndIndexesLength); err != nil {
+	if err := binary.Read(hr, o, &hdr.HeaderAndIndexesLength); err != nil {
 		return nil, fmt.Errorf("reading header and indexes length: %w", err)
 	}
 	hdr.Unknown = make([]byte, unknownSize)
-	if _, err := io.ReadFull(r, hdr.Unknown); err != nil {
+	if _, err := io.ReadFull(hr, hdr.Unknown); err != nil {
 		return nil, fmt.Errorf("reading header unknown data (size %d): %w", unknownSize, err)
 	}
 	pck.Header = hdr
 
-	// Read BNK counts and indexes
-	var bnkCount uint32
-	if err := binary.Read(r, binary.LittleEndian, &bnkCount); err != nil {
-		return nil, fmt.Errorf("reading bnk count: %w", err)
-	}
-	pck.BnkIndexes = make([]*FileIndex, 0, bnkCount)
-	for i := uint32(0); i < bnkCount; i++ {
-		idx := new(FileIndex)
-		if err := binary.Read(r, binary.LittleEndian, idx); err != nil {
-			return nil, fmt.Errorf("reading bnk index %d: %w", i, err)
+	// Read the index tables, in the order they are stored, within the rest of
+	// the header and index tables.
+	tables := []*[]*FileIndex{&pck.BnkIndexes, &pck.WemIndexes, &pck.ExternalIndexes}
+	left := int64(hdr.HeaderAndIndexesLength) - int64(unknownSize)
+	for i, c := range format.tables() {
+		indexes, err := readIndexes(hr, o, c, left)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s table: %w", tableNames[i], err)
 		}
-		pck.BnkIndexes = append(pck.BnkIndexes, idx)
+		*tables[i] = indexes
+		left -= int64(indexTableSize(c, len(indexes)))
 	}
 
-	// Read WEM counts and indexes
-	var wemCount uint32
-	if err := binary.Read(r, binary.LittleEndian, &wemCount); err != nil {
-		return nil, fmt.Errorf("reading wem count: %w", err)
+	pck.readLanguages()
+	pck.Alignment = pck.detectAlignment()
+	pck.DataAlignment = pck.detectDataAlignment()
+	if format != FormatStandard {
+		pck.blockAligned = pck.detectBlockAlignment()
 	}
-	pck.WemIndexes = make([]*FileIndex, 0, wemCount)
-	for i := uint32(0); i < wemCount; i++ {
-		idx := new(FileIndex)
-		if err := binary.Read(r, binary.LittleEndian, idx); err != nil {
-			return nil, fmt.Errorf("reading wem index %d: %w", i, err)
-		}
-		pck.WemIndexes = append(pck.WemIndexes, idx)
+	pck.embedFiles(r)
+	if pck.sizeErr == nil {
+		pck.gaps = pck.findGaps(pck.size)
 	}
+	return pck, nil
+}
 
-	// Create readers for BNK file data
-	pck.Bnks = make([]*EmbeddedFile, len(pck.BnkIndexes))
-	for i, idx := range pck.BnkIndexes {
-		pck.Bnks[i] = &EmbeddedFile{
-			Index:  idx,
-			Reader: io.NewSectionReader(r, int64(idx.Offset), int64(idx.Length)),
-			Name:   fmt.Sprintf("%d.bnk", idx.ID),
+// The names of the index tables, in the order they are stored.
+var tableNames = []string{"bnk", "wem", "externals"}
+
+// embedFiles creates the embedded files of the index tables of pck, reading
+// their data from r.
+func (pck *File) embedFiles(r io.ReaderAt) {
+	size := int64(-1)
+	if pck.sizeErr == nil {
+		size = pck.size
+	}
+	pck.Bnks = embeddedFiles(r, pck.BnkIndexes, "bnk", size)
+	pck.Wems = embeddedFiles(r, pck.WemIndexes, "wem", size)
+	pck.Externals = embeddedFiles(r, pck.ExternalIndexes, "wem", size)
+}
+
+// embeddedFiles creates readers over the data of each of indexes, naming each
+// file by its ID and the extension ext. The data of files extending past size,
+// the size of the package or -1 if it is unknown, is cut short at size, and
+// the files are marked as truncated.
+func embeddedFiles(r io.ReaderAt, indexes []*FileIndex, ext string, size int64) []*EmbeddedFile {
+	files := make([]*EmbeddedFile, len(indexes))
+	for i, idx := range indexes {
+		length, truncated := int64(idx.Length), false
+		if size >= 0 && idx.Offset+uint64(idx.Length) > uint64(size) {
+			length, truncated = 0, true
+			if idx.Offset < uint64(size) {
+				length = size - int64(idx.Offset)
+			}
+		}
+		reader := util.NewResettingReader(r, int64(idx.Offset), length)
+		files[i] = &EmbeddedFile{
+			Index:     idx,
+			Reader:    reader,
+			Name:      fmt.Sprintf("%d.%s", idx.ID, ext),
+			section:   io.NewSectionReader(r, int64(idx.Offset), length),
+			reader:    reader,
+			truncated: truncated,
 		}
 	}
+	return files
+}
 
-	// Create readers for WEM file data
-	pck.Wems = make([]*EmbeddedFile, len(pck.WemIndexes))
-	for i, idx := range pck.WemIndexes {
-		pck.Wems[i] = &EmbeddedFile{
-			Index:  idx,
-			Reader: io.NewSectionReader(r, int64(idx.Offset), int64(idx.Length)),
-			Name:   fmt.Sprintf("%d.wem", idx.ID),
+// Truncated returns the entries of pck, including externals, whose data
+// extends past the end of the package, see EmbeddedFile.Truncated.
+func (pck *File) Truncated() []*EmbeddedFile {
+	var truncated []*EmbeddedFile
+	for _, files := range [][]*EmbeddedFile{pck.Bnks, pck.Wems, pck.Externals} {
+		for _, f := range files {
+			if f.truncated {
+				truncated = append(truncated, f)
+			}
 		}
 	}
+	return truncated
+}
 
-	return pck, nil
+// Truncated reports whether the data of this file extends past the end of the
+// package, as in a package cut short by an interrupted download or copy. Only
+// the part of its data within the package can be read: Reader, Open and Bytes
+// read the data up to the end of the package, and Kind inspects that part.
+func (f *EmbeddedFile) Truncated() bool {
+	return f.truncated
 }
 
 // Open opens the File at the specified path and prepares it for use.
-// It determines the header's 'Unknown' field size based on the filename.
-func Open(path string) (*File, error) {
-	var unknownSize int
-	lowerPath := strings.ToLower(path)
-
-	if strings.HasSuffix(lowerPath, "sfx.pck") {
-		unknownSize = 36
-	} else if strings.HasSuffix(lowerPath, "english(us).pck") {
-		unknownSize = 68
-	} else {
-		return nil, fmt.Errorf("unsupported pck file: %s - unknown header size", filepath.Base(path))
-	}
-
+// The byte order and format of the package and the size of the header's
+// 'Unknown' field are detected from the header itself, unless the byte order
+// is given by WithByteOrder. Should that fail, the first registered Profile
+// matching the filename is used, such as those of the Sleeping Dogs:
+// Definitive Edition sfx.pck and english(us).pck files. WithProfile skips
+// detection altogether. If no profile matches either, the error is an
+// *OpenError.
+//
+// The alignment of entry data is detected from the offsets of the entries, so
+// that packages whose data is aligned to sector boundaries are rewritten with
+// the same alignment. The Alignment of a profile, or WithAlignment, takes
+// precedence.
+func Open(path string, opts ...Option) (*File, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
 	}
+	o := newOptions(opts)
+	if o.mmap {
+		if m, err := mapFile(f); err == nil {
+			return open(path, m, o)
+		}
+	}
+	return open(path, f, o)
+}
+
+// OpenReader opens the package stored in the first size bytes of r, such as a
+// package held in memory or embedded in another archive, as Open does. Closing
+// the File does not close r. Since the package has no file name, registered
+// profiles are only used if given by WithProfile.
+func OpenReader(r io.ReaderAt, size int64, opts ...Option) (*File, error) {
+	return open("", readerAtFile{io.NewSectionReader(r, 0, size)}, newOptions(opts))
+}
+
+// readerAtFile is a readerAtSeeker over a section of an io.ReaderAt, whose
+// Close does nothing.
+type readerAtFile struct {
+	*io.SectionReader
+}
+
+func (readerAtFile) Close() error { return nil }
+
+// open is Open for the package stored in f, named path. f is closed if the
+// package cannot be read.
+func open(path string, f readerAtSeeker, o *options) (*File, error) {
+	var err error
+	var throttle *util.Throttle
+	if o.rateLimit > 0 {
+		throttle = util.NewThrottle(o.rateLimit)
+		f = &throttledFile{f, throttle}
+	}
+	if o.cipher != nil {
+		f = &cipherFile{f, o.cipher}
+	}
 
-	pck, err := NewFile(f, unknownSize)
+	prof := o.profile
+	var format Format
+	var order binary.ByteOrder
+	var unknownSize int
+	if prof == nil {
+		format, order, unknownSize, err = detectLayout(f, o.byteOrder)
+		if err != nil {
+			var ok bool
+			if prof, ok = profileFor(path); !ok {
+				size, _ := f.Seek(0, io.SeekEnd)
+				openErr := newOpenError(path, f, size, o.byteOrder, err)
+				f.Close()
+				return nil, openErr
+			}
+		}
+	}
+	if prof != nil {
+		if format, err = prof.format(); err != nil {
+			f.Close()
+			return nil, err
+		}
+		order, unknownSize = prof.byteOrder(), prof.UnknownSize
+	}
+
+	pck, err := newFile(f, format, order, unknownSize)
 	if err != nil {
 		f.Close()
 		return nil, err
 	}
+	pck.cipher = o.cipher
+	pck.throttle = throttle
+	if o.entryCipher != nil {
+		pck.decryptEntries(o.entryCipher)
+	}
+	if o.decompress {
+		pck.detectCompression()
+	}
+	if prof != nil && prof.Alignment != 0 {
+		pck.Alignment = prof.Alignment
+	}
+	if o.alignment != 0 {
+		pck.Alignment = o.alignment
+	}
+	if o.requireSorted {
+		if err := pck.CheckIndexOrder(); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	pck.strict = o.strict
+	if o.cacheBytes > 0 {
+		cache := newDataCache(o.cacheBytes)
+		for _, files := range [][]*EmbeddedFile{pck.Bnks, pck.Wems, pck.Externals} {
+			for _, ef := range files {
+				ef.cache = cache
+			}
+		}
+	}
 	return pck, nil
 }
 
+// detectLayout detects the format, byte order and the size of the Unknown
+// header section of the package stored in r. If order is not nil, the package
+// is read in that byte order instead of detecting it.
+func detectLayout(r io.ReaderAt, order binary.ByteOrder) (Format, binary.ByteOrder, int, error) {
+	if order == nil {
+		var err error
+		if order, err = DetectByteOrder(r); err != nil {
+			return 0, nil, 0, err
+		}
+	}
+	format, unknownSize, err := detectFormat(r, order)
+	return format, order, unknownSize, err
+}
+
 // Close closes the File.
 func (pck *File) Close() error {
 	if pck.closer != nil {
@@ -164,301 +417,804 @@ func (pck *File) Close() error {
 	return nil
 }
 
+// Kind infers the kind of content this file holds by inspecting its data. The
+// result is one of the Kind constants, such as KindWem or KindText.
+func (f *EmbeddedFile) Kind() (string, error) {
+	if data, ok := f.cached(); ok {
+		return SniffKind(bytes.NewReader(data), int64(len(data)))
+	}
+	return SniffKind(f.section, f.section.Size())
+}
+
+// Bytes returns the full contents of this file. If the package was opened with
+// WithCache, recently read contents are returned from memory. The returned
+// slice must not be modified.
+func (f *EmbeddedFile) Bytes() ([]byte, error) {
+	if data, ok := f.cached(); ok {
+		return data, nil
+	}
+	data := make([]byte, f.section.Size())
+	if _, err := f.section.ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if f.cache != nil {
+		f.cache.add(f.cacheKey(), data)
+	}
+	return data, nil
+}
+
+// cached returns the contents of this file if they are held in the cache.
+func (f *EmbeddedFile) cached() ([]byte, bool) {
+	if f.cache == nil {
+		return nil, false
+	}
+	return f.cache.get(f.cacheKey())
+}
+
+// Open returns a new reader over the data of this file, positioned at its
+// start, independent of Reader and of the readers returned by other calls, so
+// that the data can be read any number of times, and by several goroutines at
+// once. Data cached by WithCache is read from memory, as by Hash.
+func (f *EmbeddedFile) Open() *io.SectionReader {
+	if data, ok := f.cached(); ok {
+		return io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data)))
+	}
+	return io.NewSectionReader(f.section, 0, f.section.Size())
+}
+
+// data returns a reader over the data of f to write it out: a reader of its
+// own from Open, or over its stored data if it is compressed, unless the
+// caller replaced Reader, which is then read from its start.
+func (f *EmbeddedFile) data() io.Reader {
+	if f.Reader == f.reader {
+		if f.stored != nil {
+			return io.NewSectionReader(f.stored, 0, f.stored.Size())
+		}
+		return f.Open()
+	}
+	if r, ok := f.Reader.(io.ReadSeeker); ok {
+		r.Seek(0, io.SeekStart)
+	}
+	return f.Reader
+}
+
+func (f *EmbeddedFile) cacheKey() cacheKey {
+	return cacheKey{int64(f.Index.Offset), int64(f.Index.Length)}
+}
+
+// IsEmpty reports whether this file is a zero-length placeholder entry.
+func (f *EmbeddedFile) IsEmpty() bool {
+	return f.Index.Length == 0
+}
+
 // UnpackTo extracts all BNK and WEM files to a specified directory.
-func (pck *File) UnpackTo(outputDir string) error {
-	// Unpack BNKs
-	bnkDir := filepath.Join(outputDir, "bnk")
-	if err := os.MkdirAll(bnkDir, 0755); err != nil {
-		return err
+// Files are written to a bnk and a wem subdirectory according to the table
+// they are indexed in, and are named by their ID. The file extension is
+// inferred from the content of each file, so that payloads which are not
+// actually SoundBanks or wems are not mislabeled. Empty placeholder entries are
+// extracted as empty files, unless SkipEmpty is given. With SplitLanguages, the
+// bnk and wem subdirectories are created in a directory per language. With
+// WithWorkers, several entries are written at once. With DecodeWems, wems
+// that can be decoded are written as WAVE files, and the others are reported to
+// the function given by WithUndecodedReport.
+//
+// The entries of a truncated package, whose data extends past its end, are
+// salvaged: the part of their data within the package is unpacked, unless
+// none of it is, and once every entry is unpacked, a *TruncatedError listing
+// them is returned.
+func (pck *File) UnpackTo(outputDir string, opts ...Option) error {
+	return pck.UnpackToContext(context.Background(), outputDir, opts...)
+}
+
+// UnpackToContext is UnpackTo, stopping with ctx's error if ctx is done before
+// every entry is unpacked. The files unpacked so far are left in outputDir,
+// but none is left partly written.
+func (pck *File) UnpackToContext(ctx context.Context, outputDir string, opts ...Option) error {
+	o := newOptions(opts)
+	if report := o.undecodedReport; report != nil {
+		var mu sync.Mutex
+		o.undecodedReport = func(f *EmbeddedFile, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			report(f, err)
+		}
 	}
-	for _, bnk := range pck.Bnks {
-		outFile, err := os.Create(filepath.Join(bnkDir, bnk.Name))
-		if err != nil {
-			return err // No need to close if creation failed
+	if o.throttle = pck.throttle; o.throttle == nil && o.rateLimit > 0 {
+		o.throttle = util.NewThrottle(o.rateLimit)
+	}
+	total := 0
+	for _, files := range [][]*EmbeddedFile{pck.Bnks, pck.Wems} {
+		for _, f := range files {
+			if o.unpacks(f) {
+				total++
+			}
+		}
+	}
+	progress := newProgressTracker(o.progress, total)
+
+	for i, files := range [][]*EmbeddedFile{pck.Bnks, pck.Wems} {
+		typ := tableNames[i]
+		if !o.splitLanguages {
+			if err := unpackFiles(ctx, filepath.Join(outputDir, typ), files, o, progress); err != nil {
+				return err
+			}
+			continue
 		}
-		defer outFile.Close()
 
-		if _, err := io.Copy(outFile, bnk.Reader); err != nil {
-			return err
+		var dirs []string
+		byDir := make(map[string][]*EmbeddedFile)
+		for _, f := range files {
+			dir := pck.languageDir(f.Index)
+			if _, ok := byDir[dir]; !ok {
+				dirs = append(dirs, dir)
+			}
+			byDir[dir] = append(byDir[dir], f)
+		}
+		for _, dir := range dirs {
+			if err := unpackFiles(ctx, filepath.Join(outputDir, dir, typ), byDir[dir], o, progress); err != nil {
+				return err
+			}
 		}
 	}
 
-	// Unpack WEMs
-	wemDir := filepath.Join(outputDir, "wem")
-	if err := os.MkdirAll(wemDir, 0755); err != nil {
+	var truncated []*EmbeddedFile
+	for _, files := range [][]*EmbeddedFile{pck.Bnks, pck.Wems} {
+		for _, f := range files {
+			if f.truncated && o.unpacks(f) {
+				truncated = append(truncated, f)
+			}
+		}
+	}
+	if len(truncated) > 0 {
+		return &TruncatedError{truncated}
+	}
+	return nil
+}
+
+// unpackFiles writes each of files selected by o to dir, creating dir if
+// needed. The files are written by the number of workers given by WithWorkers.
+// Files with the same ID are written by the same worker, in order, so that the
+// last of them is the one left in dir, as when unpacking sequentially. Each file
+// written is reported to progress.
+func unpackFiles(ctx context.Context, dir string, files []*EmbeddedFile, o *options,
+	progress *progressTracker) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
-	for _, wem := range pck.Wems {
-		outFile, err := os.Create(filepath.Join(wemDir, wem.Name))
-		if err != nil {
-			return err
+	var jobs [][]*EmbeddedFile
+	jobOf := make(map[uint32]int)
+	for _, f := range files {
+		if !o.unpacks(f) {
+			continue
+		}
+		if i, ok := jobOf[f.Index.ID]; ok {
+			jobs[i] = append(jobs[i], f)
+			continue
+		}
+		jobOf[f.Index.ID] = len(jobs)
+		jobs = append(jobs, []*EmbeddedFile{f})
+	}
+	return runWorkers(o.workers, len(jobs), func(i int) error {
+		for _, f := range jobs[i] {
+			if err := unpackFile(ctx, dir, f, o); err != nil {
+				return err
+			}
+			progress.add(1, int64(f.Index.Length))
 		}
-		defer outFile.Close()
+		return nil
+	})
+}
 
-		if _, err := io.Copy(outFile, wem.Reader); err != nil {
-			return err
+// unpackFile writes f to dir, named by its ID and the kind of its content.
+// With DecodeWems, a decodable wem is written as a WAVE file instead.
+func unpackFile(ctx context.Context, dir string, f *EmbeddedFile, o *options) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	// Nothing can be salvaged of entries starting past the end of a truncated
+	// package.
+	if f.truncated && f.section.Size() == 0 {
+		return nil
+	}
+	// Empty entries have no content to infer a kind from; keep the name of
+	// their table so that they are replaced into the same table.
+	name := f.Name
+	if !f.IsEmpty() {
+		kind, err := f.Kind()
+		if err != nil {
+			return fmt.Errorf("inspecting %s: %w", f.Name, err)
+		}
+		name = fmt.Sprintf("%d.%s", f.Index.ID, kind)
+		if kind == KindWem && o.decodeRate > 0 && !f.truncated {
+			if done, err := decodeFile(dir, f, o); done || err != nil {
+				return err
+			}
 		}
 	}
-	return nil
+	r := io.NewSectionReader(f.section, 0, f.section.Size())
+	return writeFile(filepath.Join(dir, name), contextReader(ctx, r), o.throttle)
 }
 
-// WriteTo writes the entire PCK file to a writer.
-func (pck *File) WriteTo(w io.Writer) (int64, error) {
-	var written int64
+// decodeFile writes the decoded audio of the wem f to dir as a WAVE file with
+// the sample rate given by DecodeWems, named by its ID. done is false if f
+// cannot be decoded, which is reported to the function given by
+// WithUndecodedReport.
+func decodeFile(dir string, f *EmbeddedFile, o *options) (done bool, err error) {
+	w, err := wem.NewFile(f.section, f.section.Size())
+	if err != nil {
+		o.reportUndecoded(f, fmt.Errorf("parsing: %w", err))
+		return false, nil
+	}
+	var b bytes.Buffer
+	if _, err := w.WriteWAV(&b, o.decodeRate); errors.Is(err, wem.ErrUnsupportedCodec) {
+		o.reportUndecoded(f, err)
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("decoding %s: %w", f.Name, err)
+	}
+	return true, writeFile(filepath.Join(dir, fmt.Sprintf("%d.wav", f.Index.ID)), &b, o.throttle)
+}
 
-	// Use a buffered writer for efficiency
-	bufWriter := bufio.NewWriter(w)
+// reportUndecoded passes f and the reason it cannot be decoded to the function
+// given by WithUndecodedReport, if any.
+func (o *options) reportUndecoded(f *EmbeddedFile, err error) {
+	if o.undecodedReport != nil {
+		o.undecodedReport(f, err)
+	}
+}
 
-	// Write Header
-	if err := binary.Write(bufWriter, binary.LittleEndian, pck.Header.Identifier); err != nil {
-		return written, err
+// writeFile creates the file at path and copies the contents of r into it,
+// rate limited by throttle if it is not nil. The file is removed if it cannot
+// be fully written.
+func writeFile(path string, r io.Reader, throttle *util.Throttle) error {
+	outFile, err := os.Create(path)
+	if err != nil {
+		return err
 	}
-	written += 4
-	if err := binary.Write(bufWriter, binary.LittleEndian, pck.Header.HeaderAndIndexesLength); err != nil {
-		return written, err
+	var w io.Writer = outFile
+	if throttle != nil {
+		w = &throttledWriter{outFile, throttle}
+	}
+	_, err = io.Copy(w, r)
+	if cerr := outFile.Close(); err == nil {
+		err = cerr
 	}
-	written += 4
-	n, err := bufWriter.Write(pck.Header.Unknown)
 	if err != nil {
-		return written, err
+		os.Remove(path)
 	}
-	written += int64(n)
+	return err
+}
+
+// WriteTo writes the entire PCK file to a writer. If the File was opened with
+// Strict, the layout of the original package is reproduced, see writeStrict.
+func (pck *File) WriteTo(w io.Writer) (int64, error) {
+	return pck.WriteToContext(context.Background(), w)
+}
 
-	// Write BNK Count and Indexes
-	bnkCount := uint32(len(pck.BnkIndexes))
-	if err := binary.Write(bufWriter, binary.LittleEndian, bnkCount); err != nil {
+// WriteToContext is WriteTo, stopping with ctx's error if ctx is done before
+// the package is fully written.
+func (pck *File) WriteToContext(ctx context.Context, w io.Writer) (int64, error) {
+	w = pck.encryptPackage(pck.throttleWriter(contextWriter(ctx, w)))
+	if pck.strict {
+		return pck.writeStrict(w, true)
+	}
+	written, err := writeHeader(w, pck.Format, pck.ByteOrder, pck.Header, pck.indexTables())
+	if err != nil {
 		return written, err
 	}
-	written += 4
-	for _, idx := range pck.BnkIndexes {
-		if err := binary.Write(bufWriter, binary.LittleEndian, idx); err != nil {
-			return written, err
+
+	// Write Data
+	for _, files := range [][]*EmbeddedFile{pck.Bnks, pck.Wems, pck.Externals} {
+		for _, f := range files {
+			// Standard packages may pad entries to their block size.
+			n, err := writePadding(w, int64(f.Index.Offset)-written)
+			written += n
+			if err != nil {
+				return written, err
+			}
+			n, err = io.Copy(pck.encryptEntry(w), f.data())
+			written += n
+			if err != nil {
+				return written, err
+			}
+			if n < int64(f.Index.Length) {
+				return written, fmt.Errorf("writing %s: %w: only %d of its %d bytes could be read",
+					f.Name, ErrTruncated, n, f.Index.Length)
+			}
 		}
-		written += 24
 	}
 
-	// Write WEM Count and Indexes
-	wemCount := uint32(len(pck.WemIndexes))
-	if err := binary.Write(bufWriter, binary.LittleEndian, wemCount); err != nil {
+	return written, nil
+}
+
+// fileSize returns the size of the package, found when it was opened, so that
+// it is never sought while other goroutines read it.
+func (pck *File) fileSize() (int64, error) {
+	return pck.size, pck.sizeErr
+}
+
+// dataStart returns the offset at which the header and index tables of this
+// File end, and entry data may begin.
+func (pck *File) dataStart() int64 {
+	return 8 + int64(pck.Header.HeaderAndIndexesLength)
+}
+
+// indexTables returns the index tables of this File, in the order they are
+// stored.
+func (pck *File) indexTables() [][]*FileIndex {
+	return [][]*FileIndex{pck.BnkIndexes, pck.WemIndexes, pck.ExternalIndexes}
+}
+
+// writePadding writes n zero bytes to w. Nothing is written if n is not
+// positive.
+func writePadding(w io.Writer, n int64) (int64, error) {
+	if n <= 0 {
+		return 0, nil
+	}
+	return io.CopyN(w, zeroReader{}, n)
+}
+
+// zeroReader is an io.Reader that reads an endless stream of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+// writeHeader writes the header of a package of the given format and byte
+// order, followed by its index tables, to w. tables holds the BNK, WEM and, for
+// standard packages, externals indexes.
+func writeHeader(w io.Writer, format Format, o binary.ByteOrder, hdr *Header, tables [][]*FileIndex) (int64, error) {
+	var written int64
+
+	// Use a buffered writer for efficiency
+	bufWriter := bufio.NewWriter(w)
+
+	// Write Header
+	if err := binary.Write(bufWriter, o, hdr.Identifier); err != nil {
 		return written, err
 	}
 	written += 4
-	for _, idx := range pck.WemIndexes {
-		if err := binary.Write(bufWriter, binary.LittleEndian, idx); err != nil {
-			return written, err
-		}
-		written += 24
+	if err := binary.Write(bufWriter, o, hdr.HeaderAndIndexesLength); err != nil {
+		return written, err
 	}
-
-	// Flush header/index data
-	if err := bufWriter.Flush(); err != nil {
+	written += 4
+	n, err := bufWriter.Write(hdr.Unknown)
+	if err != nil {
 		return written, err
 	}
+	written += int64(n)
 
-	// Write Data
-	for _, bnk := range pck.Bnks {
-		if r, ok := bnk.Reader.(io.ReadSeeker); ok {
-			r.Seek(0, io.SeekStart)
-		}
-		n, err := io.Copy(w, bnk.Reader)
-		if err != nil {
-			return written, err
-		}
+	// Write each table's count and indexes
+	for i, c := range format.tables() {
+		n, err := writeIndexes(bufWriter, o, c, tables[i])
 		written += n
-	}
-	for _, wem := range pck.Wems {
-		if r, ok := wem.Reader.(io.ReadSeeker); ok {
-			r.Seek(0, io.SeekStart)
-		}
-		n, err := io.Copy(w, wem.Reader)
 		if err != nil {
 			return written, err
 		}
-		written += n
 	}
 
-	return written, nil
+	// Flush header/index data
+	return written, bufWriter.Flush()
 }
 
 func (pck *File) String() string {
+	return pck.describe(nil)
+}
+
+// describe returns the description of pck given by String, with a column of
+// the checksums of the entries in the index tables if sums is not nil.
+func (pck *File) describe(sums *checksumColumn) string {
 	b := new(strings.Builder)
-	fmt.Fprintf(b, "PCK File (Hybrid BNK/WEM Format)\n")
+	fmt.Fprintf(b, "PCK File (%s)\n", pck.Format)
+	fmt.Fprintf(b, "Byte Order: %s\n", pck.ByteOrder)
+	if h, ok := pck.HeaderFields(); ok {
+		fmt.Fprintf(b, "Version: %d\n", h.Version)
+	} else {
+		fmt.Fprintf(b, "Header: %d bytes of unknown layout\n", len(h.Unrecognized))
+	}
+	fmt.Fprintf(b, "Alignment: %d\n", pck.Alignment)
+	if pck.DataAlignment > 1 {
+		fmt.Fprintf(b, "Data Alignment: %d\n", pck.DataAlignment)
+	}
+	fmt.Fprintf(b, "Fingerprint: %s\n", pck.Fingerprint())
+	if label, ok := pck.Identify(); ok {
+		fmt.Fprintf(b, "This looks like %s audio package\n", label)
+	}
+	for _, l := range pck.Languages {
+		fmt.Fprintf(b, "Language: %s (ID %d)\n", l.Name, l.ID)
+	}
+	if w, ok := pck.Watermark(); ok {
+		fmt.Fprintf(b, "Watermark: %s\n", w)
+	}
 	fmt.Fprintf(b, "BNK Count: %d\n", len(pck.BnkIndexes))
-	fmt.Fprintf(b, "WEM Count: %d\n\n", len(pck.WemIndexes))
+	fmt.Fprintf(b, "WEM Count: %d\n", len(pck.WemIndexes))
+	if pck.Format == FormatStandard {
+		fmt.Fprintf(b, "External Count: %d\n", len(pck.ExternalIndexes))
+	}
+	b.WriteString("\n")
+	writeIndexTables(b, pck.BnkIndexes, pck.WemIndexes, sums)
+	if len(pck.ExternalIndexes) > 0 {
+		b.WriteString("\n--- External Files ---\n")
+		writeExternalTable(b, pck.ExternalIndexes, sums)
+	}
+	return b.String()
+}
 
+// writeIndexTables writes a human readable table of the BNK and WEM indexes to
+// b, with a column of the checksums of their entries if sums is not nil.
+func writeIndexTables(b *strings.Builder, bnkIndexes, wemIndexes []*FileIndex, sums *checksumColumn) {
 	b.WriteString("--- BNK Files ---\n")
-	fmt.Fprintf(b, "%-7s | %-10s | %-15s | %-10s\n", "Index", "ID", "Offset", "Length")
-	for i, idx := range pck.BnkIndexes {
-		fmt.Fprintf(b, "%-7d | %-10d | %-15d | %-10d\n", i+1, idx.ID, idx.Offset, idx.Length)
+	writeIndexTable(b, bnkIndexes, sums)
+	b.WriteString("\n--- WEM Files ---\n")
+	writeIndexTable(b, wemIndexes, sums)
+}
+
+// writeIndexTable writes a human readable table of indexes to b. IDs are shown
+// in both decimal and hexadecimal.
+func writeIndexTable(b *strings.Builder, indexes []*FileIndex, sums *checksumColumn) {
+	fmt.Fprintf(b, "%-7s | %-10s | %-10s | %-15s | %-10s", "Index", "ID", "ID (hex)", "Offset", "Length")
+	sums.writeHeader(b)
+	for i, idx := range indexes {
+		fmt.Fprintf(b, "%-7d | %-10d | 0x%08X | %-15d | %-10d", i+1, idx.ID, idx.ID, idx.Offset, idx.Length)
+		sums.writeCell(b, idx)
 	}
+}
 
-	b.WriteString("\n--- WEM Files ---\n")
-	fmt.Fprintf(b, "%-7s | %-10s | %-15s | %-10s\n", "Index", "ID", "Offset", "Length")
-	for i, idx := range pck.WemIndexes {
-		fmt.Fprintf(b, "%-7d | %-10d | %-15d | %-10d\n", i+1, idx.ID, idx.Offset, idx.Length)
+// writeExternalTable writes a human readable table of the indexes of the
+// externals table to b. The 64 bit IDs of externals are kept in the ID and
+// Unknown1 fields of their indexes, see FormatStandard.
+func writeExternalTable(b *strings.Builder, indexes []*FileIndex, sums *checksumColumn) {
+	fmt.Fprintf(b, "%-7s | %-20s | %-18s | %-15s | %-10s", "Index", "ID", "ID (hex)", "Offset", "Length")
+	sums.writeHeader(b)
+	for i, idx := range indexes {
+		id := idx.ExternalID()
+		fmt.Fprintf(b, "%-7d | %-20d | 0x%016X | %-15d | %-10d", i+1, id, id, idx.Offset, idx.Length)
+		sums.writeCell(b, idx)
 	}
+}
 
-	return b.String()
+// The largest number of hexadecimal digits of the checksums shown in index
+// tables.
+const shownHashDigits = 16
+
+// A checksumColumn is the column of the checksums of the entries of index
+// tables.
+type checksumColumn struct {
+	heading string
+	// The number of hexadecimal digits shown of each checksum.
+	digits int
+	sums   map[*FileIndex]string
+}
+
+// writeHeader ends the header line of an index table, with the heading of the
+// column if c is not nil.
+func (c *checksumColumn) writeHeader(b *strings.Builder) {
+	if c != nil {
+		fmt.Fprintf(b, " | %-*s", c.digits, c.heading)
+	}
+	b.WriteString("\n")
+}
+
+// writeCell ends the line of idx in an index table, with the start of the
+// checksum of its entry if c is not nil.
+func (c *checksumColumn) writeCell(b *strings.Builder, idx *FileIndex) {
+	if c != nil {
+		fmt.Fprintf(b, " | %-*.*s", c.digits, c.digits, c.sums[idx])
+	}
+	if idx.Length == 0 {
+		b.WriteString(" (empty)")
+	}
+	b.WriteString("\n")
 }
 
 // ReplacementFile defines a file to be used for replacement.
 type ReplacementFile struct {
 	ID   uint32
 	Path string
+	// The data of the file, if it is held in memory. When Data is nil, the file
+	// at Path is streamed from disk as the package is written.
 	Data []byte
 	Type string // "bnk" or "wem"
+	// Whether the file is added as a new entry with ID, rather than replacing
+	// the existing entry with that ID.
+	New bool
+	// Overrides of the fields of the entry's index, if any.
+	Override *IndexOverride
+	// If not nil, the file only replaces the bytes of the entry starting at
+	// this offset, as by Session.ReplaceRange, rather than all of its data.
+	Offset *int64
 }
 
 // Repack rebuilds the PCK file with replacement files in a memory-efficient way.
-func Repack(inputFile string, outputFile string, replacements []*ReplacementFile) (int64, error) {
+// Replacement files are streamed from disk as the package is written, so only
+// one of them is open, and none is held in memory, at a time.
+// Replacement files marked New are added as new entries, the entries given by
+// RemoveIDs are removed, and those given by RemapIDs are remapped. The options
+// are applied when opening the original file. Unless AllowTypeMismatch is
+// given, a replacement file that looks like the wrong type for its entry
+// results in a *TypeMismatchError. With VerifyOutput, the output file is read
+// back once written.
+//
+// The package is written to a temporary file next to outputFile, which only
+// replaces outputFile once fully written and verified, so that a failed
+// repack leaves any existing file at outputFile as it was. Unless
+// AllowOverwrite is given, it is an error wrapping ErrOverwrite for outputFile
+// to be inputFile.
+func Repack(inputFile string, outputFile string, replacements []*ReplacementFile, opts ...Option) (int64, error) {
+	return RepackContext(context.Background(), inputFile, outputFile, replacements, opts...)
+}
+
+// RepackContext is Repack, stopping with ctx's error if ctx is done before the
+// output file is fully written, in which case outputFile is left as it was.
+func RepackContext(ctx context.Context, inputFile string, outputFile string,
+	replacements []*ReplacementFile, opts ...Option) (int64, error) {
+	o := newOptions(opts)
+	if err := checkOverwrite(inputFile, outputFile, o); err != nil {
+		return 0, err
+	}
+
 	// Open the original file
-	pckFile, err := Open(inputFile)
+	pckFile, err := Open(inputFile, opts...)
 	if err != nil {
 		return 0, fmt.Errorf("opening original file for repack: %w", err)
 	}
 	defer pckFile.Close()
 
+	session := pckFile.NewSession(opts...)
+	files, err := session.applyReplacements(replacements, o)
+	defer closeFiles(files)
+	if err != nil {
+		return 0, err
+	}
+
 	// Create the output file
-	outFile, err := os.Create(outputFile)
+	outFile, err := util.CreateAtomic(outputFile)
 	if err != nil {
 		return 0, fmt.Errorf("creating output file: %w", err)
 	}
-	defer outFile.Close()
-
-	// Create a map for quick lookup of replacements
-	replacementMap := make(map[string]map[uint32]*ReplacementFile)
-	replacementMap["bnk"] = make(map[uint32]*ReplacementFile)
-	replacementMap["wem"] = make(map[uint32]*ReplacementFile)
-
-	for _, r := range replacements {
-		data, err := os.ReadFile(r.Path)
-		if err != nil {
-			return 0, fmt.Errorf("reading replacement file %s: %w", r.Path, err)
+	defer outFile.Abort()
+	n, err := session.WriteToContext(ctx, outFile)
+	if err == nil && o.verifyOutput {
+		if err = verifySize(outFile.Name(), n); err == nil {
+			err = session.Verify(outFile.Name(), opts...)
 		}
-		r.Data = data
-		replacementMap[r.Type][r.ID] = r
 	}
+	if err != nil {
+		return n, err
+	}
+	// The original file may be replaced, which some systems only allow once
+	// it is closed.
+	pckFile.Close()
+	return n, outFile.Commit()
+}
 
-	// Create new index slices
-	newBnkIndexes := make([]*FileIndex, len(pckFile.BnkIndexes))
-	newWemIndexes := make([]*FileIndex, len(pckFile.WemIndexes))
+// PreviewRepack returns the layout of the package Repack would write for the
+// same arguments, see Session.Preview: its header, its index tables with the
+// offsets and lengths of the entries, and its size. Nothing is written, and
+// only the headers of the replacement files are read, to check their type, so
+// that a large repack, such as one run by a mod pipeline, can be checked in
+// moments before hours are spent writing it. It fails where Repack would fail
+// before writing anything.
+func PreviewRepack(inputFile string, replacements []*ReplacementFile, opts ...Option) (*Layout, error) {
+	o := newOptions(opts)
+	pckFile, err := Open(inputFile, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("opening original file for repack: %w", err)
+	}
+	defer pckFile.Close()
 
-	// Copy original indexes and update lengths for replaced files
-	for i, idx := range pckFile.BnkIndexes {
-		newIdx := *idx // Make a copy
-		if r, ok := replacementMap["bnk"][idx.ID]; ok {
-			newIdx.Length = uint32(len(r.Data))
-		}
-		newBnkIndexes[i] = &newIdx
+	session := pckFile.NewSession(opts...)
+	files, err := session.applyReplacements(replacements, o)
+	defer closeFiles(files)
+	if err != nil {
+		return nil, err
 	}
-	for i, idx := range pckFile.WemIndexes {
-		newIdx := *idx // Make a copy
-		if r, ok := replacementMap["wem"][idx.ID]; ok {
-			newIdx.Length = uint32(len(r.Data))
-		}
-		newWemIndexes[i] = &newIdx
+	l := session.Preview()
+	if err := session.checkLimits(l); err != nil {
+		return nil, err
 	}
+	return l, nil
+}
 
-	// === Recalculate Offsets and Header Length ===
-	headerSize := uint32(4 + 4 + len(pckFile.Header.Unknown))
-	bnkIndexSize := uint32(len(newBnkIndexes) * 24)
-	wemIndexSize := uint32(len(newWemIndexes) * 24)
-	dataAreaStartOffset := headerSize + 4 + bnkIndexSize + 4 + wemIndexSize
+// checkOverwrite returns an error wrapping ErrOverwrite if outputFile is the
+// package at inputFile, unless o allows overwriting it.
+func checkOverwrite(inputFile, outputFile string, o *options) error {
+	if o.allowOverwrite {
+		return nil
+	}
+	in, err := os.Stat(inputFile)
+	if err != nil {
+		return nil
+	}
+	if out, err := os.Stat(outputFile); err == nil && os.SameFile(in, out) {
+		return fmt.Errorf("%w: %s", ErrOverwrite, outputFile)
+	}
+	return nil
+}
 
-	pckFile.Header.HeaderAndIndexesLength = dataAreaStartOffset - 8 // Subtract Identifier and the field itself
+// Patch applies replacement files to the PCK file at path in place, only
+// overwriting its header and the data of the replaced entries, rather than
+// rewriting the whole package as Repack does. See Session.WriteInPlace for
+// when this is possible; otherwise the returned error wraps ErrDoesNotFit and
+// the file is left unmodified. The options are applied as by Repack.
+func Patch(path string, replacements []*ReplacementFile, opts ...Option) (int64, error) {
+	return patch(path, replacements, (*Session).checkInPlace, (*Session).WriteInPlace, opts)
+}
 
-	currentOffset := dataAreaStartOffset
-	for _, idx := range newBnkIndexes {
-		idx.Offset = currentOffset
-		currentOffset += idx.Length
-	}
-	for _, idx := range newWemIndexes {
-		idx.Offset = currentOffset
-		currentOffset += idx.Length
+// PatchAppend applies replacement files to the PCK file at path by appending
+// their data to its end and rewriting only its header and index tables, as
+// Session.WriteAppend does, so that replacements of any size are applied in
+// moments. If that is not possible, the returned error wraps ErrDoesNotFit and
+// the file is left unmodified. The options are applied as by Repack.
+func PatchAppend(path string, replacements []*ReplacementFile, opts ...Option) (int64, error) {
+	return patch(path, replacements, (*Session).checkAppend, (*Session).WriteAppend, opts)
+}
+
+// patch is Patch and PatchAppend, checking that the replacements can be
+// applied to the file at path with check before writing them with write.
+func patch(path string, replacements []*ReplacementFile, check func(*Session) error,
+	write func(*Session, io.WriterAt) (int64, error), opts []Option) (int64, error) {
+	o := newOptions(opts)
+
+	pckFile, err := Open(path, opts...)
+	if err != nil {
+		return 0, fmt.Errorf("opening file to patch: %w", err)
 	}
+	defer pckFile.Close()
 
-	// === Write the new PCK file ===
-	var written int64
-	bufWriter := bufio.NewWriter(outFile)
+	session := pckFile.NewSession(opts...)
+	files, err := session.applyReplacements(replacements, o)
+	defer closeFiles(files)
+	if err != nil {
+		return 0, err
+	}
+	if err := check(session); err != nil {
+		return 0, err
+	}
 
-	// 1. Write Header
-	if err := binary.Write(bufWriter, binary.LittleEndian, pckFile.Header.Identifier); err != nil {
-		return written, err
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return 0, fmt.Errorf("opening file to patch: %w", err)
 	}
-	if err := binary.Write(bufWriter, binary.LittleEndian, pckFile.Header.HeaderAndIndexesLength); err != nil {
-		return written, err
+	n, err := write(session, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
 	}
-	if _, err := bufWriter.Write(pckFile.Header.Unknown); err != nil {
-		return written, err
+	if err != nil || !o.verifyOutput {
+		return n, err
 	}
+	return n, session.Verify(path, opts...)
+}
 
-	// 2. Write BNK Indexes
-	if err := binary.Write(bufWriter, binary.LittleEndian, uint32(len(newBnkIndexes))); err != nil {
-		return written, err
+// applyReplacements records replacements, the removal of the entries given by
+// RemoveIDs and the remapping of those given by RemapIDs in the session,
+// resolving duplicated entries and replacement files as given by OnDuplicate.
+// Replacement files are read lazily; the returned files must be closed with
+// closeFiles once the session is written.
+func (s *Session) applyReplacements(replacements []*ReplacementFile, o *options) ([]*lazyFile, error) {
+	if err := s.applyDuplicatePolicy(o); err != nil {
+		return nil, err
 	}
-	for _, idx := range newBnkIndexes {
-		if err := binary.Write(bufWriter, binary.LittleEndian, idx); err != nil {
-			return written, err
+	replacements, err := dedupeReplacements(replacements, o)
+	if err != nil {
+		return nil, err
+	}
+	var files []*lazyFile
+	for _, r := range replacements {
+		data, length, err := r.reader()
+		if err != nil {
+			return files, fmt.Errorf("reading replacement file %s: %w", r.Path, err)
+		}
+		if f, ok := data.(*lazyFile); ok {
+			files = append(files, f)
+		}
+		if !o.allowTypeMismatch && r.Offset == nil {
+			err := CheckReplacementType(r.Type, data, length)
+			if f, ok := data.(*lazyFile); ok {
+				// Keep no more than one replacement file open at a time.
+				f.close()
+			}
+			if err != nil {
+				return files, fmt.Errorf("checking replacement file %s: %w", r.Path, err)
+			}
+		}
+		if r.New {
+			if r.Offset != nil {
+				return files, fmt.Errorf("adding %s: new entries cannot be replaced partially", r.Path)
+			}
+			if err := s.Add(r.Type, r.ID, data, length); err != nil {
+				return files, fmt.Errorf("adding %s: %w", r.Path, err)
+			}
+		} else if r.Offset != nil {
+			if err := s.ReplaceRange(r.Type, r.ID, *r.Offset, data, length); err != nil {
+				return files, fmt.Errorf("replacing part of %s ID %d with %s: %w", r.Type, r.ID, r.Path, err)
+			}
+		} else if err := s.Replace(r.Type, r.ID, data, length); err != nil {
+			return files, fmt.Errorf("replacing with %s: %w", r.Path, err)
+		}
+		if r.Override != nil {
+			if err := s.Override(r.Type, r.ID, r.Override); err != nil {
+				return files, err
+			}
 		}
 	}
 
-	// 3. Write WEM Indexes
-	if err := binary.Write(bufWriter, binary.LittleEndian, uint32(len(newWemIndexes))); err != nil {
-		return written, err
+	for _, id := range o.removeIDs {
+		if err := removeID(s, id); err != nil {
+			return files, err
+		}
 	}
-	for _, idx := range newWemIndexes {
-		if err := binary.Write(bufWriter, binary.LittleEndian, idx); err != nil {
-			return written, err
+	// The IDs are remapped in order, so that the error reported for several
+	// IDs that cannot be remapped is the same from run to run.
+	ids := make([]uint32, 0, len(o.remapIDs))
+	for id := range o.remapIDs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		if err := remapID(s, id, o.remapIDs[id]); err != nil {
+			return files, err
 		}
 	}
+	return files, nil
+}
 
-	// Flush header/index data to ensure it's written before data blocks
-	if err := bufWriter.Flush(); err != nil {
-		return written, err
+// closeFiles closes files.
+func closeFiles(files []*lazyFile) {
+	for _, f := range files {
+		f.close()
 	}
-	written = int64(dataAreaStartOffset)
+}
 
+// reader returns a reader over the data of the replacement file, and its
+// length.
+func (r *ReplacementFile) reader() (io.ReaderAt, int64, error) {
+	if r.Data != nil {
+		return bytes.NewReader(r.Data), int64(len(r.Data)), nil
+	}
+	info, err := os.Stat(r.Path)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &lazyFile{path: r.Path, size: info.Size()}, info.Size(), nil
+}
 
-	// 4. Write Data Blocks
-	// BNKs
-	for i, idx := range pckFile.BnkIndexes {
-		var n int64
-		var err error
-		if r, ok := replacementMap["bnk"][idx.ID]; ok {
-			// Write replacement data
-			nW, errWrite := outFile.Write(r.Data)
-			n = int64(nW)
-			err = errWrite
-		} else {
-			// Copy original data
-			pckFile.reader.Seek(int64(idx.Offset), io.SeekStart)
-			n, err = io.CopyN(outFile, pckFile.reader, int64(newBnkIndexes[i].Length))
+// removeID removes the BNK and WEM entries with the given ID in session. It is
+// an error for neither kind of entry to have the ID.
+func removeID(session *Session, id uint32) error {
+	removed := false
+	for _, typ := range []string{"bnk", "wem"} {
+		if indexes, _ := session.src.indexesOf(typ); containsID(indexes, id) {
+			if err := session.Remove(typ, id); err != nil {
+				return err
+			}
+			removed = true
 		}
-		if err != nil {
-			return written, fmt.Errorf("writing bnk ID %d: %w", idx.ID, err)
-		}
-		written += n
 	}
+	if !removed {
+		return fmt.Errorf("removing ID %d: no such entry", id)
+	}
+	return nil
+}
 
-	// WEMs
-	for i, idx := range pckFile.WemIndexes {
-		var n int64
-		var err error
-		if r, ok := replacementMap["wem"][idx.ID]; ok {
-			// Write replacement data
-			nW, errWrite := outFile.Write(r.Data)
-			n = int64(nW)
-			err = errWrite
-		} else {
-			// Copy original data
-			pckFile.reader.Seek(int64(idx.Offset), io.SeekStart)
-			n, err = io.CopyN(outFile, pckFile.reader, int64(newWemIndexes[i].Length))
+// remapID remaps the BNK and WEM entries with the given ID in session to the
+// ID to. It is an error for neither kind of entry to have the ID.
+func remapID(session *Session, id, to uint32) error {
+	remapped := false
+	for _, typ := range []string{"bnk", "wem"} {
+		if indexes, _ := session.src.indexesOf(typ); containsID(indexes, id) {
+			if err := session.Remap(typ, id, to); err != nil {
+				return fmt.Errorf("remapping ID %d: %w", id, err)
+			}
+			remapped = true
 		}
-		if err != nil {
-			return written, fmt.Errorf("writing wem ID %d: %w", idx.ID, err)
-		}
-		written += n
 	}
-
-	return written, nil
+	if !remapped {
+		return fmt.Errorf("remapping ID %d: no such entry", id)
+	}
+	return nil
 }
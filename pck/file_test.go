@@ -0,0 +1,317 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// writeTestPackage writes data to a file of the test and returns its path.
+func writeTestPackage(t *testing.T, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.pck")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRepackRemoveIDs(t *testing.T) {
+	data := buildPackage(testBnks, testWems)
+	path := writeTestPackage(t, data)
+	out := filepath.Join(t.TempDir(), "removed.pck")
+	if _, err := Repack(path, out, nil, RemoveIDs(1, 2)); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[uint32][]byte{3: testWems[1]}
+	if got := wemData(t, f); len(f.Bnks) != 0 || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("%d bnks and wems %q are left, want none and %q", len(f.Bnks), got, want)
+	}
+	if fi, err := os.Stat(out); err != nil || fi.Size() >= int64(len(data)) {
+		t.Errorf("the package grew from %d bytes (%v)", len(data), err)
+	}
+	f.Close()
+
+	if _, err := Repack(path, out, nil, RemoveIDs(12345)); err == nil {
+		t.Error("removed an ID the package does not hold")
+	}
+}
+
+func TestRepackStreamsReplacementFiles(t *testing.T) {
+	entries := testEntries()
+	path := writeTestPackage(t, buildPackage(testBnks, testWems))
+	dir := t.TempDir()
+	want := make(map[uint32][]byte)
+	var r []*ReplacementFile
+	for id := range entries["wem"] {
+		data := bytes.Repeat([]byte(fmt.Sprintf("RIFF replaced %d ", id)), 30)
+		file := filepath.Join(dir, fmt.Sprintf("%d.wem", id))
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		want[id] = data
+		r = append(r, &ReplacementFile{ID: id, Path: file, Type: "wem"})
+	}
+	out := filepath.Join(t.TempDir(), "replaced.pck")
+	if _, err := Repack(path, out, r); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := wemData(t, f); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("the wems hold %q, want %q", got, want)
+	}
+	f.Close()
+
+	r = append(r, &ReplacementFile{ID: 1, Path: filepath.Join(dir, "missing.bnk"), Type: "bnk"})
+	if _, err := Repack(path, out, r); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("repacking with a missing replacement file: got %v", err)
+	}
+}
+
+func TestRepackOverwrite(t *testing.T) {
+	data := buildPackage(testBnks, testWems)
+	path := writeTestPackage(t, data)
+	r := []*ReplacementFile{{ID: 2, Data: []byte("RIFF replaced"), Type: "wem"}}
+	if _, err := Repack(path, path, r); !errors.Is(err, ErrOverwrite) {
+		t.Errorf("repacking a package over itself: got %v, want ErrOverwrite", err)
+	}
+	// A repack that fails leaves the output as it was.
+	missing := append(r, &ReplacementFile{ID: 3, Path: filepath.Join(t.TempDir(), "missing.wem"),
+		Type: "wem"})
+	if _, err := Repack(path, path, missing, AllowOverwrite()); err == nil {
+		t.Error("repacked with a missing replacement file")
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, data) {
+		t.Error("the package was changed by repacks that failed")
+	}
+
+	if _, err := Repack(path, path, r, AllowOverwrite()); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if got, _ := mustFind(t, f, "wem", 2).Bytes(); string(got) != "RIFF replaced" {
+		t.Errorf("repacking the package over itself replaced wem ID 2 with %q", got)
+	}
+	if entries, _ := os.ReadDir(filepath.Dir(path)); len(entries) != 1 {
+		t.Errorf("repacking left %d files next to the package", len(entries)-1)
+	}
+}
+
+func TestPreviewRepack(t *testing.T) {
+	path := writeTestPackage(t, buildPackage(testBnks, testWems))
+	file := filepath.Join(t.TempDir(), "2.wem")
+	if err := os.WriteFile(file, bytes.Repeat([]byte("RIFF larger "), 50), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := []*ReplacementFile{{ID: 2, Path: file, Type: "wem"}}
+	opts := []Option{RemoveIDs(3)}
+	l, err := PreviewRepack(path, r, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(t.TempDir(), "repacked.pck")
+	n, err := Repack(path, out, r, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Size != n || l.DataStart != f.dataStart() || !reflect.DeepEqual(l.Header, f.Header) {
+		t.Errorf("previewed a package of %d bytes with data from %d, but repacked %d bytes "+
+			"with data from %d", l.Size, l.DataStart, n, f.dataStart())
+	}
+	for i, tables := range [][2][]*FileIndex{{l.BnkIndexes, f.BnkIndexes}, {l.WemIndexes, f.WemIndexes}} {
+		previewed, written := tables[0], tables[1]
+		if len(previewed) != len(written) {
+			t.Errorf("previewed %d %s entries, want %d", len(previewed), tableNames[i], len(written))
+			continue
+		}
+		for j := range written {
+			if *previewed[j] != *written[j] {
+				t.Errorf("previewed %s entry %d as %+v, want %+v", tableNames[i], j, *previewed[j],
+					*written[j])
+			}
+		}
+	}
+	f.Close()
+
+	r = append(r, &ReplacementFile{ID: 3, Path: filepath.Join(t.TempDir(), "missing.wem"), Type: "wem"})
+	if _, err := PreviewRepack(path, r); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("previewing with a missing replacement file: got %v", err)
+	}
+}
+
+func TestConcurrentReads(t *testing.T) {
+	entries := testEntries()
+	data := buildPackage(testBnks, testWems)
+	path := writeTestPackage(t, data)
+	for name, opts := range map[string][]Option{"uncached": nil, "cached": {WithCache(1 << 20)}} {
+		f, err := Open(path, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wg sync.WaitGroup
+		errs := make(chan error, 4*len(f.Wems)+1)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := new(bytes.Buffer)
+			if _, err := f.WriteTo(buf); err != nil {
+				errs <- err
+			} else if !bytes.Equal(buf.Bytes(), data) {
+				errs <- errors.New("writing the package while reading it wrote other bytes")
+			}
+		}()
+		for _, w := range f.Wems {
+			want := entries["wem"][w.Index.ID]
+			// Each entry is read whole and a byte at a time from its end, by
+			// several goroutines at once.
+			for i := 0; i < 2; i++ {
+				wg.Add(2)
+				go func(w *EmbeddedFile) {
+					defer wg.Done()
+					if got, err := io.ReadAll(w.Open()); err != nil || !bytes.Equal(got, want) {
+						errs <- fmt.Errorf("wem ID %d reads %q (%v)", w.Index.ID, got, err)
+					}
+				}(w)
+				go func(r io.ReaderAt) {
+					defer wg.Done()
+					var b [1]byte
+					for off := len(want) - 1; off >= 0; off-- {
+						if _, err := r.ReadAt(b[:], int64(off)); err != nil || b[0] != want[off] {
+							errs <- fmt.Errorf("byte %d of a wem reads 0x%02X (%v)", off, b[0], err)
+							return
+						}
+					}
+				}(w.Open())
+			}
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			t.Errorf("%s: %v", name, err)
+		}
+		f.Close()
+	}
+}
+
+func TestEntriesCanBeReadAgain(t *testing.T) {
+	entries := testEntries()
+	f, data := openTestPackage(t)
+	defer f.Close()
+	if err := f.UnpackTo(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	for typ, files := range map[string][]*EmbeddedFile{"bnk": f.Bnks, "wem": f.Wems} {
+		for _, e := range files {
+			want := entries[typ][e.Index.ID]
+			for pass := 1; pass <= 2; pass++ {
+				if got, err := io.ReadAll(e.Reader); err != nil || !bytes.Equal(got, want) {
+					t.Errorf("reading %s ID %d from Reader (pass %d) read %q (%v)", typ, e.Index.ID,
+						pass, got, err)
+				}
+				if got, err := io.ReadAll(e.Open()); err != nil || !bytes.Equal(got, want) {
+					t.Errorf("reading %s ID %d from Open (pass %d) read %q (%v)", typ, e.Index.ID,
+						pass, got, err)
+				}
+			}
+		}
+	}
+	// Writing the package reads every entry again.
+	assertWritesBytes(t, f, data)
+}
+
+func TestPatchAppend(t *testing.T) {
+	entries := testEntries()
+	path := writeTestPackage(t, buildPackage(testBnks, testWems))
+	data := bytes.Repeat([]byte{0x5A}, 777)
+	r := []*ReplacementFile{{ID: 3, Data: data, Type: "wem"}}
+	if _, err := PatchAppend(path, r, AllowTypeMismatch(), VerifyOutput()); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Open(path)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	for _, w := range f.Wems {
+		want := entries["wem"][w.Index.ID]
+		if w.Index.ID == 3 {
+			want = data
+		}
+		if got, err := w.Bytes(); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("wem ID %d holds %d bytes (%v), want %d", w.Index.ID, len(got), err, len(want))
+		}
+	}
+	f.Close()
+
+	patched, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	added := []*ReplacementFile{{ID: 1, Data: data, Type: "wem", New: true}}
+	if _, err := PatchAppend(path, added, AllowTypeMismatch()); !errors.Is(err, ErrDoesNotFit) {
+		t.Errorf("adding an entry by appending: got %v, want ErrDoesNotFit", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, patched) {
+		t.Error("the package was written to although the entry could not be added")
+	}
+}
+
+func TestRepackRemapIDsInOrder(t *testing.T) {
+	p := buildTestPackages(t)[0]
+	path := writeTestPackage(t, p.data)
+	out := filepath.Join(t.TempDir(), "remapped.pck")
+	// Of several IDs that cannot be remapped, the smallest is reported.
+	ids := map[uint32]uint32{100: 101, 9: 1, 7: 2, 8: 3, 200: 201}
+	for i := 0; i < 20; i++ {
+		_, err := Repack(path, out, nil, RemapIDs(ids))
+		if err == nil || err.Error() != "remapping ID 7: no such entry" {
+			t.Fatalf("got %v, want an error for ID 7", err)
+		}
+	}
+
+	delete(ids, 7)
+	delete(ids, 8)
+	delete(ids, 9)
+	ids[20] = 10
+	ids[10] = 20
+	if _, err := Repack(path, out, nil, RemapIDs(ids)); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for typ, want := range map[string]map[uint32][]byte{
+		"bnk": {10: p.entries["bnk"][20], 20: p.entries["bnk"][10]},
+		"wem": {101: p.entries["wem"][100], 201: p.entries["wem"][200]},
+	} {
+		for id, data := range want {
+			if got, err := mustFind(t, f, typ, id).Bytes(); err != nil || !bytes.Equal(got, data) {
+				t.Errorf("remapped %s ID %d holds %q (%v), want %q", typ, id, got, err, data)
+			}
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+)
+
+// fingerprints maps the fingerprint of a known package, as returned by
+// File.Fingerprint, to a label describing the game and version it shipped
+// with.
+//
+// Entries are added as packages are reported by users; run the tool with -v
+// to print the fingerprint of a package.
+var fingerprints = map[string]string{}
+
+// RegisterFingerprint records that packages with the given fingerprint belong
+// to the release described by label, e.g. "Sleeping Dogs: Definitive Edition
+// v1.0 sfx". Registering a fingerprint that is already known replaces its
+// label.
+func RegisterFingerprint(fingerprint, label string) {
+	fingerprints[fingerprint] = label
+}
+
+// Fingerprint returns a hex encoded SHA-1 digest of the header and index tables
+// of this File. Since the index tables record the ID, offset and length of
+// every entry, the fingerprint identifies a specific release of a package.
+func (pck *File) Fingerprint() string {
+	h := sha1.New()
+	// Writes to a hash.Hash never fail.
+	writeHeader(h, pck.Format, pck.ByteOrder, pck.Header, pck.indexTables())
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Identify returns the label of the known release this File matches, if its
+// fingerprint has been registered.
+func (pck *File) Identify() (label string, ok bool) {
+	label, ok = fingerprints[pck.Fingerprint()]
+	return
+}
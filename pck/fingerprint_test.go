@@ -0,0 +1,34 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"testing"
+)
+
+func TestFingerprintIdentifiesRelease(t *testing.T) {
+	simple, _ := openTestPackage(t)
+	defer simple.Close()
+	complex, _ := openPackage(t, testBnks, append(testWems, []byte("RIFF")))
+	defer complex.Close()
+
+	fp := simple.Fingerprint()
+	if len(fp) != 40 {
+		t.Errorf("fingerprint %q is not a hex encoded SHA-1 digest", fp)
+	}
+	if fp == complex.Fingerprint() {
+		t.Error("two different packages have the same fingerprint")
+	}
+	if _, ok := simple.Identify(); ok {
+		t.Error("a package whose fingerprint is not registered was identified")
+	}
+
+	defer delete(fingerprints, fp)
+	RegisterFingerprint(fp, "simple v1")
+	RegisterFingerprint(fp, "simple v2")
+	if label, ok := simple.Identify(); !ok || label != "simple v2" {
+		t.Errorf("Identify() = %q, %v, want %q, true", label, ok, "simple v2")
+	}
+	if _, ok := complex.Identify(); ok {
+		t.Error("a package was identified by the fingerprint of another")
+	}
+}
@@ -0,0 +1,452 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// A Format is a variant of the File Package layout. Packages of every format
+// are accessed through the same File API.
+type Format int
+
+const (
+	// FormatHybrid is the special layout used by Sleeping Dogs: Definitive
+	// Edition, with a BNK and a WEM index table of 24 byte entries.
+	FormatHybrid Format = iota
+	// FormatHybrid64 is the variant of FormatHybrid used by packages larger
+	// than 4 GB, whose 28 byte index entries hold 64 bit offsets.
+	FormatHybrid64
+	// FormatStandard is the layout written by the Wwise authoring tool, with a
+	// banks table and a streamed files table of 20 byte entries, followed by an
+	// externals table of 24 byte entries.
+	FormatStandard
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatHybrid:
+		return "Hybrid BNK/WEM Format"
+	case FormatHybrid64:
+		return "Hybrid BNK/WEM Format, 64-bit Offsets"
+	case FormatStandard:
+		return "Standard AKPK Format"
+	}
+	return fmt.Sprintf("Format(%d)", int(f))
+}
+
+// The size in bytes of the fixed fields at the start of the Unknown header
+// section of a standard package: the package version, followed by the sizes
+// of the language map, the banks table, the streamed files table and the
+// externals table.
+const standardFieldsBytes = 20
+
+// standardHeaderFields are the fixed fields at the start of a standard
+// package, up to and including the sizes of each of the sections that follow
+// them.
+type standardHeaderFields struct {
+	Identifier             [4]byte
+	HeaderAndIndexesLength uint32
+	Version                uint32
+	LanguageMapLength      uint32
+	BnkTableLength         uint32
+	WemTableLength         uint32
+	ExternalTableLength    uint32
+}
+
+// DetectByteOrder determines the byte order of the package stored in r. Since
+// the header and index tables are small, the byte order is the one that reads
+// the smaller HeaderAndIndexesLength.
+func DetectByteOrder(r io.ReaderAt) (binary.ByteOrder, error) {
+	var b [4]byte
+	if _, err := r.ReadAt(b[:], 4); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	if binary.BigEndian.Uint32(b[:]) < binary.LittleEndian.Uint32(b[:]) {
+		return binary.BigEndian, nil
+	}
+	return binary.LittleEndian, nil
+}
+
+// DetectFormat determines the format of the package stored in r, and the size
+// of its Unknown header section, from the section sizes recorded in its header.
+func DetectFormat(r io.ReaderAt) (Format, int, error) {
+	o, err := DetectByteOrder(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	return detectFormat(r, o)
+}
+
+// detectFormat is DetectFormat for a package of a known byte order.
+func detectFormat(r io.ReaderAt, o binary.ByteOrder) (Format, int, error) {
+	format, unknownSize, hybridErr := detectHybrid(r, o)
+	if hybridErr == nil {
+		return format, unknownSize, nil
+	}
+	unknownSize, standardErr := detectStandardUnknownSize(r, o)
+	if standardErr == nil {
+		return FormatStandard, unknownSize, nil
+	}
+	return 0, 0, fmt.Errorf("not a hybrid package (%v) nor a standard package (%v)",
+		hybridErr, standardErr)
+}
+
+// detectStandardUnknownSize is the counterpart of DetectUnknownSize for
+// standard packages.
+func detectStandardUnknownSize(r io.ReaderAt, o binary.ByteOrder) (int, error) {
+	var f standardHeaderFields
+	sr := io.NewSectionReader(r, 0, int64(binary.Size(f)))
+	if err := binary.Read(sr, o, &f); err != nil {
+		return 0, fmt.Errorf("reading header: %w", err)
+	}
+
+	unknownSize := int64(standardFieldsBytes) + int64(f.LanguageMapLength)
+	total := unknownSize + int64(f.BnkTableLength) + int64(f.WemTableLength) +
+		int64(f.ExternalTableLength)
+	if total != int64(f.HeaderAndIndexesLength) {
+		return 0, fmt.Errorf("header section sizes add up to %d bytes, but the header "+
+			"and indexes are %d bytes long", total, f.HeaderAndIndexesLength)
+	}
+	lengths := []uint32{f.BnkTableLength, f.WemTableLength, f.ExternalTableLength}
+	for i, c := range FormatStandard.tables() {
+		if lengths[i] < 4 || (lengths[i]-4)%uint32(c.size) != 0 {
+			return 0, fmt.Errorf("an index table of %d bytes does not hold whole "+
+				"%d byte entries", lengths[i], c.size)
+		}
+	}
+	return int(unknownSize), nil
+}
+
+// An entryCodec converts the index entries of one table of a format to and
+// from FileIndex, using the byte order of the package. Whatever the format,
+// FileIndex.Offset always holds the absolute offset of an entry's data.
+type entryCodec struct {
+	// The size in bytes of a single entry.
+	size   int
+	decode func(b []byte, o binary.ByteOrder) *FileIndex
+	encode func(b []byte, o binary.ByteOrder, idx *FileIndex)
+	// maxOffset returns the largest offset of the data of idx an entry can
+	// hold.
+	maxOffset func(idx *FileIndex) uint64
+}
+
+// overflowHint suggests how to write a package of format f whose entries do not
+// fit in its index entries, see ErrOffsetOverflow.
+func (f Format) overflowHint() string {
+	split := "split it into volumes that each fit"
+	switch f {
+	case FormatHybrid:
+		return "the 64-bit variant of the format can hold it, if the game reads it, " +
+			"otherwise " + split
+	case FormatStandard:
+		return "standard packages count offsets in blocks of the block size of each " +
+			"entry, so larger block sizes can hold it, otherwise " + split
+	}
+	return split
+}
+
+// tables returns the codecs of the index tables of a package of format f, in
+// the order the tables are stored. The first table holds BNK entries and the
+// second WEM entries.
+func (f Format) tables() []entryCodec {
+	switch f {
+	case FormatStandard:
+		return []entryCodec{standardEntry, standardEntry, externalEntry}
+	case FormatHybrid64:
+		return []entryCodec{hybrid64Entry, hybrid64Entry}
+	}
+	return []entryCodec{hybridEntry, hybridEntry}
+}
+
+// hybridEntry is stored exactly as FileIndex is laid out.
+var hybridEntry = entryCodec{
+	size: indexEntryBytes,
+	decode: func(b []byte, o binary.ByteOrder) *FileIndex {
+		return &FileIndex{
+			ID:       o.Uint32(b[0:]),
+			Type:     o.Uint32(b[4:]),
+			Length:   o.Uint32(b[8:]),
+			Unknown1: o.Uint32(b[12:]),
+			Offset:   uint64(o.Uint32(b[16:])),
+			Unknown2: o.Uint32(b[20:]),
+		}
+	},
+	encode: func(b []byte, o binary.ByteOrder, idx *FileIndex) {
+		o.PutUint32(b[0:], idx.ID)
+		o.PutUint32(b[4:], idx.Type)
+		o.PutUint32(b[8:], idx.Length)
+		o.PutUint32(b[12:], idx.Unknown1)
+		o.PutUint32(b[16:], uint32(idx.Offset))
+		o.PutUint32(b[20:], idx.Unknown2)
+	},
+	maxOffset: func(idx *FileIndex) uint64 { return math.MaxUint32 },
+}
+
+// hybrid64Entry is laid out as hybridEntry, except that the offset is 64 bits
+// wide.
+var hybrid64Entry = entryCodec{
+	size: indexEntry64Bytes,
+	decode: func(b []byte, o binary.ByteOrder) *FileIndex {
+		return &FileIndex{
+			ID:       o.Uint32(b[0:]),
+			Type:     o.Uint32(b[4:]),
+			Length:   o.Uint32(b[8:]),
+			Unknown1: o.Uint32(b[12:]),
+			Offset:   o.Uint64(b[16:]),
+			Unknown2: o.Uint32(b[24:]),
+		}
+	},
+	encode: func(b []byte, o binary.ByteOrder, idx *FileIndex) {
+		o.PutUint32(b[0:], idx.ID)
+		o.PutUint32(b[4:], idx.Type)
+		o.PutUint32(b[8:], idx.Length)
+		o.PutUint32(b[12:], idx.Unknown1)
+		o.PutUint64(b[16:], idx.Offset)
+		o.PutUint32(b[24:], idx.Unknown2)
+	},
+	maxOffset: func(idx *FileIndex) uint64 { return math.MaxUint64 },
+}
+
+// standardEntry is an entry of the banks or streamed files table of a standard
+// package: the file ID, block size, file size, starting block and language ID.
+// The block size is kept in Type and the language ID in Unknown2.
+var standardEntry = entryCodec{
+	size: 20,
+	decode: func(b []byte, o binary.ByteOrder) *FileIndex {
+		idx := &FileIndex{
+			ID:       o.Uint32(b[0:]),
+			Type:     o.Uint32(b[4:]),
+			Length:   o.Uint32(b[8:]),
+			Unknown2: o.Uint32(b[16:]),
+		}
+		idx.Offset = uint64(o.Uint32(b[12:])) * uint64(idx.BlockSize())
+		return idx
+	},
+	encode: func(b []byte, o binary.ByteOrder, idx *FileIndex) {
+		o.PutUint32(b[0:], idx.ID)
+		o.PutUint32(b[4:], idx.Type)
+		o.PutUint32(b[8:], idx.Length)
+		o.PutUint32(b[12:], uint32(idx.Offset/uint64(idx.BlockSize())))
+		o.PutUint32(b[16:], idx.Unknown2)
+	},
+	maxOffset: maxBlockOffset,
+}
+
+// externalEntry is an entry of the externals table of a standard package. It
+// differs from standardEntry only in having a 64 bit ID, whose low half is kept
+// in ID and high half in Unknown1.
+var externalEntry = entryCodec{
+	size: 24,
+	decode: func(b []byte, o binary.ByteOrder) *FileIndex {
+		idx := standardEntry.decode(b[4:], o)
+		id := o.Uint64(b[0:])
+		idx.ID, idx.Unknown1 = uint32(id), uint32(id>>32)
+		return idx
+	},
+	encode: func(b []byte, o binary.ByteOrder, idx *FileIndex) {
+		standardEntry.encode(b[4:], o, idx)
+		o.PutUint64(b[0:], uint64(idx.Unknown1)<<32|uint64(idx.ID))
+	},
+	maxOffset: maxBlockOffset,
+}
+
+// maxBlockOffset returns the largest offset of the data of idx, a standard
+// entry, whose offset is counted in blocks.
+func maxBlockOffset(idx *FileIndex) uint64 {
+	return math.MaxUint32 * uint64(idx.BlockSize())
+}
+
+// BlockSize returns the block size of the entry, kept in Type: the size of the
+// blocks its data is aligned to, so that the stream device of the game can
+// read it in whole blocks. The offsets of standard packages are stored as a
+// number of blocks, see StartBlock, so standard packages larger than 4 GB use
+// block sizes greater than 1. A block size of 0 is treated as 1.
+func (idx *FileIndex) BlockSize() uint32 {
+	if idx.Type == 0 {
+		return 1
+	}
+	return idx.Type
+}
+
+// StartBlock returns the offset of the data of the entry as a number of
+// blocks of its BlockSize, as stored by standard packages.
+func (idx *FileIndex) StartBlock() uint64 {
+	return idx.Offset / uint64(idx.BlockSize())
+}
+
+// LanguageID returns the ID of the language of the entry in the language map
+// of its package, kept in Unknown2, see File.LanguageOf. Entries of the same
+// ID in several languages differ by their language ID.
+func (idx *FileIndex) LanguageID() uint32 {
+	return idx.Unknown2
+}
+
+// ExternalID returns the 64 bit ID of an entry of the externals table of a
+// standard package, whose low half is kept in ID and high half in Unknown1.
+func (idx *FileIndex) ExternalID() uint64 {
+	return uint64(idx.Unknown1)<<32 | uint64(idx.ID)
+}
+
+// hasBlockSize reports whether idx, an entry of a hybrid package, has a block
+// size its data should be aligned to. Hybrid packages store absolute offsets,
+// so the block size only matters for aligning the data, and Type is only taken
+// for one if it is a power of two of a plausible size, see
+// detectBlockAlignment.
+func hasBlockSize(idx *FileIndex) bool {
+	size := idx.BlockSize()
+	return size > 1 && size <= maxDetectedAlignment && size&(size-1) == 0
+}
+
+// alignOffset returns the first offset at or after offset at which the data of
+// idx can be stored in pck: a multiple of the block size of idx, and of the
+// Alignment of pck.
+func (pck *File) alignOffset(offset uint64, idx *FileIndex) uint64 {
+	align := pck.alignment(idx)
+	return (offset + align - 1) / align * align
+}
+
+// alignment returns the alignment of the data of idx in pck, see alignOffset.
+func (pck *File) alignment(idx *FileIndex) uint64 {
+	align := uint64(1)
+	if pck.Alignment > 1 {
+		align = uint64(pck.Alignment)
+	}
+	if pck.Format == FormatStandard || (pck.blockAligned && hasBlockSize(idx)) {
+		align = lcm(align, uint64(idx.BlockSize()))
+	}
+	return align
+}
+
+// detectBlockAlignment reports whether pck, a hybrid package, aligns the data
+// of its entries to their block sizes: whether at least one entry has a block
+// size, see hasBlockSize, and the data of every such entry starts on a
+// multiple of it. Only then are block sizes kept when the package is
+// rewritten, so packages using Type otherwise are not padded.
+func (pck *File) detectBlockAlignment() bool {
+	found := false
+	for _, indexes := range pck.indexTables() {
+		for _, idx := range indexes {
+			if idx.Length == 0 || !hasBlockSize(idx) {
+				continue
+			}
+			if idx.Offset%uint64(idx.BlockSize()) != 0 {
+				return false
+			}
+			found = true
+		}
+	}
+	return found
+}
+
+// The largest alignment detectAlignment reports.
+const maxDetectedAlignment = 1 << 16
+
+// detectAlignment returns the largest power of two, up to
+// maxDetectedAlignment, that the offset of every non-empty entry of pck is a
+// multiple of. Entries starting directly after the index tables are not
+// considered, since the end of the index tables need not be aligned. If fewer
+// than two entries are considered, there is nothing to detect and 1 is
+// returned.
+func (pck *File) detectAlignment() uint32 {
+	align, considered := uint64(maxDetectedAlignment), 0
+	for _, indexes := range pck.indexTables() {
+		for _, idx := range indexes {
+			if idx.Length == 0 || int64(idx.Offset) == pck.dataStart() {
+				continue
+			}
+			considered++
+			for idx.Offset%align != 0 {
+				align /= 2
+			}
+		}
+	}
+	if considered < 2 {
+		return 1
+	}
+	return uint32(align)
+}
+
+// detectDataAlignment returns the largest power of two, up to
+// maxDetectedAlignment, that the offset of the first non-empty entry of pck is
+// a multiple of, or 1 if that entry starts directly after the index tables or
+// pck has no such entry.
+func (pck *File) detectDataAlignment() uint32 {
+	first := int64(-1)
+	for _, indexes := range pck.indexTables() {
+		for _, idx := range indexes {
+			if idx.Length > 0 && (first < 0 || int64(idx.Offset) < first) {
+				first = int64(idx.Offset)
+			}
+		}
+	}
+	if first <= pck.dataStart() {
+		return 1
+	}
+	align := int64(maxDetectedAlignment)
+	for first%align != 0 {
+		align /= 2
+	}
+	return uint32(align)
+}
+
+// lcm returns the least common multiple of a and b.
+func lcm(a, b uint64) uint64 {
+	x, y := a, b
+	for y != 0 {
+		x, y = y, x%y
+	}
+	return a / x * b
+}
+
+// readIndexes reads a table of entries encoded by c, preceded by a count of
+// its entries, from r, where at most size bytes of the header and index tables
+// are left. A count of more entries than fit in size results in an error
+// wrapping ErrFormat, rather than in a huge allocation.
+func readIndexes(r io.Reader, o binary.ByteOrder, c entryCodec, size int64) ([]*FileIndex, error) {
+	var count uint32
+	if err := binary.Read(r, o, &count); err != nil {
+		return nil, fmt.Errorf("reading count: %w", err)
+	}
+	if max := (size - 4) / int64(c.size); int64(count) > max {
+		return nil, fmt.Errorf("%w: %d entries of %d bytes do not fit in the %d bytes left of "+
+			"the header and index tables", ErrFormat, count, c.size, size)
+	}
+	b := make([]byte, c.size)
+	indexes := make([]*FileIndex, 0, count)
+	for i := uint32(0); i < count; i++ {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, fmt.Errorf("reading index %d: %w", i, err)
+		}
+		indexes = append(indexes, c.decode(b, o))
+	}
+	return indexes, nil
+}
+
+// writeIndexes writes a table of entries encoded by c, preceded by a count of
+// its entries, to w.
+func writeIndexes(w io.Writer, o binary.ByteOrder, c entryCodec, indexes []*FileIndex) (int64, error) {
+	written := int64(0)
+	if err := binary.Write(w, o, uint32(len(indexes))); err != nil {
+		return written, err
+	}
+	written += 4
+	b := make([]byte, c.size)
+	for _, idx := range indexes {
+		c.encode(b, o, idx)
+		n, err := w.Write(b)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// indexTableSize returns the size in bytes of a table of n entries encoded by
+// c, including its count.
+func indexTableSize(c entryCodec, n int) uint32 {
+	return uint32(4 + n*c.size)
+}
@@ -0,0 +1,298 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// A testPackage is a small package of one of the supported layouts, built in
+// memory by buildTestPackages.
+type testPackage struct {
+	name   string
+	format Format
+	order  binary.ByteOrder
+	data   []byte
+	// The data of the entries, by type and ID.
+	entries map[string]map[uint32][]byte
+}
+
+// testLayouts are the layouts of the packages built by buildTestPackages, one
+// for each Format and byte order.
+var testLayouts = []*Profile{
+	{Name: "hybrid", EntrySize: indexEntryBytes},
+	{Name: "hybrid big endian", EntrySize: indexEntryBytes, ByteOrder: binary.BigEndian},
+	{Name: "hybrid 64-bit", EntrySize: indexEntry64Bytes, Alignment: 16},
+	{Name: "standard", EntrySize: standardEntry.size},
+	{Name: "standard big endian", EntrySize: standardEntry.size, ByteOrder: binary.BigEndian,
+		Alignment: 2048},
+}
+
+// buildTestPackages returns a package of every layout of testLayouts, each
+// holding two SoundBanks and five wems of different sizes.
+func buildTestPackages(t *testing.T) []*testPackage {
+	t.Helper()
+	var pkgs []*testPackage
+	for _, p := range testLayouts {
+		format, err := p.format()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pkg := &testPackage{
+			name:    p.Name,
+			format:  format,
+			order:   p.byteOrder(),
+			entries: map[string]map[uint32][]byte{"bnk": {}, "wem": {}},
+		}
+		b := NewBuilder(WithProfile(p))
+		for i, id := range []uint32{20, 10} {
+			data := []byte(fmt.Sprintf("BKHD bank %d of the %s package", i, p.Name))
+			pkg.entries["bnk"][id] = data
+			if err := b.AddBnk(id, bytes.NewReader(data), int64(len(data))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for i, id := range []uint32{300, 100, 0xFFFFFFFE, 200, 400} {
+			data := append([]byte("RIFF"), bytes.Repeat([]byte{byte(id), byte(i)}, 40*i+3)...)
+			pkg.entries["wem"][id] = data
+			if err := b.AddWem(id, bytes.NewReader(data), int64(len(data))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		buf := new(bytes.Buffer)
+		if _, err := b.WriteTo(buf); err != nil {
+			t.Fatalf("building the %s package: %v", p.Name, err)
+		}
+		pkg.data = buf.Bytes()
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs
+}
+
+// open opens the package in memory.
+func (p *testPackage) open(t *testing.T, opts ...Option) *File {
+	t.Helper()
+	f, err := OpenReader(bytes.NewReader(p.data), int64(len(p.data)), opts...)
+	if err != nil {
+		t.Fatalf("%s: %v", p.name, err)
+	}
+	return f
+}
+
+// mustFind returns the entry of f of type typ with the given ID.
+func mustFind(t *testing.T, f *File, typ string, id uint32) *EmbeddedFile {
+	t.Helper()
+	for _, e := range map[string][]*EmbeddedFile{"bnk": f.Bnks, "wem": f.Wems}[typ] {
+		if e.Index.ID == id {
+			return e
+		}
+	}
+	t.Fatalf("no %s entry with ID %d", typ, id)
+	return nil
+}
+
+// entryData returns the data of the entry idx describes in the package stored
+// in data.
+func entryData(data []byte, idx *FileIndex) []byte {
+	return data[idx.Offset : idx.Offset+uint64(idx.Length)]
+}
+
+func TestDetectUnknownSize(t *testing.T) {
+	for _, wems := range [][][]byte{nil, testWems} {
+		data := buildPackage(testBnks, wems)
+		if got, err := DetectUnknownSize(bytes.NewReader(data)); err != nil || got != testUnknownSize {
+			t.Errorf("DetectUnknownSize() = %d, %v, want %d", got, err, testUnknownSize)
+		}
+	}
+}
+
+func TestDetectUnknownSizeRejectsInconsistentHeader(t *testing.T) {
+	data := buildPackage(testBnks, testWems)
+	// Claim one more byte of header and indexes than the sections hold.
+	n := binary.LittleEndian.Uint32(data[4:])
+	binary.LittleEndian.PutUint32(data[4:], n+1)
+	if size, err := DetectUnknownSize(bytes.NewReader(data)); err == nil {
+		t.Errorf("a header whose sizes do not add up was detected to have %d unknown bytes", size)
+	}
+
+	data = buildPackage(testBnks, testWems)
+	// Move a byte from the WEM table to the BNK table, so that the sizes
+	// still add up but neither table holds whole entries.
+	binary.LittleEndian.PutUint32(data[16:], uint32(4+24*len(testBnks)+1))
+	binary.LittleEndian.PutUint32(data[20:], uint32(4+24*len(testWems)-1))
+	if size, err := DetectUnknownSize(bytes.NewReader(data)); err == nil {
+		t.Errorf("index tables of partial entries were detected to have %d unknown bytes", size)
+	}
+
+	if _, err := DetectUnknownSize(bytes.NewReader([]byte("AKPK"))); err == nil {
+		t.Error("a header cut short was detected")
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	for _, p := range buildTestPackages(t) {
+		r := bytes.NewReader(p.data)
+		order, err := DetectByteOrder(r)
+		if err != nil || order != p.order {
+			t.Errorf("%s: DetectByteOrder() = %v, %v, want %v", p.name, order, err, p.order)
+		}
+		format, unknownSize, err := DetectFormat(r)
+		if err != nil || format != p.format {
+			t.Errorf("%s: DetectFormat() = %v, %v, want %v", p.name, format, err, p.format)
+			continue
+		}
+
+		f := p.open(t)
+		if f.Format != p.format || f.ByteOrder != p.order {
+			t.Errorf("%s: opened as a %s package in %v", p.name, f.Format, f.ByteOrder)
+		}
+		if unknownSize != len(f.Header.Unknown) {
+			t.Errorf("%s: detected an Unknown section of %d bytes, want %d", p.name,
+				unknownSize, len(f.Header.Unknown))
+		}
+		for typ, files := range map[string][]*EmbeddedFile{"bnk": f.Bnks, "wem": f.Wems} {
+			if len(files) != len(p.entries[typ]) {
+				t.Errorf("%s: read %d %s entries, want %d", p.name, len(files), typ, len(p.entries[typ]))
+			}
+			for _, e := range files {
+				if got, err := e.Bytes(); err != nil || !bytes.Equal(got, p.entries[typ][e.Index.ID]) {
+					t.Errorf("%s: %s ID %d holds %q (%v)", p.name, typ, e.Index.ID, got, err)
+				}
+			}
+		}
+		assertWritesBytes(t, f, p.data)
+		f.Close()
+	}
+}
+
+func TestDetectFormatRejectsInconsistentHeader(t *testing.T) {
+	for _, p := range buildTestPackages(t) {
+		data := append([]byte(nil), p.data...)
+		// Claim one more byte of header and indexes than the sections hold.
+		n := p.order.Uint32(data[4:])
+		p.order.PutUint32(data[4:], n+1)
+		if format, _, err := DetectFormat(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: a header whose sizes do not add up was detected as %s", p.name, format)
+		}
+	}
+	if _, _, err := DetectFormat(bytes.NewReader([]byte("AKPK"))); err == nil {
+		t.Error("a header cut short was detected")
+	}
+}
+
+func TestReadIndexesRejectsImpossibleCount(t *testing.T) {
+	for _, count := range []uint32{3, math.MaxUint32} {
+		data := buildPackage(testBnks, testWems)
+		// Count more wems than the rest of the header and index tables hold,
+		// opening the package with its layout given, as profiles do, since
+		// detecting it would already fail.
+		binary.LittleEndian.PutUint32(data[wemIndexPos(0)-4:], count)
+		r := memReader{bytes.NewReader(data)}
+		_, err := newFile(r, FormatHybrid, binary.LittleEndian, testUnknownSize)
+		if !errors.Is(err, ErrFormat) {
+			t.Errorf("opening a package counting %d wems: got %v, want ErrFormat", count, err)
+		}
+	}
+}
+
+func TestEntryCodecsRoundTrip(t *testing.T) {
+	idx := &FileIndex{ID: 0x01020304, Type: 2048, Length: 5000, Unknown1: 7, Offset: 2048 * 9,
+		Unknown2: 3}
+	codecs := map[string]entryCodec{"hybrid": hybridEntry, "hybrid 64-bit": hybrid64Entry,
+		"standard": standardEntry, "external": externalEntry}
+	for name, c := range codecs {
+		for _, o := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			want := *idx
+			if c.size == standardEntry.size {
+				// Only external entries have a 64 bit ID.
+				want.Unknown1 = 0
+			}
+			b := make([]byte, c.size)
+			c.encode(b, o, &want)
+			if got := c.decode(b, o); *got != want {
+				t.Errorf("%s entry in %v: decoded %+v, want %+v", name, o, *got, want)
+			}
+		}
+	}
+
+	big := &FileIndex{Type: 2048, Offset: 1 << 40}
+	if hybridEntry.maxOffset(big) >= big.Offset || hybrid64Entry.maxOffset(big) < big.Offset ||
+		standardEntry.maxOffset(big) < big.Offset {
+		t.Error("the largest offsets of the entry codecs are wrong")
+	}
+}
+
+func TestFileIndexFields(t *testing.T) {
+	idx := &FileIndex{ID: 0x01020304, Type: 2048, Length: 10, Unknown1: 7, Offset: 2048 * 9, Unknown2: 3}
+	if idx.BlockSize() != 2048 || idx.StartBlock() != 9 || idx.LanguageID() != 3 ||
+		idx.ExternalID() != 0x0000000701020304 {
+		t.Errorf("the fields of %+v are decoded as block size %d, start block %d, language %d and "+
+			"external ID 0x%X", *idx, idx.BlockSize(), idx.StartBlock(), idx.LanguageID(), idx.ExternalID())
+	}
+	// A block size of 0 is counted as 1.
+	if idx := (&FileIndex{Offset: 17}); idx.BlockSize() != 1 || idx.StartBlock() != 17 {
+		t.Errorf("an entry without a block size has block size %d and start block %d", idx.BlockSize(),
+			idx.StartBlock())
+	}
+
+	f := openMemory(t, withDefaultLanguage(editWemIndex(1, func(idx *FileIndex) { idx.Unknown2 = 7 })))
+	defer f.Close()
+	if name, ok := f.LanguageOf(mustFind(t, f, "wem", 2).Index); !ok || name != defaultLanguage {
+		t.Errorf("the language of wem ID 2 is %q (%v)", name, ok)
+	}
+	if name, ok := f.LanguageOf(mustFind(t, f, "wem", 3).Index); ok {
+		t.Errorf("the language ID 7 is found as %q", name)
+	}
+	if dir := f.languageDir(mustFind(t, f, "wem", 3).Index); dir != "language_7" {
+		t.Errorf("wem ID 3 is unpacked to %q", dir)
+	}
+}
+
+func TestBlockAlignment(t *testing.T) {
+	entries := testEntries()
+	f, _ := openTestPackage(t)
+	offset := mustFind(t, f, "wem", 3).Index.Offset
+	f.Close()
+	// The largest block size the data of wem ID 3 is already aligned to.
+	size := uint32(offset & -offset)
+	if size > maxDetectedAlignment {
+		size = maxDetectedAlignment
+	}
+
+	// Hybrid packages store absolute offsets, so an entry can claim a block
+	// size its data is not aligned to. The data of such a package is not
+	// aligned to block sizes when it is rewritten.
+	for _, blockSize := range []uint32{size, 2 * size} {
+		aligned := blockSize == size
+		f := openMemory(t, editWemIndex(1, func(idx *FileIndex) { idx.Type = blockSize }))
+		if got := mustFind(t, f, "wem", 3).Index; got.BlockSize() != blockSize || got.Offset != offset {
+			t.Fatalf("wem ID 3 is read as %+v", *got)
+		}
+		if f.blockAligned != aligned {
+			t.Errorf("detected block alignment %v for a block size of %d at offset %d", f.blockAligned,
+				blockSize, offset)
+		}
+		// Growing the wem stored before wem ID 3 moves its data.
+		longer := append(append([]byte(nil), entries["wem"][2]...), 0)
+		s := f.NewSession()
+		if err := s.Replace("wem", 2, bytes.NewReader(longer), int64(len(longer))); err != nil {
+			t.Fatal(err)
+		}
+		rewritten, _ := writeSession(t, s)
+		idx := mustFind(t, rewritten, "wem", 3).Index
+		if idx.Type != blockSize {
+			t.Errorf("the block size of wem ID 3 is rewritten as %d, want %d", idx.Type, blockSize)
+		}
+		if aligned && idx.Offset%uint64(blockSize) != 0 {
+			t.Errorf("wem ID 3 of block size %d is rewritten at offset %d", blockSize, idx.Offset)
+		}
+		want := map[string]map[uint32][]byte{"bnk": entries["bnk"], "wem": {2: longer, 3: entries["wem"][3]}}
+		assertHolds(t, "the rewritten package", rewritten, want)
+		rewritten.Close()
+		f.Close()
+	}
+}
@@ -0,0 +1,137 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// Open opens the named file of the package, implementing fs.FS. The package
+// is presented as a read-only file system holding a bnk and a wem directory,
+// which hold the BNK and WEM entries named as EmbeddedFile.Name, e.g.
+// "wem/456.wem". This lets fs.WalkDir, fs.ReadFile and other standard tooling
+// be used on a package without unpacking it. Should an ID appear more than
+// once in a table, only its first entry is present.
+func (pck *File) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	if name == "." {
+		return &fsDir{info: dirInfo("."), entries: []fs.DirEntry{
+			fs.FileInfoToDirEntry(dirInfo("bnk")),
+			fs.FileInfoToDirEntry(dirInfo("wem")),
+		}}, nil
+	}
+
+	dir, base := path.Split(name)
+	files, ok := pck.fsTable(path.Clean(dir))
+	if dir == "" {
+		// A table directory.
+		if files, ok = pck.fsTable(base); ok {
+			return &fsDir{info: dirInfo(base), entries: fsEntries(files)}, nil
+		}
+	} else if ok {
+		for _, f := range files {
+			if f.Name == base {
+				sr := f.Open()
+				return &fsFile{fileInfo{base, sr.Size(), false}, sr}, nil
+			}
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+// fsTable returns the embedded files in the directory of the package file
+// system with the given name.
+func (pck *File) fsTable(name string) ([]*EmbeddedFile, bool) {
+	switch name {
+	case "bnk":
+		return pck.Bnks, true
+	case "wem":
+		return pck.Wems, true
+	}
+	return nil, false
+}
+
+// fsEntries returns the directory entries of files, sorted by name as
+// fs.ReadDir requires, without the later entries of duplicated names.
+func fsEntries(files []*EmbeddedFile) []fs.DirEntry {
+	seen := make(map[string]bool)
+	var entries []fs.DirEntry
+	for _, f := range files {
+		if seen[f.Name] {
+			continue
+		}
+		seen[f.Name] = true
+		entries = append(entries, fs.FileInfoToDirEntry(fileInfo{f.Name, int64(f.Index.Length), false}))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries
+}
+
+// fileInfo describes a file or directory of the package file system.
+type fileInfo struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func dirInfo(name string) fileInfo { return fileInfo{name, 0, true} }
+
+func (fi fileInfo) Name() string       { return fi.name }
+func (fi fileInfo) Size() int64        { return fi.size }
+func (fi fileInfo) ModTime() time.Time { return time.Time{} }
+func (fi fileInfo) IsDir() bool        { return fi.dir }
+func (fi fileInfo) Sys() interface{}   { return nil }
+
+func (fi fileInfo) Mode() fs.FileMode {
+	if fi.dir {
+		return fs.ModeDir | 0555
+	}
+	return 0444
+}
+
+// fsFile is an open entry of the package file system.
+type fsFile struct {
+	info fileInfo
+	*io.SectionReader
+}
+
+func (f *fsFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *fsFile) Close() error               { return nil }
+
+// fsDir is an open directory of the package file system.
+type fsDir struct {
+	info    fileInfo
+	entries []fs.DirEntry
+	// The number of entries already returned by ReadDir.
+	offset int
+}
+
+func (d *fsDir) Stat() (fs.FileInfo, error) { return d.info, nil }
+func (d *fsDir) Close() error               { return nil }
+
+func (d *fsDir) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.info.name, Err: errors.New("is a directory")}
+}
+
+// ReadDir implements fs.ReadDirFile.
+func (d *fsDir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entries[d.offset:]
+	if n <= 0 {
+		d.offset = len(d.entries)
+		return rest, nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	if n > len(rest) {
+		n = len(rest)
+	}
+	d.offset += n
+	return rest[:n], nil
+}
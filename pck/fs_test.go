@@ -0,0 +1,41 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFileSystem(t *testing.T) {
+	entries := testEntries()
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	var names []string
+	for typ, ids := range entries {
+		for id := range ids {
+			names = append(names, fmt.Sprintf("%s/%d.%s", typ, id, typ))
+		}
+	}
+	if err := fstest.TestFS(f, names...); err != nil {
+		t.Error(err)
+	}
+	for typ, ids := range entries {
+		for id, want := range ids {
+			name := fmt.Sprintf("%s/%d.%s", typ, id, typ)
+			if got, err := fs.ReadFile(f, name); err != nil || !bytes.Equal(got, want) {
+				t.Errorf("%s holds %q (%v)", name, got, err)
+			}
+		}
+	}
+	for _, name := range []string{"wem/1.wem", "externals", "/wem", "wem/../bnk"} {
+		if _, err := f.Open(name); err == nil {
+			t.Errorf("opened %s", name)
+		} else if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrInvalid) {
+			t.Errorf("opening %s: %v", name, err)
+		}
+	}
+}
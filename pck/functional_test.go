@@ -1,19 +1,15 @@
 // Package pck implements access to the Wwise File Package file format.
 package pck
 
-// Large system tests for the bnk package.
+// Large system tests for the pck package.
 import (
 	"bytes"
+	"encoding/binary"
 	"os"
 	"path/filepath"
 	"testing"
 )
 
-import (
-	"github.com/hpxro7/wwiseutil/util"
-	"github.com/hpxro7/wwiseutil/wwise"
-)
-
 const (
 	testDir = "testdata"
 
@@ -21,98 +17,141 @@ const (
 	complexFilePackage = "complex.pck"
 )
 
-func TestSimpleUnchangedFileIsEqual(t *testing.T) {
-	unchangedFileIsEqual(simpleFilePackage, t)
-}
+// The size of the unknown header field of the packages built by these tests.
+const testUnknownSize = 36
 
-func unchangedFileIsEqual(name string, t *testing.T) {
-	util.SkipIfShort(t)
+// memReader adapts an in-memory package to the reader expected by NewFile.
+type memReader struct {
+	*bytes.Reader
+}
 
-	f, err := os.Open(filepath.Join(testDir, name))
-	if err != nil {
-		t.Error(err)
-		t.FailNow()
+func (memReader) Close() error { return nil }
+
+// buildPackage serializes a package holding bnks and then wems, each entry
+// being indexed by its position starting from 1.
+func buildPackage(bnks, wems [][]byte) []byte {
+	indexed := testUnknownSize + 4 + 24*len(bnks) + 4 + 24*len(wems)
+	hdr := new(bytes.Buffer)
+	hdr.WriteString("AKPK")
+	binary.Write(hdr, binary.LittleEndian, uint32(indexed))
+	// The version and section sizes, followed by an empty language map.
+	for _, v := range []int{1, testUnknownSize - 16, 4 + 24*len(bnks), 4 + 24*len(wems)} {
+		binary.Write(hdr, binary.LittleEndian, uint32(v))
 	}
-	pck, err := NewFile(f)
-	if err != nil {
-		t.Error(err)
-		t.FailNow()
+	hdr.Write(make([]byte, testUnknownSize-16))
+
+	data := new(bytes.Buffer)
+	offset := 8 + indexed
+	id := uint32(1)
+	for _, table := range [][][]byte{bnks, wems} {
+		binary.Write(hdr, binary.LittleEndian, uint32(len(table)))
+		for _, b := range table {
+			// The ID, type, length, an unknown field, offset and another
+			// unknown field of the entry.
+			for _, v := range []int{int(id), 1, len(b), 0, offset, 0} {
+				binary.Write(hdr, binary.LittleEndian, uint32(v))
+			}
+			data.Write(b)
+			offset += len(b)
+			id++
+		}
 	}
-	wwise.AssertContainerEqualToFile(t, f, pck)
+	return append(hdr.Bytes(), data.Bytes()...)
 }
 
-func TestUnchangedWriteFileTwiceIsEqual(t *testing.T) {
-	util.SkipIfShort(t)
+// withDefaultLanguage returns data, a package serialized by buildPackage, with
+// its empty language map replaced by one holding defaultLanguage, which is
+// just as long.
+func withDefaultLanguage(data []byte) []byte {
+	copy(data[8+16:8+testUnknownSize], languageMap(binary.LittleEndian, defaultLanguage))
+	return data
+}
 
-	f, err := os.Open(filepath.Join(testDir, simpleFilePackage))
-	if err != nil {
-		t.Error(err)
-	}
-	pck, err := NewFile(f)
-	if err != nil {
-		t.Error(err)
-	}
-	wwise.AssertContainerEqualToFile(t, f, pck)
-	f, err = os.Open(filepath.Join(testDir, simpleFilePackage))
-	if err != nil {
-		t.Error(err)
+// The entries of the package returned by openTestPackage.
+var (
+	testBnks = [][]byte{[]byte("BKHD\x18\x00\x00\x00")}
+	testWems = [][]byte{[]byte("RIFF\x00\x10\x00\x00WAVEfmt "), []byte("plain text")}
+)
+
+// testEntries returns the data of the entries of the package returned by
+// openTestPackage, by type and ID.
+func testEntries() map[string]map[uint32][]byte {
+	return map[string]map[uint32][]byte{
+		"bnk": {1: testBnks[0]},
+		"wem": {2: testWems[0], 3: testWems[1]},
 	}
-	wwise.AssertContainerEqualToFile(t, f, pck)
 }
 
-func TestReplaceWemCases(t *testing.T) {
-	util.SkipIfShort(t)
-
-	for _, c := range wwise.ReplacementTestCases {
-		org, err := Open(filepath.Join(testDir, complexFilePackage))
-		if err != nil {
-			t.Error(c.Name+"failed: ", err)
-			t.FailNow()
-		}
+// openTestPackage opens a package holding testBnks and testWems, and returns it
+// along with its serialized form.
+func openTestPackage(t *testing.T) (*File, []byte) {
+	t.Helper()
+	return openPackage(t, testBnks, testWems)
+}
 
-		rs := c.Test.Expand(org)
-		failed := assertReplacedFileCorrectness(t, complexFilePackage, rs...)
-		if failed {
-			t.Error("The", c.Name, "test case has failed.\n")
-		}
-	}
+// openPackage opens the package serialized by buildPackage(bnks, wems).
+func openPackage(t *testing.T, bnks, wems [][]byte) (*File, []byte) {
+	t.Helper()
+	want := buildPackage(bnks, wems)
+	return openMemory(t, want), want
 }
 
-func assertReplacedFileCorrectness(t *testing.T, pckPath string,
-	rs ...*wwise.ReplacementWem) (failed bool) {
-	org, err := Open(filepath.Join(testDir, pckPath))
+// openMemory opens the package stored in data, detecting its layout.
+func openMemory(t *testing.T, data []byte) *File {
+	t.Helper()
+	r := memReader{bytes.NewReader(data)}
+	format, order, unknownSize, err := detectLayout(r, nil)
 	if err != nil {
-		t.Error(err)
-		return true
+		t.Fatal(err)
 	}
-
-	replaced, err := Open(filepath.Join(testDir, pckPath))
+	pck, err := newFile(r, format, order, unknownSize)
 	if err != nil {
-		t.Error(err)
-		return true
+		t.Fatal(err)
 	}
-	replaced.ReplaceWems(rs...)
-	reread := rereadFile(t, replaced)
+	return pck
+}
 
-	failed =
-		wwise.AssertReplacementsConsistent(t, org, replaced, reread, rs...)
-	return
+func TestUnchangedFileIsEqual(t *testing.T) {
+	pck, want := openTestPackage(t)
+	defer pck.Close()
+	assertWritesBytes(t, pck, want)
 }
 
-func rereadFile(t *testing.T, org *File) *File {
-	orgBytes := new(bytes.Buffer)
-	_, err := org.WriteTo(orgBytes)
-	if err != nil {
-		t.Error(err)
-		t.FailNow()
-	}
-	bs := bytes.NewReader(orgBytes.Bytes())
+func TestUnchangedWriteFileTwiceIsEqual(t *testing.T) {
+	pck, want := openTestPackage(t)
+	defer pck.Close()
+	assertWritesBytes(t, pck, want)
+	assertWritesBytes(t, pck, want)
+}
 
-	ctn, err := NewFile(bs)
+// assertWritesBytes checks that pck is written as exactly want.
+func assertWritesBytes(t *testing.T, pck *File, want []byte) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	total, err := pck.WriteTo(buf)
 	if err != nil {
-		t.Error(err)
-		t.FailNow()
+		t.Fatal(err)
+	}
+	if total != int64(buf.Len()) {
+		t.Errorf("%d bytes were actually written, but %d bytes were "+
+			"reported to be written", buf.Len(), total)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("The %d bytes written differ from the %d bytes of the file.",
+			buf.Len(), len(want))
 	}
+}
 
-	return ctn
+func TestUnpackToInfersExtensions(t *testing.T) {
+	pck, _ := openTestPackage(t)
+	defer pck.Close()
+	dir := t.TempDir()
+	if err := pck.UnpackTo(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"bnk/1.bnk", "wem/2.wem", "wem/3.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("UnpackTo did not write %s: %v", name, err)
+		}
+	}
 }
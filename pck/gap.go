@@ -0,0 +1,84 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"io"
+	"sort"
+)
+
+// A Gap is a range of bytes of a package that is not part of the data of any
+// entry, such as alignment padding, slack left after an entry by the tool that
+// built the package, or bytes after the data of the last entry.
+type Gap struct {
+	Offset, Length int64
+	// The index of the entry the gap precedes, or nil for the bytes after the
+	// data of the last entry.
+	Before *FileIndex
+}
+
+// Gaps returns the gaps between the data of the entries of this File, from the
+// end of its index tables to the end of the package, in the order they are
+// stored. Sessions, and so Repack, write the bytes of each gap before the
+// entry it precedes, unless given DropGaps, so that games that hash their
+// packages still accept rewritten ones.
+func (pck *File) Gaps() []*Gap {
+	return pck.gaps
+}
+
+// findGaps returns the gaps of pck, a package of size bytes. Empty entries,
+// and entries starting past the end of the package, hold no data and so are
+// not preceded by a gap.
+func (pck *File) findGaps(size int64) []*Gap {
+	var indexes []*FileIndex
+	for _, table := range pck.indexTables() {
+		for _, idx := range table {
+			if idx.Length > 0 && int64(idx.Offset) <= size {
+				indexes = append(indexes, idx)
+			}
+		}
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return indexes[i].Offset < indexes[j].Offset
+	})
+
+	var gaps []*Gap
+	end := pck.dataStart()
+	for _, idx := range indexes {
+		offset := int64(idx.Offset)
+		if offset > end {
+			gaps = append(gaps, &Gap{Offset: end, Length: offset - end, Before: idx})
+		}
+		// Entries may share or overlap the data of the entries before them.
+		if e := offset + int64(idx.Length); e > end {
+			end = e
+		}
+	}
+	if size > end {
+		gaps = append(gaps, &Gap{Offset: end, Length: size - end})
+	}
+	return gaps
+}
+
+// gapsByEntry returns the gaps of pck by the index of the entry they precede,
+// and the gap after the data of the last entry, if any.
+func (pck *File) gapsByEntry() (map[*FileIndex]*Gap, *Gap) {
+	gaps := make(map[*FileIndex]*Gap)
+	var trailer *Gap
+	for _, g := range pck.gaps {
+		if g.Before == nil {
+			trailer = g
+		} else {
+			gaps[g.Before] = g
+		}
+	}
+	return gaps, trailer
+}
+
+// writeGap writes the bytes of g, a gap of the original File, to w. Nothing is
+// written if g is nil.
+func (s *Session) writeGap(w io.Writer, g *Gap) (int64, error) {
+	if g == nil {
+		return 0, nil
+	}
+	return s.src.copyOriginal(w, g.Offset, g.Offset+g.Length)
+}
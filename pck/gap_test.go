@@ -0,0 +1,67 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestGapsAreKeptWhenRewriting(t *testing.T) {
+	entries := testEntries()
+	// Fill the padding between entries with slack, and add bytes after the
+	// data of the last entry.
+	f, pck := openTestPackage(t)
+	data := append(append([]byte(nil), pck...), "trailing slack"...)
+	inEntry := make([]bool, len(data))
+	for _, files := range [][]*EmbeddedFile{f.Bnks, f.Wems} {
+		for _, e := range files {
+			for i := e.Index.Offset; i < e.Index.Offset+uint64(e.Index.Length); i++ {
+				inEntry[i] = true
+			}
+		}
+	}
+	for i := f.dataStart(); i < int64(len(pck)); i++ {
+		if !inEntry[i] {
+			data[i] = 0xCC
+		}
+	}
+	f.Close()
+
+	f = openMemory(t, data)
+	defer f.Close()
+	gaps := f.Gaps()
+	if len(gaps) == 0 || gaps[len(gaps)-1].Before != nil ||
+		gaps[len(gaps)-1].Length < int64(len("trailing slack")) {
+		t.Fatalf("the gap after the last entry was not found: %+v", gaps)
+	}
+	var total int64
+	for _, g := range gaps {
+		for i := g.Offset; i < g.Offset+g.Length; i++ {
+			if inEntry[i] {
+				t.Fatalf("gap %+v holds the data of an entry", *g)
+			}
+		}
+		total += g.Length
+	}
+
+	_, kept := writeSession(t, f.NewSession())
+	if !bytes.Equal(kept, data) {
+		t.Error("rewriting the package without changes did not keep its gaps")
+	}
+	dropped, written := writeSession(t, f.NewSession(DropGaps()))
+	if int64(len(written)) > int64(len(data))-int64(len("trailing slack")) {
+		t.Errorf("dropping the %d bytes of gaps wrote %d bytes of the %d", total,
+			len(written), len(data))
+	}
+	if bytes.Contains(written, []byte{0xCC}) {
+		t.Error("slack was written although gaps are dropped")
+	}
+	for typ, files := range map[string][]*EmbeddedFile{"bnk": dropped.Bnks, "wem": dropped.Wems} {
+		for _, e := range files {
+			if got, err := e.Bytes(); err != nil || !bytes.Equal(got, entries[typ][e.Index.ID]) {
+				t.Errorf("%s ID %d holds %q (%v)", typ, e.Index.ID, got, err)
+			}
+		}
+	}
+	dropped.Close()
+}
@@ -0,0 +1,147 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sync"
+)
+
+// The checksums of the data of entries computed by EntryChecksums.
+const (
+	ChecksumSHA256 = "sha256"
+	ChecksumMD5    = "md5"
+	// The IEEE CRC-32, as used by zip and PNG.
+	ChecksumCRC32 = "crc32"
+)
+
+// A checksum describes one of the checksums of EntryChecksums.
+type checksum struct {
+	// The heading of its column in index tables.
+	heading string
+	new     func() hash.Hash
+}
+
+var checksums = map[string]*checksum{
+	ChecksumSHA256: {"SHA-256", sha256.New},
+	ChecksumMD5:    {"MD5", md5.New},
+	ChecksumCRC32:  {"CRC32", func() hash.Hash { return crc32.NewIEEE() }},
+}
+
+// lookupChecksum returns the checksum named algorithm.
+func lookupChecksum(algorithm string) (*checksum, error) {
+	c, ok := checksums[algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown checksum %q", algorithm)
+	}
+	return c, nil
+}
+
+// Hash returns the SHA-256 hash of the contents of this file, in hexadecimal.
+// If the package was opened with WithCache and the contents are cached, they
+// are hashed from memory rather than read again.
+func (f *EmbeddedFile) Hash() (string, error) {
+	return f.Checksum(ChecksumSHA256)
+}
+
+// Checksum returns the checksum of the contents of this file computed by
+// algorithm, one of ChecksumSHA256, ChecksumMD5 or ChecksumCRC32, in
+// hexadecimal. Contents cached by WithCache are read from memory, as by Hash.
+func (f *EmbeddedFile) Checksum(algorithm string) (string, error) {
+	c, err := lookupChecksum(algorithm)
+	if err != nil {
+		return "", err
+	}
+	return f.checksum(c)
+}
+
+// checksum returns the checksum c of the contents of f, in hexadecimal.
+func (f *EmbeddedFile) checksum(c *checksum) (string, error) {
+	h := c.new()
+	if _, err := io.Copy(h, f.Open()); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// EntryHashes returns the SHA-256 hashes of the data of every entry of pck,
+// including externals, in hexadecimal and keyed by the index of the entry.
+// The data of entries sharing it is only hashed once, and up to workers
+// entries are hashed at once.
+func (pck *File) EntryHashes(workers int) (map[*FileIndex]string, error) {
+	return pck.EntryChecksums(ChecksumSHA256, workers)
+}
+
+// EntryChecksums is EntryHashes, computing the checksums of the data of the
+// entries by algorithm, one of ChecksumSHA256, ChecksumMD5 or ChecksumCRC32.
+// CRC-32 is much faster to compute than the others, and is enough to tell
+// which entries changed between two versions of a game.
+func (pck *File) EntryChecksums(algorithm string, workers int) (map[*FileIndex]string, error) {
+	c, err := lookupChecksum(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	var files []*EmbeddedFile
+	first := make(map[cacheKey]*EmbeddedFile)
+	for _, fs := range [][]*EmbeddedFile{pck.Bnks, pck.Wems, pck.Externals} {
+		for _, f := range fs {
+			if _, ok := first[f.cacheKey()]; !ok {
+				first[f.cacheKey()] = f
+				files = append(files, f)
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	byData := make(map[cacheKey]string, len(files))
+	err = runWorkers(workers, len(files), func(job int) error {
+		f := files[job]
+		sum, err := f.checksum(c)
+		if err != nil {
+			return fmt.Errorf("hashing %s: %w", f.Name, err)
+		}
+		mu.Lock()
+		byData[f.cacheKey()] = sum
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sums := make(map[*FileIndex]string)
+	for _, fs := range [][]*EmbeddedFile{pck.Bnks, pck.Wems, pck.Externals} {
+		for _, f := range fs {
+			sums[f.Index] = byData[f.cacheKey()]
+		}
+	}
+	return sums, nil
+}
+
+// StringWithHashes returns the description of pck given by String, with a
+// column of the start of the SHA-256 hash of each entry added to the index
+// tables, so that duplicated and changed entries can be spotted in the listing.
+// The hashes are computed as by EntryHashes.
+func (pck *File) StringWithHashes(workers int) (string, error) {
+	return pck.StringWithChecksums(ChecksumSHA256, workers)
+}
+
+// StringWithChecksums is StringWithHashes, with a column of the checksums of
+// the entries computed by algorithm, as by EntryChecksums.
+func (pck *File) StringWithChecksums(algorithm string, workers int) (string, error) {
+	sums, err := pck.EntryChecksums(algorithm, workers)
+	if err != nil {
+		return "", err
+	}
+	c := checksums[algorithm]
+	digits := c.new().Size() * 2
+	if digits > shownHashDigits {
+		digits = shownHashDigits
+	}
+	return pck.describe(&checksumColumn{heading: c.heading, digits: digits, sums: sums}), nil
+}
@@ -0,0 +1,129 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestEntryHashes(t *testing.T) {
+	entries := testEntries()
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	for _, workers := range []int{1, 4} {
+		hashes, err := f.EntryHashes(workers)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(hashes) != len(f.Bnks)+len(f.Wems) {
+			t.Errorf("hashed %d entries, want %d", len(hashes), len(f.Bnks)+len(f.Wems))
+		}
+		for typ, files := range map[string][]*EmbeddedFile{"bnk": f.Bnks, "wem": f.Wems} {
+			for _, e := range files {
+				sum := sha256.Sum256(entries[typ][e.Index.ID])
+				want := hex.EncodeToString(sum[:])
+				if hashes[e.Index] != want {
+					t.Errorf("%s ID %d is hashed to %s, want %s", typ, e.Index.ID,
+						hashes[e.Index], want)
+				}
+				if hash, err := e.Hash(); err != nil || hash != want {
+					t.Errorf("Hash of %s ID %d is %s (%v)", typ, e.Index.ID, hash, err)
+				}
+			}
+		}
+	}
+
+	s, err := f.StringWithHashes(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, _ := f.Wems[0].Hash()
+	if !strings.Contains(s, "SHA-256") || !strings.Contains(s, hash[:8]) {
+		t.Errorf("the listing does not show the hashes:\n%s", s)
+	}
+}
+
+func TestEntryHashesOfSharedData(t *testing.T) {
+	// Wem ID 3 is pointed at the data of wem ID 2, copying the length and
+	// offset fields of its index entry.
+	data := buildPackage(testBnks, testWems)
+	from, to := wemIndexPos(0), wemIndexPos(1)
+	copy(data[to+8:to+20], data[from+8:from+20])
+	r := &countingReaderAt{r: bytes.NewReader(data)}
+	f, err := OpenReader(r, int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	before := atomic.LoadInt64(&r.read)
+	hashes, err := f.EntryHashes(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := hashes[mustFind(t, f, "wem", 3).Index]; h != hashes[mustFind(t, f, "wem", 2).Index] {
+		t.Errorf("entries sharing their data are hashed differently")
+	}
+	var want int64
+	for _, files := range [][]*EmbeddedFile{f.Bnks, f.Wems} {
+		for _, e := range files {
+			if e.Index.ID != 3 {
+				want += int64(e.Index.Length)
+			}
+		}
+	}
+	if read := atomic.LoadInt64(&r.read) - before; read != want {
+		t.Errorf("read %d bytes to hash entries holding %d bytes of data", read, want)
+	}
+}
+
+func TestEntryChecksums(t *testing.T) {
+	entries := testEntries()
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	for algorithm, sum := range map[string]func(data []byte) string{
+		ChecksumMD5: func(data []byte) string {
+			sum := md5.Sum(data)
+			return hex.EncodeToString(sum[:])
+		},
+		ChecksumCRC32: func(data []byte) string {
+			return fmt.Sprintf("%08x", crc32.ChecksumIEEE(data))
+		},
+	} {
+		sums, err := f.EntryChecksums(algorithm, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for typ, files := range map[string][]*EmbeddedFile{"bnk": f.Bnks, "wem": f.Wems} {
+			for _, e := range files {
+				want := sum(entries[typ][e.Index.ID])
+				if sums[e.Index] != want {
+					t.Errorf("the %s of %s ID %d is %s, want %s", algorithm, typ, e.Index.ID,
+						sums[e.Index], want)
+				}
+				if got, err := e.Checksum(algorithm); err != nil || got != want {
+					t.Errorf("Checksum(%q) of %s ID %d is %s (%v)", algorithm, typ,
+						e.Index.ID, got, err)
+				}
+			}
+		}
+	}
+
+	s, err := f.StringWithChecksums(ChecksumCRC32, 1)
+	if err != nil || !strings.Contains(s, "CRC32") {
+		t.Errorf("the listing with CRC-32 checksums is (%v)\n%s", err, s)
+	}
+	if _, err := f.EntryChecksums("sha1", 1); err == nil {
+		t.Error("computed checksums with an unknown algorithm")
+	}
+	if _, err := f.Wems[0].Checksum("sha1"); err == nil {
+		t.Error("computed a checksum with an unknown algorithm")
+	}
+}
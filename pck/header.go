@@ -0,0 +1,159 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// The size in bytes of the fixed fields at the start of the Unknown header
+// section: the package version, followed by the sizes of the language map,
+// the BNK index table and the WEM index table.
+const headerFieldsBytes = 16
+
+// The size in bytes of a single index entry.
+const indexEntryBytes = 24
+
+// The size in bytes of a single index entry holding a 64 bit offset.
+const indexEntry64Bytes = 28
+
+// headerFields are the fixed fields at the start of a package, up to and
+// including the sizes of each of the sections that follow them.
+type headerFields struct {
+	Identifier             [4]byte
+	HeaderAndIndexesLength uint32
+	Version                uint32
+	LanguageMapLength      uint32
+	BnkTableLength         uint32
+	WemTableLength         uint32
+}
+
+// Raw returns the header as it is stored at the start of a package: its
+// identifier, its HeaderAndIndexesLength, in the byte order of the package it
+// was read from, and its Unknown section. Saved from a package of a game whose
+// header is not understood, it can be injected into the packages written for
+// that game with WithRawHeader.
+func (h *Header) Raw() []byte {
+	o := h.order
+	if o == nil {
+		o = binary.LittleEndian
+	}
+	raw := make([]byte, 8, 8+len(h.Unknown))
+	copy(raw, h.Identifier[:])
+	o.PutUint32(raw[4:], h.HeaderAndIndexesLength)
+	return append(raw, h.Unknown...)
+}
+
+// DetectUnknownSize determines the size of the Unknown header section of the
+// package stored in r from the section sizes recorded in its header. The
+// section sizes must add up to HeaderAndIndexesLength and describe whole
+// index tables, otherwise an error is returned.
+func DetectUnknownSize(r io.ReaderAt) (int, error) {
+	o, err := DetectByteOrder(r)
+	if err != nil {
+		return 0, err
+	}
+	_, unknownSize, err := detectHybrid(r, o)
+	return unknownSize, err
+}
+
+// detectHybrid determines the size of the Unknown header section of the hybrid
+// package stored in r, in byte order o, and whether its index entries hold 32
+// or 64 bit offsets. The width of the entries is given by the size of each
+// index table and the count of entries it starts with.
+func detectHybrid(r io.ReaderAt, o binary.ByteOrder) (Format, int, error) {
+	var f headerFields
+	sr := io.NewSectionReader(r, 0, int64(binary.Size(f)))
+	if err := binary.Read(sr, o, &f); err != nil {
+		return 0, 0, fmt.Errorf("reading header: %w", err)
+	}
+
+	unknownSize := int64(headerFieldsBytes) + int64(f.LanguageMapLength)
+	total := unknownSize + int64(f.BnkTableLength) + int64(f.WemTableLength)
+	if total != int64(f.HeaderAndIndexesLength) {
+		return 0, 0, fmt.Errorf("header section sizes add up to %d bytes, but the header "+
+			"and indexes are %d bytes long", total, f.HeaderAndIndexesLength)
+	}
+
+	format := FormatHybrid
+	entrySize := uint32(0)
+	tableOffset := 8 + unknownSize
+	for _, length := range []uint32{f.BnkTableLength, f.WemTableLength} {
+		// Each table starts with a 4 byte count of its entries.
+		var count uint32
+		countReader := io.NewSectionReader(r, tableOffset, 4)
+		if err := binary.Read(countReader, o, &count); err != nil {
+			return 0, 0, fmt.Errorf("reading index table count: %w", err)
+		}
+		tableOffset += int64(length)
+
+		if length < 4 || (count == 0 && length != 4) {
+			return 0, 0, fmt.Errorf("an index table of %d bytes does not hold %d "+
+				"entries", length, count)
+		}
+		if count == 0 {
+			continue
+		}
+		size := (length - 4) / count
+		if size*count != length-4 || (size != indexEntryBytes && size != indexEntry64Bytes) {
+			return 0, 0, fmt.Errorf("an index table of %d bytes does not hold %d "+
+				"whole %d or %d byte entries", length, count, indexEntryBytes, indexEntry64Bytes)
+		}
+		if entrySize != 0 && size != entrySize {
+			return 0, 0, fmt.Errorf("the index tables hold entries of both %d and %d bytes",
+				entrySize, size)
+		}
+		entrySize = size
+		if size == indexEntry64Bytes {
+			format = FormatHybrid64
+		}
+	}
+	return format, int(unknownSize), nil
+}
+
+// HeaderFields are the fields of the Unknown header section of a package,
+// decoded by File.HeaderFields. Header.Unknown still holds the section
+// verbatim, and is what is written back when the package is rewritten.
+type HeaderFields struct {
+	// The version of the package format, 1 in every package seen so far.
+	Version uint32
+	// The sizes in bytes of the sections following the fixed fields. The size
+	// of each index table includes the count of its entries, and only standard
+	// packages have an externals table.
+	LanguageMapLength   uint32
+	BnkTableLength      uint32
+	WemTableLength      uint32
+	ExternalTableLength uint32
+	// The languages of the language map, as in File.Languages.
+	Languages []*Language
+	// The watermark stored in the language map, or nil.
+	Watermark *Watermark
+	// The bytes of the section that could not be decoded: the whole section of
+	// packages whose header does not follow the usual layout, such as those
+	// opened by a Profile.
+	Unrecognized []byte
+}
+
+// HeaderFields decodes the Unknown header section of pck. ok is false if the
+// section does not follow the usual layout, in which case only the
+// Unrecognized field is set.
+func (pck *File) HeaderFields() (fields *HeaderFields, ok bool) {
+	u := pck.Header.Unknown
+	if _, _, ok := languageMapBounds(u, pck.Format, pck.ByteOrder); !ok {
+		return &HeaderFields{Unrecognized: append([]byte(nil), u...)}, false
+	}
+	o := pck.ByteOrder
+	fields = &HeaderFields{
+		Version:           o.Uint32(u[0:]),
+		LanguageMapLength: o.Uint32(u[languageMapLengthOffset:]),
+		BnkTableLength:    o.Uint32(u[tableLengthsOffset:]),
+		WemTableLength:    o.Uint32(u[tableLengthsOffset+4:]),
+		Languages:         pck.Languages,
+	}
+	if pck.Format == FormatStandard {
+		fields.ExternalTableLength = o.Uint32(u[tableLengthsOffset+8:])
+	}
+	fields.Watermark, _ = pck.Watermark()
+	return fields, true
+}
@@ -0,0 +1,44 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestHeaderFields(t *testing.T) {
+	entries := testEntries()
+	f := openMemory(t, withDefaultLanguage(buildPackage(testBnks, testWems)))
+	fields, ok := f.HeaderFields()
+	if !ok {
+		t.Fatal("the header was not decoded")
+	}
+	tables := f.Format.tables()
+	want := [5]uint32{defaultVersion, uint32(len(languageMap(f.ByteOrder, defaultLanguage))),
+		indexTableSize(tables[0], len(entries["bnk"])), indexTableSize(tables[1], len(entries["wem"]))}
+	got := [5]uint32{fields.Version, fields.LanguageMapLength, fields.BnkTableLength,
+		fields.WemTableLength, fields.ExternalTableLength}
+	if got != want || fields.Watermark != nil || fields.Unrecognized != nil {
+		t.Errorf("decoded the header fields %+v, want the version and lengths %v", *fields, want)
+	}
+	if len(fields.Languages) != 1 || *fields.Languages[0] != (Language{0, defaultLanguage}) {
+		t.Errorf("decoded the languages %v", fields.Languages)
+	}
+
+	stamped, _ := writeSession(t, f.NewSession(WithWatermark(NewWatermark("a mod", 3))))
+	if fields, ok := stamped.HeaderFields(); !ok || fields.Watermark == nil || fields.Watermark.Version != 3 ||
+		fields.LanguageMapLength == want[1] {
+		t.Errorf("decoded the header fields of a stamped package as %+v", fields)
+	}
+	stamped.Close()
+	f.Close()
+
+	// A header of another layout is kept whole.
+	unknown := []byte("a header of another game")
+	f = &File{Format: FormatHybrid, ByteOrder: binary.LittleEndian, Header: &Header{Unknown: unknown}}
+	if fields, ok := f.HeaderFields(); ok || !bytes.Equal(fields.Unrecognized, unknown) ||
+		fields.Version != 0 || fields.Languages != nil {
+		t.Errorf("decoded an unknown header as %+v (%v)", fields, ok)
+	}
+}
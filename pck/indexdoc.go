@@ -0,0 +1,133 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// An indexDocument is the header and index tables of a package as a JSON
+// document, see MarshalIndex.
+type indexDocument struct {
+	// The format and byte order of the package, which cannot be changed.
+	Format    string `json:"format"`
+	ByteOrder string `json:"byte_order"`
+	// The fields of the header, with Unknown encoded in base64.
+	Identifier             string `json:"identifier"`
+	HeaderAndIndexesLength uint32 `json:"header_and_indexes_length"`
+	Unknown                []byte `json:"unknown"`
+	// The language map held in Unknown, for reference only.
+	Languages []*Language  `json:"languages,omitempty"`
+	Bnk       []*FileIndex `json:"bnk"`
+	Wem       []*FileIndex `json:"wem"`
+	Externals []*FileIndex `json:"externals,omitempty"`
+}
+
+// MarshalIndex returns the header and index tables of pck as an indented JSON
+// document, so that external tools can inspect them, or edit them and apply
+// them back with UnmarshalIndex. Offsets are absolute, as in FileIndex, and
+// the Unknown field of the header is encoded in base64.
+func (pck *File) MarshalIndex() ([]byte, error) {
+	doc := &indexDocument{
+		Format:                 pck.Format.String(),
+		ByteOrder:              pck.ByteOrder.String(),
+		Identifier:             string(pck.Header.Identifier[:]),
+		HeaderAndIndexesLength: pck.Header.HeaderAndIndexesLength,
+		Unknown:                pck.Header.Unknown,
+		Languages:              pck.Languages,
+		Bnk:                    pck.BnkIndexes,
+		Wem:                    pck.WemIndexes,
+		Externals:              pck.ExternalIndexes,
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// UnmarshalIndex replaces the header and index tables of pck by those of the
+// JSON document data, written by MarshalIndex and possibly edited, so that
+// WriteTo writes the package with the layout the document describes. The data
+// of each entry is kept: entries are matched with the entries of pck by their
+// type and ID, in order for IDs occurring several times, and must keep their
+// length. Entries missing from the document are dropped from the package, and
+// it is an error for the document to hold entries pck does not. The data of the
+// entries must be laid out in the order of the index tables, after the header
+// and index tables, without overlapping; their offsets may be moved, for
+// instance to pad or align them, though the data of an entry of a standard
+// package must start on a multiple of its block size. The document must
+// describe a package of the same format and byte order. The
+// HeaderAndIndexesLength of the document, and the lengths of the index tables
+// recorded in its Unknown field, are updated to match its index tables; the
+// languages of the document are ignored, since the language map is held in the
+// Unknown field. pck is left unchanged if the document cannot be applied, and
+// WriteTo no longer reproduces the original layout once it is, even with
+// Strict.
+func (pck *File) UnmarshalIndex(data []byte) error {
+	doc := new(indexDocument)
+	if err := json.Unmarshal(data, doc); err != nil {
+		return fmt.Errorf("reading index document: %w", err)
+	}
+	if doc.Format != pck.Format.String() || doc.ByteOrder != pck.ByteOrder.String() {
+		return fmt.Errorf("the document describes a %s package in %s, not a %s package in %s",
+			doc.Format, doc.ByteOrder, pck.Format, pck.ByteOrder)
+	}
+	if len(doc.Identifier) != 4 {
+		return fmt.Errorf("the identifier %q is not 4 bytes long", doc.Identifier)
+	}
+	tables := [][]*FileIndex{doc.Bnk, doc.Wem, doc.Externals}
+	hdr := &Header{Unknown: pck.updateTableLengths(doc.Unknown, tables), order: pck.ByteOrder}
+	copy(hdr.Identifier[:], doc.Identifier)
+	hdr.HeaderAndIndexesLength = uint32(len(hdr.Unknown))
+	for i, c := range pck.Format.tables() {
+		hdr.HeaderAndIndexesLength += indexTableSize(c, len(tables[i]))
+	}
+
+	var files [3][]*EmbeddedFile
+	end := uint64(8 + hdr.HeaderAndIndexesLength)
+	for i, indexes := range tables {
+		typ := tableNames[i]
+		if i == 2 && pck.Format != FormatStandard && len(indexes) > 0 {
+			return errors.New("only standard packages have an externals table")
+		}
+		// The entries of pck not yet matched, by ID.
+		unmatched := make(map[uint32][]*EmbeddedFile)
+		for _, f := range [][]*EmbeddedFile{pck.Bnks, pck.Wems, pck.Externals}[i] {
+			unmatched[f.Index.ID] = append(unmatched[f.Index.ID], f)
+		}
+		for _, idx := range indexes {
+			if idx == nil {
+				return fmt.Errorf("the %s table holds a null entry", typ)
+			}
+			candidates := unmatched[idx.ID]
+			if len(candidates) == 0 {
+				return fmt.Errorf("%s ID %d is not in the package", typ, idx.ID)
+			}
+			f := candidates[0]
+			unmatched[idx.ID] = candidates[1:]
+			if idx.Length != f.Index.Length {
+				return fmt.Errorf("%s ID %d is %d bytes long, not %d bytes", typ, idx.ID, f.Index.Length, idx.Length)
+			}
+			if idx.Length > 0 {
+				if idx.Offset < end {
+					return fmt.Errorf("the data of %s ID %d starts at offset %d, before the end of the "+
+						"header or the previous entry at %d", typ, idx.ID, idx.Offset, end)
+				}
+				if pck.Format == FormatStandard && idx.Offset%uint64(idx.BlockSize()) != 0 {
+					return fmt.Errorf("the data of %s ID %d starts at offset %d, which is not a "+
+						"multiple of its block size of %d", typ, idx.ID, idx.Offset, idx.BlockSize())
+				}
+				end = idx.Offset + uint64(idx.Length)
+			}
+			moved := *f
+			moved.Index = idx
+			files[i] = append(files[i], &moved)
+		}
+	}
+
+	pck.Header = hdr
+	pck.BnkIndexes, pck.WemIndexes, pck.ExternalIndexes = doc.Bnk, doc.Wem, doc.Externals
+	pck.Bnks, pck.Wems, pck.Externals = files[0], files[1], files[2]
+	pck.Languages = nil
+	pck.readLanguages()
+	pck.strict = false
+	return nil
+}
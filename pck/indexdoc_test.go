@@ -0,0 +1,82 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// editIndex returns the index document of f, as edited by edit.
+func editIndex(t *testing.T, f *File, edit func(doc *indexDocument)) []byte {
+	t.Helper()
+	data, err := f.MarshalIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := new(indexDocument)
+	if err := json.Unmarshal(data, doc); err != nil {
+		t.Fatal(err)
+	}
+	edit(doc)
+	if data, err = json.Marshal(doc); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestUnmarshalIndex(t *testing.T) {
+	entries := testEntries()
+	f, data := openTestPackage(t)
+	defer f.Close()
+	index, err := f.MarshalIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.UnmarshalIndex(index); err != nil {
+		t.Fatalf("applying the unchanged index: %v", err)
+	}
+	assertWritesBytes(t, f, data)
+
+	for _, tt := range []struct {
+		err  string
+		edit func(doc *indexDocument)
+	}{
+		{"not in the package", func(doc *indexDocument) { doc.Wem[0].ID = 12345 }},
+		{"bytes long", func(doc *indexDocument) { doc.Wem[0].Length++ }},
+		{"before the end", func(doc *indexDocument) { doc.Wem[1].Offset = doc.Wem[0].Offset }},
+		{"not a", func(doc *indexDocument) { doc.ByteOrder = "middle endian" }},
+		{"4 bytes", func(doc *indexDocument) { doc.Identifier = "AKPK2" }},
+	} {
+		err := f.UnmarshalIndex(editIndex(t, f, tt.edit))
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("got %v, want an error about %q", err, tt.err)
+		}
+	}
+	if err := f.UnmarshalIndex([]byte("{")); err == nil {
+		t.Errorf("a malformed document was applied")
+	}
+	// The package is left unchanged by the documents that cannot be applied.
+	assertWritesBytes(t, f, data)
+
+	// Entries left out of the document are dropped.
+	removed := f.WemIndexes[0].ID
+	edited := editIndex(t, f, func(doc *indexDocument) { doc.Wem = doc.Wem[1:] })
+	if err := f.UnmarshalIndex(edited); err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	if _, err := f.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]map[uint32][]byte{"bnk": entries["bnk"], "wem": {}}
+	for id, data := range entries["wem"] {
+		if id != removed {
+			want["wem"][id] = data
+		}
+	}
+	written := openMemory(t, buf.Bytes())
+	assertHolds(t, "the edited index", written, want)
+	written.Close()
+}
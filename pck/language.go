@@ -0,0 +1,169 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+)
+
+// A Language is an entry of the language map of a package. The entries of a
+// package refer to their language by its ID; SoundBanks and wems that are not
+// localised use the language named "sfx".
+type Language struct {
+	ID   uint32 `json:"id"`
+	Name string `json:"name"`
+}
+
+// The offset into the Unknown header section of the length of the language
+// map. It follows the package version.
+const languageMapLengthOffset = 4
+
+// languageMapBounds returns the start and end offsets of the language map in
+// unknown, the Unknown header section of a package of format f in byte order
+// o. ok is false if the header does not record the length of the map.
+func languageMapBounds(unknown []byte, f Format, o binary.ByteOrder) (start, end int, ok bool) {
+	start = headerFieldsBytes
+	if f == FormatStandard {
+		start = standardFieldsBytes
+	}
+	if len(unknown) < start {
+		return 0, 0, false
+	}
+	end = start + int(o.Uint32(unknown[languageMapLengthOffset:]))
+	return start, end, end == len(unknown)
+}
+
+// parseLanguageMap decodes the language map m, stored in byte order o. The map
+// starts with a count of its languages, followed by the offset of the name
+// and the ID of each language, and then the names themselves as null
+// terminated strings. Offsets are relative to the start of the map. Names are
+// UTF-16 on most platforms, and single byte characters on some older ones.
+func parseLanguageMap(m []byte, o binary.ByteOrder) ([]*Language, error) {
+	if len(m) < 4 {
+		return nil, fmt.Errorf("language map of %d bytes is too short", len(m))
+	}
+	count := int(o.Uint32(m))
+	if count < 0 || 4+8*count > len(m) {
+		return nil, fmt.Errorf("language map of %d bytes cannot hold %d languages",
+			len(m), count)
+	}
+	languages := make([]*Language, count)
+	for i := range languages {
+		offset := int(o.Uint32(m[4+8*i:]))
+		if offset < 4+8*count || offset >= len(m) {
+			return nil, fmt.Errorf("language %d: name offset %d is out of bounds", i, offset)
+		}
+		languages[i] = &Language{
+			ID:   o.Uint32(m[8+8*i:]),
+			Name: decodeName(m[offset:], o),
+		}
+	}
+	return languages, nil
+}
+
+// languageMap encodes a language map holding names, whose IDs are their
+// positions in names. The map starts with a count of its languages, followed
+// by the offset of the name and the ID of each language, and then the names
+// themselves as null terminated UTF-16 strings. Offsets are relative to the
+// start of the map, which is padded to a multiple of 4 bytes.
+func languageMap(o binary.ByteOrder, names ...string) []byte {
+	m := make([]byte, 4+8*len(names))
+	o.PutUint32(m[0:], uint32(len(names)))
+	for i, name := range names {
+		o.PutUint32(m[4+8*i:], uint32(len(m)))
+		o.PutUint32(m[8+8*i:], uint32(i))
+		for _, u := range utf16.Encode([]rune(name + "\x00")) {
+			m = append(m, 0, 0)
+			o.PutUint16(m[len(m)-2:], u)
+		}
+	}
+	for len(m)%4 != 0 {
+		m = append(m, 0)
+	}
+	return m
+}
+
+// decodeName decodes the null terminated string at the start of b. The string
+// is taken to be UTF-16 if its first two bytes hold a character below U+0100,
+// as the first character of a language name does. Such strings are usually
+// in byte order o, but some big endian packages store them little endian.
+func decodeName(b []byte, o binary.ByteOrder) string {
+	for _, order := range []binary.ByteOrder{o, otherOrder(o)} {
+		if len(b) < 2 || order.Uint16(b) >= 0x100 {
+			continue
+		}
+		var units []uint16
+		for i := 0; i+1 < len(b); i += 2 {
+			u := order.Uint16(b[i:])
+			if u == 0 {
+				break
+			}
+			units = append(units, u)
+		}
+		return string(utf16.Decode(units))
+	}
+	for i, c := range b {
+		if c == 0 {
+			return string(b[:i])
+		}
+	}
+	return string(b)
+}
+
+// otherOrder returns the byte order opposite to o.
+func otherOrder(o binary.ByteOrder) binary.ByteOrder {
+	if o == binary.BigEndian {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// readLanguages sets the Languages of pck from its language map, if its header
+// records one that can be decoded.
+func (pck *File) readLanguages() {
+	start, end, ok := languageMapBounds(pck.Header.Unknown, pck.Format, pck.ByteOrder)
+	if !ok {
+		return
+	}
+	languages, err := parseLanguageMap(pck.Header.Unknown[start:end], pck.ByteOrder)
+	if err == nil {
+		pck.Languages = languages
+	}
+}
+
+// LanguageOf returns the name of the language of the entry described by idx,
+// whose language ID is given by LanguageID. ok is false if the language map of
+// the package does not hold that ID.
+func (pck *File) LanguageOf(idx *FileIndex) (name string, ok bool) {
+	for _, l := range pck.Languages {
+		if l.ID == idx.LanguageID() {
+			return l.Name, true
+		}
+	}
+	return "", false
+}
+
+// languageDir returns the name of the directory the entry described by idx is
+// unpacked to when splitting a package by language.
+func (pck *File) languageDir(idx *FileIndex) string {
+	if name, ok := pck.LanguageOf(idx); ok && isSafeDirName(name) {
+		return name
+	}
+	return fmt.Sprintf("language_%d", idx.LanguageID())
+}
+
+// isSafeDirName reports whether name can be used as the name of a directory
+// without escaping its parent.
+func isSafeDirName(name string) bool {
+	if name == "" || name == "." || name == ".." {
+		return false
+	}
+	for _, c := range name {
+		switch c {
+		case '/', '\\', ':', 0:
+			return false
+		}
+	}
+	return true
+}
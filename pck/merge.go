@@ -0,0 +1,110 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"fmt"
+	"io"
+)
+
+// Merge writes a package to w that holds the entries of all of sources, such
+// as a game's package and the packages of its DLC. The header, format and
+// externals of the package are those of the first source, and all sources must
+// be of the same format. Entries added from the other sources keep the fields
+// of their indexes; their languages are matched by name to the language map of
+// the first source, and it is an error for a language to be missing from it.
+// Entries with the same type and ID in several sources are resolved by the
+// policy given by OnDuplicate, and reported to WithDuplicateReport; it is an
+// error wrapping ErrDuplicateID for there to be any without OnDuplicate. The
+// options also configure how the package is written, as by NewSession.
+func Merge(w io.Writer, sources []*File, opts ...Option) (int64, error) {
+	if len(sources) == 0 {
+		return 0, fmt.Errorf("no packages to merge")
+	}
+	o := newOptions(opts)
+	base := sources[0]
+	s := base.NewSession(opts...)
+	type key struct {
+		typ string
+		id  uint32
+	}
+	// The package each entry was first found in, and the duplicates found.
+	origins := make(map[key]int)
+	var choices []*DuplicateChoice
+	choiceOf := make(map[key]*DuplicateChoice)
+	for i, src := range sources[1:] {
+		n := i + 2
+		if src.Format != base.Format {
+			return 0, fmt.Errorf("package %d is a %s package, while the first package is a %s package",
+				n, src.Format, base.Format)
+		}
+		if len(src.ExternalIndexes) > 0 {
+			return 0, fmt.Errorf("package %d has an externals table, which cannot be merged", n)
+		}
+		for t, indexes := range [][]*FileIndex{src.BnkIndexes, src.WemIndexes} {
+			typ := tableNames[t]
+			baseIndexes, _ := base.indexesOf(typ)
+			for _, idx := range indexes {
+				k := key{typ, idx.ID}
+				if _, ok := origins[k]; !ok && containsID(baseIndexes, idx.ID) {
+					origins[k] = 1
+				}
+				origin, ok := origins[k]
+				if !ok {
+					origins[k] = n
+					if err := s.mergeEntry(src, typ, idx); err != nil {
+						return 0, fmt.Errorf("merging package %d: %w", n, err)
+					}
+					continue
+				}
+
+				d := choiceOf[k]
+				if d == nil {
+					d = &DuplicateChoice{Type: typ, ID: idx.ID, Sources: []string{fmt.Sprintf("package %d", origin)}}
+					choiceOf[k] = d
+					choices = append(choices, d)
+				}
+				d.Sources = append(d.Sources, fmt.Sprintf("package %d", n))
+				kept, err := keptDuplicate(o.onDuplicate, d)
+				if err != nil {
+					return 0, err
+				}
+				d.Kept = kept
+				if kept == len(d.Sources)-1 {
+					data := io.NewSectionReader(src.reader, int64(idx.Offset), int64(idx.Length))
+					if err := s.replaceStored(typ, idx.ID, data, int64(idx.Length)); err != nil {
+						return 0, fmt.Errorf("merging package %d: %w", n, err)
+					}
+				}
+			}
+		}
+	}
+	for _, d := range choices {
+		o.reportDuplicate(d)
+	}
+	return s.WriteTo(w)
+}
+
+// mergeEntry adds the entry of type typ described by idx, of the package src,
+// to the package written by this session.
+func (s *Session) mergeEntry(src *File, typ string, idx *FileIndex) error {
+	data := io.NewSectionReader(src.reader, int64(idx.Offset), int64(idx.Length))
+	language := idx.LanguageID()
+	if name, ok := src.LanguageOf(idx); ok {
+		found := false
+		for _, l := range s.src.Languages {
+			if l.Name == name {
+				language, found = l.ID, true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("the language %q of %s ID %d is not in the language map of the "+
+				"first package", name, typ, idx.ID)
+		}
+	}
+	if err := s.Add(typ, idx.ID, data, int64(idx.Length)); err != nil {
+		return err
+	}
+	entryType, unknown1 := idx.Type, idx.Unknown1
+	return s.Override(typ, idx.ID, &IndexOverride{Type: &entryType, Unknown1: &unknown1, Unknown2: &language})
+}
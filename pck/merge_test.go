@@ -0,0 +1,89 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+// buildDLC returns a package in the layout of the package returned by
+// openTestPackage holding wem IDs 2 and 4, and the data of its entries. Only
+// wem ID 2 is also held by the package returned by openTestPackage.
+func buildDLC(t *testing.T) (*File, map[string]map[uint32][]byte) {
+	t.Helper()
+	entries := map[string]map[uint32][]byte{
+		"wem": {2: []byte("RIFF dlc wem 2"), 4: []byte("RIFF dlc wem 4")},
+	}
+	full, _ := openPackage(t, [][]byte{[]byte("BKHD dlc bank")},
+		[][]byte{entries["wem"][2], []byte("RIFF dlc wem 3"), entries["wem"][4]})
+	defer full.Close()
+	s := full.NewSession()
+	if err := s.Remove("bnk", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove("wem", 3); err != nil {
+		t.Fatal(err)
+	}
+	dlc, _ := writeSession(t, s)
+	return dlc, entries
+}
+
+func TestMerge(t *testing.T) {
+	entries := testEntries()
+	base, _ := openTestPackage(t)
+	defer base.Close()
+	dlc, dlcEntries := buildDLC(t)
+	defer dlc.Close()
+
+	if _, err := Merge(new(bytes.Buffer), []*File{base, dlc}); !errors.Is(err, ErrDuplicateID) {
+		t.Errorf("merging a duplicated wem without OnDuplicate: got %v", err)
+	}
+	for _, policy := range []DuplicatePolicy{DuplicateKeepFirst, DuplicateKeepLast} {
+		var reports []*DuplicateChoice
+		report := WithDuplicateReport(func(d *DuplicateChoice) { reports = append(reports, d) })
+		buf := new(bytes.Buffer)
+		if _, err := Merge(buf, []*File{base, dlc}, OnDuplicate(policy), report); err != nil {
+			t.Fatalf("merging keeping the %s duplicate: %v", policy, err)
+		}
+
+		want := map[string]map[uint32][]byte{"bnk": {}, "wem": {}}
+		for _, entries := range []map[string]map[uint32][]byte{entries, dlcEntries} {
+			for typ, files := range entries {
+				for id, data := range files {
+					if _, ok := want[typ][id]; !ok || policy == DuplicateKeepLast {
+						want[typ][id] = data
+					}
+				}
+			}
+		}
+		merged := openMemory(t, buf.Bytes())
+		if merged.Format != base.Format || merged.ByteOrder != base.ByteOrder {
+			t.Errorf("merged into a %s package in %v", merged.Format, merged.ByteOrder)
+		}
+		assertHolds(t, "keeping the "+policy.String()+" duplicate", merged, want)
+		merged.Close()
+
+		wantKept := 0
+		if policy == DuplicateKeepLast {
+			wantKept = 1
+		}
+		if len(reports) != 1 || reports[0].ID != 2 || reports[0].Kept != wantKept ||
+			len(reports[0].Sources) != 2 {
+			t.Errorf("reported the duplicates %v", reports)
+		}
+	}
+
+	standard, err := Open(filepath.Join(testDir, simpleFilePackage))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer standard.Close()
+	if _, err := Merge(new(bytes.Buffer), []*File{base, standard}); err == nil {
+		t.Errorf("a %s package was merged into a %s package", standard.Format, base.Format)
+	}
+	if _, err := Merge(new(bytes.Buffer), nil); err == nil {
+		t.Error("no packages were merged")
+	}
+}
@@ -0,0 +1,165 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"encoding/binary"
+	"io"
+	"sort"
+)
+
+// The number of bytes of each entry's data kept by default when a package is
+// minimized. It covers the headers that SniffKind inspects.
+const DefaultMinimizeBytes = 16
+
+// Minimize writes a minimized copy of the package of size bytes stored in r to
+// w, for sharing as a reproduction of a bug without distributing the audio it
+// holds. The header and index tables are copied byte for byte, but only the
+// first keep bytes of the data of each entry are kept, and the offsets and
+// lengths of the entries are updated to match. Entries whose data lies beyond
+// the end of r, and empty entries, are left as they are, since they may be
+// what triggers the bug.
+//
+// If the index tables of the package cannot be read, as when the header is
+// malformed, only the header region is copied. The layout of the package is
+// detected as by Open, unless given by WithByteOrder or WithProfile.
+func Minimize(r io.ReaderAt, size int64, w io.Writer, keep int64, opts ...Option) (int64, error) {
+	o := newOptions(opts)
+	order := o.byteOrder
+	if p := o.profile; p != nil {
+		order = p.byteOrder()
+	}
+	if order == nil {
+		var err error
+		if order, err = DetectByteOrder(r); err != nil {
+			return 0, err
+		}
+	}
+
+	var length [4]byte
+	if _, err := r.ReadAt(length[:], 4); err != nil {
+		return 0, err
+	}
+	headerEnd := 8 + int64(order.Uint32(length[:]))
+	if headerEnd > size {
+		headerEnd = size
+	}
+	header := make([]byte, headerEnd)
+	if _, err := io.ReadFull(io.NewSectionReader(r, 0, headerEnd), header); err != nil {
+		return 0, err
+	}
+
+	var chunks []*minimizedChunk
+	if pck, err := minimizeParse(r, size, o); err == nil {
+		chunks = pck.minimizeEntries(header, size, keep)
+	}
+
+	n, err := w.Write(header)
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+	for _, c := range chunks {
+		var n int64
+		if c.offset < 0 {
+			n, err = writePadding(w, c.length)
+		} else {
+			n, err = io.Copy(w, io.NewSectionReader(r, c.offset, c.length))
+		}
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// A minimizedChunk is a run of data copied into a minimized package.
+type minimizedChunk struct {
+	// The offset and length of the data in the original package. A negative
+	// offset stands for length zero bytes of padding.
+	offset, length int64
+}
+
+// minimizeParse reads the header and index tables of the package of size bytes
+// stored in r, as described by o.
+func minimizeParse(r io.ReaderAt, size int64, o *options) (*File, error) {
+	var format Format
+	var order binary.ByteOrder
+	var unknownSize int
+	var err error
+	if p := o.profile; p != nil {
+		if format, err = p.format(); err != nil {
+			return nil, err
+		}
+		order, unknownSize = p.byteOrder(), p.UnknownSize
+	} else if format, order, unknownSize, err = detectLayout(r, o.byteOrder); err != nil {
+		return nil, err
+	}
+	return newFile(nopCloser{io.NewSectionReader(r, 0, size)}, format, order, unknownSize)
+}
+
+// minimizeEntries updates the index tables in header, the header region of
+// pck, for the data of each entry to be cut to keep bytes and laid out
+// directly after the header region, and returns the chunks of data to copy.
+// The data of entries that share it in pck is still shared.
+func (pck *File) minimizeEntries(header []byte, size, keep int64) []*minimizedChunk {
+	type entry struct {
+		idx *FileIndex
+		// The offset of the entry in header.
+		pos  int
+		code entryCodec
+	}
+	var entries []*entry
+	pos := 8 + len(pck.Header.Unknown)
+	for i, c := range pck.Format.tables() {
+		indexes := pck.indexTables()[i]
+		for j, idx := range indexes {
+			end := int64(idx.Offset) + int64(idx.Length)
+			if idx.Length > 0 && end <= size {
+				entries = append(entries, &entry{idx, pos + 4 + j*c.size, c})
+			}
+		}
+		pos += int(indexTableSize(c, len(indexes)))
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].idx.Offset < entries[j].idx.Offset
+	})
+
+	var chunks []*minimizedChunk
+	type span struct{ offset, length uint64 }
+	placed := make(map[span]uint64)
+	offset := uint64(len(header))
+	pck.Alignment = 1
+	for _, e := range entries {
+		length := int64(e.idx.Length)
+		if length > keep {
+			length = keep
+		}
+		idx := *e.idx
+		s := span{idx.Offset, uint64(idx.Length)}
+		if at, ok := placed[s]; ok {
+			idx.Offset = at
+		} else {
+			idx.Offset = pck.alignOffset(offset, &idx)
+			if idx.Offset > offset {
+				chunks = append(chunks, &minimizedChunk{-1, int64(idx.Offset - offset)})
+			}
+			chunks = append(chunks, &minimizedChunk{int64(s.offset), length})
+			offset = idx.Offset + uint64(length)
+			placed[s] = idx.Offset
+		}
+		idx.Length = uint32(length)
+		if e.pos+e.code.size <= len(header) {
+			e.code.encode(header[e.pos:], pck.ByteOrder, &idx)
+		}
+	}
+	return chunks
+}
+
+// nopCloser is a readerAtSeeker over a section of a package whose Close does
+// nothing.
+type nopCloser struct {
+	*io.SectionReader
+}
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,56 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestMinimize(t *testing.T) {
+	const keep = 6
+	data := buildPackage(testBnks, testWems)
+	buf := new(bytes.Buffer)
+	n, err := Minimize(bytes.NewReader(data), int64(len(data)), buf, keep)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) || n >= int64(len(data)) {
+		t.Errorf("minimized %d bytes to %d, reporting %d", len(data), buf.Len(), n)
+	}
+	want := testEntries()
+	for _, entries := range want {
+		for id, data := range entries {
+			if len(data) > keep {
+				entries[id] = data[:keep]
+			}
+		}
+	}
+	f := openMemory(t, buf.Bytes())
+	defer f.Close()
+	if f.Format != FormatHybrid || f.ByteOrder != binary.LittleEndian {
+		t.Errorf("minimized to a %s package in %v", f.Format, f.ByteOrder)
+	}
+	if got := buf.Bytes()[:8+f.Header.HeaderAndIndexesLength]; len(got) < 12 ||
+		!bytes.Equal(got[:12], data[:12]) {
+		t.Error("the start of the header was not copied")
+	}
+	assertHolds(t, "the minimized package", f, want)
+}
+
+func TestMinimizeMalformedHeader(t *testing.T) {
+	data := buildPackage(testBnks, testWems)
+	tables := 8 + testUnknownSize
+	// An index table that runs past the header region cannot be read, so only
+	// the header region is copied.
+	binary.LittleEndian.PutUint32(data[tables:], 0xFFFFFF)
+	buf := new(bytes.Buffer)
+	_, err := Minimize(bytes.NewReader(data), int64(len(data)), buf, 4, WithByteOrder(binary.LittleEndian))
+	if err != nil {
+		t.Fatal(err)
+	}
+	headerEnd := 8 + binary.LittleEndian.Uint32(data[4:])
+	if !bytes.Equal(buf.Bytes(), data[:headerEnd]) {
+		t.Errorf("minimized to %d bytes, want the %d bytes of the header region", buf.Len(), headerEnd)
+	}
+}
@@ -0,0 +1,47 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"errors"
+	"os"
+)
+
+// errMmapUnsupported is returned by mmap on platforms it is not supported on.
+var errMmapUnsupported = errors.New("memory mapping is not supported on this platform")
+
+// A mappedFile is a readerAtSeeker over the contents of a memory-mapped file.
+type mappedFile struct {
+	*bytes.Reader
+	data []byte
+	f    *os.File
+}
+
+// mapFile maps the contents of f into memory. f is closed along with the
+// returned file, and is left open if mapping fails.
+func mapFile(f *os.File) (*mappedFile, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() == 0 || int64(int(info.Size())) != info.Size() {
+		return nil, errors.New("the file cannot be mapped into memory")
+	}
+	data, err := mmap(f, int(info.Size()))
+	if err != nil {
+		return nil, err
+	}
+	return &mappedFile{bytes.NewReader(data), data, f}, nil
+}
+
+func (m *mappedFile) Close() error {
+	if m.data == nil {
+		return nil
+	}
+	err := munmap(m.data)
+	m.data = nil
+	if cerr := m.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,50 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithMmap(t *testing.T) {
+	entries := testEntries()
+	data := buildPackage(testBnks, testWems)
+	path := writeTestPackage(t, data)
+	f, err := Open(path, WithMmap())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Where mapping is not supported, mmap fails without using its file.
+	m, mapped := f.reader.(*mappedFile)
+	if _, err := mmap(nil, 0); !mapped && !errors.Is(err, errMmapUnsupported) {
+		t.Error("the package was not mapped into memory")
+	}
+	for typ, files := range map[string][]*EmbeddedFile{"bnk": f.Bnks, "wem": f.Wems} {
+		for _, e := range files {
+			if got, err := e.Bytes(); err != nil || string(got) != string(entries[typ][e.Index.ID]) {
+				t.Errorf("%s ID %d holds %q (%v)", typ, e.Index.ID, got, err)
+			}
+		}
+	}
+	assertWritesBytes(t, f, data)
+	if err := f.Close(); err != nil {
+		t.Errorf("closing: %v", err)
+	}
+	if mapped {
+		if err := m.Close(); err != nil {
+			t.Errorf("closing a second time: %v", err)
+		}
+	}
+
+	// An empty file cannot be mapped, so it is opened as usual, and fails to
+	// open as a package.
+	path = filepath.Join(t.TempDir(), "empty.pck")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path, WithMmap()); err == nil {
+		t.Error("an empty file was opened as a package")
+	}
+}
@@ -0,0 +1,555 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+import (
+	"wwiseutil/util"
+)
+
+// An Option configures how a package is opened, unpacked and rebuilt.
+type Option func(*options)
+
+type options struct {
+	// The maximum number of bytes read and written per second, or 0 if I/O is
+	// not throttled.
+	rateLimit int64
+	// The throttle the files unpacked are written through, shared with the
+	// package they are unpacked from, or nil.
+	throttle *util.Throttle
+	// The maximum number of bytes of entry data to cache in memory, or 0 if
+	// entry data is not cached.
+	cacheBytes int64
+	// The byte order of the package, or nil to detect it.
+	byteOrder binary.ByteOrder
+	// The profile describing the layout of the package, or nil to detect it.
+	profile *Profile
+	// The alignment of entry data when the package is rewritten, or 0 to
+	// detect it.
+	alignment uint32
+	// The package whose header Build copies, if any.
+	template *File
+	// The IDs of the entries to operate on, or nil to operate on every entry.
+	ids map[uint32]bool
+	// Whether empty placeholder entries are skipped when unpacking.
+	skipEmpty bool
+	// Whether entries are unpacked into a directory per language.
+	splitLanguages bool
+	// Whether replacements may hold content of the wrong type for their entry.
+	allowTypeMismatch bool
+	// Whether rebuilt packages keep the data start offset of the original.
+	preserveDataStart bool
+	// The alignment of the start of the entry data of rebuilt packages, or 0
+	// to start it directly after the index tables.
+	dataAlignment uint32
+	// Whether rebuilt packages keep the data start alignment of the original.
+	preserveDataAlignment bool
+	// Whether rebuilt packages store entry data in the order of the original.
+	preserveDataOrder bool
+	// Whether rebuilt packages leave out the gaps between entries of the
+	// original.
+	dropGaps bool
+	// Whether rebuilt packages have their BNK and WEM index tables sorted by
+	// ID.
+	sortIndexes bool
+	// Whether Open fails for packages whose index tables are not sorted by ID.
+	requireSorted bool
+	// The transform applied to entry data when a package is written, if any.
+	transform TransformFunc
+	// The watermark stamped into written packages, if any.
+	watermark *Watermark
+	// The IDs of the entries Repack removes.
+	removeIDs []uint32
+	// The IDs the BNK and WEM entries are remapped to by Repack, keyed by
+	// their original IDs.
+	remapIDs map[uint32]uint32
+	// The number of bytes of a stream kept in memory by OpenStream, or 0 for
+	// DefaultSpoolMemory.
+	spoolMemory int64
+	// The number of entries unpacked at once, or 0 or 1 to unpack them one at
+	// a time.
+	workers int
+	// The function reporting the progress of unpacking or writing, if any.
+	progress ProgressFunc
+	// Whether File.WriteTo reproduces the layout of the original package.
+	strict bool
+	// Whether Repack and Patch read the written package back to verify it.
+	verifyOutput bool
+	// Whether Repack may replace the package it repacks.
+	allowOverwrite bool
+	// Whether Open maps the package into memory.
+	mmap bool
+	// The sample rate UnpackTo decodes wems at, or 0 if they are not decoded.
+	decodeRate int
+	// The function reporting the wems UnpackTo could not decode, if any.
+	undecodedReport func(f *EmbeddedFile, err error)
+	// The policy applied to duplicated entries, and whether it was given to
+	// Repack and Patch, see OnDuplicate.
+	onDuplicate       DuplicatePolicy
+	resolveDuplicates bool
+	// The function reporting how duplicates were resolved, if any.
+	duplicateReport func(d *DuplicateChoice)
+	// The cipher the whole package is encrypted with, if any.
+	cipher Cipher
+	// The cipher the data of each entry is encrypted with, if any.
+	entryCipher Cipher
+	// Whether compressed entries are detected and read decompressed.
+	decompress bool
+	// The header written to packages instead of that of the source package,
+	// as returned by Header.Raw, if any.
+	rawHeader []byte
+}
+
+func newOptions(opts []Option) *options {
+	o := new(options)
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithRateLimit limits the rate of I/O on behalf of the package to
+// bytesPerSecond: the data read from the package, the data of the replacements
+// written into it, and the packages and files written from it, all sharing
+// the limit. This lets long extractions and repacks run in the background
+// without saturating the disk. A limit of zero disables throttling.
+func WithRateLimit(bytesPerSecond int64) Option {
+	return func(o *options) {
+		o.rateLimit = bytesPerSecond
+	}
+}
+
+// WithCipher opens a package that is encrypted as a whole with c, such as one
+// obfuscated with NewXORCipher, decrypting it as it is read: every byte of the
+// package, including its header, is encrypted at its offset from the start of
+// the package. Packages written from it, by a Session or WriteTo, are
+// encrypted with c the same way.
+func WithCipher(c Cipher) Option {
+	return func(o *options) {
+		o.cipher = c
+	}
+}
+
+// WithEntryCipher opens a package whose header and index tables are stored as
+// they are, but whose data of each entry is encrypted with c, the key stream
+// starting over at the start of each entry. The data of the entries is
+// decrypted as it is read, and the entries of packages written from it, by a
+// Session or WriteTo, are encrypted with c the same way, including replaced
+// and added entries.
+func WithEntryCipher(c Cipher) Option {
+	return func(o *options) {
+		o.entryCipher = c
+	}
+}
+
+// Decompress detects the entries of a package stored compressed with zlib or
+// as LZ4 frames, as some games do, and reads their data decompressed, so that
+// they are unpacked, extracted and hashed as plain BNK and WEM files. Packages
+// written from it keep the compressed data of the entries, and the data that
+// replaces a compressed entry through Session.Replace is compressed the same
+// way. Since the index tables only record the stored length of an entry, each
+// entry that looks compressed is decompressed once as the package is opened,
+// to check that it is and to find its decompressed length. See
+// EmbeddedFile.Compression.
+func Decompress() Option {
+	return func(o *options) {
+		o.decompress = true
+	}
+}
+
+// WithRawHeader makes sessions, and so Repack, Merge and Build, write the
+// identifier and Unknown section of raw, a header returned by Header.Raw, in
+// place of those of the package they write, so that packages can be built for
+// a game whose header is not understood by copying it from one of its
+// packages. The HeaderAndIndexesLength of raw is replaced by that of the
+// package written, and so are the lengths of the index tables it records if
+// its layout is that of the format of the package written; the rest of raw is
+// written as it is. It is an error for raw to be shorter than 8 bytes.
+func WithRawHeader(raw []byte) Option {
+	return func(o *options) {
+		o.rawHeader = raw
+	}
+}
+
+// WithByteOrder reads the package in byte order o, instead of detecting its
+// byte order from its header.
+func WithByteOrder(o binary.ByteOrder) Option {
+	return func(opts *options) {
+		opts.byteOrder = o
+	}
+}
+
+// WithProfile opens the package using the layout described by p, instead of
+// detecting its layout from its header. Build lays out packages as described
+// by p.
+func WithProfile(p *Profile) Option {
+	return func(opts *options) {
+		opts.profile = p
+	}
+}
+
+// WithAlignment makes the entry data of rewritten packages start on multiples
+// of align bytes, such as a 2048 byte sector, instead of the alignment
+// detected from the original package. An alignment of 1 lays entries
+// back-to-back. Build aligns the entries of built packages likewise.
+func WithAlignment(align uint32) Option {
+	return func(o *options) {
+		o.alignment = align
+	}
+}
+
+// WithTemplate makes Build copy the header, format, byte order and alignment
+// of t, such as a package of the game the built package is meant for.
+func WithTemplate(t *File) Option {
+	return func(o *options) {
+		o.template = t
+	}
+}
+
+// WithCache keeps up to maxBytes of recently read entry data in memory, so that
+// repeated reads of the same entries through EmbeddedFile.Bytes, such as when
+// browsing a package or running several analyses over it, do not read the
+// package again.
+func WithCache(maxBytes int64) Option {
+	return func(o *options) {
+		o.cacheBytes = maxBytes
+	}
+}
+
+// WithIDs restricts unpacking to the entries with the given IDs.
+func WithIDs(ids ...uint32) Option {
+	return func(o *options) {
+		if o.ids == nil {
+			o.ids = make(map[uint32]bool)
+		}
+		for _, id := range ids {
+			o.ids[id] = true
+		}
+	}
+}
+
+// SkipEmpty makes unpacking skip empty placeholder entries instead of
+// extracting them as empty files.
+func SkipEmpty() Option {
+	return func(o *options) {
+		o.skipEmpty = true
+	}
+}
+
+// SplitLanguages makes unpacking place the entries of each language in a
+// subdirectory named after the language, such as "english(us)/wem", instead of
+// directly in the bnk and wem directories. Entries whose language is not in the
+// language map of the package are placed in a directory named after their
+// language ID, such as "language_3".
+func SplitLanguages() Option {
+	return func(o *options) {
+		o.splitLanguages = true
+	}
+}
+
+// AllowTypeMismatch lets Repack use replacement files that look like the wrong
+// type for the entry they replace, such as a SoundBank replacing a wem.
+func AllowTypeMismatch() Option {
+	return func(o *options) {
+		o.allowTypeMismatch = true
+	}
+}
+
+// RemoveIDs makes Repack remove the BNK and WEM entries with the given IDs,
+// stripping unused audio from the package. Every ID must belong to an entry.
+func RemoveIDs(ids ...uint32) Option {
+	return func(o *options) {
+		o.removeIDs = append(o.removeIDs, ids...)
+	}
+}
+
+// RemapIDs makes Repack, Patch and PatchAppend write the BNK and WEM entries
+// whose IDs are keys of ids with the IDs they map to instead, as
+// Session.Remap does. Every key must be the ID of an entry that is not
+// removed; replacement files are matched to entries by their original IDs.
+func RemapIDs(ids map[uint32]uint32) Option {
+	return func(o *options) {
+		if o.remapIDs == nil {
+			o.remapIDs = make(map[uint32]uint32)
+		}
+		for id, to := range ids {
+			o.remapIDs[id] = to
+		}
+	}
+}
+
+// PreserveDataStart makes sessions, and so Repack, keep the header and index
+// region of the original package exactly as long, so that entry data starts at
+// the same offset. Some loaders hard-code that offset. If entries were removed,
+// the region is padded; if the index tables no longer fit in it, writing the
+// package fails.
+func PreserveDataStart() Option {
+	return func(o *options) {
+		o.preserveDataStart = true
+	}
+}
+
+// WithDataAlignment makes sessions, and so Repack and Build, start the entry
+// data of the packages they write on a multiple of align bytes, padding the
+// index tables with zeros, as some games expect the data area to start on a
+// sector boundary. The padding is not part of the header and index region, so
+// the header's length still ends at the index tables. The gap between the
+// index tables and the first entry of the original package, if any, is
+// replaced by the padding rather than kept, see DropGaps. An alignment of 0 or
+// 1 starts the data directly after the index tables.
+func WithDataAlignment(align uint32) Option {
+	return func(o *options) {
+		o.dataAlignment = align
+	}
+}
+
+// PreserveDataAlignment is WithDataAlignment with the data start alignment of
+// the original package, see File.DataAlignment, so that the data of rewritten
+// packages stays aligned as the index tables grow or shrink.
+// WithDataAlignment takes precedence.
+func PreserveDataAlignment() Option {
+	return func(o *options) {
+		o.preserveDataAlignment = true
+	}
+}
+
+// PreserveDataOrder makes sessions, and so Repack, store entry data in the
+// order the original package stores it, which may differ from the order of
+// the index tables, rather than in index order. Engines that stream entries
+// sequentially may rely on related entries being stored close together. Added
+// entries are stored after all others.
+func PreserveDataOrder() Option {
+	return func(o *options) {
+		o.preserveDataOrder = true
+	}
+}
+
+// DropGaps makes sessions, and so Repack, leave out the gaps between the data
+// of the entries of the original package, see File.Gaps, laying entries out
+// only as their alignment requires. This makes rewritten packages smaller,
+// but games that verify the hashes of their packages may reject them.
+func DropGaps() Option {
+	return func(o *options) {
+		o.dropGaps = true
+	}
+}
+
+// SortIndexes makes sessions, and so Repack, sort the BNK and WEM index tables
+// of the packages they write by ID, and entries of standard packages of the
+// same ID by language, for engines that look entries up by binary search.
+// Entry data is stored in the sorted index order or, with PreserveDataOrder,
+// in the order of the original package, with added entries last, so the same
+// inputs always give the same package.
+func SortIndexes() Option {
+	return func(o *options) {
+		o.sortIndexes = true
+	}
+}
+
+// RequireSortedIndexes makes Open fail with an error wrapping ErrUnsorted if
+// the BNK or WEM index table of the package is not sorted by ID, see
+// File.CheckIndexOrder.
+func RequireSortedIndexes() Option {
+	return func(o *options) {
+		o.requireSorted = true
+	}
+}
+
+// WithTransform makes sessions, and so Repack and Build, pass the data of
+// every entry through fn as the package is written. Transformed entries are
+// held in memory until the package is written.
+func WithTransform(fn TransformFunc) Option {
+	return func(o *options) {
+		o.transform = fn
+	}
+}
+
+// WithWatermark makes sessions, and so Repack and Build, stamp w into the
+// packages they write, replacing any watermark the original package had.
+// Writing fails if the header of the package has no language map to hold it.
+func WithWatermark(w *Watermark) Option {
+	return func(o *options) {
+		o.watermark = w
+	}
+}
+
+// WithSpoolMemory sets the number of bytes of a stream OpenStream keeps in
+// memory before spooling it to a temporary file instead.
+func WithSpoolMemory(maxBytes int64) Option {
+	return func(o *options) {
+		o.spoolMemory = maxBytes
+	}
+}
+
+// WithWorkers unpacks up to n entries at once, each read through its own
+// section of the package and written to its own file. On fast drives, this
+// speeds up unpacking packages of thousands of small entries.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// WithProgress calls fn as UnpackTo, Session.WriteTo, Repack and Build make
+// progress, after each entry is written. Calls to fn are never concurrent, but
+// may come from different goroutines when unpacking WithWorkers.
+func WithProgress(fn ProgressFunc) Option {
+	return func(o *options) {
+		o.progress = fn
+	}
+}
+
+// Strict makes the WriteTo method of opened packages reproduce the original
+// package byte for byte when it is not modified, which can be checked with
+// VerifyRoundTrip. Entry data is written at the offsets recorded in the index
+// tables, in the order it is stored, data shared by several entries is written
+// once, and the bytes between entries, such as padding, and after the last
+// entry are copied from the original package rather than written as zeros.
+func Strict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// VerifyOutput makes Repack and Patch read the package back once it is
+// written, as Session.Verify does, so that a package truncated by a full disk
+// or mangled by other software is reported rather than shipped.
+func VerifyOutput() Option {
+	return func(o *options) {
+		o.verifyOutput = true
+	}
+}
+
+// AllowOverwrite lets Repack write over the package it repacks. The package is
+// only replaced once the output is fully written, so it is not lost if
+// repacking fails.
+func AllowOverwrite() Option {
+	return func(o *options) {
+		o.allowOverwrite = true
+	}
+}
+
+// WithMmap makes Open map the package into memory rather than reading it with
+// a system call per read, which speeds up unpacking and repeated random access
+// to very large packages. The entries of the package must not be read once it
+// is closed. Where mapping is not supported, such as on Windows, or fails, the
+// package is read as usual.
+func WithMmap() Option {
+	return func(o *options) {
+		o.mmap = true
+	}
+}
+
+// DecodeWems makes UnpackTo decode the wems it can, see wem.CanDecode, and
+// write them as 16 bit PCM WAVE files with the given sample rate, such as
+// 44100 or 48000, resampling them as needed. Only PCM and IMA ADPCM wems are
+// decoded, unless a decoder for another codec is registered with
+// wem.RegisterDecoder; wems of other codecs, such as Vorbis, are written as
+// they are and reported to the function given by WithUndecodedReport.
+func DecodeWems(sampleRate int) Option {
+	return func(o *options) {
+		o.decodeRate = sampleRate
+	}
+}
+
+// WithUndecodedReport sets a function called with every wem that UnpackTo,
+// with DecodeWems, writes as it is because it cannot be decoded, and the
+// reason why, which wraps wem.ErrUnsupportedCodec if no decoder supports its
+// codec. The function is not called concurrently, even with WithWorkers.
+func WithUndecodedReport(fn func(f *EmbeddedFile, err error)) Option {
+	return func(o *options) {
+		o.undecodedReport = fn
+	}
+}
+
+// selects reports whether the entry with the given ID should be operated on.
+func (o *options) selects(id uint32) bool {
+	return o.ids == nil || o.ids[id]
+}
+
+// unpacks reports whether f should be unpacked.
+func (o *options) unpacks(f *EmbeddedFile) bool {
+	return o.selects(f.Index.ID) && !(o.skipEmpty && f.IsEmpty())
+}
+
+// throttledFile is a readerAtSeeker whose reads are rate limited.
+type throttledFile struct {
+	readerAtSeeker
+	throttle *util.Throttle
+}
+
+func (f *throttledFile) Read(p []byte) (int, error) {
+	n, err := f.readerAtSeeker.Read(p)
+	f.throttle.Wait(n)
+	return n, err
+}
+
+func (f *throttledFile) ReadAt(p []byte, off int64) (int, error) {
+	n, err := f.readerAtSeeker.ReadAt(p, off)
+	f.throttle.Wait(n)
+	return n, err
+}
+
+// throttledReaderAt is an io.ReaderAt whose reads are rate limited.
+type throttledReaderAt struct {
+	r        io.ReaderAt
+	throttle *util.Throttle
+}
+
+func (r *throttledReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.r.ReadAt(p, off)
+	r.throttle.Wait(n)
+	return n, err
+}
+
+// throttledWriter is an io.Writer whose writes are rate limited.
+type throttledWriter struct {
+	w        io.Writer
+	throttle *util.Throttle
+}
+
+func (w *throttledWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.throttle.Wait(n)
+	return n, err
+}
+
+// throttledWriterAt is an io.WriterAt whose writes are rate limited.
+type throttledWriterAt struct {
+	w        io.WriterAt
+	throttle *util.Throttle
+}
+
+func (w *throttledWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	n, err := w.w.WriteAt(p, off)
+	w.throttle.Wait(n)
+	return n, err
+}
+
+// throttleReaderAt returns r, rate limited by the throttle of pck if it was
+// opened with WithRateLimit.
+func (pck *File) throttleReaderAt(r io.ReaderAt) io.ReaderAt {
+	if pck.throttle == nil {
+		return r
+	}
+	return &throttledReaderAt{r, pck.throttle}
+}
+
+// throttleWriter returns w, rate limited as throttleReaderAt does.
+func (pck *File) throttleWriter(w io.Writer) io.Writer {
+	if pck.throttle == nil {
+		return w
+	}
+	return &throttledWriter{w, pck.throttle}
+}
+
+// throttleWriterAt returns w, rate limited as throttleReaderAt does.
+func (pck *File) throttleWriterAt(w io.WriterAt) io.WriterAt {
+	if pck.throttle == nil {
+		return w
+	}
+	return &throttledWriterAt{w, pck.throttle}
+}
@@ -0,0 +1,33 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestWithRateLimitThrottlesReadsAndWrites(t *testing.T) {
+	p := buildTestPackages(t)[0]
+	const rate = 20000
+	f := p.open(t, WithRateLimit(rate))
+	defer f.Close()
+
+	data := bytes.Repeat([]byte("RIFF"), 500)
+	s := f.NewSession()
+	if err := s.Replace("wem", 100, bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	written, err := s.WriteTo(io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	elapsed := time.Since(start)
+	// Every byte written is also read, from the package or the replacement.
+	read := written - f.dataStart()
+	if want := time.Duration((written + read) * int64(time.Second) / rate); elapsed < want {
+		t.Errorf("writing %d bytes took %v, less than the %v the limit allows", written, elapsed, want)
+	}
+}
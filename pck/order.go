@@ -0,0 +1,58 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"fmt"
+	"sort"
+)
+
+// indexLess reports whether the entry a sorts before the entry b in an index
+// table sorted by ID. Entries of standard packages with the same ID are sorted
+// by language.
+func (pck *File) indexLess(a, b *FileIndex) bool {
+	if a.ID != b.ID || pck.Format != FormatStandard {
+		return a.ID < b.ID
+	}
+	return a.LanguageID() < b.LanguageID()
+}
+
+// CheckIndexOrder returns an error wrapping ErrUnsorted if the BNK or WEM index
+// table of pck is not sorted by ID, as engines that look entries up by binary
+// search expect, naming the first entry out of order. Entries of standard
+// packages with the same ID must be sorted by language.
+func (pck *File) CheckIndexOrder() error {
+	for i, indexes := range [][]*FileIndex{pck.BnkIndexes, pck.WemIndexes} {
+		for j := 1; j < len(indexes); j++ {
+			if pck.indexLess(indexes[j], indexes[j-1]) {
+				return fmt.Errorf("%w: %s ID %d at index %d follows ID %d", ErrUnsorted,
+					tableNames[i], indexes[j].ID, j, indexes[j-1].ID)
+			}
+		}
+	}
+	return nil
+}
+
+// sortPlanned sorts planned, the indexes of a package a session writes, by ID,
+// keeping sources, their original indexes, in step. Entries of the same ID keep
+// their order.
+func (s *Session) sortPlanned(planned, sources []*FileIndex) {
+	sort.Stable(&plannedIndexes{s.src, planned, sources})
+}
+
+// plannedIndexes is a sort.Interface over the indexes planned by a session and
+// their original indexes.
+type plannedIndexes struct {
+	pck              *File
+	planned, sources []*FileIndex
+}
+
+func (p *plannedIndexes) Len() int { return len(p.planned) }
+
+func (p *plannedIndexes) Less(i, j int) bool {
+	return p.pck.indexLess(p.planned[i], p.planned[j])
+}
+
+func (p *plannedIndexes) Swap(i, j int) {
+	p.planned[i], p.planned[j] = p.planned[j], p.planned[i]
+	p.sources[i], p.sources[j] = p.sources[j], p.sources[i]
+}
@@ -0,0 +1,41 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestSortIndexes(t *testing.T) {
+	f, _ := openTestPackage(t)
+	if err := f.CheckIndexOrder(); err != nil {
+		t.Error(err)
+	}
+	f.Close()
+	unsorted := withWemID(0, 9)
+
+	f = openMemory(t, unsorted)
+	defer f.Close()
+	err := f.CheckIndexOrder()
+	if !errors.Is(err, ErrUnsorted) || !strings.Contains(err.Error(), "ID 3 at index 1 follows ID 9") {
+		t.Errorf("checking the order of an unsorted table: got %v", err)
+	}
+	r := bytes.NewReader(unsorted)
+	if _, err := OpenReader(r, r.Size(), RequireSortedIndexes()); !errors.Is(err, ErrUnsorted) {
+		t.Errorf("opening an unsorted package requiring sorted tables: got %v", err)
+	}
+
+	sorted, _ := writeSession(t, f.NewSession(SortIndexes()))
+	defer sorted.Close()
+	var ids []uint32
+	for _, idx := range sorted.WemIndexes {
+		ids = append(ids, idx.ID)
+	}
+	if err := sorted.CheckIndexOrder(); err != nil || ids[len(ids)-1] != 9 {
+		t.Errorf("sorted the wems as %v (%v)", ids, err)
+	}
+	want := map[string]map[uint32][]byte{"bnk": testEntries()["bnk"], "wem": {9: testWems[0], 3: testWems[1]}}
+	assertHolds(t, "the sorted package", sorted, want)
+}
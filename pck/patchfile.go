@@ -0,0 +1,138 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The identifier a patch file written by CreatePatch starts with.
+var patchMagic = [4]byte{'W', 'U', 'P', 'T'}
+
+// The version of the patch file format written by CreatePatch.
+const patchVersion = 1
+
+// The size in bytes of the fixed fields at the start of a patch file: its
+// identifier, version and the length of its manifest.
+const patchHeaderBytes = 12
+
+// A patchManifest describes the contents of a patch file. It is stored as JSON
+// after the fixed fields of the file, and followed by the data of its blobs.
+type patchManifest struct {
+	// The skeleton of the modified package.
+	Skeleton *Skeleton `json:"skeleton"`
+	// The data of the modified package that is not in the original package.
+	Blobs []*patchBlob `json:"blobs"`
+}
+
+// A patchBlob is the data of one or more entries of the modified package,
+// stored in a patch file.
+type patchBlob struct {
+	SHA256 string `json:"sha256"`
+	// The offset of the data from the end of the manifest.
+	Offset int64  `json:"offset"`
+	Length uint32 `json:"length"`
+}
+
+// CreatePatch writes a patch file to w that turns the package orig into the
+// package modified, as ApplyPatch does. The patch holds the skeleton of
+// modified, see Skeleton, and only the data of the entries of modified that is
+// not found in any entry of orig, so it is usually much smaller than modified
+// itself. Every entry of modified, and every entry of orig of the same length
+// as one of them, is read to compare their hashes.
+func CreatePatch(w io.Writer, orig, modified *File) (int64, error) {
+	s, err := modified.Skeleton()
+	if err != nil {
+		return 0, err
+	}
+	m := &patchManifest{Skeleton: s}
+	sources := newDataSources([]*File{orig})
+	var blobs []*SkeletonEntry
+	included := make(map[string]bool)
+	var offset int64
+	for _, e := range s.Entries {
+		if included[e.SHA256] {
+			continue
+		}
+		r, err := sources.find(e)
+		if err != nil {
+			return 0, err
+		}
+		if r != nil {
+			continue
+		}
+		included[e.SHA256] = true
+		blobs = append(blobs, e)
+		m.Blobs = append(m.Blobs, &patchBlob{e.SHA256, offset, e.Length})
+		offset += int64(e.Length)
+	}
+
+	manifest, err := json.Marshal(m)
+	if err != nil {
+		return 0, err
+	}
+	var hdr [patchHeaderBytes]byte
+	copy(hdr[:], patchMagic[:])
+	binary.LittleEndian.PutUint32(hdr[4:], patchVersion)
+	binary.LittleEndian.PutUint32(hdr[8:], uint32(len(manifest)))
+	n, err := io.Copy(w, io.MultiReader(bytes.NewReader(hdr[:]), bytes.NewReader(manifest)))
+	written := n
+	if err != nil {
+		return written, err
+	}
+	for _, e := range blobs {
+		n, err := io.Copy(w, io.NewSectionReader(modified.reader, int64(e.Offset), int64(e.Length)))
+		written += n
+		if err != nil {
+			return written, fmt.Errorf("writing %s ID %d: %w", e.Type, e.ID, err)
+		}
+	}
+	return written, nil
+}
+
+// ApplyPatch rebuilds the modified package described by the patch file stored
+// in patch, written by CreatePatch, from orig, writing it to w. The data of
+// each entry is taken from the patch or, by hash, from the entries of orig, as
+// by Skeleton.Rehydrate. It is an error for orig not to hold the data the
+// patch needs, as when the patch was created from another version of the
+// package.
+func ApplyPatch(w io.Writer, orig *File, patch io.ReaderAt) (int64, error) {
+	var hdr [patchHeaderBytes]byte
+	if _, err := patch.ReadAt(hdr[:], 0); err != nil {
+		return 0, fmt.Errorf("reading patch header: %w", err)
+	}
+	if !bytes.Equal(hdr[:4], patchMagic[:]) {
+		return 0, errors.New("not a patch file")
+	}
+	if v := binary.LittleEndian.Uint32(hdr[4:]); v != patchVersion {
+		return 0, fmt.Errorf("unsupported patch version %d", v)
+	}
+	length := int64(binary.LittleEndian.Uint32(hdr[8:]))
+	m := new(patchManifest)
+	if err := json.NewDecoder(io.NewSectionReader(patch, patchHeaderBytes, length)).Decode(m); err != nil {
+		return 0, fmt.Errorf("reading patch manifest: %w", err)
+	}
+	if m.Skeleton == nil || m.Skeleton.Version != skeletonVersion {
+		return 0, errors.New("the patch holds no supported skeleton")
+	}
+
+	sources := newDataSources([]*File{orig})
+	dataStart := patchHeaderBytes + length
+	for _, b := range m.Blobs {
+		r := io.NewSectionReader(patch, dataStart+b.Offset, int64(b.Length))
+		hash, err := hashData(r)
+		if err != nil {
+			return 0, fmt.Errorf("reading patch data: %w", err)
+		}
+		if hash != b.SHA256 {
+			return 0, fmt.Errorf("the patch is corrupt: data at offset %d does not match its hash",
+				dataStart+b.Offset)
+		}
+		sources.loose[b.SHA256] = r
+	}
+	return m.Skeleton.rehydrate(w, sources)
+}
@@ -0,0 +1,74 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPatchFile(t *testing.T) {
+	entries := testEntries()
+	f, data := openTestPackage(t)
+	defer f.Close()
+	modded := bytes.Repeat([]byte("RIFF modded "), 20)
+	s := f.NewSession()
+	if err := s.Replace("wem", 2, bytes.NewReader(modded), int64(len(modded))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("wem", 4, bytes.NewReader(testWems[1]), int64(len(testWems[1]))); err != nil {
+		t.Fatal(err)
+	}
+	m, want := writeSession(t, s)
+
+	patch := new(bytes.Buffer)
+	n, err := CreatePatch(patch, f, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+	if n != int64(patch.Len()) {
+		t.Errorf("wrote a patch of %d bytes, reporting %d", patch.Len(), n)
+	}
+	// Only the modded wem is stored in the patch.
+	if bytes.Count(patch.Bytes(), modded) != 1 || bytes.Contains(patch.Bytes(), entries["wem"][3]) {
+		t.Errorf("the patch does not hold only the data of the modded wem")
+	}
+
+	buf := new(bytes.Buffer)
+	if _, err := ApplyPatch(buf, f, bytes.NewReader(patch.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("applying the patch wrote %d bytes, not the %d bytes of the modded package",
+			buf.Len(), len(want))
+	}
+
+	// The patch cannot be applied to a package without the data of wem ID 3.
+	s = f.NewSession()
+	if err := s.Remove("wem", 3); err != nil {
+		t.Fatal(err)
+	}
+	other, _ := writeSession(t, s)
+	if _, err := ApplyPatch(new(bytes.Buffer), other, bytes.NewReader(patch.Bytes())); err == nil {
+		t.Errorf("the patch was applied to a package without the data it needs")
+	}
+	other.Close()
+
+	corrupt := append([]byte(nil), patch.Bytes()...)
+	corrupt[len(corrupt)-1] ^= 0xFF
+	for name, tt := range map[string]struct {
+		data []byte
+		err  string
+	}{
+		"corrupt data":  {corrupt, "corrupt"},
+		"not a patch":   {data, "not a patch file"},
+		"short":         {patch.Bytes()[:8], "reading patch header"},
+		"newer version": {append([]byte("WUPT\x02"), patch.Bytes()[5:]...), "version 2"},
+	} {
+		if _, err := ApplyPatch(new(bytes.Buffer), f, bytes.NewReader(tt.data)); err == nil ||
+			!strings.Contains(err.Error(), tt.err) {
+			t.Errorf("applying a patch that is %s: got %v, want an error about %q", name, err, tt.err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"encoding/binary"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// A Profile describes the layout of the packages of a particular game. Open
+// uses a profile for packages whose layout cannot be detected from their
+// header, or when one is given by WithProfile.
+type Profile struct {
+	// A description of the packages this profile applies to, such as the name
+	// of the game.
+	Name string
+	// Match reports whether the package with the given base file name, in lower
+	// case, uses this profile.
+	Match func(name string) bool
+	// The size of the Unknown header section, see Header.
+	UnknownSize int
+	// The size in bytes of a single index entry: 24 or 28 for hybrid packages
+	// with 32 or 64 bit offsets, or 20 for standard packages.
+	EntrySize int
+	// The byte order of the header and index tables. A nil ByteOrder is little
+	// endian.
+	ByteOrder binary.ByteOrder
+	// The alignment of entry data when the package is rewritten. 0 or 1 lays
+	// entries back-to-back.
+	Alignment uint32
+}
+
+// profiles are the registered profiles, most recently registered first.
+var profiles = []*Profile{
+	{
+		Name:        "Sleeping Dogs: Definitive Edition sfx.pck",
+		Match:       MatchSuffix("sfx.pck"),
+		UnknownSize: 36,
+		EntrySize:   indexEntryBytes,
+	},
+	{
+		Name:        "Sleeping Dogs: Definitive Edition english(us).pck",
+		Match:       MatchSuffix("english(us).pck"),
+		UnknownSize: 68,
+		EntrySize:   indexEntryBytes,
+	},
+}
+
+// RegisterProfile adds p to the profiles consulted by Open. Profiles are
+// consulted from the most to the least recently registered, so a profile can
+// take precedence over a built-in one by matching the same packages.
+func RegisterProfile(p *Profile) {
+	profiles = append([]*Profile{p}, profiles...)
+}
+
+// Profiles returns the registered profiles, in the order Open consults them.
+func Profiles() []*Profile {
+	return append([]*Profile(nil), profiles...)
+}
+
+// MatchSuffix returns a Profile.Match function that matches file names ending
+// in suffix, in any case.
+func MatchSuffix(suffix string) func(name string) bool {
+	suffix = strings.ToLower(suffix)
+	return func(name string) bool {
+		return strings.HasSuffix(name, suffix)
+	}
+}
+
+// profileFor returns the first registered profile that matches the package at
+// path.
+func profileFor(path string) (*Profile, bool) {
+	name := strings.ToLower(filepath.Base(path))
+	for _, p := range profiles {
+		if p.Match != nil && p.Match(name) {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+// format returns the format of the packages described by this profile.
+func (p *Profile) format() (Format, error) {
+	switch p.EntrySize {
+	case indexEntryBytes:
+		return FormatHybrid, nil
+	case indexEntry64Bytes:
+		return FormatHybrid64, nil
+	case standardEntry.size:
+		return FormatStandard, nil
+	}
+	return 0, fmt.Errorf("profile %q: unsupported index entry size %d", p.Name, p.EntrySize)
+}
+
+// byteOrder returns the byte order of the packages described by this profile.
+func (p *Profile) byteOrder() binary.ByteOrder {
+	if p.ByteOrder == nil {
+		return binary.LittleEndian
+	}
+	return p.ByteOrder
+}
@@ -0,0 +1,48 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"sync"
+)
+
+// Progress describes how far an operation on a package has got.
+type Progress struct {
+	// The number of entries processed so far, out of EntriesTotal.
+	EntriesDone  int
+	EntriesTotal int
+	// The number of bytes written so far.
+	BytesWritten int64
+}
+
+// A ProgressFunc is called as an operation makes progress, see WithProgress.
+type ProgressFunc func(p Progress)
+
+// progressTracker reports the progress of an operation to a ProgressFunc. It
+// is safe for concurrent use, and a nil tracker reports nothing.
+type progressTracker struct {
+	fn ProgressFunc
+	mu sync.Mutex
+	p  Progress
+}
+
+// newProgressTracker returns a tracker reporting to fn the progress of an
+// operation on total entries, or nil if fn is nil.
+func newProgressTracker(fn ProgressFunc, total int) *progressTracker {
+	if fn == nil {
+		return nil
+	}
+	return &progressTracker{fn: fn, p: Progress{EntriesTotal: total}}
+}
+
+// add records that entries more entries were processed, writing n more bytes,
+// and reports the progress so far.
+func (t *progressTracker) add(entries int, n int64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.p.EntriesDone += entries
+	t.p.BytesWritten += n
+	t.fn(t.p)
+}
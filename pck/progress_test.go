@@ -0,0 +1,72 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// recordProgress returns a ProgressFunc recording each report in reports.
+func recordProgress(reports *[]Progress) ProgressFunc {
+	return func(p Progress) {
+		*reports = append(*reports, p)
+	}
+}
+
+// checkProgress checks that reports count up to total entries and size bytes,
+// each report counting no more than one more entry than the last.
+func checkProgress(t *testing.T, name string, reports []Progress, total int, size int64) {
+	t.Helper()
+	if len(reports) == 0 {
+		t.Errorf("%s: no progress was reported", name)
+		return
+	}
+	var last Progress
+	for i, p := range reports {
+		if p.EntriesTotal != total || p.EntriesDone < last.EntriesDone ||
+			p.EntriesDone > last.EntriesDone+1 || p.BytesWritten < last.BytesWritten {
+			t.Errorf("%s: report %d is %+v after %+v", name, i, p, last)
+		}
+		last = p
+	}
+	if last.EntriesDone != total || last.BytesWritten != size {
+		t.Errorf("%s: the last report is %+v, want %d entries and %d bytes", name, last, total, size)
+	}
+}
+
+func TestWithProgress(t *testing.T) {
+	entries := testEntries()
+	total := len(entries["bnk"]) + len(entries["wem"])
+	var size int64
+	for _, files := range entries {
+		for _, data := range files {
+			size += int64(len(data))
+		}
+	}
+
+	f, data := openTestPackage(t)
+	var reports []Progress
+	if err := f.UnpackTo(t.TempDir(), WithProgress(recordProgress(&reports)), WithWorkers(4)); err != nil {
+		t.Fatal(err)
+	}
+	checkProgress(t, "unpacking", reports, total, size)
+
+	reports = nil
+	err := f.UnpackTo(t.TempDir(), WithProgress(recordProgress(&reports)), WithIDs(3, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkProgress(t, "unpacking some IDs", reports, 2, int64(len(entries["wem"][3])+len(entries["bnk"][1])))
+
+	reports = nil
+	writeSession(t, f.NewSession(WithProgress(recordProgress(&reports))))
+	checkProgress(t, "writing", reports, total, int64(len(data)))
+	f.Close()
+
+	reports = nil
+	out := filepath.Join(t.TempDir(), "repacked.pck")
+	if _, err := Repack(writeTestPackage(t, data), out, nil, WithProgress(recordProgress(&reports))); err != nil {
+		t.Fatal(err)
+	}
+	checkProgress(t, "repacking", reports, total, int64(len(data)))
+}
@@ -0,0 +1,178 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+// A Project indexes the entries of several packages at once, such as every
+// package of a game, so that an entry can be found by ID without knowing which
+// package holds it. Unlike a Scan, the packages are kept open, so that the
+// entries found can be read, until Close is called.
+type Project struct {
+	// The packages of the project, in the order they were added.
+	Packages []*ProjectPackage
+	// The packages that could not be opened by OpenProjectDir, and the reason
+	// why.
+	Skipped map[string]error
+	// The entries of every table of the packages, by ID.
+	entries map[uint32][]*ProjectEntry
+}
+
+// A ProjectPackage is a package of a Project.
+type ProjectPackage struct {
+	// The name of the package: its path relative to the directory given to
+	// OpenProjectDir, or its path as given to OpenProject.
+	Name string
+	File *File
+}
+
+// A ProjectEntry is an entry found by Project.Lookup.
+type ProjectEntry struct {
+	Package *ProjectPackage
+	// The table of the entry: "bnk", "wem" or "externals".
+	Table string
+	Entry *EmbeddedFile
+}
+
+// OpenProject opens the packages at paths, with opts, as a Project. If a
+// package cannot be opened, the packages opened so far are closed and the
+// error is returned.
+func OpenProject(paths []string, opts ...Option) (*Project, error) {
+	p := NewProject()
+	for _, path := range paths {
+		f, err := Open(path, opts...)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.Add(path, f)
+	}
+	return p, nil
+}
+
+// OpenProjectDir opens every .pck file in dir and its subdirectories, with
+// opts, as a Project. Packages that cannot be opened are recorded in Skipped,
+// as by ScanDir.
+func OpenProjectDir(dir string, opts ...Option) (*Project, error) {
+	p := NewProject()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.ToLower(filepath.Ext(path)) != ".pck" {
+			return nil
+		}
+		f, err := Open(path, opts...)
+		if err != nil {
+			p.Skipped[path] = err
+			return nil
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			name = path
+		}
+		p.Add(filepath.ToSlash(name), f)
+		return nil
+	})
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	return p, nil
+}
+
+// NewProject returns an empty Project, to which packages opened by the caller
+// are added with Add.
+func NewProject() *Project {
+	return &Project{
+		Skipped: make(map[string]error),
+		entries: make(map[uint32][]*ProjectEntry),
+	}
+}
+
+// Add adds the package f to p under the given name, indexing its entries. f
+// is closed by p.Close.
+func (p *Project) Add(name string, f *File) {
+	pp := &ProjectPackage{Name: name, File: f}
+	p.Packages = append(p.Packages, pp)
+	for i, files := range [][]*EmbeddedFile{f.Bnks, f.Wems, f.Externals} {
+		for _, e := range files {
+			// Externals are only found by their whole 64 bit ID.
+			if i == 2 && e.Index.Unknown1 != 0 {
+				continue
+			}
+			p.entries[e.Index.ID] = append(p.entries[e.Index.ID], &ProjectEntry{pp, tableNames[i], e})
+		}
+	}
+}
+
+// Lookup returns the entries with the given ID in every package of p and
+// every table, in the order the packages were added and the entries occur
+// in their tables. It returns nil if no package holds the ID.
+func (p *Project) Lookup(id uint32) []*ProjectEntry {
+	return p.entries[id]
+}
+
+// UnpackTo unpacks every package of p as File.UnpackTo does, each to a
+// directory of outputDir named after the package without its extension. With
+// WithIDs, only the entries with those IDs are unpacked, from whichever
+// packages hold them, and packages holding none of them are skipped. The
+// truncated entries of every package are salvaged, and reported by a single
+// *TruncatedError once every package is unpacked.
+func (p *Project) UnpackTo(outputDir string, opts ...Option) error {
+	return p.UnpackToContext(context.Background(), outputDir, opts...)
+}
+
+// UnpackToContext is UnpackTo, stopping with ctx's error if ctx is done before
+// every entry is unpacked.
+func (p *Project) UnpackToContext(ctx context.Context, outputDir string, opts ...Option) error {
+	o := newOptions(opts)
+	var truncated []*EmbeddedFile
+	for _, pp := range p.Packages {
+		if !pp.holdsAny(o) {
+			continue
+		}
+		dir := filepath.Join(outputDir, filepath.FromSlash(strings.TrimSuffix(pp.Name, filepath.Ext(pp.Name))))
+		err := pp.File.UnpackToContext(ctx, dir, opts...)
+		var t *TruncatedError
+		if errors.As(err, &t) {
+			truncated = append(truncated, t.Entries...)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(truncated) > 0 {
+		return &TruncatedError{truncated}
+	}
+	return nil
+}
+
+// holdsAny reports whether pp holds an entry that o unpacks.
+func (pp *ProjectPackage) holdsAny(o *options) bool {
+	for _, files := range [][]*EmbeddedFile{pp.File.Bnks, pp.File.Wems} {
+		for _, f := range files {
+			if o.unpacks(f) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Close closes every package of p, returning the first error.
+func (p *Project) Close() error {
+	var first error
+	for _, pp := range p.Packages {
+		if err := pp.File.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,121 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestProject writes the packages serialized in pkgs to a directory, the first at
+// its root and the others in a subdirectory, next to a file that is not a
+// package and a .pck file that cannot be opened. It returns the directory and
+// the names of the packages relative to it.
+func writeTestProject(t *testing.T, pkgs [][]byte) (string, []string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for i, data := range pkgs {
+		name := fmt.Sprintf("sub/%d.pck", i)
+		if i == 0 {
+			name = "0.pck"
+		}
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	for name, data := range map[string]string{"notes.txt": "not a package", "sub/broken.pck": "AKPK"} {
+		if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir, names
+}
+
+func TestProjectLookup(t *testing.T) {
+	// The packages hold the same IDs, with different data.
+	wems := [][][]byte{testWems, {[]byte("RIFF second"), []byte("second text")}}
+	var pkgs [][]byte
+	for _, w := range wems {
+		pkgs = append(pkgs, buildPackage(testBnks, w))
+	}
+	dir, names := writeTestProject(t, pkgs)
+	p, err := OpenProjectDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	if _, ok := p.Skipped[filepath.Join(dir, "sub", "broken.pck")]; !ok || len(p.Skipped) != 1 {
+		t.Errorf("skipped %v, want only sub/broken.pck", p.Skipped)
+	}
+	if len(p.Packages) != len(names) {
+		t.Fatalf("opened %d packages, want %d", len(p.Packages), len(names))
+	}
+	for i, pp := range p.Packages {
+		if pp.Name != names[i] {
+			t.Errorf("package %d is named %q, want %q", i, pp.Name, names[i])
+		}
+	}
+
+	// Every package holds the same IDs, so each is found in every package, in
+	// the order the packages were added.
+	for typ, ids := range map[string][]uint32{"bnk": {1}, "wem": {2, 3}} {
+		for _, id := range ids {
+			found := p.Lookup(id)
+			if len(found) != len(pkgs) {
+				t.Errorf("looking up %s ID %d found %d entries, want %d", typ, id, len(found), len(pkgs))
+				continue
+			}
+			for i, e := range found {
+				if e.Package != p.Packages[i] || e.Table != typ || e.Entry.Index.ID != id {
+					t.Errorf("looking up %s ID %d found %s ID %d of %s as entry %d", typ, id, e.Table,
+						e.Entry.Index.ID, e.Package.Name, i)
+				}
+				want := testBnks[0]
+				if typ == "wem" {
+					want = wems[i][id-2]
+				}
+				if got, err := e.Entry.Bytes(); err != nil || !bytes.Equal(got, want) {
+					t.Errorf("%s ID %d of %s holds %q (%v)", typ, id, e.Package.Name, got, err)
+				}
+			}
+		}
+	}
+	if found := p.Lookup(12345); found != nil {
+		t.Errorf("looking up an ID no package holds found %d entries", len(found))
+	}
+}
+
+func TestProjectUnpackWithIDs(t *testing.T) {
+	p := NewProject()
+	defer p.Close()
+	first, _ := openTestPackage(t)
+	p.Add("first.pck", first)
+	// A package without wem ID 2 is skipped.
+	second, _ := openTestPackage(t)
+	defer second.Close()
+	s := second.NewSession()
+	if err := s.Remove("wem", 2); err != nil {
+		t.Fatal(err)
+	}
+	f, _ := writeSession(t, s)
+	p.Add("sub/second.pck", f)
+
+	out := t.TempDir()
+	if err := p.UnpackTo(out, WithIDs(2)); err != nil {
+		t.Fatal(err)
+	}
+	matches, _ := filepath.Glob(filepath.Join(out, "*", "*", "*"))
+	if want := filepath.Join(out, "first", "wem", "2.wem"); len(matches) != 1 || matches[0] != want {
+		t.Errorf("unpacked %v, want only %s", matches, want)
+	}
+	if _, err := os.Stat(filepath.Join(out, "sub")); !os.IsNotExist(err) {
+		t.Errorf("the package without wem ID 2 was unpacked (%v)", err)
+	}
+}
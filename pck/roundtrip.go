@@ -0,0 +1,141 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// writeStrict writes pck to w as WriteTo does for a File opened with Strict.
+// If encrypt is true, the data of the entries is encrypted as by
+// WithEntryCipher; otherwise, the package is written as it is read.
+func (pck *File) writeStrict(w io.Writer, encrypt bool) (int64, error) {
+	size, err := pck.fileSize()
+	if err != nil {
+		return 0, err
+	}
+	written, err := writeHeader(w, pck.Format, pck.ByteOrder, pck.Header, pck.indexTables())
+	if err != nil {
+		return written, err
+	}
+
+	var files []*EmbeddedFile
+	for _, list := range [][]*EmbeddedFile{pck.Bnks, pck.Wems, pck.Externals} {
+		files = append(files, list...)
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].Index.Offset < files[j].Index.Offset
+	})
+	for _, f := range files {
+		offset, length := int64(f.Index.Offset), int64(f.Index.Length)
+		// The data of an entry starting before the end of the data written so
+		// far is shared with, or overlaps, an entry already written.
+		skip := written - offset
+		if skip >= length {
+			continue
+		}
+		n, err := pck.copyOriginal(w, written, offset)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		data := f.data()
+		if skip > 0 {
+			if _, err := io.CopyN(io.Discard, data, skip); err != nil {
+				return written, err
+			}
+		}
+		dst := w
+		if encrypt {
+			dst = pck.encryptEntry(w)
+		}
+		n, err = io.Copy(dst, data)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	n, err := pck.copyOriginal(w, written, size)
+	return written + n, err
+}
+
+// copyOriginal writes the bytes of the original package from offset from up
+// to offset to to w. Bytes past the end of the original package are written as
+// zeros.
+func (pck *File) copyOriginal(w io.Writer, from, to int64) (int64, error) {
+	if to <= from {
+		return 0, nil
+	}
+	n, err := io.Copy(w, io.NewSectionReader(pck.reader, from, to-from))
+	if err != nil {
+		return n, err
+	}
+	m, err := writePadding(w, to-from-n)
+	return n + m, err
+}
+
+// errDiffers stops writing a package once it differs from the original, see
+// VerifyRoundTrip.
+var errDiffers = errors.New("the written package differs from the original")
+
+// VerifyRoundTrip checks that pck, written by WriteTo as if it was opened with
+// Strict, is identical to the package it was read from. It returns an error
+// giving the offset of the first difference if it is not, which means that the
+// layout of the package is not fully understood, and that packages rewritten
+// from it may not load. The package is compared as it is written, so nothing
+// is kept in memory.
+func (pck *File) VerifyRoundTrip() error {
+	size, err := pck.fileSize()
+	if err != nil {
+		return err
+	}
+	c := &compareWriter{r: pck.reader, diff: -1}
+	n, err := pck.writeStrict(c, false)
+	if c.diff >= 0 {
+		return fmt.Errorf("the rewritten package differs from the original at offset %d", c.diff)
+	}
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("the rewritten package is %d bytes long, but the original is %d bytes",
+			n, size)
+	}
+	return nil
+}
+
+// A compareWriter compares the bytes written to it with those of r, from its
+// start, failing with errDiffers at the first difference.
+type compareWriter struct {
+	r      io.ReaderAt
+	offset int64
+	buf    []byte
+	// The offset of the first difference, or -1 if there is none.
+	diff int64
+}
+
+func (c *compareWriter) Write(p []byte) (int, error) {
+	if cap(c.buf) < len(p) {
+		c.buf = make([]byte, len(p))
+	}
+	b := c.buf[:len(p)]
+	n, err := c.r.ReadAt(b, c.offset)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	if n == len(p) && bytes.Equal(p, b) {
+		c.offset += int64(len(p))
+		return len(p), nil
+	}
+	for i := range p {
+		if i >= n || p[i] != b[i] {
+			c.diff = c.offset + int64(i)
+			return i, errDiffers
+		}
+	}
+	c.offset += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,58 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStrictWritesOriginalBytes(t *testing.T) {
+	// The data is stored out of index order, with bytes after the last entry
+	// that WriteTo only copies when strict.
+	f, _ := openTestPackage(t)
+	s := f.NewSession(PreserveDataOrder())
+	added := []byte("RIFF added")
+	if err := s.Add("wem", 1, bytes.NewReader(added), int64(len(added))); err != nil {
+		t.Fatal(err)
+	}
+	written, data := writeSession(t, s)
+	written.Close()
+	f.Close()
+	data = append(data, "trailer"...)
+	path := writeTestPackage(t, data)
+	f, err := Open(path, Strict())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertWritesBytes(t, f, data)
+	if err := f.VerifyRoundTrip(); err != nil {
+		t.Error(err)
+	}
+	f.Close()
+
+	// Without Strict, the data is written in index order.
+	f, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	buf := new(bytes.Buffer)
+	if _, err := f.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(buf.Bytes(), data) {
+		t.Error("the package was written byte for byte without Strict")
+	}
+	// VerifyRoundTrip always writes as if strict.
+	if err := f.VerifyRoundTrip(); err != nil {
+		t.Error(err)
+	}
+
+	// A header the package is not written back with is reported at the offset
+	// of its first difference.
+	f.Header.Unknown[0] ^= 0xFF
+	if err := f.VerifyRoundTrip(); err == nil || !strings.Contains(err.Error(), "at offset 8") {
+		t.Errorf("verifying a changed header: got %v", err)
+	}
+}
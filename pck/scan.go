@@ -0,0 +1,140 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// A Scan indexes the WEM entries of every package found in a directory, such
+// as the audio directory of a game, by ID.
+type Scan struct {
+	// The paths of the packages that were scanned.
+	Packages []string
+	// The packages that could not be opened, and the reason why.
+	Skipped map[string]error
+	// The occurrences of each WEM ID, in the order they were scanned.
+	wems map[uint32][]*ScanEntry
+}
+
+// A ScanEntry is an occurrence of a WEM entry in a scanned package.
+type ScanEntry struct {
+	Package string
+	Index   *FileIndex
+}
+
+// A Duplicate is a WEM ID that occurs more than once across scanned packages.
+type Duplicate struct {
+	ID      uint32
+	Entries []*ScanEntry
+	// The number of bytes taken by all but the largest occurrence.
+	DuplicatedBytes int64
+}
+
+// An Overlap describes the WEM IDs two packages have in common. Packages with
+// a large overlap could reference a single shared copy of those entries.
+type Overlap struct {
+	A, B string
+	// The number of IDs found in both packages.
+	IDs int
+	// The number of bytes taken by the copies of those IDs in B.
+	Bytes int64
+}
+
+// ScanDir opens every .pck file in dir and its subdirectories and indexes
+// their WEM entries. Packages that cannot be opened are recorded in Skipped
+// rather than stopping the scan. The options are used to open each package.
+func ScanDir(dir string, opts ...Option) (*Scan, error) {
+	s := &Scan{
+		Skipped: make(map[string]error),
+		wems:    make(map[uint32][]*ScanEntry),
+	}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.ToLower(filepath.Ext(path)) != ".pck" {
+			return nil
+		}
+		f, err := Open(path, opts...)
+		if err != nil {
+			s.Skipped[path] = err
+			return nil
+		}
+		defer f.Close()
+
+		s.Packages = append(s.Packages, path)
+		for _, idx := range f.WemIndexes {
+			s.wems[idx.ID] = append(s.wems[idx.ID], &ScanEntry{path, idx})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Duplicates returns the WEM IDs that occur more than once, ordered from the
+// most to the least duplicated bytes.
+func (s *Scan) Duplicates() []*Duplicate {
+	var dups []*Duplicate
+	for id, entries := range s.wems {
+		if len(entries) < 2 {
+			continue
+		}
+		var total, largest int64
+		for _, e := range entries {
+			total += int64(e.Index.Length)
+			if int64(e.Index.Length) > largest {
+				largest = int64(e.Index.Length)
+			}
+		}
+		dups = append(dups, &Duplicate{id, entries, total - largest})
+	}
+	sort.Slice(dups, func(i, j int) bool {
+		if dups[i].DuplicatedBytes != dups[j].DuplicatedBytes {
+			return dups[i].DuplicatedBytes > dups[j].DuplicatedBytes
+		}
+		return dups[i].ID < dups[j].ID
+	})
+	return dups
+}
+
+// Overlaps returns every pair of packages that have WEM IDs in common, ordered
+// from the most to the least bytes in common.
+func (s *Scan) Overlaps() []*Overlap {
+	type pair struct{ a, b string }
+	overlaps := make(map[pair]*Overlap)
+	for _, entries := range s.wems {
+		for i, a := range entries {
+			for _, b := range entries[i+1:] {
+				if a.Package == b.Package {
+					continue
+				}
+				p := pair{a.Package, b.Package}
+				o, ok := overlaps[p]
+				if !ok {
+					o = &Overlap{A: a.Package, B: b.Package}
+					overlaps[p] = o
+				}
+				o.IDs++
+				o.Bytes += int64(b.Index.Length)
+			}
+		}
+	}
+
+	list := make([]*Overlap, 0, len(overlaps))
+	for _, o := range overlaps {
+		list = append(list, o)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Bytes != list[j].Bytes {
+			return list[i].Bytes > list[j].Bytes
+		}
+		return list[i].A+list[i].B < list[j].A+list[j].B
+	})
+	return list
+}
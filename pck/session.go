@@ -0,0 +1,1211 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// A Session records pending edits to a File without modifying the File it was
+// created from. Several sessions may be created from one File, and a session
+// may be cloned, allowing alternative outputs to be produced from a single
+// parsed package.
+type Session struct {
+	src *File
+	// The pending changes, keyed by entry type ("bnk" or "wem") and then by ID.
+	changes map[string]map[uint32]*Change
+	// The changes that add new entries, in the order they were added.
+	added []*Change
+	// Whether the data start offset of the original File is kept, see
+	// PreserveDataStart.
+	preserveDataStart bool
+	// The alignment of the start of the entry data, or 0 or 1 to start it
+	// directly after the index tables, see WithDataAlignment.
+	dataAlignment uint32
+	// Whether entry data is stored in the order of the original File, see
+	// PreserveDataOrder.
+	preserveDataOrder bool
+	// Whether the BNK and WEM index tables are sorted by ID, see SortIndexes.
+	sortIndexes bool
+	// The gaps of the original File written before the entries they precede,
+	// and after the data of the last entry, unless the session drops them, see
+	// DropGaps.
+	gaps    map[*FileIndex]*Gap
+	trailer *Gap
+	// The transform applied to the data of every entry, see WithTransform.
+	transform TransformFunc
+	// The watermark stamped into the written package, if any.
+	watermark *Watermark
+	// The header written in place of that of the original File, see
+	// WithRawHeader.
+	rawHeader []byte
+	// The function reporting the progress of writing the package, if any.
+	progress ProgressFunc
+	// The overrides of the indexes of entries, keyed by entry type and then
+	// by ID.
+	overrides map[string]map[uint32]*IndexOverride
+	// The indexes of the original File left out of the written package as
+	// duplicates, see ResolveDuplicates.
+	dropped map[*FileIndex]bool
+	// The IDs entries of the original File are written with instead of their
+	// own, keyed by entry type and then by original ID, see Remap.
+	remaps map[string]map[uint32]uint32
+}
+
+// A Change is a pending replacement of the data of a single entry, a new entry
+// to be added, or an entry to be removed.
+type Change struct {
+	Type string // "bnk" or "wem"
+	ID   uint32
+	// A reader over the new contents of the entry.
+	Data io.ReaderAt
+	// The number of bytes to read from Data.
+	Length int64
+	// Whether the entry is added by this change rather than already being in
+	// the package.
+	New bool
+	// Whether the entry is removed by this change. Data and Length are unused.
+	Removed bool
+}
+
+// An IndexOverride overrides fields of the index of an entry in the package a
+// Session writes, for edge cases such as an entry a game expects to have
+// different flags than the rest. Nil fields are left as they are.
+type IndexOverride struct {
+	// The Type field; in standard packages, the block size of the entry.
+	Type     *uint32
+	Unknown1 *uint32
+	// The Unknown2 field; the language ID of the entry.
+	Unknown2 *uint32
+	// The alignment of the data of the entry, in addition to the alignment of
+	// the package, or 0 or 1 for none.
+	Alignment uint32
+}
+
+// apply applies the overridden fields to idx.
+func (o *IndexOverride) apply(idx *FileIndex) {
+	if o.Type != nil {
+		idx.Type = *o.Type
+	}
+	if o.Unknown1 != nil {
+		idx.Unknown1 = *o.Unknown1
+	}
+	if o.Unknown2 != nil {
+		idx.Unknown2 = *o.Unknown2
+	}
+}
+
+// An Entry identifies an entry of a package, such as one being written by a
+// Session, or one yielded by an EntryIterator.
+type Entry struct {
+	Type  string // "bnk", "wem" or "externals"
+	Index *FileIndex
+}
+
+// A TransformFunc transforms the data of entry, read from r, as a package is
+// written, returning a reader over the data to write instead. It lets callers
+// re-encrypt, recompress or watermark entry data without rewriting the
+// package writer. The Length of entry.Index is that of the data read from r.
+type TransformFunc func(entry *Entry, r io.Reader) (io.Reader, error)
+
+// A Layout describes the structure of the package a Session would write.
+type Layout struct {
+	Header     *Header
+	BnkIndexes []*FileIndex
+	WemIndexes []*FileIndex
+	// The externals indexes of a standard package.
+	ExternalIndexes []*FileIndex
+	// The offset into the package where the data of the first entry begins.
+	DataStart int64
+	// The total size of the package in bytes.
+	Size int64
+}
+
+// NewSession creates a new Session for editing pck. Of the options, only
+// PreserveDataStart, WithDataAlignment, PreserveDataAlignment,
+// PreserveDataOrder, DropGaps, SortIndexes, WithTransform, WithWatermark,
+// WithRawHeader and WithProgress affect a session.
+//
+// Unless given DropGaps, the session keeps the gaps of pck, see File.Gaps:
+// the bytes of each gap are written before the entry they precede, wherever
+// it is stored, so a package written without changes is laid out as pck was.
+// The gaps before removed entries are left out along with them.
+func (pck *File) NewSession(opts ...Option) *Session {
+	o := newOptions(opts)
+	s := &Session{
+		src: pck,
+		changes: map[string]map[uint32]*Change{
+			"bnk": make(map[uint32]*Change),
+			"wem": make(map[uint32]*Change),
+		},
+		preserveDataStart: o.preserveDataStart,
+		preserveDataOrder: o.preserveDataOrder,
+		sortIndexes:       o.sortIndexes,
+		transform:         o.transform,
+		watermark:         o.watermark,
+		rawHeader:         o.rawHeader,
+		progress:          o.progress,
+		overrides: map[string]map[uint32]*IndexOverride{
+			"bnk": make(map[uint32]*IndexOverride),
+			"wem": make(map[uint32]*IndexOverride),
+		},
+		dropped: make(map[*FileIndex]bool),
+		remaps: map[string]map[uint32]uint32{
+			"bnk": make(map[uint32]uint32),
+			"wem": make(map[uint32]uint32),
+		},
+	}
+	s.dataAlignment = o.dataAlignment
+	if s.dataAlignment == 0 && o.preserveDataAlignment {
+		s.dataAlignment = pck.DataAlignment
+	}
+	if !o.dropGaps {
+		s.gaps, s.trailer = pck.gapsByEntry()
+		if s.dataAlignment > 1 {
+			// The data start padding replaces the gap after the index tables.
+			for idx, g := range s.gaps {
+				if g.Offset == pck.dataStart() {
+					delete(s.gaps, idx)
+				}
+			}
+		}
+	}
+	return s
+}
+
+// Replace records that the data of the entry of type typ ("bnk" or "wem") with
+// the given ID should be replaced by the first length bytes of data. Replacing
+// an entry that already has a pending change overrides that change, including
+// an entry added by Add. If the package was opened with Decompress and the
+// entry is compressed, data is compressed the same way before it is stored.
+func (s *Session) Replace(typ string, id uint32, data io.ReaderAt, length int64) error {
+	indexes, ok := s.src.indexesOf(typ)
+	if !ok {
+		return fmt.Errorf("unknown entry type %q", typ)
+	}
+	if c, ok := s.changes[typ][id]; ok && c.New {
+		c.Data, c.Length = data, length
+		return nil
+	}
+	if !containsID(indexes, id) {
+		return fmt.Errorf("no %s entry with ID %d", typ, id)
+	}
+	if compression := s.src.compressionOf(typ, id); compression != "" && length > 0 {
+		var err error
+		if data, length, err = compressReplacement(compression, data, length); err != nil {
+			return fmt.Errorf("compressing %s ID %d: %w", typ, id, err)
+		}
+	}
+	return s.replaceStored(typ, id, data, length)
+}
+
+// replaceStored is Replace for data to be stored as it is, such as the stored
+// data of an entry of another package, even if the entry is compressed.
+func (s *Session) replaceStored(typ string, id uint32, data io.ReaderAt, length int64) error {
+	if c, ok := s.changes[typ][id]; ok && c.New {
+		c.Data, c.Length = data, length
+		return nil
+	}
+	s.changes[typ][id] = &Change{Type: typ, ID: id, Data: data, Length: length}
+	return nil
+}
+
+// ReplaceRange records that length bytes of the data of the entry of type typ
+// ("bnk" or "wem") with the given ID, starting offset bytes from the start of
+// the entry, should be replaced by the first length bytes of data. The rest of
+// the entry is kept, so that a section of a very long streamed wem can be
+// changed without replacing, or re-encoding, all of it; the range should start
+// and end on the block boundaries of the codec of the wem. The entry grows if
+// the range extends past its end, but offset must not be past its end. The
+// range is applied on top of any pending change to the entry, so several
+// ranges of one entry can be replaced.
+func (s *Session) ReplaceRange(typ string, id uint32, offset int64, data io.ReaderAt, length int64) error {
+	indexes, ok := s.src.indexesOf(typ)
+	if !ok {
+		return fmt.Errorf("unknown entry type %q", typ)
+	}
+	if s.src.compressionOf(typ, id) != "" {
+		return fmt.Errorf("%s ID %d is compressed, so it can only be replaced whole", typ, id)
+	}
+	var base io.ReaderAt
+	var baseLength int64
+	if c, ok := s.changes[typ][id]; ok && !c.Removed {
+		base, baseLength = c.Data, c.Length
+	} else if idx := findIndex(indexes, id); idx != nil {
+		base = io.NewSectionReader(s.src.reader, int64(idx.Offset), int64(idx.Length))
+		baseLength = int64(idx.Length)
+	} else {
+		return fmt.Errorf("no %s entry with ID %d", typ, id)
+	}
+	if offset < 0 || offset > baseLength {
+		return fmt.Errorf("offset %d is outside of %s ID %d, which is %d bytes long",
+			offset, typ, id, baseLength)
+	}
+
+	spliced := &splicedReader{base, baseLength, offset, data, length}
+	newLength := offset + length
+	if newLength < baseLength {
+		newLength = baseLength
+	}
+	if c, ok := s.changes[typ][id]; ok && c.New {
+		c.Data, c.Length = spliced, newLength
+		return nil
+	}
+	s.changes[typ][id] = &Change{Type: typ, ID: id, Data: spliced, Length: newLength}
+	return nil
+}
+
+// A splicedReader reads the data of base with the bytes starting at offset
+// replaced by those of data.
+type splicedReader struct {
+	base       io.ReaderAt
+	baseLength int64
+	offset     int64
+	data       io.ReaderAt
+	length     int64
+}
+
+func (r *splicedReader) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for len(p) > 0 {
+		var src io.ReaderAt
+		var srcOff, avail int64
+		switch {
+		case off < r.offset:
+			src, srcOff, avail = r.base, off, r.offset-off
+		case off < r.offset+r.length:
+			src, srcOff, avail = r.data, off-r.offset, r.offset+r.length-off
+		case off < r.baseLength:
+			src, srcOff, avail = r.base, off, r.baseLength-off
+		default:
+			return n, io.EOF
+		}
+		chunk := p
+		if int64(len(chunk)) > avail {
+			chunk = chunk[:avail]
+		}
+		m, err := src.ReadAt(chunk, srcOff)
+		n += m
+		if m < len(chunk) {
+			if err == nil || err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return n, err
+		}
+		p, off = p[m:], off+int64(m)
+	}
+	return n, nil
+}
+
+// Add records that a new entry of type typ ("bnk" or "wem") with the given ID,
+// holding the first length bytes of data, should be added to the package. The
+// ID must not already be used by an entry of that type. If the index table is
+// ordered by ID, as Wwise expects, the new entry is inserted in order;
+// otherwise it is appended to the table.
+func (s *Session) Add(typ string, id uint32, data io.ReaderAt, length int64) error {
+	indexes, ok := s.src.indexesOf(typ)
+	if !ok {
+		return fmt.Errorf("unknown entry type %q", typ)
+	}
+	if _, ok := s.changes[typ][id]; ok || containsID(indexes, id) {
+		return fmt.Errorf("a %s entry with ID %d already exists", typ, id)
+	}
+	c := &Change{Type: typ, ID: id, Data: data, Length: length, New: true}
+	s.changes[typ][id] = c
+	s.added = append(s.added, c)
+	return nil
+}
+
+// Remove records that the entry of type typ ("bnk" or "wem") with the given ID
+// should be removed from the package, along with its data. Removing an entry
+// added by Add discards it, as Revert does.
+func (s *Session) Remove(typ string, id uint32) error {
+	indexes, ok := s.src.indexesOf(typ)
+	if !ok {
+		return fmt.Errorf("unknown entry type %q", typ)
+	}
+	if c, ok := s.changes[typ][id]; ok && c.New {
+		s.Revert(typ, id)
+		return nil
+	}
+	if !containsID(indexes, id) {
+		return fmt.Errorf("no %s entry with ID %d", typ, id)
+	}
+	s.changes[typ][id] = &Change{Type: typ, ID: id, Removed: true}
+	return nil
+}
+
+// Empty records that the entry of type typ with the given ID should be replaced
+// by a zero-length placeholder, which effectively disables a sound.
+func (s *Session) Empty(typ string, id uint32) error {
+	return s.Replace(typ, id, bytes.NewReader(nil), 0)
+}
+
+// Revert discards any pending change to the entry of type typ with the given
+// ID. Reverting an added entry removes it again.
+func (s *Session) Revert(typ string, id uint32) {
+	if c, ok := s.changes[typ][id]; ok && c.New {
+		for i, a := range s.added {
+			if a == c {
+				s.added = append(s.added[:i:i], s.added[i+1:]...)
+				break
+			}
+		}
+	}
+	delete(s.changes[typ], id)
+}
+
+// Override records that the index of the entry of type typ ("bnk" or "wem")
+// with the given ID should have the fields set in o, replacing any previous
+// override of the entry. The entry may be one added by Add.
+func (s *Session) Override(typ string, id uint32, o *IndexOverride) error {
+	indexes, ok := s.src.indexesOf(typ)
+	if !ok {
+		return fmt.Errorf("unknown entry type %q", typ)
+	}
+	if c, ok := s.changes[typ][id]; !containsID(indexes, id) && !(ok && c.New) {
+		return fmt.Errorf("no %s entry with ID %d", typ, id)
+	}
+	s.overrides[typ][id] = o
+	return nil
+}
+
+// Remap records that the entry of type typ ("bnk" or "wem") with the given ID
+// should be written with the ID to instead, keeping its data and the rest of
+// its index, for instance to port a mod between regions of a game whose banks
+// use different IDs. Pending changes and overrides of the entry are still made
+// by its original ID. If the index table is ordered by ID, it is reordered to
+// stay so. Remapping an entry to its own ID discards any previous remapping.
+//
+// Entries are remapped as a whole, so the entry must be one of the original
+// File that is not removed; entries added by Add are given their ID there.
+// Writing the package fails with an error wrapping ErrDuplicateID if to is
+// also the ID of another entry of that type once all entries are remapped.
+func (s *Session) Remap(typ string, id, to uint32) error {
+	indexes, ok := s.src.indexesOf(typ)
+	if !ok {
+		return fmt.Errorf("unknown entry type %q", typ)
+	}
+	c, ok := s.changes[typ][id]
+	if !containsID(indexes, id) || (ok && (c.New || c.Removed)) {
+		return fmt.Errorf("no %s entry with ID %d in the original package", typ, id)
+	}
+	if to == id {
+		delete(s.remaps[typ], id)
+	} else {
+		s.remaps[typ][id] = to
+	}
+	return nil
+}
+
+// writtenID returns the ID the entry of type typ with the given original ID
+// is written with, see Remap.
+func (s *Session) writtenID(typ string, id uint32) uint32 {
+	if to, ok := s.remaps[typ][id]; ok {
+		return to
+	}
+	return id
+}
+
+// checkRemaps returns an error wrapping ErrDuplicateID if an entry is remapped
+// to the ID of another entry of the written package. Entries of the original
+// File sharing an ID are not told apart, and may be remapped together.
+func (s *Session) checkRemaps() error {
+	for _, typ := range []string{"bnk", "wem"} {
+		if len(s.remaps[typ]) == 0 {
+			continue
+		}
+		planned, sources := s.planIndexes(typ)
+		// The original ID of the first entry written with each ID.
+		original := make(map[uint32]uint32)
+		for i, idx := range planned {
+			id := idx.ID
+			if sources[i] != nil {
+				id = sources[i].ID
+			}
+			if first, ok := original[idx.ID]; !ok {
+				original[idx.ID] = id
+			} else if first != id {
+				if _, ok := s.remaps[typ][id]; !ok {
+					id = first
+				}
+				return fmt.Errorf("%w: remapping %s ID %d to %d: another entry has that ID",
+					ErrDuplicateID, typ, id, idx.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// Changes returns the pending changes of this session, ordered as their
+// entries appear in the index tables of the original File, followed by the
+// added entries in the order they were added.
+func (s *Session) Changes() []*Change {
+	var cs []*Change
+	for _, typ := range []string{"bnk", "wem"} {
+		indexes, _ := s.src.indexesOf(typ)
+		for _, idx := range indexes {
+			if c, ok := s.changes[typ][idx.ID]; ok {
+				cs = append(cs, c)
+			}
+		}
+	}
+	return append(cs, s.added...)
+}
+
+// Clone returns a copy of this session. Changes made to the copy do not affect
+// this session, and vice versa.
+func (s *Session) Clone() *Session {
+	c := s.src.NewSession()
+	c.preserveDataStart = s.preserveDataStart
+	c.dataAlignment = s.dataAlignment
+	c.preserveDataOrder = s.preserveDataOrder
+	c.sortIndexes = s.sortIndexes
+	c.gaps, c.trailer = s.gaps, s.trailer
+	c.transform = s.transform
+	c.watermark = s.watermark
+	if s.rawHeader != nil {
+		c.rawHeader = append([]byte{}, s.rawHeader...)
+	}
+	c.progress = s.progress
+	for typ, m := range s.overrides {
+		for id, o := range m {
+			c.overrides[typ][id] = o
+		}
+	}
+	for idx := range s.dropped {
+		c.dropped[idx] = true
+	}
+	for typ, m := range s.remaps {
+		for id, to := range m {
+			c.remaps[typ][id] = to
+		}
+	}
+	for typ, m := range s.changes {
+		for id, change := range m {
+			if !change.New {
+				c.changes[typ][id] = change
+			}
+		}
+	}
+	// Added changes are copied, since Replace modifies them.
+	for _, change := range s.added {
+		copied := *change
+		c.changes[copied.Type][copied.ID] = &copied
+		c.added = append(c.added, &copied)
+	}
+	return c
+}
+
+// WriteTo writes the package that results from applying all pending changes to
+// the original File to w. Neither the File nor the session are modified.
+func (s *Session) WriteTo(w io.Writer) (int64, error) {
+	return s.WriteToContext(context.Background(), w)
+}
+
+// WriteToContext is WriteTo, stopping with ctx's error if ctx is done before
+// the package is fully written. Entry data is written in chunks, so even a
+// very large entry stops soon after ctx is done.
+func (s *Session) WriteToContext(ctx context.Context, w io.Writer) (int64, error) {
+	w = s.src.encryptPackage(s.src.throttleWriter(contextWriter(ctx, w)))
+	l, entries := s.layout()
+	if s.rawHeader != nil && len(s.rawHeader) < 8 {
+		return 0, fmt.Errorf("the raw header is %d bytes long, shorter than its identifier and length",
+			len(s.rawHeader))
+	}
+	if s.watermark != nil && !s.canStamp() {
+		return 0, fmt.Errorf("the header of this package has no language map to hold a watermark")
+	}
+	if err := s.checkRemaps(); err != nil {
+		return 0, err
+	}
+	if s.transform != nil {
+		if err := s.applyTransform(entries); err != nil {
+			return 0, err
+		}
+		l.Size = s.placeEntries(entries, l.DataStart)
+	}
+	if err := s.checkLimits(l); err != nil {
+		return 0, err
+	}
+	indexEnd := 8 + int64(l.Header.HeaderAndIndexesLength)
+	if s.preserveDataStart && indexEnd != s.src.dataStart() {
+		return 0, fmt.Errorf("the header and index tables need %d bytes more than the original "+
+			"header and index region, so the data start offset cannot be preserved",
+			indexEnd-s.src.dataStart())
+	}
+
+	progress := newProgressTracker(s.progress, len(entries))
+	tables := [][]*FileIndex{l.BnkIndexes, l.WemIndexes, l.ExternalIndexes}
+	written, err := writeHeader(w, s.src.Format, s.src.ByteOrder, l.Header, tables)
+	if err != nil {
+		return written, err
+	}
+	// The index tables may end before the data start, see PreserveDataStart
+	// and WithDataAlignment.
+	n, err := writePadding(w, l.DataStart-written)
+	written += n
+	if err != nil {
+		return written, err
+	}
+	progress.add(0, written)
+
+	for _, e := range entries {
+		start := written
+		n, err := s.writeGap(w, s.gaps[e.src])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		n, err = writePadding(w, int64(e.idx.Offset)-written)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		var r io.Reader = e.transformed
+		if e.transformed == nil {
+			r = s.dataOf(e.typ, e.id(), e.src)
+		}
+		n, err = io.Copy(s.src.encryptEntry(w), r)
+		if err != nil {
+			return written, fmt.Errorf("writing %s ID %d: %w", e.typ, e.idx.ID, err)
+		}
+		written += n
+		if n < int64(e.idx.Length) {
+			return written, fmt.Errorf("writing %s ID %d: %w: only %d of its %d bytes could be read",
+				e.typ, e.idx.ID, ErrTruncated, n, e.idx.Length)
+		}
+		progress.add(1, written-start)
+	}
+	n, err = s.writeGap(w, s.trailer)
+	return written + n, err
+}
+
+// WriteInPlace applies all pending changes by overwriting the original package
+// through w, which must write to the file the session's File was opened from,
+// rather than writing a whole new package. Only the header and the data of
+// the replaced entries are written, so large packages can be patched quickly.
+//
+// This is only possible when no entry is added or removed, every replacement
+// holds no more data than the entry it replaces, and no replaced entry shares
+// its data with another entry. The rest of each replaced entry is zeroed.
+// Otherwise, or if the session has a TransformFunc or watermark, an error
+// wrapping ErrDoesNotFit is returned and nothing is written.
+func (s *Session) WriteInPlace(w io.WriterAt) (int64, error) {
+	if err := s.checkInPlace(); err != nil {
+		return 0, err
+	}
+	w = s.src.encryptPackageAt(s.src.throttleWriterAt(w))
+	bnks, _ := s.planIndexes("bnk")
+	wems, _ := s.planIndexes("wem")
+	tables := [][]*FileIndex{bnks, wems, s.src.ExternalIndexes}
+
+	var written int64
+	progress := newProgressTracker(s.progress, len(s.Changes()))
+	for _, c := range s.Changes() {
+		start := written
+		indexes, _ := s.src.indexesOf(c.Type)
+		for _, idx := range indexes {
+			if idx.ID != c.ID {
+				continue
+			}
+			dst := s.src.encryptEntry(&offsetWriter{w, int64(idx.Offset)})
+			n, err := io.Copy(dst, io.NewSectionReader(s.src.throttleReaderAt(c.Data), 0, c.Length))
+			written += n
+			if err != nil {
+				return written, fmt.Errorf("writing %s ID %d: %w", c.Type, c.ID, err)
+			}
+			n, err = writePadding(dst, int64(idx.Length)-c.Length)
+			written += n
+			if err != nil {
+				return written, fmt.Errorf("writing %s ID %d: %w", c.Type, c.ID, err)
+			}
+		}
+		progress.add(1, written-start)
+	}
+
+	// The index tables keep their size, so the header is rewritten as is, with
+	// only the lengths of the replaced entries changed.
+	header := new(bytes.Buffer)
+	if _, err := writeHeader(header, s.src.Format, s.src.ByteOrder, s.src.Header, tables); err != nil {
+		return written, err
+	}
+	n, err := w.WriteAt(header.Bytes(), 0)
+	progress.add(0, int64(n))
+	return written + int64(n), err
+}
+
+// WriteAppend applies all pending changes to the original package through w,
+// which must write to the file the session's File was opened from, by
+// appending the data of the replaced entries to the end of the package and
+// rewriting its index tables to point at it. The rest of the package is left
+// untouched, including the original data of the replaced entries, which
+// becomes unused; File.Gaps reports it, and compacting the package reclaims
+// it. Unlike WriteInPlace, replacements may be of any size, so that an entry
+// of a very large package can be replaced again and again in moments while a
+// mod is being tested.
+//
+// This is only possible when no entry is added or removed, and the session has
+// no TransformFunc or watermark; otherwise an error wrapping ErrDoesNotFit is
+// returned and nothing is written. Entries sharing the data of a replaced
+// entry keep the original data. Emptied entries keep their offset.
+func (s *Session) WriteAppend(w io.WriterAt) (int64, error) {
+	if err := s.checkAppend(); err != nil {
+		return 0, err
+	}
+	end, err := s.src.fileSize()
+	if err != nil {
+		return 0, err
+	}
+	bnks, bnkSources := s.planIndexes("bnk")
+	wems, wemSources := s.planIndexes("wem")
+	planned := map[string][]*FileIndex{"bnk": bnks, "wem": wems}
+	sources := map[string][]*FileIndex{"bnk": bnkSources, "wem": wemSources}
+
+	// Lay the replaced entries out after the end of the package, once each
+	// even if their ID occurs several times.
+	offsets := make(map[*Change]uint64)
+	var changes []*Change
+	for _, c := range s.Changes() {
+		if _, ok := offsets[c]; ok {
+			continue
+		}
+		for i, idx := range planned[c.Type] {
+			if sources[c.Type][i].ID != c.ID || c.Length == 0 {
+				continue
+			}
+			offset, ok := offsets[c]
+			if !ok {
+				align := s.entryAlignment(c.Type, c.ID, idx)
+				offset = (uint64(end) + align - 1) / align * align
+				offsets[c] = offset
+				changes = append(changes, c)
+				end = int64(offset) + c.Length
+			}
+			idx.Offset = offset
+		}
+	}
+	l := &Layout{BnkIndexes: bnks, WemIndexes: wems, ExternalIndexes: s.src.ExternalIndexes}
+	if err := s.checkLimits(l); err != nil {
+		return 0, err
+	}
+
+	w = s.src.encryptPackageAt(s.src.throttleWriterAt(w))
+	var written int64
+	progress := newProgressTracker(s.progress, len(changes))
+	next, _ := s.src.fileSize()
+	for _, c := range changes {
+		start := written
+		n, err := writePadding(&offsetWriter{w, next}, int64(offsets[c])-next)
+		written += n
+		if err != nil {
+			return written, fmt.Errorf("writing %s ID %d: %w", c.Type, c.ID, err)
+		}
+		dst := s.src.encryptEntry(&offsetWriter{w, int64(offsets[c])})
+		n, err = io.Copy(dst, io.NewSectionReader(s.src.throttleReaderAt(c.Data), 0, c.Length))
+		written += n
+		if err != nil {
+			return written, fmt.Errorf("writing %s ID %d: %w", c.Type, c.ID, err)
+		}
+		next = int64(offsets[c]) + c.Length
+		progress.add(1, written-start)
+	}
+
+	// The data is written before the index tables point at it, so that the
+	// package stays whole if writing the data fails.
+	header := new(bytes.Buffer)
+	tables := [][]*FileIndex{bnks, wems, s.src.ExternalIndexes}
+	if _, err := writeHeader(header, s.src.Format, s.src.ByteOrder, s.src.Header, tables); err != nil {
+		return written, err
+	}
+	n, err := w.WriteAt(header.Bytes(), 0)
+	progress.add(0, int64(n))
+	return written + int64(n), err
+}
+
+// checkInPlace returns an error wrapping ErrDoesNotFit if the pending changes
+// cannot be written in place, see WriteInPlace.
+func (s *Session) checkInPlace() error {
+	if err := s.checkTablesKept(false); err != nil {
+		return err
+	}
+	if err := s.checkRemaps(); err != nil {
+		return err
+	}
+	for _, c := range s.Changes() {
+		indexes, _ := s.src.indexesOf(c.Type)
+		for _, idx := range indexes {
+			if idx.ID != c.ID {
+				continue
+			}
+			if c.Length > int64(idx.Length) {
+				return fmt.Errorf("%w: %s ID %d needs %d bytes, but its entry holds %d",
+					ErrDoesNotFit, c.Type, c.ID, c.Length, idx.Length)
+			}
+			if other, ok := s.src.sharesData(idx); ok {
+				return fmt.Errorf("%w: %s ID %d shares its data with ID %d",
+					ErrDoesNotFit, c.Type, c.ID, other.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// checkAppend returns an error wrapping ErrDoesNotFit if the pending changes
+// cannot be written by appending them, see WriteAppend.
+func (s *Session) checkAppend() error {
+	if err := s.checkTablesKept(true); err != nil {
+		return err
+	}
+	return s.checkRemaps()
+}
+
+// checkTablesKept returns an error wrapping ErrDoesNotFit if the pending
+// changes cannot be written without rewriting the index tables as a whole or
+// moving the data of an entry, other than that of the replaced entries if
+// moveReplaced is true.
+func (s *Session) checkTablesKept(moveReplaced bool) error {
+	if s.transform != nil || s.watermark != nil || s.rawHeader != nil {
+		return fmt.Errorf("%w: transforms, watermarks and injected headers need the package to be "+
+			"rewritten", ErrDoesNotFit)
+	}
+	for typ, m := range s.overrides {
+		indexes, _ := s.src.indexesOf(typ)
+		for _, idx := range indexes {
+			if _, ok := s.changes[typ][idx.ID]; ok && moveReplaced {
+				continue
+			}
+			if o, ok := m[idx.ID]; ok {
+				overridden := *idx
+				o.apply(&overridden)
+				if overridden.Offset%s.entryAlignment(typ, idx.ID, &overridden) != 0 {
+					return fmt.Errorf("%w: the data of %s ID %d would have to be moved to meet "+
+						"its alignment", ErrDoesNotFit, typ, idx.ID)
+				}
+			}
+		}
+	}
+	if len(s.dropped) > 0 {
+		return fmt.Errorf("%w: leaving out duplicated entries changes the index tables", ErrDoesNotFit)
+	}
+	for _, c := range s.Changes() {
+		if c.New || c.Removed {
+			return fmt.Errorf("%w: adding or removing %s ID %d changes the index tables",
+				ErrDoesNotFit, c.Type, c.ID)
+		}
+	}
+	return nil
+}
+
+// sharesData returns another entry of the package whose data overlaps that of
+// idx, if there is one.
+func (pck *File) sharesData(idx *FileIndex) (*FileIndex, bool) {
+	start, end := idx.Offset, idx.Offset+uint64(idx.Length)
+	for _, indexes := range pck.indexTables() {
+		for _, other := range indexes {
+			if other == idx || other.Length == 0 {
+				continue
+			}
+			if other.Offset < end && start < other.Offset+uint64(other.Length) {
+				return other, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// offsetWriter is an io.Writer that writes to an io.WriterAt, starting at
+// offset.
+type offsetWriter struct {
+	w      io.WriterAt
+	offset int64
+}
+
+func (o *offsetWriter) Write(p []byte) (int, error) {
+	n, err := o.w.WriteAt(p, o.offset)
+	o.offset += int64(n)
+	return n, err
+}
+
+// Preview computes the layout of the package that results from applying all
+// pending changes, without writing anything. Entry data is laid out after the
+// index tables, in index order or, if the session preserves the data order, in
+// the order of the original File, after the gaps the session keeps. Each entry
+// starts on a multiple of the File's Alignment and, in standard packages, of
+// its block size. If the session preserves the data start offset and the index
+// tables do not fit before it, DataStart is where data would have to start.
+// The layout does not account for the session's TransformFunc, if any, since
+// the lengths of transformed entries are only known once they are written.
+func (s *Session) Preview() *Layout {
+	l, _ := s.layout()
+	return l
+}
+
+// checkLimits returns an error wrapping ErrOffsetOverflow if the length of a
+// pending change, or the offset of an entry of l, does not fit in the index
+// entries of the session's File.
+func (s *Session) checkLimits(l *Layout) error {
+	for _, c := range s.Changes() {
+		if !c.Removed && c.Length > math.MaxUint32 {
+			return fmt.Errorf("%w: %s ID %d would be %d bytes long, but entries hold at most %d bytes",
+				ErrOffsetOverflow, c.Type, c.ID, c.Length, uint32(math.MaxUint32))
+		}
+	}
+	tables := [][]*FileIndex{l.BnkIndexes, l.WemIndexes, l.ExternalIndexes}
+	for i, c := range s.src.Format.tables() {
+		for _, idx := range tables[i] {
+			if max := c.maxOffset(idx); idx.Offset > max {
+				return fmt.Errorf("%w: %s ID %d would start at offset %d, past the largest offset "+
+					"of %d its index entry can hold; %s", ErrOffsetOverflow, tableNames[i], idx.ID,
+					idx.Offset, max, s.src.Format.overflowHint())
+			}
+		}
+	}
+	return nil
+}
+
+// A plannedEntry is an entry of the package a session would write.
+type plannedEntry struct {
+	typ string
+	// The index of the entry in the written package.
+	idx *FileIndex
+	// The index of the entry in the original File, or nil if it is added.
+	src *FileIndex
+	// The data of the entry as transformed by the session's TransformFunc, if
+	// any.
+	transformed *bytes.Reader
+}
+
+// id returns the ID the pending change and override of the entry are keyed
+// by, that of its original index even if the entry is remapped, see Remap.
+func (e *plannedEntry) id() uint32 {
+	if e.src != nil {
+		return e.src.ID
+	}
+	return e.idx.ID
+}
+
+// layout computes the layout of the package that results from applying all
+// pending changes, see Preview. It also returns the entries of the layout in
+// the order their data is stored.
+func (s *Session) layout() (*Layout, []*plannedEntry) {
+	bnks, bnkSources := s.planIndexes("bnk")
+	wems, wemSources := s.planIndexes("wem")
+	tables := [][]*FileIndex{bnks, wems, copyIndexes(s.src.ExternalIndexes)}
+	sources := [][]*FileIndex{bnkSources, wemSources, s.src.ExternalIndexes}
+
+	hdr := *s.src.Header
+	hdr.Unknown = s.updateTableLengths(tables)
+	if len(s.rawHeader) >= 8 {
+		hdr = *s.injectedHeader(tables)
+	}
+	if s.watermark != nil {
+		hdr.Unknown, _ = stampWatermark(hdr.Unknown, s.src.Format, s.src.ByteOrder, s.watermark)
+	}
+	dataAreaStartOffset := uint32(4 + 4 + len(hdr.Unknown))
+	for i, c := range s.src.Format.tables() {
+		dataAreaStartOffset += indexTableSize(c, len(tables[i]))
+	}
+	if want := uint32(s.src.dataStart()); s.preserveDataStart && dataAreaStartOffset < want {
+		hdr.Unknown = s.padLanguageMap(hdr.Unknown, int(want-dataAreaStartOffset))
+		dataAreaStartOffset = want
+	}
+
+	// Subtract Identifier and the field itself
+	hdr.HeaderAndIndexesLength = dataAreaStartOffset - 8
+
+	dataStart := int64(dataAreaStartOffset)
+	if align := int64(s.dataAlignment); align > 1 {
+		dataStart = (dataStart + align - 1) / align * align
+	}
+	entries := s.dataOrder(tables, sources)
+	l := &Layout{
+		Header:          &hdr,
+		BnkIndexes:      tables[0],
+		WemIndexes:      tables[1],
+		ExternalIndexes: tables[2],
+		DataStart:       dataStart,
+		Size:            s.placeEntries(entries, dataStart),
+	}
+	return l, entries
+}
+
+// placeEntries sets the offset of each of entries, which are stored in order
+// from dataStart after the gaps the session keeps, and returns the offset at
+// which the package ends.
+func (s *Session) placeEntries(entries []*plannedEntry, dataStart int64) int64 {
+	currentOffset := uint64(dataStart)
+	for _, e := range entries {
+		if g, ok := s.gaps[e.src]; ok {
+			currentOffset += uint64(g.Length)
+		}
+		align := s.entryAlignment(e.typ, e.id(), e.idx)
+		e.idx.Offset = (currentOffset + align - 1) / align * align
+		currentOffset = e.idx.Offset + uint64(e.idx.Length)
+	}
+	if s.trailer != nil {
+		currentOffset += uint64(s.trailer.Length)
+	}
+	return int64(currentOffset)
+}
+
+// entryAlignment returns the alignment of the data of idx, an entry of type typ
+// in the package the session writes whose changes are keyed by id: that of the
+// original File, and of any override of the entry.
+func (s *Session) entryAlignment(typ string, id uint32, idx *FileIndex) uint64 {
+	align := s.src.alignment(idx)
+	if o, ok := s.overrides[typ][id]; ok && o.Alignment > 1 {
+		align = lcm(align, uint64(o.Alignment))
+	}
+	return align
+}
+
+// applyTransform passes the data of each of entries through the session's
+// TransformFunc, keeping the transformed data in memory so that the lengths of
+// the entries are known before the index tables are written.
+func (s *Session) applyTransform(entries []*plannedEntry) error {
+	for _, e := range entries {
+		r, err := s.transform(&Entry{e.typ, e.idx}, s.dataOf(e.typ, e.id(), e.src))
+		if err != nil {
+			return fmt.Errorf("transforming %s ID %d: %w", e.typ, e.idx.ID, err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return fmt.Errorf("transforming %s ID %d: %w", e.typ, e.idx.ID, err)
+		}
+		if int64(len(data)) > math.MaxUint32 {
+			return fmt.Errorf("transforming %s ID %d: %d bytes do not fit in an entry",
+				e.typ, e.idx.ID, len(data))
+		}
+		e.idx.Length = uint32(len(data))
+		e.transformed = bytes.NewReader(data)
+	}
+	return nil
+}
+
+// dataOrder returns the entries of tables, whose original indexes are given by
+// sources, in the order their data is to be stored: in index order or, if the
+// session preserves the data order, in the order of the original File, with
+// added entries last.
+func (s *Session) dataOrder(tables, sources [][]*FileIndex) []*plannedEntry {
+	var entries []*plannedEntry
+	for i, indexes := range tables {
+		for j, idx := range indexes {
+			entries = append(entries, &plannedEntry{typ: tableNames[i], idx: idx, src: sources[i][j]})
+		}
+	}
+	if s.preserveDataOrder {
+		sort.SliceStable(entries, func(i, j int) bool {
+			a, b := entries[i].src, entries[j].src
+			return a != nil && (b == nil || a.Offset < b.Offset)
+		})
+	}
+	return entries
+}
+
+// The offset into the Unknown header section of the length of the first index
+// table. It follows the package version and the language map length, and is
+// followed by the lengths of the other tables.
+const tableLengthsOffset = 8
+
+// updateTableLengths returns a copy of the Unknown header section of the
+// original File, with the index table lengths it records updated to those of
+// tables. A length is only updated if it matched the length of the original
+// table, so the Unknown section of packages whose header does not record the
+// table lengths is left as is.
+func (s *Session) updateTableLengths(tables [][]*FileIndex) []byte {
+	return s.src.updateTableLengths(s.src.Header.Unknown, tables)
+}
+
+// injectedHeader returns the header given by WithRawHeader, with the lengths
+// of the index tables it records set to those of tables if its layout is that
+// of the format of the package written. HeaderAndIndexesLength is left for the
+// caller to set.
+func (s *Session) injectedHeader(tables [][]*FileIndex) *Header {
+	hdr := &Header{Unknown: append([]byte(nil), s.rawHeader[8:]...), order: s.src.ByteOrder}
+	copy(hdr.Identifier[:], s.rawHeader)
+	if _, _, ok := languageMapBounds(hdr.Unknown, s.src.Format, s.src.ByteOrder); ok {
+		for i, c := range s.src.Format.tables() {
+			s.src.ByteOrder.PutUint32(hdr.Unknown[tableLengthsOffset+4*i:], indexTableSize(c, len(tables[i])))
+		}
+	}
+	return hdr
+}
+
+// updateTableLengths returns a copy of unknown, an Unknown header section of
+// pck, with the index table lengths it records updated from those of the
+// index tables of pck to those of tables.
+func (pck *File) updateTableLengths(unknown []byte, tables [][]*FileIndex) []byte {
+	unknown = append([]byte(nil), unknown...)
+	o := pck.ByteOrder
+	for i, c := range pck.Format.tables() {
+		off := tableLengthsOffset + 4*i
+		if off+4 > len(unknown) {
+			break
+		}
+		if o.Uint32(unknown[off:]) == indexTableSize(c, len(pck.indexTables()[i])) {
+			o.PutUint32(unknown[off:], indexTableSize(c, len(tables[i])))
+		}
+	}
+	return unknown
+}
+
+// canStamp reports whether a watermark can be stored in the header of the
+// original File, or in the header given by WithRawHeader if any.
+func (s *Session) canStamp() bool {
+	unknown := s.src.Header.Unknown
+	if len(s.rawHeader) >= 8 {
+		unknown = s.rawHeader[8:]
+	}
+	_, _, ok := languageMapBounds(unknown, s.src.Format, s.src.ByteOrder)
+	return ok
+}
+
+// padLanguageMap returns unknown, an Unknown header section, with n zero bytes
+// of padding added to the end of its language map, so that the index tables
+// still directly follow it and the header remains readable. If the language map
+// length is not recorded in the header, unknown is returned unchanged, and the
+// padding is left between the index tables and the data instead.
+func (s *Session) padLanguageMap(unknown []byte, n int) []byte {
+	start, _, ok := languageMapBounds(unknown, s.src.Format, s.src.ByteOrder)
+	if !ok {
+		return unknown
+	}
+	padded := append(unknown, make([]byte, n)...)
+	s.src.ByteOrder.PutUint32(padded[languageMapLengthOffset:], uint32(len(padded)-start))
+	return padded
+}
+
+func (l *Layout) String() string {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "Data Start: %d\n", l.DataStart)
+	fmt.Fprintf(b, "Total Size: %d\n", l.Size)
+	fmt.Fprintf(b, "BNK Count: %d\n", len(l.BnkIndexes))
+	fmt.Fprintf(b, "WEM Count: %d\n", len(l.WemIndexes))
+	if len(l.ExternalIndexes) > 0 {
+		fmt.Fprintf(b, "External Count: %d\n", len(l.ExternalIndexes))
+	}
+	b.WriteString("\n")
+	writeIndexTables(b, l.BnkIndexes, l.WemIndexes, nil)
+	if len(l.ExternalIndexes) > 0 {
+		b.WriteString("\n--- External Files ---\n")
+		writeExternalTable(b, l.ExternalIndexes, nil)
+	}
+	return b.String()
+}
+
+// planIndexes returns copies of the indexes of type typ with their lengths
+// updated to account for any pending changes, without those of removed entries
+// and with new indexes for any added entries, sorted by ID if the session
+// sorts indexes. It also returns the original index of each planned index, or nil
+// for added entries.
+func (s *Session) planIndexes(typ string) (planned, sources []*FileIndex) {
+	indexes, _ := s.src.indexesOf(typ)
+	for _, idx := range indexes {
+		c, ok := s.changes[typ][idx.ID]
+		if (ok && c.Removed) || s.dropped[idx] {
+			continue
+		}
+		newIdx := *idx // Make a copy
+		if ok {
+			newIdx.Length = uint32(c.Length)
+		}
+		if o, ok := s.overrides[typ][idx.ID]; ok {
+			o.apply(&newIdx)
+		}
+		planned = append(planned, &newIdx)
+		sources = append(sources, idx)
+	}
+
+	sorted := sort.SliceIsSorted(planned, func(i, j int) bool {
+		return planned[i].ID < planned[j].ID
+	})
+	if len(s.remaps[typ]) > 0 {
+		for _, idx := range planned {
+			idx.ID = s.writtenID(typ, idx.ID)
+		}
+		if sorted {
+			s.sortPlanned(planned, sources)
+		}
+	}
+	for _, c := range s.added {
+		if c.Type != typ {
+			continue
+		}
+		idx := s.newIndex(c)
+		if o, ok := s.overrides[typ][c.ID]; ok {
+			o.apply(idx)
+		}
+		i := len(planned)
+		if sorted {
+			i = sort.Search(len(planned), func(i int) bool { return planned[i].ID > c.ID })
+		}
+		planned = append(planned[:i], append([]*FileIndex{idx}, planned[i:]...)...)
+		sources = append(sources[:i], append([]*FileIndex{nil}, sources[i:]...)...)
+	}
+	if s.sortIndexes {
+		s.sortPlanned(planned, sources)
+	}
+	return planned, sources
+}
+
+// newIndex returns the index of the entry added by c. The fields whose meaning
+// is unknown, and the block size of standard entries, are taken from the first
+// entry of the same type, if any, since they are usually shared by the entries
+// of a package. Otherwise Type, the block size of standard entries, is 1.
+func (s *Session) newIndex(c *Change) *FileIndex {
+	idx := &FileIndex{ID: c.ID, Type: 1, Length: uint32(c.Length)}
+	indexes, _ := s.src.indexesOf(c.Type)
+	if len(indexes) > 0 {
+		idx.Type = indexes[0].Type
+		idx.Unknown2 = indexes[0].Unknown2
+	}
+	return idx
+}
+
+// copyIndexes returns copies of indexes.
+func copyIndexes(indexes []*FileIndex) []*FileIndex {
+	copies := make([]*FileIndex, len(indexes))
+	for i, idx := range indexes {
+		newIdx := *idx // Make a copy
+		copies[i] = &newIdx
+	}
+	return copies
+}
+
+// dataOf returns a reader over the data that the entry of type typ with the
+// given ID will hold once pending changes are applied. src is the original
+// index of the entry, or nil if it is added by a pending change.
+func (s *Session) dataOf(typ string, id uint32, src *FileIndex) io.Reader {
+	if c, ok := s.changes[typ][id]; ok {
+		return io.NewSectionReader(s.src.throttleReaderAt(c.Data), 0, c.Length)
+	}
+	return io.NewSectionReader(s.src.reader, int64(src.Offset), int64(src.Length))
+}
+
+// indexesOf returns the index table of type typ ("bnk" or "wem"). ok is false
+// if typ is not a valid entry type.
+func (pck *File) indexesOf(typ string) (indexes []*FileIndex, ok bool) {
+	switch typ {
+	case "bnk":
+		return pck.BnkIndexes, true
+	case "wem":
+		return pck.WemIndexes, true
+	}
+	return nil, false
+}
+
+// containsID reports whether any of indexes has the given ID.
+func containsID(indexes []*FileIndex, id uint32) bool {
+	for _, idx := range indexes {
+		if idx.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,665 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// writeSession writes s and opens the package written, returning its data.
+func writeSession(t *testing.T, s *Session) (*File, []byte) {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if _, err := s.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	return openMemory(t, buf.Bytes()), buf.Bytes()
+}
+
+// sessionBytes returns the bytes of the package s writes, as they are
+// written, without opening it.
+func sessionBytes(t *testing.T, s *Session) []byte {
+	t.Helper()
+	buf := new(bytes.Buffer)
+	if _, err := s.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// wemData returns the data of every wem of f, by ID.
+func wemData(t *testing.T, f *File) map[uint32][]byte {
+	t.Helper()
+	wems := make(map[uint32][]byte)
+	for _, w := range f.Wems {
+		data, err := io.ReadAll(w.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		wems[w.Index.ID] = data
+	}
+	return wems
+}
+
+func TestSessionCloneIsCopyOnWrite(t *testing.T) {
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	orig := []byte("RIFF replaced in the original")
+	s := f.NewSession()
+	if err := s.Replace("wem", 2, bytes.NewReader(orig), int64(len(orig))); err != nil {
+		t.Fatal(err)
+	}
+
+	c := s.Clone()
+	cloned := []byte("RIFF replaced in the clone")
+	if err := c.Replace("wem", 3, bytes.NewReader(cloned), int64(len(cloned))); err != nil {
+		t.Fatal(err)
+	}
+	c.Revert("wem", 2)
+
+	if len(s.Changes()) != 1 {
+		t.Errorf("changing the clone changed the session: %d changes, want 1", len(s.Changes()))
+	}
+	written, _ := writeSession(t, s)
+	wems := wemData(t, written)
+	if !bytes.Equal(wems[2], orig) || !bytes.Equal(wems[3], testWems[1]) {
+		t.Error("the session does not write its own changes")
+	}
+	written, _ = writeSession(t, c)
+	wems = wemData(t, written)
+	if !bytes.Equal(wems[2], testWems[0]) || !bytes.Equal(wems[3], cloned) {
+		t.Error("the clone does not write the changes of the session and its own")
+	}
+}
+
+func TestSessionRejectsUnknownEntries(t *testing.T) {
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	s := f.NewSession()
+	data := bytes.NewReader(nil)
+	if err := s.Replace("wem", 1, data, 0); err == nil {
+		t.Error("replacing a wem with the ID of a bnk succeeded")
+	}
+	if err := s.Replace("txt", 2, data, 0); err == nil {
+		t.Error("replacing an entry of an unknown type succeeded")
+	}
+}
+
+func TestSessionPreviewMatchesWrittenPackage(t *testing.T) {
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	s := f.NewSession()
+	larger := bytes.Repeat([]byte("RIFF"), 100)
+	if err := s.Replace("wem", 2, bytes.NewReader(larger), int64(len(larger))); err != nil {
+		t.Fatal(err)
+	}
+	l := s.Preview()
+	written, data := writeSession(t, s)
+	if l.Size != int64(len(data)) {
+		t.Errorf("previewed a package of %d bytes, but %d were written", l.Size, len(data))
+	}
+	for _, c := range []struct {
+		name      string
+		got, want []*FileIndex
+	}{{"bnk", l.BnkIndexes, written.BnkIndexes}, {"wem", l.WemIndexes, written.WemIndexes}} {
+		if len(c.got) != len(c.want) {
+			t.Errorf("previewed %d %s indexes, but %d were written", len(c.got), c.name, len(c.want))
+			continue
+		}
+		for i := range c.got {
+			if *c.got[i] != *c.want[i] {
+				t.Errorf("previewed %s index %+v, but %+v was written", c.name, *c.got[i], *c.want[i])
+			}
+		}
+	}
+	if f.Wems[0].Index.Length != uint32(len(testWems[0])) {
+		t.Error("previewing modified the File")
+	}
+}
+
+func TestSessionAdd(t *testing.T) {
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	s := f.NewSession()
+	data := []byte("RIFF added")
+	if err := s.Add("wem", 250, bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Add("wem", 2, bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("added a wem with the ID of an existing one")
+	}
+	// A bank may have the ID of a wem.
+	if err := s.Add("bnk", 250, bytes.NewReader(data), 4); err != nil {
+		t.Error(err)
+	}
+	// Reverting an added entry discards it.
+	if err := s.Add("wem", 100, bytes.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	s.Revert("wem", 100)
+
+	added, _ := writeSession(t, s)
+	// The table is ordered by ID, so the new wem is inserted in order.
+	var ids []uint32
+	for _, w := range added.Wems {
+		ids = append(ids, w.Index.ID)
+	}
+	if fmt.Sprint(ids) != "[2 3 250]" {
+		t.Errorf("wrote wems with IDs %v, want [2 3 250]", ids)
+	}
+	want := map[uint32][]byte{2: testWems[0], 3: testWems[1], 250: data}
+	if got := wemData(t, added); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("wems hold %q, want %q", got, want)
+	}
+	if len(added.Bnks) != 2 {
+		t.Fatalf("wrote %d banks, want 2", len(added.Bnks))
+	}
+	if got, _ := added.Bnks[1].Bytes(); string(got) != "RIFF" {
+		t.Errorf("the added bank holds %q", got)
+	}
+}
+
+// firstDataOffset returns the offset of the data of the first entry of f.
+func firstDataOffset(f *File) uint64 {
+	first := uint64(math.MaxUint64)
+	for _, indexes := range f.indexTables() {
+		for _, idx := range indexes {
+			if idx.Length > 0 && idx.Offset < first {
+				first = idx.Offset
+			}
+		}
+	}
+	return first
+}
+
+func TestPreserveDataStart(t *testing.T) {
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	s := f.NewSession(PreserveDataStart())
+	smaller := []byte("RIFF")
+	if err := s.Replace("wem", 2, bytes.NewReader(smaller), int64(len(smaller))); err != nil {
+		t.Fatal(err)
+	}
+	replaced, _ := writeSession(t, s)
+	if replaced.Header.HeaderAndIndexesLength != f.Header.HeaderAndIndexesLength ||
+		firstDataOffset(replaced) != firstDataOffset(f) {
+		t.Errorf("the data starts at %d after a header region of %d bytes, want %d and %d",
+			firstDataOffset(replaced), replaced.Header.HeaderAndIndexesLength,
+			firstDataOffset(f), f.Header.HeaderAndIndexesLength)
+	}
+	want := map[uint32][]byte{2: smaller, 3: testWems[1]}
+	if got := wemData(t, replaced); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("wems hold %q, want %q", got, want)
+	}
+
+	// The index tables of a package whose data starts right after them no
+	// longer fit once an entry is added.
+	if err := s.Add("wem", 1, bytes.NewReader(smaller), int64(len(smaller))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.WriteTo(io.Discard); err == nil {
+		t.Error("the index tables grew past the start of the data")
+	}
+}
+
+// dataOrder returns the IDs of the wems of f in the order their data is
+// stored.
+func dataOrder(f *File) []uint32 {
+	wems := append([]*EmbeddedFile(nil), f.Wems...)
+	sort.Slice(wems, func(i, j int) bool { return wems[i].Index.Offset < wems[j].Index.Offset })
+	ids := make([]uint32, len(wems))
+	for i, w := range wems {
+		ids[i] = w.Index.ID
+	}
+	return ids
+}
+
+func TestPreserveDataOrder(t *testing.T) {
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	// Adding wem ID 1 inserts its index first in the sorted table, but stores
+	// its data after that of the others.
+	s := f.NewSession(PreserveDataOrder())
+	added := []byte("RIFF added")
+	if err := s.Add("wem", 1, bytes.NewReader(added), int64(len(added))); err != nil {
+		t.Fatal(err)
+	}
+	reordered, data := writeSession(t, s)
+	if got, want := fmt.Sprint(dataOrder(reordered)), fmt.Sprint([]uint32{2, 3, 1}); got != want {
+		t.Errorf("the data of the wems is stored in the order %s, want %s", got, want)
+	}
+
+	// Rewriting the package stores the data in index order, unless the order
+	// is preserved.
+	for _, preserve := range []bool{false, true} {
+		var opts []Option
+		want := fmt.Sprint([]uint32{1, 2, 3})
+		if preserve {
+			opts = append(opts, PreserveDataOrder())
+			want = fmt.Sprint(dataOrder(reordered))
+		}
+		rewritten, rewrittenData := writeSession(t, reordered.NewSession(opts...))
+		if got := fmt.Sprint(dataOrder(rewritten)); got != want {
+			t.Errorf("rewritten with PreserveDataOrder %v, the data of the wems is stored in the "+
+				"order %s, want %s", preserve, got, want)
+		}
+		if preserve && !bytes.Equal(rewrittenData, data) {
+			t.Error("rewriting the package with its data order preserved changed it")
+		}
+	}
+}
+
+func TestWithTransform(t *testing.T) {
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	entries := testEntries()
+	// Reverse the data of every wem, keeping that of the banks.
+	seen := make(map[string]int)
+	transform := func(e *Entry, r io.Reader) (io.Reader, error) {
+		seen[e.Type]++
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		if int64(len(data)) != int64(e.Index.Length) {
+			t.Errorf("%s ID %d has a length of %d, but %d bytes were read", e.Type,
+				e.Index.ID, e.Index.Length, len(data))
+		}
+		if e.Type == "bnk" {
+			return bytes.NewReader(data), nil
+		}
+		for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+			data[i], data[j] = data[j], data[i]
+		}
+		// Transformed data may have another length.
+		return bytes.NewReader(append(data, '!')), nil
+	}
+	transformed, _ := writeSession(t, f.NewSession(WithTransform(transform)))
+	if seen["bnk"] != len(entries["bnk"]) || seen["wem"] != len(entries["wem"]) {
+		t.Errorf("transformed %v entries", seen)
+	}
+	for typ, files := range map[string][]*EmbeddedFile{"bnk": transformed.Bnks, "wem": transformed.Wems} {
+		for _, e := range files {
+			want := append([]byte(nil), entries[typ][e.Index.ID]...)
+			if typ == "wem" {
+				for i, j := 0, len(want)-1; i < j; i, j = i+1, j-1 {
+					want[i], want[j] = want[j], want[i]
+				}
+				want = append(want, '!')
+			}
+			if got, err := e.Bytes(); err != nil || !bytes.Equal(got, want) {
+				t.Errorf("%s ID %d holds %q (%v), want %q", typ, e.Index.ID, got, err, want)
+			}
+		}
+	}
+	transformed.Close()
+
+	failed := errors.New("transform failed")
+	s := f.NewSession(WithTransform(func(*Entry, io.Reader) (io.Reader, error) { return nil, failed }))
+	if _, err := s.WriteTo(io.Discard); !errors.Is(err, failed) {
+		t.Errorf("got %v, want the error of the transform", err)
+	}
+}
+
+func TestAlignmentIsKept(t *testing.T) {
+	f, err := Open(writeTestPackage(t, buildPackage(testBnks, testWems)), WithAlignment(16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	s := f.NewSession()
+	odd := []byte("RIFF odd")[:7]
+	if err := s.Replace("wem", 2, bytes.NewReader(odd), int64(len(odd))); err != nil {
+		t.Fatal(err)
+	}
+	replaced, _ := writeSession(t, s)
+	for _, indexes := range replaced.indexTables() {
+		for _, idx := range indexes {
+			if idx.Length > 0 && idx.Offset%16 != 0 {
+				t.Errorf("ID %d is stored at offset %d, which is not aligned to 16", idx.ID, idx.Offset)
+			}
+		}
+	}
+	// The alignment of the rewritten package is detected from its offsets.
+	if replaced.Alignment%16 != 0 {
+		t.Errorf("the rewritten package has an alignment of %d, want a multiple of 16", replaced.Alignment)
+	}
+	rewritten, _ := writeSession(t, replaced.NewSession())
+	if !bytes.Equal(wemData(t, rewritten)[2], odd) || rewritten.Alignment%16 != 0 {
+		t.Errorf("rewriting the package lost its alignment of 16 bytes, it now has %d", rewritten.Alignment)
+	}
+}
+
+// memFile is an io.WriterAt over a package held in memory, which grows as
+// data is written past its end.
+type memFile struct {
+	data []byte
+}
+
+func (m *memFile) WriteAt(p []byte, off int64) (int, error) {
+	if end := int(off) + len(p); end > len(m.data) {
+		m.data = append(m.data, make([]byte, end-len(m.data))...)
+	}
+	return copy(m.data[off:], p), nil
+}
+
+func TestSessionWriteInPlace(t *testing.T) {
+	entries := testEntries()
+	f, data := openTestPackage(t)
+	target := f.Wems[0].Index
+	smaller := []byte("RIFF in place")
+	s := f.NewSession()
+	if err := s.Replace("wem", target.ID, bytes.NewReader(smaller), int64(len(smaller))); err != nil {
+		t.Fatal(err)
+	}
+	m := &memFile{append([]byte(nil), data...)}
+	if _, err := s.WriteInPlace(m); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if len(m.data) != len(data) {
+		t.Errorf("patching in place changed the size of the package from %d to %d bytes",
+			len(data), len(m.data))
+	}
+	g := openMemory(t, m.data)
+	for _, w := range g.Wems {
+		want := entries["wem"][w.Index.ID]
+		if w.Index.ID == target.ID {
+			want = smaller
+			if w.Index.Offset != target.Offset {
+				t.Errorf("the replaced wem moved from offset %d to %d", target.Offset, w.Index.Offset)
+			}
+			// The rest of the original data is zeroed.
+			rest := m.data[w.Index.Offset+uint64(len(smaller)) : target.Offset+uint64(target.Length)]
+			if !bytes.Equal(rest, make([]byte, len(rest))) {
+				t.Errorf("the rest of the replaced wem was not zeroed")
+			}
+		}
+		if got, err := w.Bytes(); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("wem ID %d holds %q (%v), want %q", w.Index.ID, got, err, want)
+		}
+	}
+	g.Close()
+}
+
+func TestSessionWriteInPlaceDoesNotFit(t *testing.T) {
+	f, orig := openTestPackage(t)
+	defer f.Close()
+	larger := make([]byte, f.Wems[0].Index.Length+1)
+	data := []byte("RIFF")
+	for name, change := range map[string]func(s *Session) error{
+		"larger replacement": func(s *Session) error {
+			return s.Replace("wem", f.Wems[0].Index.ID, bytes.NewReader(larger), int64(len(larger)))
+		},
+		"added entry": func(s *Session) error {
+			return s.Add("wem", 1, bytes.NewReader(data), int64(len(data)))
+		},
+		"removed entry": func(s *Session) error { return s.Remove("bnk", 1) },
+	} {
+		s := f.NewSession()
+		if err := change(s); err != nil {
+			t.Fatal(err)
+		}
+		m := &memFile{append([]byte(nil), orig...)}
+		if _, err := s.WriteInPlace(m); !errors.Is(err, ErrDoesNotFit) {
+			t.Errorf("%s: got %v, want ErrDoesNotFit", name, err)
+		}
+		if !bytes.Equal(m.data, orig) {
+			t.Errorf("%s: the package was written to although the changes do not fit", name)
+		}
+	}
+}
+
+func TestSessionReplaceRange(t *testing.T) {
+	entries := testEntries()
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	s := f.NewSession()
+	orig := entries["wem"][2]
+	want := append([]byte(nil), orig...)
+	// Ranges are applied on top of each other, and may extend the entry.
+	for _, r := range []struct {
+		offset int64
+		data   string
+	}{{4, "fmt "}, {10, "data"}, {6, "ABCDEFG"}, {int64(len(orig)) - 2, "tail past the end"}} {
+		err := s.ReplaceRange("wem", 2, r.offset, strings.NewReader(r.data), int64(len(r.data)))
+		if err != nil {
+			t.Fatalf("replacing at %d: %v", r.offset, err)
+		}
+		if end := int(r.offset) + len(r.data); end > len(want) {
+			want = append(want, make([]byte, end-len(want))...)
+		}
+		copy(want[r.offset:], r.data)
+	}
+	for _, offset := range []int64{-1, int64(len(want)) + 1} {
+		if err := s.ReplaceRange("wem", 2, offset, strings.NewReader("x"), 1); err == nil {
+			t.Errorf("a range at %d outside of the entry was replaced", offset)
+		}
+	}
+	if err := s.ReplaceRange("wem", 12345, 0, strings.NewReader("x"), 1); err == nil {
+		t.Errorf("a range of a missing entry was replaced")
+	}
+
+	// Replacing a range of a replaced entry splices into the replacement.
+	if err := s.Replace("wem", 3, strings.NewReader("RIFF replaced"), 13); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ReplaceRange("wem", 3, 5, strings.NewReader("REPLACED"), 8); err != nil {
+		t.Fatal(err)
+	}
+
+	c := s.changes["wem"][2]
+	if err := iotest.TestReader(io.NewSectionReader(c.Data, 0, c.Length), want); err != nil {
+		t.Error(err)
+	}
+	written, _ := writeSession(t, s)
+	for id, data := range map[uint32]string{2: string(want), 3: "RIFF REPLACED"} {
+		if got, err := mustFind(t, written, "wem", id).Bytes(); err != nil || string(got) != data {
+			t.Errorf("wem ID %d holds %q (%v), want %q", id, got, err, data)
+		}
+	}
+	written.Close()
+}
+
+func TestWithDataAlignment(t *testing.T) {
+	entries := testEntries()
+	f, _ := openTestPackage(t)
+	aligned, _ := writeSession(t, f.NewSession(WithDataAlignment(4096)))
+	f.Close()
+	defer aligned.Close()
+	if first := firstDataOffset(aligned); first%4096 != 0 || aligned.DataAlignment%4096 != 0 {
+		t.Errorf("the data starts at offset %d, with an alignment of %d", first, aligned.DataAlignment)
+	}
+	// The padding is not part of the header region.
+	if end := 8 + int64(aligned.Header.HeaderAndIndexesLength); end == int64(firstDataOffset(aligned)) {
+		t.Error("the header region was padded to the start of the data")
+	}
+	for _, w := range aligned.Wems {
+		if got, err := w.Bytes(); err != nil || !bytes.Equal(got, entries["wem"][w.Index.ID]) {
+			t.Errorf("wem ID %d holds %q (%v)", w.Index.ID, got, err)
+		}
+	}
+
+	// Adding an entry grows the index tables; the data start stays aligned
+	// only if the alignment is preserved.
+	data := []byte("RIFF")
+	for _, preserve := range []bool{false, true} {
+		var opts []Option
+		if preserve {
+			opts = append(opts, PreserveDataAlignment())
+		}
+		s := aligned.NewSession(opts...)
+		if err := s.Add("wem", 1, bytes.NewReader(data), int64(len(data))); err != nil {
+			t.Fatal(err)
+		}
+		added, _ := writeSession(t, s)
+		if first := firstDataOffset(added); (first%4096 == 0) != preserve {
+			t.Errorf("with PreserveDataAlignment %v, the data starts at offset %d", preserve, first)
+		}
+		added.Close()
+	}
+}
+
+func TestSessionWriteAppend(t *testing.T) {
+	entries := testEntries()
+	f, data := openTestPackage(t)
+	target := f.Wems[1].Index
+	larger := bytes.Repeat([]byte("RIFF appended "), 50)
+	s := f.NewSession()
+	if err := s.Replace("wem", target.ID, bytes.NewReader(larger), int64(len(larger))); err != nil {
+		t.Fatal(err)
+	}
+	m := &memFile{append([]byte(nil), data...)}
+	if _, err := s.WriteAppend(m); err != nil {
+		t.Fatal(err)
+	}
+	dataStart := 8 + int(f.Header.HeaderAndIndexesLength)
+	f.Close()
+
+	if len(m.data) < len(data) || !bytes.Equal(m.data[dataStart:len(data)], data[dataStart:]) {
+		t.Error("the data of the original package was changed")
+	}
+	g := openMemory(t, m.data)
+	defer g.Close()
+	for _, w := range g.Wems {
+		want := entries["wem"][w.Index.ID]
+		if w.Index.ID == target.ID {
+			want = larger
+			if w.Index.Offset < uint64(len(data)) {
+				t.Errorf("the replaced wem was not appended, but written at offset %d", w.Index.Offset)
+			}
+		}
+		if got, err := w.Bytes(); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("wem ID %d holds %q (%v), want %q", w.Index.ID, got, err, want)
+		}
+	}
+	// The original data of the replaced wem is left unused.
+	unused := false
+	for _, gap := range g.Gaps() {
+		unused = unused || gap.Offset <= int64(target.Offset) &&
+			gap.Offset+gap.Length >= int64(target.Offset+uint64(target.Length))
+	}
+	if !unused {
+		t.Error("the original data of the replaced wem is not reported as a gap")
+	}
+}
+
+func TestSessionWriteAppendDoesNotFit(t *testing.T) {
+	f, orig := openTestPackage(t)
+	defer f.Close()
+	data := []byte("RIFF")
+	for name, change := range map[string]func(s *Session) error{
+		"added entry": func(s *Session) error {
+			return s.Add("wem", 1, bytes.NewReader(data), int64(len(data)))
+		},
+		"removed entry": func(s *Session) error { return s.Remove("bnk", 1) },
+	} {
+		s := f.NewSession()
+		if err := change(s); err != nil {
+			t.Fatal(err)
+		}
+		m := &memFile{append([]byte(nil), orig...)}
+		if _, err := s.WriteAppend(m); !errors.Is(err, ErrDoesNotFit) {
+			t.Errorf("%s: got %v, want ErrDoesNotFit", name, err)
+		}
+		if !bytes.Equal(m.data, orig) {
+			t.Errorf("%s: the package was written to although the changes do not fit", name)
+		}
+	}
+}
+
+func TestSessionWithRawHeader(t *testing.T) {
+	for _, p := range buildTestPackages(t) {
+		f := p.open(t)
+		raw := f.Header.Raw()
+		if !bytes.HasPrefix(p.data, raw) {
+			t.Errorf("%s: the raw header differs from the first %d bytes of the package", p.name, len(raw))
+		}
+
+		// Injecting the header of the package itself writes the same package
+		// as keeping it, with the table lengths updated alike.
+		write := func(opts ...Option) []byte {
+			s := f.NewSession(opts...)
+			if err := s.Remove("wem", 100); err != nil {
+				t.Fatal(err)
+			}
+			return sessionBytes(t, s)
+		}
+		if want, got := write(), write(WithRawHeader(raw)); !bytes.Equal(got, want) {
+			t.Errorf("%s: injecting its own header wrote a different package", p.name)
+		}
+
+		// A clone writes the injected header too.
+		injected := append([]byte(nil), raw...)
+		injected[len(injected)-1] ^= 0xFF
+		s := f.NewSession(WithRawHeader(injected))
+		want := sessionBytes(t, s)
+		if !bytes.Equal(want[8:len(raw)], injected[8:]) {
+			t.Errorf("%s: the injected header was not written", p.name)
+		}
+		if got := sessionBytes(t, s.Clone()); !bytes.Equal(got, want) {
+			t.Errorf("%s: a clone of the session wrote a different package", p.name)
+		}
+
+		short := f.NewSession(WithRawHeader(raw[:7]))
+		for _, s := range []*Session{short, short.Clone()} {
+			if _, err := s.WriteTo(io.Discard); err == nil {
+				t.Errorf("%s: a 7 byte raw header was accepted", p.name)
+			}
+		}
+		f.Close()
+	}
+}
+
+func TestSessionRemap(t *testing.T) {
+	for _, p := range buildTestPackages(t) {
+		f := p.open(t)
+		s := f.NewSession()
+		if err := s.Remap("wem", 300, 100); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.WriteTo(io.Discard); !errors.Is(err, ErrDuplicateID) {
+			t.Errorf("%s: remapping wem ID 300 onto ID 100: got %v, want ErrDuplicateID", p.name, err)
+		}
+		// Entries may swap their IDs.
+		if err := s.Remap("wem", 100, 300); err != nil {
+			t.Fatal(err)
+		}
+		// The replacement is made by the original ID of the remapped entry.
+		data := []byte("RIFF remapped")
+		if err := s.Replace("wem", 200, bytes.NewReader(data), int64(len(data))); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Remap("wem", 200, 500); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Remap("wem", 12345, 1); err == nil {
+			t.Errorf("%s: remapped an ID the package does not hold", p.name)
+		}
+
+		remapped, _ := writeSession(t, s)
+		if err := remapped.CheckIndexOrder(); err != nil {
+			t.Errorf("%s: %v", p.name, err)
+		}
+		want := map[uint32][]byte{
+			100:        p.entries["wem"][300],
+			300:        p.entries["wem"][100],
+			500:        data,
+			400:        p.entries["wem"][400],
+			0xFFFFFFFE: p.entries["wem"][0xFFFFFFFE],
+		}
+		if got := wemData(t, remapped); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: remapped wems hold %q, want %q", p.name, got, want)
+		}
+		remapped.Close()
+		f.Close()
+	}
+}
@@ -0,0 +1,303 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// The version of the skeleton format written by WriteSkeleton.
+const skeletonVersion = 1
+
+// A Skeleton is the complete structure of a package without the data of its
+// entries: its header and index tables, byte for byte, and the SHA-256 hash of
+// the data of each entry. Unlike the package itself, a skeleton holds no
+// audio, so it can be freely shared, for instance to describe the layout of a
+// modded package. The package can be rebuilt from the skeleton and a copy of
+// the original game files holding the same data.
+type Skeleton struct {
+	Version int `json:"version"`
+	// The header and index tables of the package, up to the start of its data.
+	Header []byte `json:"header"`
+	// The size of the package in bytes.
+	Size    int64            `json:"size"`
+	Entries []*SkeletonEntry `json:"entries"`
+}
+
+// A SkeletonEntry describes an entry of a package in a Skeleton.
+type SkeletonEntry struct {
+	Type   string `json:"type"` // "bnk", "wem" or "externals"
+	ID     uint32 `json:"id"`
+	Offset uint64 `json:"offset"`
+	Length uint32 `json:"length"`
+	// The SHA-256 hash of the data of the entry, in hexadecimal.
+	SHA256 string `json:"sha256"`
+}
+
+// Skeleton returns the skeleton of pck, reading the data of every entry to hash
+// it. Entries that share their data are only read once.
+func (pck *File) Skeleton() (*Skeleton, error) {
+	header := make([]byte, pck.dataStart())
+	if _, err := pck.reader.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	size, err := pck.fileSize()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Skeleton{Version: skeletonVersion, Header: header, Size: size}
+	hashes := make(map[span]string)
+	for i, indexes := range pck.indexTables() {
+		for _, idx := range indexes {
+			key := span{idx.Offset, idx.Length}
+			hash, ok := hashes[key]
+			if !ok {
+				if hash, err = hashData(io.NewSectionReader(pck.reader, int64(idx.Offset), int64(idx.Length))); err != nil {
+					return nil, fmt.Errorf("hashing %s ID %d: %w", tableNames[i], idx.ID, err)
+				}
+				hashes[key] = hash
+			}
+			s.Entries = append(s.Entries, &SkeletonEntry{
+				Type:   tableNames[i],
+				ID:     idx.ID,
+				Offset: idx.Offset,
+				Length: idx.Length,
+				SHA256: hash,
+			})
+		}
+	}
+	return s, nil
+}
+
+// ZeroPayloads makes s describe its package with the data of every entry
+// zeroed, by replacing the hash of each entry with that of as many zero bytes.
+// The skeleton then holds nothing of the original audio but the header and
+// index tables, so the layout of a game's package can be shared, such as for
+// test fixtures, and rebuilt by Rehydrate without any data.
+func (s *Skeleton) ZeroPayloads() {
+	hashes := make(map[uint32]string)
+	for _, e := range s.Entries {
+		hash, ok := hashes[e.Length]
+		if !ok {
+			hash = zeroHash(e.Length)
+			hashes[e.Length] = hash
+		}
+		e.SHA256 = hash
+	}
+}
+
+// zeroHash returns the SHA-256 hash of length zero bytes, in hexadecimal.
+func zeroHash(length uint32) string {
+	// Reading zeros cannot fail.
+	hash, _ := hashData(io.LimitReader(zeroReader{}, int64(length)))
+	return hash
+}
+
+// hashData returns the SHA-256 hash of the data read from r, in hexadecimal.
+func hashData(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// WriteSkeleton writes s to w as JSON.
+func WriteSkeleton(w io.Writer, s *Skeleton) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// ReadSkeleton reads a skeleton written by WriteSkeleton from r.
+func ReadSkeleton(r io.Reader) (*Skeleton, error) {
+	s := new(Skeleton)
+	if err := json.NewDecoder(r).Decode(s); err != nil {
+		return nil, fmt.Errorf("reading skeleton: %w", err)
+	}
+	if s.Version != skeletonVersion {
+		return nil, fmt.Errorf("unsupported skeleton version %d", s.Version)
+	}
+	if int64(len(s.Header)) > s.Size {
+		return nil, fmt.Errorf("skeleton header of %d bytes is larger than its package of %d bytes",
+			len(s.Header), s.Size)
+	}
+	return s, nil
+}
+
+// Rehydrate rebuilds the package described by s, writing it to w. The data of
+// each entry is found by its hash among the entries of packages, typically the
+// player's own copy of the original package, and the loose files at the paths
+// in files, such as the files a mod replaces entries with. The data of an
+// entry is first looked for in files, then in the entry of packages with the
+// same type, ID and length, and then in any entry of the same length. Entries
+// whose data is all zeros, such as those of a skeleton given ZeroPayloads,
+// need no data. It is an error for the data of an entry not to be found.
+func (s *Skeleton) Rehydrate(w io.Writer, packages []*File, files []string) (int64, error) {
+	sources := newDataSources(packages)
+	for _, path := range files {
+		if err := sources.addFile(path); err != nil {
+			return 0, err
+		}
+	}
+	return s.rehydrate(w, sources)
+}
+
+// rehydrate is Rehydrate, taking the data of the entries from sources.
+func (s *Skeleton) rehydrate(w io.Writer, sources *dataSources) (int64, error) {
+	entries := append([]*SkeletonEntry(nil), s.Entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Offset < entries[j].Offset })
+	data := make([]io.Reader, len(entries))
+	var missing []*SkeletonEntry
+	for i, e := range entries {
+		r, err := sources.find(e)
+		if err != nil {
+			return 0, err
+		}
+		if r == nil {
+			missing = append(missing, e)
+		}
+		data[i] = r
+	}
+	if len(missing) > 0 {
+		return 0, fmt.Errorf("the data of %d entries, such as %s ID %d, was not found in the "+
+			"given data", len(missing), missing[0].Type, missing[0].ID)
+	}
+
+	n, err := w.Write(s.Header)
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+	for i, e := range entries {
+		end := int64(e.Offset) + int64(e.Length)
+		if int64(e.Offset) < written {
+			// The entry shares its data with an entry that was already written.
+			if end > written {
+				return written, fmt.Errorf("%s ID %d overlaps the data of another entry", e.Type, e.ID)
+			}
+			continue
+		}
+		n, err := writePadding(w, int64(e.Offset)-written)
+		written += n
+		if err != nil {
+			return written, err
+		}
+		n, err = io.Copy(w, data[i])
+		written += n
+		if err != nil {
+			return written, fmt.Errorf("writing %s ID %d: %w", e.Type, e.ID, err)
+		}
+	}
+	n64, err := writePadding(w, s.Size-written)
+	return written + n64, err
+}
+
+// dataSources finds the data of the entries of a skeleton by hash.
+type dataSources struct {
+	// The contents of loose files, by hash.
+	loose map[string]io.ReaderAt
+	// The entries of the packages, by type, ID and length, and by length.
+	byEntry  map[entryKey][]*dataSource
+	byLength map[uint32][]*dataSource
+	// The hashes of zeroed data, by length.
+	zeroHashes map[uint32]string
+}
+
+// A dataSource is an entry of a package that may hold the data of an entry of
+// a skeleton.
+type dataSource struct {
+	pck *File
+	idx *FileIndex
+	// The hash of the data of the entry, once computed.
+	hash string
+}
+
+// reader returns a reader over the data of the entry.
+func (src *dataSource) reader() *io.SectionReader {
+	return io.NewSectionReader(src.pck.reader, int64(src.idx.Offset), int64(src.idx.Length))
+}
+
+// An entryKey identifies an entry of a package.
+type entryKey struct {
+	typ    string
+	id     uint32
+	length uint32
+}
+
+// newDataSources returns the sources of the data of the entries of packages.
+func newDataSources(packages []*File) *dataSources {
+	d := &dataSources{
+		loose:      make(map[string]io.ReaderAt),
+		byEntry:    make(map[entryKey][]*dataSource),
+		byLength:   make(map[uint32][]*dataSource),
+		zeroHashes: make(map[uint32]string),
+	}
+	for _, p := range packages {
+		for i, indexes := range p.indexTables() {
+			for _, idx := range indexes {
+				src := &dataSource{pck: p, idx: idx}
+				key := entryKey{tableNames[i], idx.ID, idx.Length}
+				d.byEntry[key] = append(d.byEntry[key], src)
+				d.byLength[idx.Length] = append(d.byLength[idx.Length], src)
+			}
+		}
+	}
+	return d
+}
+
+// addFile hashes the loose file at path, making its contents available.
+func (d *dataSources) addFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return fmt.Errorf("hashing %s: %w", path, err)
+	}
+	d.loose[hex.EncodeToString(h.Sum(nil))] = &lazyFile{path: path, size: size}
+	return nil
+}
+
+// find returns a reader over data with the length and hash of e, or nil if
+// there is none.
+func (d *dataSources) find(e *SkeletonEntry) (io.Reader, error) {
+	if r, ok := d.loose[e.SHA256]; ok {
+		return io.NewSectionReader(r, 0, int64(e.Length)), nil
+	}
+	// The entry with the same type and ID is the most likely to match, so it
+	// is tried before every entry of the same length is hashed.
+	candidates := [][]*dataSource{d.byEntry[entryKey{e.Type, e.ID, e.Length}], d.byLength[e.Length]}
+	for _, sources := range candidates {
+		for _, src := range sources {
+			if src.hash == "" {
+				hash, err := hashData(src.reader())
+				if err != nil {
+					return nil, fmt.Errorf("hashing ID %d: %w", src.idx.ID, err)
+				}
+				src.hash = hash
+			}
+			if src.hash == e.SHA256 {
+				return src.reader(), nil
+			}
+		}
+	}
+	zeros, ok := d.zeroHashes[e.Length]
+	if !ok {
+		zeros = zeroHash(e.Length)
+		d.zeroHashes[e.Length] = zeros
+	}
+	if zeros == e.SHA256 {
+		return io.LimitReader(zeroReader{}, int64(e.Length)), nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,110 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSkeleton(t *testing.T) {
+	entries := testEntries()
+	f, data := openTestPackage(t)
+	defer f.Close()
+	s, err := f.Skeleton()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Size != int64(len(data)) || !bytes.Equal(s.Header, data[:len(s.Header)]) {
+		t.Errorf("the skeleton describes %d bytes starting %x", s.Size, s.Header)
+	}
+	if want := f.dataStart(); int64(len(s.Header)) != want {
+		t.Errorf("the skeleton header has %d bytes, want %d", len(s.Header), want)
+	}
+	n := 0
+	for _, files := range entries {
+		n += len(files)
+	}
+	if len(s.Entries) != n {
+		t.Errorf("the skeleton has %d entries, want %d", len(s.Entries), n)
+	}
+	for _, e := range s.Entries {
+		sum := sha256.Sum256(entries[e.Type][e.ID])
+		if e.SHA256 != hex.EncodeToString(sum[:]) {
+			t.Errorf("%s ID %d is hashed to %s", e.Type, e.ID, e.SHA256)
+		}
+		idx := mustFind(t, f, e.Type, e.ID).Index
+		if e.Offset != idx.Offset || e.Length != idx.Length {
+			t.Errorf("%s ID %d is at %d+%d, want %d+%d", e.Type, e.ID, e.Offset, e.Length,
+				idx.Offset, idx.Length)
+		}
+	}
+
+	buf := new(bytes.Buffer)
+	if err := WriteSkeleton(buf, s); err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadSkeleton(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	again := new(bytes.Buffer)
+	WriteSkeleton(again, read)
+	if again.String() != buf.String() {
+		t.Errorf("the skeleton read back is written as\n%s\nnot\n%s", again, buf)
+	}
+
+	for _, tt := range []struct{ json, err string }{
+		{`{"version": 2, "header": "", "size": 0}`, "version"},
+		{`{"version": 1, "header": "QUtQSw==", "size": 2}`, "larger"},
+		{`{"version": 1,`, "reading skeleton"},
+	} {
+		if _, err := ReadSkeleton(strings.NewReader(tt.json)); err == nil ||
+			!strings.Contains(err.Error(), tt.err) {
+			t.Errorf("reading %s: got %v, want an error about %q", tt.json, err, tt.err)
+		}
+	}
+}
+
+func TestRehydrate(t *testing.T) {
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	modded := []byte("RIFF modded wem 2")
+	s := f.NewSession()
+	if err := s.Replace("wem", 2, bytes.NewReader(modded), int64(len(modded))); err != nil {
+		t.Fatal(err)
+	}
+	// The data of wem ID 3 is added again with a new ID, so it is found by
+	// its hash.
+	if err := s.Add("wem", 4, bytes.NewReader(testWems[1]), int64(len(testWems[1]))); err != nil {
+		t.Fatal(err)
+	}
+	m, want := writeSession(t, s)
+	skeleton, err := m.Skeleton()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	loose := filepath.Join(t.TempDir(), "2.wem")
+	if err := os.WriteFile(loose, modded, 0644); err != nil {
+		t.Fatal(err)
+	}
+	buf := new(bytes.Buffer)
+	n, err := skeleton.Rehydrate(buf, []*File{f}, []string{loose})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) || !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("rehydrated %d bytes (reporting %d), not the %d bytes of the modded package",
+			buf.Len(), n, len(want))
+	}
+	if _, err := skeleton.Rehydrate(new(bytes.Buffer), []*File{f}, nil); err == nil ||
+		!strings.Contains(err.Error(), "wem ID 2") {
+		t.Errorf("rehydrating without the modded wem: got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// A SlackReport describes the bytes of a package that do not hold the data of
+// any entry, as returned by File.Slack.
+type SlackReport struct {
+	// The size of the package in bytes.
+	Size int64
+	// The gaps of the package, see File.Gaps, and the number of bytes they
+	// hold in total.
+	Gaps      []*Gap
+	GapsBytes int64
+	// The bytes of the gaps needed to align the entries following them, which
+	// a compacted package still holds.
+	AlignmentBytes int64
+	// The gaps holding bytes other than zeros, such as the data of entries
+	// removed from the index tables by other tools, which no entry refers
+	// to, and the number of bytes they hold in total.
+	Orphaned      []*Gap
+	OrphanedBytes int64
+	// The size of the package written by Compact.
+	CompactSize int64
+}
+
+// Saved returns the number of bytes Compact would save.
+func (r *SlackReport) Saved() int64 {
+	return r.Size - r.CompactSize
+}
+
+func (r *SlackReport) String() string {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "Size: %d bytes\n", r.Size)
+	fmt.Fprintf(b, "Gaps: %d bytes in %d gap(s), %d of them needed for alignment\n",
+		r.GapsBytes, len(r.Gaps), r.AlignmentBytes)
+	fmt.Fprintf(b, "Orphaned data: %d bytes in %d gap(s)\n", r.OrphanedBytes, len(r.Orphaned))
+	for _, g := range r.Orphaned {
+		fmt.Fprintf(b, "  %d bytes at offset %d\n", g.Length, g.Offset)
+	}
+	fmt.Fprintf(b, "Compacted size: %d bytes (%d bytes saved)", r.CompactSize, r.Saved())
+	return b.String()
+}
+
+// Slack analyses the space of pck that is wasted: the gaps between the data of
+// its entries, see Gaps, of which those holding bytes other than zeros are
+// reported as orphaned data, and the size of the package once compacted. The
+// bytes of every gap are read.
+func (pck *File) Slack() (*SlackReport, error) {
+	size, err := pck.fileSize()
+	if err != nil {
+		return nil, err
+	}
+	r := &SlackReport{Size: size, Gaps: pck.gaps}
+	for _, g := range pck.gaps {
+		r.GapsBytes += g.Length
+		if g.Before != nil {
+			align := int64(pck.alignment(g.Before))
+			if pad := (align - g.Offset%align) % align; pad < g.Length {
+				r.AlignmentBytes += pad
+			} else {
+				r.AlignmentBytes += g.Length
+			}
+		}
+		zero, err := pck.isZero(g)
+		if err != nil {
+			return nil, fmt.Errorf("reading the gap at offset %d: %w", g.Offset, err)
+		}
+		if !zero {
+			r.Orphaned = append(r.Orphaned, g)
+			r.OrphanedBytes += g.Length
+		}
+	}
+	r.CompactSize = pck.NewSession(DropGaps()).Preview().Size
+	return r, nil
+}
+
+// isZero reports whether the bytes of g, a gap of pck, are all zeros.
+func (pck *File) isZero(g *Gap) (bool, error) {
+	buf := make([]byte, 32*1024)
+	zeros := make([]byte, len(buf))
+	sr := io.NewSectionReader(pck.reader, g.Offset, g.Length)
+	for {
+		n, err := sr.Read(buf)
+		if !bytes.Equal(buf[:n], zeros[:n]) {
+			return false, nil
+		}
+		if err == io.EOF {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// Compact writes pck to w tightly packed: without the gaps between the data of
+// its entries, laid out only as their alignment requires, as by a session
+// given DropGaps. It returns the number of bytes written and the number of
+// bytes saved relative to pck, which is negative if the package grew, as when
+// entries sharing their data are written separately. The options configure
+// how the package is written, as by NewSession.
+func (pck *File) Compact(w io.Writer, opts ...Option) (written, saved int64, err error) {
+	size, err := pck.fileSize()
+	if err != nil {
+		return 0, 0, err
+	}
+	written, err = pck.NewSession(append(opts, DropGaps())...).WriteTo(w)
+	return written, size - written, err
+}
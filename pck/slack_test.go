@@ -0,0 +1,57 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSlackAndCompact(t *testing.T) {
+	entries := testEntries()
+	f, data := openTestPackage(t)
+	r, err := f.Slack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if r.Size != int64(len(data)) || len(r.Orphaned) != 0 || r.GapsBytes != r.AlignmentBytes {
+		t.Errorf("a package without gaps has slack:\n%s", r)
+	}
+	tight := r.CompactSize
+
+	// Data left after the last entry, such as that of an entry removed by
+	// another tool, is orphaned.
+	orphan := "orphaned data of a removed entry"
+	withOrphan := append(append(append([]byte(nil), data...), orphan...), make([]byte, 16)...)
+	f = openMemory(t, withOrphan)
+	defer f.Close()
+	r, err = f.Slack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra := int64(len(orphan) + 16)
+	if r.Size != int64(len(withOrphan)) || len(r.Orphaned) != 1 || r.OrphanedBytes < extra ||
+		r.GapsBytes-r.AlignmentBytes != r.OrphanedBytes || r.CompactSize != tight || r.Saved() < extra {
+		t.Errorf("the slack of a package with orphaned data is reported as\n%s", r)
+	}
+	if s := r.String(); !strings.Contains(s, "Orphaned data: ") || !strings.Contains(s, "bytes saved") {
+		t.Errorf("the slack report reads\n%s", s)
+	}
+
+	buf := new(bytes.Buffer)
+	written, saved, err := f.Compact(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if written != int64(buf.Len()) || written != r.CompactSize || saved != r.Saved() {
+		t.Errorf("compacted to %d bytes, reporting %d written and %d saved", buf.Len(),
+			written, saved)
+	}
+	if bytes.Contains(buf.Bytes(), []byte(orphan)) {
+		t.Error("the orphaned data was kept")
+	}
+	compacted := openMemory(t, buf.Bytes())
+	assertHolds(t, "the compacted package", compacted, entries)
+	compacted.Close()
+}
@@ -0,0 +1,105 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// The kinds of content an embedded file can be inferred to hold. Each kind is
+// named after the file extension used when the entry is unpacked.
+const (
+	KindBnk  = "bnk"
+	KindWem  = "wem"
+	KindOgg  = "ogg"
+	KindPck  = "pck"
+	KindText = "txt"
+	KindData = "bin"
+)
+
+// The number of leading bytes of an entry inspected when inferring its kind.
+const sniffBytes = 512
+
+// The magic numbers of the container formats that can be recognised from the
+// start of an entry's data.
+var magicKinds = []struct {
+	magic []byte
+	kind  string
+}{
+	{[]byte("BKHD"), KindBnk},
+	{[]byte("RIFF"), KindWem},
+	{[]byte("RIFX"), KindWem},
+	{[]byte("OggS"), KindOgg},
+	{[]byte("AKPK"), KindPck},
+}
+
+// SniffKind infers the kind of content stored in the first length bytes of r.
+// Data that does not start with a known magic number is reported as KindText
+// if it looks like printable UTF-8 text, and KindData otherwise.
+func SniffKind(r io.ReaderAt, length int64) (string, error) {
+	n := int64(sniffBytes)
+	if length < n {
+		n = length
+	}
+	head := make([]byte, n)
+	if _, err := r.ReadAt(head, 0); err != nil && err != io.EOF {
+		return "", err
+	}
+
+	for _, m := range magicKinds {
+		if bytes.HasPrefix(head, m.magic) {
+			return m.kind, nil
+		}
+	}
+	if len(head) > 0 && isText(head) {
+		return KindText, nil
+	}
+	return KindData, nil
+}
+
+// isText reports whether b looks like the start of a UTF-8 text document. The
+// final rune is allowed to be cut short, since b may be a prefix of the text.
+func isText(b []byte) bool {
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size == 1 {
+			return len(b) < utf8.UTFMax && !utf8.FullRune(b)
+		}
+		if r < 0x20 && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+		b = b[size:]
+	}
+	return true
+}
+
+// A TypeMismatchError reports replacement data whose content is of a kind that
+// cannot be stored in the table of the entry it replaces, such as a SoundBank
+// placed in the WEM table.
+type TypeMismatchError struct {
+	// The type of the entry being replaced, "bnk" or "wem".
+	Type string
+	// The kind of content the replacement data was inferred to hold.
+	Kind string
+}
+
+func (e *TypeMismatchError) Error() string {
+	return fmt.Sprintf("replacement for a %s entry looks like a %s file", e.Type, e.Kind)
+}
+
+// CheckReplacementType inspects the first length bytes of r and returns a
+// *TypeMismatchError if they hold a SoundBank but are replacing a WEM entry, or
+// a wem but are replacing a BNK entry. Packages with swapped entries fail only
+// at game runtime, so catching this early avoids shipping a corrupt package.
+func CheckReplacementType(typ string, r io.ReaderAt, length int64) error {
+	kind, err := SniffKind(r, length)
+	if err != nil {
+		return err
+	}
+	if (typ == "wem" && kind == KindBnk) || (typ == "bnk" && kind == KindWem) {
+		return &TypeMismatchError{typ, kind}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSniffKind(t *testing.T) {
+	cases := []struct {
+		data string
+		want string
+	}{
+		{"BKHD\x18\x00\x00\x00", KindBnk},
+		{"RIFF\x00\x10\x00\x00WAVEfmt ", KindWem},
+		{"RIFX\x00\x00\x10\x00WAVEfmt ", KindWem},
+		{"OggS\x00\x02", KindOgg},
+		{"AKPK\x40\x00\x00\x00", KindPck},
+		{"line one\r\nline two\tend\n", KindText},
+		// A multi-byte rune cut short at the end of the inspected bytes.
+		{"caf\xc3", KindText},
+		{"\x00\x01\x02\x03", KindData},
+		{"\xff\xfe\xfd", KindData},
+		{"", KindData},
+	}
+	for _, c := range cases {
+		got, err := SniffKind(bytes.NewReader([]byte(c.data)), int64(len(c.data)))
+		if err != nil {
+			t.Errorf("SniffKind(%q): %v", c.data, err)
+		} else if got != c.want {
+			t.Errorf("SniffKind(%q) = %q, want %q", c.data, got, c.want)
+		}
+	}
+}
+
+func TestSniffKindOnlyInspectsLength(t *testing.T) {
+	data := []byte("BKHD")
+	if got, _ := SniffKind(bytes.NewReader(data), 2); got != KindText {
+		t.Errorf("SniffKind of the first 2 bytes of %q = %q, want %q", data, got, KindText)
+	}
+}
+
+func TestCheckReplacementType(t *testing.T) {
+	bnk, wem := []byte("BKHD\x18\x00\x00\x00"), []byte("RIFF\x00\x10\x00\x00")
+	cases := []struct {
+		typ  string
+		data []byte
+		kind string // The kind reported as a mismatch, if any.
+	}{
+		{"wem", wem, ""},
+		{"bnk", bnk, ""},
+		{"wem", bnk, KindBnk},
+		{"bnk", wem, KindWem},
+		{"wem", []byte("\x00\x01"), ""},
+	}
+	for _, c := range cases {
+		err := CheckReplacementType(c.typ, bytes.NewReader(c.data), int64(len(c.data)))
+		var mismatch *TypeMismatchError
+		switch {
+		case c.kind == "" && err != nil:
+			t.Errorf("replacing a %s entry with %q: %v", c.typ, c.data, err)
+		case c.kind != "" && (!errors.As(err, &mismatch) || mismatch.Kind != c.kind || mismatch.Type != c.typ):
+			t.Errorf("replacing a %s entry with %q: got %v, want a mismatch with a %s file",
+				c.typ, c.data, err, c.kind)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"fmt"
+	"io"
+)
+
+// A splitGroup is the entries of a package sharing a type and an ID, such as
+// the versions of a wem in several languages, which Split keeps in the same
+// volume.
+type splitGroup struct {
+	typ  string
+	id   uint32
+	size int64
+}
+
+// Split writes the entries of pck to several packages, or volumes, of no more
+// than maxSize bytes each, for platforms limiting the size of files. Each
+// volume is a complete package with its own index tables, and the header,
+// language map and externals table of pck. Entries are distributed in the
+// order of the index tables, filling each volume before starting the next;
+// entries sharing a type and ID, as in the languages of a standard package,
+// are kept together. create is called to create the file of each volume, by
+// increasing volume number starting at 1, and the file is closed once the
+// volume is written. Split returns the number of volumes written; it is an
+// error for an entry not to fit in a volume on its own. The options configure
+// how the volumes are written, as by NewSession, except that the gaps between
+// the entries of pck are left out of them, see DropGaps.
+func (pck *File) Split(maxSize int64, create func(volume int) (io.WriteCloser, error),
+	opts ...Option) (int, error) {
+	// The gaps between entries only make sense in the original package.
+	opts = append(opts, DropGaps())
+	groups := pck.splitGroups()
+	empty := pck.NewSession(opts...)
+	for _, g := range groups {
+		if err := empty.Remove(g.typ, g.id); err != nil {
+			return 0, err
+		}
+	}
+	base := empty.Preview().Size
+
+	var volumes [][]*splitGroup
+	var current []*splitGroup
+	size := base
+	for _, g := range groups {
+		if base+g.size > maxSize {
+			return 0, fmt.Errorf("%s ID %d does not fit in a volume of %d bytes", g.typ, g.id, maxSize)
+		}
+		if size+g.size > maxSize {
+			volumes = append(volumes, current)
+			current, size = nil, base
+		}
+		current = append(current, g)
+		size += g.size
+	}
+	if len(current) > 0 || len(volumes) == 0 {
+		volumes = append(volumes, current)
+	}
+
+	for i, volume := range volumes {
+		s := pck.NewSession(opts...)
+		kept := make(map[splitGroup]bool)
+		for _, g := range volume {
+			kept[splitGroup{typ: g.typ, id: g.id}] = true
+		}
+		for _, g := range groups {
+			if !kept[splitGroup{typ: g.typ, id: g.id}] {
+				if err := s.Remove(g.typ, g.id); err != nil {
+					return i, err
+				}
+			}
+		}
+		if size := s.Preview().Size; size > maxSize {
+			return i, fmt.Errorf("volume %d would be %d bytes, more than %d bytes", i+1, size, maxSize)
+		}
+		w, err := create(i + 1)
+		if err != nil {
+			return i, err
+		}
+		_, err = s.WriteTo(w)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return i, fmt.Errorf("writing volume %d: %w", i+1, err)
+		}
+	}
+	return len(volumes), nil
+}
+
+// splitGroups returns the groups of entries of pck sharing a type and an ID,
+// in the order of the index tables, with the number of bytes they add to a
+// volume at most: their index entries, data and alignment padding.
+func (pck *File) splitGroups() []*splitGroup {
+	var groups []*splitGroup
+	byKey := make(map[splitGroup]*splitGroup)
+	codecs := pck.Format.tables()
+	for t, indexes := range [][]*FileIndex{pck.BnkIndexes, pck.WemIndexes} {
+		for _, idx := range indexes {
+			key := splitGroup{typ: tableNames[t], id: idx.ID}
+			g, ok := byKey[key]
+			if !ok {
+				g = &splitGroup{typ: key.typ, id: key.id}
+				byKey[key] = g
+				groups = append(groups, g)
+			}
+			g.size += int64(codecs[t].size) + int64(idx.Length) + int64(pck.alignment(idx)) - 1
+		}
+	}
+	return groups
+}
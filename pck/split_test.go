@@ -0,0 +1,80 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// A bufferCloser is a bytes.Buffer whose Close records that it was closed.
+type bufferCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *bufferCloser) Close() error {
+	b.closed = true
+	return nil
+}
+
+func TestSplit(t *testing.T) {
+	entries := testEntries()
+	f, data := openTestPackage(t)
+	defer f.Close()
+	maxSize := int64(len(data))/2 + 64
+	var volumes []*bufferCloser
+	create := func(volume int) (io.WriteCloser, error) {
+		if volume != len(volumes)+1 {
+			t.Errorf("volume %d was created after %d volumes", volume, len(volumes))
+		}
+		b := new(bufferCloser)
+		volumes = append(volumes, b)
+		return b, nil
+	}
+	n, err := f.Split(maxSize, create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(volumes) || n < 2 {
+		t.Errorf("split into %d volumes, creating %d", n, len(volumes))
+	}
+
+	// Every entry is in exactly one volume, whose header is that of the
+	// package.
+	got := map[string]map[uint32][]byte{"bnk": {}, "wem": {}}
+	for i, b := range volumes {
+		if !b.closed || int64(b.Len()) > maxSize {
+			t.Errorf("volume %d of %d bytes was closed: %v", i+1, b.Len(), b.closed)
+		}
+		v := openMemory(t, b.Bytes())
+		if v.Format != f.Format || v.ByteOrder != f.ByteOrder {
+			t.Errorf("volume %d is a %s package in %v", i+1, v.Format, v.ByteOrder)
+		}
+		for typ, files := range map[string][]*EmbeddedFile{"bnk": v.Bnks, "wem": v.Wems} {
+			for _, e := range files {
+				if _, ok := got[typ][e.Index.ID]; ok {
+					t.Errorf("%s ID %d is in several volumes", typ, e.Index.ID)
+				}
+				got[typ][e.Index.ID], _ = e.Bytes()
+			}
+		}
+		v.Close()
+	}
+	for typ, files := range entries {
+		for id, data := range files {
+			if !bytes.Equal(got[typ][id], data) {
+				t.Errorf("%s ID %d is split as %q", typ, id, got[typ][id])
+			}
+		}
+	}
+
+	// An entry larger than a volume cannot be split.
+	volumes = nil
+	if _, err := f.Split(int64(len(entries["wem"][2])), create); err == nil {
+		t.Error("split into volumes smaller than wem ID 2")
+	}
+	if len(volumes) != 0 {
+		t.Errorf("created %d volumes although the package cannot be split", len(volumes))
+	}
+}
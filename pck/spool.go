@@ -0,0 +1,114 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+)
+
+// The number of bytes of a stream kept in memory by OpenStream, unless set by
+// WithSpoolMemory, before it is spooled to a temporary file instead.
+const DefaultSpoolMemory = 32 << 20
+
+// The size of the chunks a stream is spooled in. The context of the spooling
+// is checked between chunks.
+const spoolChunkBytes = 1 << 20
+
+// A Spooled is a seekable copy of a stream, such as a pipe or an HTTP response
+// body, held in memory or, if it is large, in a temporary file. It lets
+// streamed packages and replacement data be used wherever an io.ReaderAt is
+// needed. It must be closed to remove its temporary file.
+type Spooled struct {
+	*io.SectionReader
+	// The temporary file holding the stream, or nil if it is held in memory.
+	file *os.File
+}
+
+// Spool reads r to its end, keeping up to maxMemory bytes in memory before
+// moving them to a temporary file that the rest of r is written to. Spooling
+// stops with ctx's error if ctx is done before the end of r is reached.
+func Spool(ctx context.Context, r io.Reader, maxMemory int64) (*Spooled, error) {
+	var mem bytes.Buffer
+	var file *os.File
+	var size int64
+	fail := func(err error) (*Spooled, error) {
+		if file != nil {
+			file.Close()
+			os.Remove(file.Name())
+		}
+		return nil, err
+	}
+
+	chunk := make([]byte, spoolChunkBytes)
+	for {
+		if err := ctx.Err(); err != nil {
+			return fail(err)
+		}
+		n, err := r.Read(chunk)
+		if n > 0 {
+			size += int64(n)
+			if file == nil && size > maxMemory {
+				if file, err = os.CreateTemp("", "wwiseutil-spool-*"); err != nil {
+					return fail(err)
+				}
+				if _, err := mem.WriteTo(file); err != nil {
+					return fail(err)
+				}
+			}
+			var werr error
+			if file != nil {
+				_, werr = file.Write(chunk[:n])
+			} else {
+				mem.Write(chunk[:n])
+			}
+			if werr != nil {
+				return fail(werr)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(err)
+		}
+	}
+
+	if file != nil {
+		return &Spooled{io.NewSectionReader(file, 0, size), file}, nil
+	}
+	return &Spooled{io.NewSectionReader(bytes.NewReader(mem.Bytes()), 0, size), nil}, nil
+}
+
+// Close releases the copy of the stream, removing its temporary file if it has
+// one.
+func (s *Spooled) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	if rerr := os.Remove(s.file.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// OpenStream opens the package read from r, which need not be seekable, as
+// Open does. r is spooled to memory or, beyond the limit given by
+// WithSpoolMemory, to a temporary file, which is removed when the File is
+// closed. Spooling stops with ctx's error if ctx is done first. Since the
+// package has no file name, registered profiles are only used if given by
+// WithProfile.
+func OpenStream(ctx context.Context, r io.Reader, opts ...Option) (*File, error) {
+	o := newOptions(opts)
+	maxMemory := o.spoolMemory
+	if maxMemory == 0 {
+		maxMemory = DefaultSpoolMemory
+	}
+	s, err := Spool(ctx, r, maxMemory)
+	if err != nil {
+		return nil, err
+	}
+	return open("", s, o)
+}
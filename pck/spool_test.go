@@ -0,0 +1,71 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"testing"
+)
+
+// onlyReader hides every method of its reader but Read, as a pipe would.
+type onlyReader struct {
+	r io.Reader
+}
+
+func (o onlyReader) Read(p []byte) (int, error) { return o.r.Read(p) }
+
+func TestSpool(t *testing.T) {
+	data := bytes.Repeat([]byte("spooled "), spoolChunkBytes/4)
+	for _, maxMemory := range []int64{int64(len(data)), 100} {
+		s, err := Spool(context.Background(), onlyReader{bytes.NewReader(data)}, maxMemory)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if inFile := s.file != nil; inFile != (maxMemory < int64(len(data))) {
+			t.Errorf("spooling %d bytes with %d bytes of memory spooled to a file: %v", len(data),
+				maxMemory, inFile)
+		}
+		if got, err := io.ReadAll(io.NewSectionReader(s, 0, s.Size())); err != nil || !bytes.Equal(got, data) {
+			t.Errorf("spooled %d bytes (%v), want %d", len(got), err, len(data))
+		}
+		var name string
+		if s.file != nil {
+			name = s.file.Name()
+		}
+		if err := s.Close(); err != nil {
+			t.Error(err)
+		}
+		if name != "" {
+			if _, err := os.Stat(name); !os.IsNotExist(err) {
+				t.Errorf("the spool file %s was not removed (%v)", name, err)
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Spool(ctx, onlyReader{bytes.NewReader(data)}, 100); !errors.Is(err, context.Canceled) {
+		t.Errorf("spooling with a canceled context: got %v", err)
+	}
+}
+
+func TestOpenStream(t *testing.T) {
+	data := buildPackage(testBnks, testWems)
+	for _, opts := range [][]Option{nil, {WithSpoolMemory(64)}} {
+		f, err := OpenStream(context.Background(), onlyReader{bytes.NewReader(data)}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Format != FormatHybrid || f.ByteOrder != binary.LittleEndian {
+			t.Errorf("opened as a %s package in %v", f.Format, f.ByteOrder)
+		}
+		assertWritesBytes(t, f, data)
+		if err := f.Close(); err != nil {
+			t.Error(err)
+		}
+	}
+}
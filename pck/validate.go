@@ -0,0 +1,152 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"fmt"
+	"sort"
+)
+
+// A ProblemKind classifies a Problem found by Validate.
+type ProblemKind int
+
+const (
+	// ProblemHeaderLength is a HeaderAndIndexesLength that differs from the
+	// size of the header and index tables that were read.
+	ProblemHeaderLength ProblemKind = iota
+	// ProblemInHeader is an entry whose data starts inside the header and
+	// index tables.
+	ProblemInHeader
+	// ProblemOutOfBounds is an entry whose data extends past the end of the
+	// package.
+	ProblemOutOfBounds
+	// ProblemOverlap is an entry whose data partly overlaps the data of
+	// another entry. Entries with the same offset and length share their data,
+	// which is not a problem.
+	ProblemOverlap
+	// ProblemDuplicateID is an ID that occurs more than once in a table. In
+	// standard packages, an ID may occur once per language.
+	ProblemDuplicateID
+)
+
+func (k ProblemKind) String() string {
+	switch k {
+	case ProblemHeaderLength:
+		return "header length"
+	case ProblemInHeader:
+		return "in header"
+	case ProblemOutOfBounds:
+		return "out of bounds"
+	case ProblemOverlap:
+		return "overlap"
+	case ProblemDuplicateID:
+		return "duplicate ID"
+	}
+	return fmt.Sprintf("ProblemKind(%d)", int(k))
+}
+
+// A Problem is an inconsistency in the header or index tables of a package,
+// found by Validate.
+type Problem struct {
+	Kind ProblemKind
+	// The table of the entry with the problem, "bnk", "wem" or "externals",
+	// or "" for a problem with the header.
+	Type  string
+	Index *FileIndex
+	// A description of the problem.
+	Message string
+}
+
+func (p *Problem) String() string {
+	if p.Type == "" {
+		return fmt.Sprintf("%s: %s", p.Kind, p.Message)
+	}
+	return fmt.Sprintf("%s ID %d: %s: %s", p.Type, p.Index.ID, p.Kind, p.Message)
+}
+
+// Validate checks the header and index tables of pck for inconsistencies that
+// would make the package unreadable by the game, or make it be rewritten
+// incorrectly: a HeaderAndIndexesLength that does not match the index tables,
+// entries whose data lies outside of the data region, entries whose data
+// partly overlaps, and IDs that occur more than once in a table. It returns
+// the problems found, or nil if there are none.
+func (pck *File) Validate() ([]*Problem, error) {
+	size, err := pck.fileSize()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []*Problem
+	length := uint32(len(pck.Header.Unknown))
+	for i, c := range pck.Format.tables() {
+		length += indexTableSize(c, len(pck.indexTables()[i]))
+	}
+	if length != pck.Header.HeaderAndIndexesLength {
+		problems = append(problems, &Problem{
+			Kind: ProblemHeaderLength,
+			Message: fmt.Sprintf("the header and indexes are recorded as %d bytes long, but take %d bytes",
+				pck.Header.HeaderAndIndexesLength, length),
+		})
+	}
+
+	type entry struct {
+		typ string
+		idx *FileIndex
+	}
+	var entries []*entry
+	for i, indexes := range pck.indexTables() {
+		typ := tableNames[i]
+		type key struct{ id, language uint32 }
+		seen := make(map[key]bool)
+		for _, idx := range indexes {
+			k := key{id: idx.ID}
+			if pck.Format == FormatStandard {
+				k.language = idx.LanguageID()
+			}
+			if seen[k] {
+				problems = append(problems, &Problem{ProblemDuplicateID, typ, idx,
+					"the ID occurs more than once in the table"})
+			}
+			seen[k] = true
+
+			if idx.Length == 0 {
+				continue
+			}
+			end := idx.Offset + uint64(idx.Length)
+			switch {
+			case int64(idx.Offset) < pck.dataStart():
+				problems = append(problems, &Problem{ProblemInHeader, typ, idx,
+					fmt.Sprintf("the data starts at offset %d, inside the header and indexes ending at %d",
+						idx.Offset, pck.dataStart())})
+			case end > uint64(size):
+				problems = append(problems, &Problem{ProblemOutOfBounds, typ, idx,
+					fmt.Sprintf("the data ends at offset %d, past the end of the %d byte package",
+						end, size)})
+			default:
+				entries = append(entries, &entry{typ, idx})
+			}
+		}
+	}
+
+	// Every entry is compared with the entry that reaches furthest among the
+	// entries before it, which it overlaps if it starts before that entry ends.
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].idx.Offset < entries[j].idx.Offset
+	})
+	var furthest *entry
+	for _, e := range entries {
+		if furthest != nil {
+			f := furthest.idx
+			shared := e.idx.Offset == f.Offset && e.idx.Length == f.Length
+			if !shared && e.idx.Offset < f.Offset+uint64(f.Length) {
+				problems = append(problems, &Problem{ProblemOverlap, e.typ, e.idx,
+					fmt.Sprintf("the data at offsets %d to %d overlaps the data of %s ID %d at %d to %d",
+						e.idx.Offset, e.idx.Offset+uint64(e.idx.Length), furthest.typ, f.ID,
+						f.Offset, f.Offset+uint64(f.Length))})
+			}
+		}
+		if furthest == nil || e.idx.Offset+uint64(e.idx.Length) > furthest.idx.Offset+uint64(furthest.idx.Length) {
+			furthest = e
+		}
+	}
+	return problems, nil
+}
@@ -0,0 +1,48 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	bnks := [][]byte{[]byte("BKHD first"), []byte("BKHD second")}
+	wems := make([][]byte, 5)
+	for i := range wems {
+		wems[i] = []byte(fmt.Sprintf("RIFF wem %d", i))
+	}
+	f, data := openPackage(t, bnks, wems)
+	defer f.Close()
+	if problems, err := f.Validate(); err != nil || len(problems) > 0 {
+		t.Errorf("a consistent package has problems %v (%v)", problems, err)
+	}
+
+	w := f.WemIndexes
+	shared := w[4]
+	shared.Offset, shared.Length = w[3].Offset, w[3].Length
+	w[1].Offset = w[0].Offset + 1
+	w[2].Offset = uint64(len(data)) - 1
+	f.BnkIndexes[0].Offset = 4
+	f.BnkIndexes[1].ID = f.BnkIndexes[0].ID
+	f.Header.HeaderAndIndexesLength++
+
+	problems, err := f.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[ProblemKind]int{ProblemHeaderLength: 1, ProblemOverlap: 1, ProblemOutOfBounds: 1,
+		ProblemInHeader: 1, ProblemDuplicateID: 1}
+	got := make(map[ProblemKind]int)
+	for _, problem := range problems {
+		got[problem.Kind]++
+		if problem.Kind == ProblemOverlap && problem.Index != w[1] {
+			t.Errorf("reported %s, but wem ID %d overlaps", problem, w[1].ID)
+		}
+	}
+	for kind, n := range want {
+		if got[kind] != n {
+			t.Errorf("found %d %s problems, want %d: %v", got[kind], kind, n, problems)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"context"
+	"fmt"
+)
+
+// A Variant is one of the platform versions of a package, such as the PC and
+// the console package of a game, rebuilt by RepackVariants from a shared set
+// of replacement files.
+type Variant struct {
+	// The name of the platform, such as "pc", used in errors.
+	Name string
+	// The path to the original package of the platform, and the path the
+	// rebuilt package is written to.
+	Input, Output string
+	// Convert, if not nil, converts each replacement file for the platform,
+	// such as by re-encoding a wem with the codec of the platform. It returns
+	// the replacement file to use instead of r, which must not be modified.
+	Convert ConvertFunc
+	// Options applied when rebuilding this variant only, after those given to
+	// RepackVariants, such as WithProfile for the layout of the package.
+	Options []Option
+}
+
+// A ConvertFunc converts the replacement file r for the platform of the
+// variant v.
+type ConvertFunc func(v *Variant, r *ReplacementFile) (*ReplacementFile, error)
+
+// RepackVariants rebuilds the package of each of variants with the same
+// replacement files, converted by the Convert function of the variant, as
+// RepackContext does, and returns the number of bytes written to each output.
+// Replacement files must refer to their entries by ID, since the entries of
+// the packages of different platforms are usually not in the same order. It
+// stops at the first variant that cannot be rebuilt, leaving the outputs of
+// the variants rebuilt before it in place.
+func RepackVariants(ctx context.Context, variants []*Variant, replacements []*ReplacementFile,
+	opts ...Option) ([]int64, error) {
+	written := make([]int64, 0, len(variants))
+	for _, v := range variants {
+		rs := replacements
+		if v.Convert != nil {
+			rs = make([]*ReplacementFile, len(replacements))
+			for i, r := range replacements {
+				converted, err := v.Convert(v, r)
+				if err != nil {
+					return written, fmt.Errorf("converting %s for %s: %w", r.Path, v.Name, err)
+				}
+				rs[i] = converted
+			}
+		}
+		vopts := append(append([]Option(nil), opts...), v.Options...)
+		n, err := RepackContext(ctx, v.Input, v.Output, rs, vopts...)
+		if err != nil {
+			return written, fmt.Errorf("repacking %s: %w", v.Name, err)
+		}
+		written = append(written, n)
+	}
+	return written, nil
+}
@@ -0,0 +1,96 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRepackVariants(t *testing.T) {
+	dir := t.TempDir()
+	inputs := []struct {
+		name, path string
+	}{
+		{"pc", writeTestPackage(t, buildPackage(testBnks, testWems))},
+		{"console", writeTestPackage(t, buildPackage(testBnks, [][]byte{[]byte("RIFF console"), nil}))},
+	}
+	var variants []*Variant
+	var layouts []*File
+	for i, in := range inputs {
+		v := &Variant{
+			Name:   in.name,
+			Input:  in.path,
+			Output: filepath.Join(dir, in.name+".pck"),
+		}
+		// The console package is converted for its platform.
+		if i > 0 {
+			v.Convert = func(v *Variant, r *ReplacementFile) (*ReplacementFile, error) {
+				c := *r
+				c.Data = append(append([]byte(nil), r.Data...), " for "+v.Name...)
+				return &c, nil
+			}
+		}
+		f, err := Open(in.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Close()
+		variants = append(variants, v)
+		layouts = append(layouts, f)
+	}
+	shared := []*ReplacementFile{{ID: 2, Data: []byte("RIFF shared"), Type: "wem"}}
+
+	written, err := RepackVariants(context.Background(), variants, shared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != len(variants) {
+		t.Fatalf("rebuilt %d variants, want %d", len(written), len(variants))
+	}
+	for i, v := range variants {
+		f, err := Open(v.Output)
+		if err != nil {
+			t.Fatalf("%s: %v", v.Name, err)
+		}
+		info, err := os.Stat(v.Output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := info.Size()
+		if f.Format != layouts[i].Format || f.ByteOrder != layouts[i].ByteOrder || size != written[i] {
+			t.Errorf("%s: rebuilt a %s package of %d bytes in %v, reporting %d bytes", v.Name, f.Format,
+				size, f.ByteOrder, written[i])
+		}
+		want := "RIFF shared"
+		if v.Convert != nil {
+			want += " for " + v.Name
+		}
+		if got, err := mustFind(t, f, "wem", 2).Bytes(); err != nil || string(got) != want {
+			t.Errorf("%s: wem ID 2 holds %q (%v), want %q", v.Name, got, err, want)
+		}
+		f.Close()
+	}
+	if string(shared[0].Data) != "RIFF shared" {
+		t.Errorf("the shared replacement file was modified to %q", shared[0].Data)
+	}
+
+	// Rebuilding stops at the first variant that cannot be rebuilt.
+	for _, v := range variants {
+		os.Remove(v.Output)
+	}
+	failure := errors.New("no codec for this platform")
+	variants[1].Convert = func(*Variant, *ReplacementFile) (*ReplacementFile, error) { return nil, failure }
+	written, err = RepackVariants(context.Background(), variants, shared)
+	if !errors.Is(err, failure) || !strings.Contains(err.Error(), variants[1].Name) || len(written) != 1 {
+		t.Errorf("rebuilt %d variants with a failing conversion (%v)", len(written), err)
+	}
+	for i, v := range variants {
+		if _, err := os.Stat(v.Output); (err == nil) != (i == 0) {
+			t.Errorf("%s: the output exists: %v", v.Name, err == nil)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Verify reads back the package at path, written from this session, and checks
+// that it holds the pending changes: that its header and index tables can be
+// parsed, that removed entries are gone, and that the data of replaced and
+// added entries matches the SHA-256 hash of their new data. If the session has
+// a transform, see WithTransform, only the presence of entries is checked.
+// Entries that are not changed are not read. The options are applied when
+// opening the package, as by Open. Any difference results in an error wrapping
+// ErrVerifyFailed.
+func (s *Session) Verify(path string, opts ...Option) error {
+	written, err := Open(path, opts...)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrVerifyFailed, err)
+	}
+	defer written.Close()
+
+	for _, typ := range []string{"bnk", "wem"} {
+		indexes, _ := written.indexesOf(typ)
+		for id, c := range s.changes[typ] {
+			idx := findIndex(indexes, s.writtenID(typ, id))
+			if c.Removed {
+				if idx != nil {
+					return fmt.Errorf("%w: removed %s ID %d is still present", ErrVerifyFailed, typ, id)
+				}
+				continue
+			}
+			if idx == nil {
+				return fmt.Errorf("%w: %s ID %d is missing", ErrVerifyFailed, typ, id)
+			}
+			if s.transform != nil {
+				continue
+			}
+			if int64(idx.Length) != c.Length {
+				return fmt.Errorf("%w: %s ID %d is %d bytes long rather than %d", ErrVerifyFailed,
+					typ, id, idx.Length, c.Length)
+			}
+			want, err := hashData(io.NewSectionReader(c.Data, 0, c.Length))
+			if err != nil {
+				return fmt.Errorf("reading the new data of %s ID %d: %w", typ, id, err)
+			}
+			got, err := hashData(io.NewSectionReader(written.reader, int64(idx.Offset), int64(idx.Length)))
+			if err != nil {
+				return fmt.Errorf("%w: reading %s ID %d: %v", ErrVerifyFailed, typ, id, err)
+			}
+			if got != want {
+				return fmt.Errorf("%w: the data of %s ID %d differs", ErrVerifyFailed, typ, id)
+			}
+		}
+	}
+	return nil
+}
+
+// verifySize checks that the file at path is size bytes long, as written.
+func verifySize(path string, size int64) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrVerifyFailed, err)
+	}
+	if info.Size() != size {
+		return fmt.Errorf("%w: %s is %d bytes long, but %d bytes were written", ErrVerifyFailed,
+			path, info.Size(), size)
+	}
+	return nil
+}
+
+// findIndex returns the first of indexes with the given ID, or nil if there is
+// none.
+func findIndex(indexes []*FileIndex, id uint32) *FileIndex {
+	for _, idx := range indexes {
+		if idx.ID == id {
+			return idx
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+)
+
+// A Watermark marks a package as modified by a mod, so that mod managers can
+// tell which installed packages are already modded, and by what. It is stored
+// after the language names at the end of the language map, which loaders
+// ignore, as the magic "WWUM" followed by ModHash and Version.
+type Watermark struct {
+	// The FNV-1a hash of the name of the mod, see HashModName.
+	ModHash uint32
+	Version uint32
+}
+
+// The magic number a watermark starts with.
+var watermarkMagic = []byte("WWUM")
+
+// The size in bytes of a stored watermark.
+const watermarkBytes = 12
+
+// NewWatermark returns the watermark of version version of the mod named mod.
+func NewWatermark(mod string, version uint32) *Watermark {
+	return &Watermark{HashModName(mod), version}
+}
+
+// HashModName returns the 32 bit FNV-1a hash of the name of a mod, which
+// identifies the mod in a watermark.
+func HashModName(mod string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(mod))
+	return h.Sum32()
+}
+
+func (w *Watermark) String() string {
+	return fmt.Sprintf("mod hash 0x%08X, version %d", w.ModHash, w.Version)
+}
+
+// Watermark returns the watermark of pck, if it has one.
+func (pck *File) Watermark() (*Watermark, bool) {
+	start, end, ok := languageMapBounds(pck.Header.Unknown, pck.Format, pck.ByteOrder)
+	if !ok {
+		return nil, false
+	}
+	m := pck.Header.Unknown[start:end]
+	i, ok := findWatermark(m, pck.ByteOrder)
+	if !ok {
+		return nil, false
+	}
+	o := pck.ByteOrder
+	return &Watermark{o.Uint32(m[i+4:]), o.Uint32(m[i+8:])}, true
+}
+
+// findWatermark returns the offset of the watermark in the language map m,
+// stored in byte order o. Only the bytes after the map's table of languages
+// are searched, since the watermark follows the language names.
+func findWatermark(m []byte, o binary.ByteOrder) (int, bool) {
+	if len(m) < 4 {
+		return 0, false
+	}
+	names := 4 + 8*int64(o.Uint32(m))
+	if names > int64(len(m)) {
+		return 0, false
+	}
+	i := bytes.LastIndex(m[names:], watermarkMagic)
+	if i < 0 || int(names)+i+watermarkBytes > len(m) {
+		return 0, false
+	}
+	return int(names) + i, true
+}
+
+// stampWatermark returns unknown, the Unknown header section of a package of
+// format f in byte order o, with its language map holding w instead of any
+// watermark it held before. ok is false if the header does not record the
+// length of the language map, leaving nowhere to store w.
+func stampWatermark(unknown []byte, f Format, o binary.ByteOrder, w *Watermark) (stamped []byte, ok bool) {
+	start, end, ok := languageMapBounds(unknown, f, o)
+	if !ok {
+		return unknown, false
+	}
+	m := append([]byte(nil), unknown[start:end]...)
+	if i, ok := findWatermark(m, o); ok {
+		m = append(m[:i], m[i+watermarkBytes:]...)
+	}
+	mark := make([]byte, watermarkBytes)
+	copy(mark, watermarkMagic)
+	o.PutUint32(mark[4:], w.ModHash)
+	o.PutUint32(mark[8:], w.Version)
+	m = append(m, mark...)
+
+	stamped = append(append([]byte(nil), unknown[:start]...), m...)
+	o.PutUint32(stamped[languageMapLengthOffset:], uint32(len(m)))
+	return stamped, true
+}
@@ -0,0 +1,43 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWatermark(t *testing.T) {
+	entries := testEntries()
+	f, _ := openTestPackage(t)
+	if w, ok := f.Watermark(); ok {
+		t.Errorf("found the watermark %v in a package without one", w)
+	}
+	// Stamping a watermark again replaces the previous one.
+	stamped := f
+	for _, w := range []*Watermark{NewWatermark("a mod", 1), NewWatermark("a mod", 2)} {
+		next, _ := writeSession(t, stamped.NewSession(WithWatermark(w)))
+		stamped.Close()
+		stamped = next
+		if got, ok := stamped.Watermark(); !ok || *got != *w {
+			t.Errorf("the watermark is %v, want %v", got, w)
+		}
+	}
+	if len(stamped.Languages) != 0 {
+		t.Errorf("the language map of the stamped package holds %v", stamped.Languages)
+	}
+	for _, w := range stamped.Wems {
+		if got, err := w.Bytes(); err != nil || !bytes.Equal(got, entries["wem"][w.Index.ID]) {
+			t.Errorf("wem ID %d holds %q (%v)", w.Index.ID, got, err)
+		}
+	}
+	// Rewriting the package keeps its watermark.
+	rewritten, _ := writeSession(t, stamped.NewSession())
+	if got, ok := rewritten.Watermark(); !ok || got.Version != 2 {
+		t.Errorf("the rewritten package has the watermark %v", got)
+	}
+	rewritten.Close()
+	stamped.Close()
+	if h := HashModName("a"); h != 0xE40C292C {
+		t.Errorf("HashModName(\"a\") = 0x%08X, want the FNV-1a hash 0xE40C292C", h)
+	}
+}
@@ -0,0 +1,49 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"sync"
+)
+
+// runWorkers calls fn for every job from 0 to jobs-1, running up to workers
+// calls at once. After a call fails, no further jobs are started, and the
+// first error is returned once the calls in progress have finished.
+func runWorkers(workers, jobs int, fn func(job int) error) error {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > jobs {
+		workers = jobs
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range next {
+				if err := fn(job); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for job := 0; job < jobs && !failed(); job++ {
+		next <- job
+	}
+	close(next)
+	wg.Wait()
+	return firstErr
+}
@@ -0,0 +1,90 @@
+// Package pck implements access to the Wwise File Package file format.
+package pck
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+// readTree returns the contents of the files under dir, by their slash
+// separated paths relative to dir.
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(dir, path)
+		files[filepath.ToSlash(rel)] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func TestRunWorkers(t *testing.T) {
+	for _, workers := range []int{0, 1, 3, 100} {
+		var calls [50]int32
+		err := runWorkers(workers, len(calls), func(job int) error {
+			atomic.AddInt32(&calls[job], 1)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("%d workers: %v", workers, err)
+		}
+		for job, n := range calls {
+			if n != 1 {
+				t.Errorf("%d workers: job %d was run %d times", workers, job, n)
+			}
+		}
+	}
+
+	// No job is started after one fails, so at most one more job per worker
+	// is run after the failing one.
+	failure := errors.New("failure")
+	var started int32
+	err := runWorkers(4, 1000, func(job int) error {
+		atomic.AddInt32(&started, 1)
+		if job == 10 {
+			return failure
+		}
+		return nil
+	})
+	if err != failure {
+		t.Errorf("got %v, want the error of the failing job", err)
+	}
+	if n := atomic.LoadInt32(&started); n > 10+1+4 {
+		t.Errorf("%d jobs were started after job 10 failed", n-11)
+	}
+}
+
+func TestUnpackWithWorkers(t *testing.T) {
+	f, _ := openTestPackage(t)
+	defer f.Close()
+	want := t.TempDir()
+	if err := f.UnpackTo(want); err != nil {
+		t.Fatal(err)
+	}
+	for _, workers := range []int{2, 8} {
+		got := t.TempDir()
+		if err := f.UnpackTo(got, WithWorkers(workers)); err != nil {
+			t.Fatalf("%d workers: %v", workers, err)
+		}
+		if g, w := readTree(t, got), readTree(t, want); fmt.Sprint(g) != fmt.Sprint(w) {
+			t.Errorf("%d workers unpacked %d files, not the %d files unpacked sequentially",
+				workers, len(g), len(w))
+		}
+	}
+}
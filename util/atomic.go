@@ -0,0 +1,69 @@
+// Package util implements common utility functions.
+package util
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// An AtomicFile is written in place of the file at a path. It is a temporary
+// file in the same directory, which only replaces the file at the path once
+// committed, so that a crash or failed write never leaves a partly written
+// file behind, nor destroys the file it was to replace.
+type AtomicFile struct {
+	*os.File
+	path string
+	done bool
+}
+
+// CreateAtomic creates an AtomicFile to be written in place of the file at
+// path. Once written, it must be committed with Commit or discarded with
+// Abort. The file is given the permissions of the file at path, if it exists.
+func CreateAtomic(path string) (*AtomicFile, error) {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return &AtomicFile{File: f, path: path}, nil
+}
+
+// Commit flushes the file to disk, closes it and moves it to its path,
+// replacing any file there. The file is removed if it cannot be committed.
+func (f *AtomicFile) Commit() error {
+	if f.done {
+		return os.ErrClosed
+	}
+	f.done = true
+	err := f.File.Sync()
+	if cerr := f.File.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.File.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(f.File.Name())
+	}
+	return err
+}
+
+// Abort closes and removes the file, leaving the file at its path as it was.
+// It does nothing once the file is committed or aborted, so it may be
+// deferred.
+func (f *AtomicFile) Abort() {
+	if f.done {
+		return
+	}
+	f.done = true
+	f.File.Close()
+	os.Remove(f.File.Name())
+}
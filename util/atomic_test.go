@@ -0,0 +1,59 @@
+// Package util implements common utility functions.
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestAtomicFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.pck")
+	if err := os.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is written to the path until the file is committed, and an
+	// aborted file leaves it as it was.
+	f, err := CreateAtomic(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("aborted")
+	f.Abort()
+	f.Abort()
+	if err := f.Commit(); err == nil {
+		t.Error("an aborted file was committed")
+	}
+	assertContents(t, dir, path, "old", 0)
+
+	f, err = CreateAtomic(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("new")
+	assertContents(t, dir, path, "old", 1)
+	if err := f.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	f.Abort()
+	assertContents(t, dir, path, "new", 0)
+	if info, err := os.Stat(path); err != nil || (runtime.GOOS != "windows" && info.Mode().Perm() != 0600) {
+		t.Errorf("the committed file does not keep the permissions of the file it replaced (%v)", err)
+	}
+}
+
+// assertContents checks that the file at path holds want, and that dir holds
+// it and tmp temporary files.
+func assertContents(t *testing.T, dir, path, want string, tmp int) {
+	t.Helper()
+	if got, err := os.ReadFile(path); err != nil || string(got) != want {
+		t.Errorf("%s holds %q (%v), want %q", path, got, err, want)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1+tmp {
+		t.Errorf("the directory holds %d files, want %d", len(entries), 1+tmp)
+	}
+}
@@ -3,6 +3,8 @@ package util
 
 import (
 	"io"
+	"sync"
+	"time"
 )
 
 type ReadSeekerAt interface {
@@ -46,3 +48,33 @@ func (r *InfiniteReaderAt) ReadAt(p []byte, off int64) (int, error) {
 func NewConstantReader(size int64) io.ReaderAt {
 	return io.NewSectionReader(&InfiniteReaderAt{'A'}, 0, size)
 }
+
+// A Throttle limits the rate of I/O to a fixed number of bytes per second.
+// A Throttle may be shared by several readers and writers, which will then
+// share the rate limit. It is safe for concurrent use.
+type Throttle struct {
+	rate int64
+	mu   sync.Mutex
+	// The earliest time at which the next transfer may proceed.
+	next time.Time
+}
+
+// NewThrottle returns a Throttle allowing bytesPerSecond bytes of I/O every
+// second.
+func NewThrottle(bytesPerSecond int64) *Throttle {
+	return &Throttle{rate: bytesPerSecond}
+}
+
+// Wait accounts for the transfer of n bytes, blocking for as long as needed to
+// keep the overall transfer rate within the limit of this Throttle.
+func (t *Throttle) Wait(n int) {
+	t.mu.Lock()
+	now := time.Now()
+	if t.next.Before(now) {
+		t.next = now
+	}
+	t.next = t.next.Add(time.Duration(int64(n) * int64(time.Second) / t.rate))
+	delay := t.next.Sub(now)
+	t.mu.Unlock()
+	time.Sleep(delay)
+}
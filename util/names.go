@@ -0,0 +1,153 @@
+// Package util implements common utility functions.
+package util
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Names maps Wwise IDs to the names they were given in the Wwise project.
+type Names map[uint32]string
+
+// ReadNames reads the names of IDs from the file at path, which is either a
+// SoundbanksInfo.xml or SoundbanksInfo.json file generated by Wwise alongside
+// its SoundBanks, or a CSV file of ID and name pairs:
+//
+//	1000217927,VO_Intro_01
+//	0x3CF7FB99,Music_Title
+//
+// Lines of a CSV file whose first field is not an ID, such as a header, are
+// ignored. In SoundbanksInfo files, every element with an Id and a ShortName,
+// such as a streamed file, a SoundBank or a file included in one, is named by
+// its ShortName.
+func ReadNames(path string) (Names, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	names := make(Names)
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml":
+		err = names.readXML(f)
+	case ".json":
+		err = names.readJSON(f)
+	default:
+		err = names.readCSV(f)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading names from %s: %w", path, err)
+	}
+	return names, nil
+}
+
+// readXML reads the names of a SoundbanksInfo.xml file.
+func (names Names) readXML(r io.Reader) error {
+	type element struct {
+		id    uint32
+		hasID bool
+	}
+	var stack []element
+	inShortName := false
+	d := xml.NewDecoder(r)
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "ShortName" {
+				inShortName = true
+				continue
+			}
+			var e element
+			for _, a := range t.Attr {
+				if a.Name.Local == "Id" {
+					if id, err := ParseID(a.Value); err == nil {
+						e = element{id, true}
+					}
+				}
+			}
+			stack = append(stack, e)
+		case xml.EndElement:
+			if t.Name.Local == "ShortName" {
+				inShortName = false
+			} else if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if inShortName && len(stack) > 0 && stack[len(stack)-1].hasID {
+				names[stack[len(stack)-1].id] = strings.TrimSpace(string(t))
+			}
+		}
+	}
+}
+
+// readJSON reads the names of a SoundbanksInfo.json file.
+func (names Names) readJSON(r io.Reader) error {
+	var v interface{}
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return err
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			id, idOK := t["Id"].(string)
+			name, nameOK := t["ShortName"].(string)
+			if idOK && nameOK {
+				if id, err := ParseID(id); err == nil {
+					names[id] = name
+				}
+			}
+			for _, child := range t {
+				walk(child)
+			}
+		case []interface{}:
+			for _, child := range t {
+				walk(child)
+			}
+		}
+	}
+	walk(v)
+	return nil
+}
+
+// readCSV reads the names of a CSV file of ID and name pairs.
+func (names Names) readCSV(r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.Comment = '#'
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if len(row) < 2 {
+			continue
+		}
+		if id, err := ParseID(row[0]); err == nil {
+			names[id] = strings.TrimSpace(row[1])
+		}
+	}
+	if len(names) == 0 {
+		return errors.New("no names found")
+	}
+	return nil
+}
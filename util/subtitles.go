@@ -0,0 +1,215 @@
+// Package util implements common utility functions.
+package util
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A Subtitle is the text of a voice line, as shown by the game.
+type Subtitle struct {
+	Text string
+	// The character speaking the line, or "" if unknown.
+	Speaker string
+}
+
+// Subtitles maps the IDs of voice lines, or of the events playing them, to
+// their subtitles.
+type Subtitles map[uint32]*Subtitle
+
+// The names of the columns or fields of a subtitle file holding the key of a
+// line, in order of preference.
+var subtitleKeys = []string{"id", "key", "line", "event", "name"}
+
+// HashName returns the Wwise ID of the object with the given name, such as an
+// event: the 32 bit FNV-1 hash of the name in lower case.
+func HashName(name string) uint32 {
+	h := fnv.New32()
+	h.Write([]byte(strings.ToLower(name)))
+	return h.Sum32()
+}
+
+// ReadSubtitles reads the subtitles of voice lines from the file at path,
+// which is either a JSON or a CSV file. A JSON file holds either an object
+// mapping keys to texts, or to objects with a "text" and optionally a "speaker"
+// field, or an array of such objects with an "id", "key", "line", "event" or
+// "name" field holding their key. A CSV file starts with a header row naming
+// its key column, like those fields, its "text" column and optionally its
+// "speaker" column:
+//
+//	id,speaker,text
+//	1000217927,Narrator,It all started on a rainy day.
+//	Play_VO_Intro_02,Narrator,Nobody saw it coming.
+//
+// A key is either an ID, in decimal or 0x-prefixed hexadecimal, or a name,
+// such as the name of an event, which is converted to its ID with HashName.
+func ReadSubtitles(path string) (Subtitles, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	subs := make(Subtitles)
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		err = subs.readJSON(f)
+	} else {
+		err = subs.readCSV(f)
+	}
+	if err == nil && len(subs) == 0 {
+		err = errors.New("no subtitles found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading subtitles from %s: %w", path, err)
+	}
+	return subs, nil
+}
+
+// Find returns the subtitle of the voice line with the given ID or, failing
+// that, of the line or event with the given name, such as a name read by
+// ReadNames. It returns nil if neither has a subtitle.
+func (subs Subtitles) Find(id uint32, name string) *Subtitle {
+	if s, ok := subs[id]; ok {
+		return s
+	}
+	if name != "" {
+		return subs[HashName(name)]
+	}
+	return nil
+}
+
+// add adds the subtitle of the line with the given key.
+func (subs Subtitles) add(key string, s *Subtitle) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return
+	}
+	id, err := ParseID(key)
+	if err != nil {
+		id = HashName(key)
+	}
+	subs[id] = s
+}
+
+// readJSON reads the subtitles of a JSON file.
+func (subs Subtitles) readJSON(r io.Reader) error {
+	type line struct {
+		Text    string `json:"text"`
+		Speaker string `json:"speaker"`
+	}
+	var v interface{}
+	if err := json.NewDecoder(r).Decode(&v); err != nil {
+		return err
+	}
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for key, value := range t {
+			switch value := value.(type) {
+			case string:
+				subs.add(key, &Subtitle{Text: value})
+			case map[string]interface{}:
+				text, _ := value["text"].(string)
+				speaker, _ := value["speaker"].(string)
+				subs.add(key, &Subtitle{text, speaker})
+			}
+		}
+	case []interface{}:
+		for i, value := range t {
+			obj, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("line %d is not an object", i+1)
+			}
+			key := ""
+			for _, k := range subtitleKeys {
+				switch v := obj[k].(type) {
+				case string:
+					key = v
+				case float64:
+					key = fmt.Sprint(uint32(v))
+				}
+				if key != "" {
+					break
+				}
+			}
+			if key == "" {
+				return fmt.Errorf("line %d has no key", i+1)
+			}
+			text, _ := obj["text"].(string)
+			speaker, _ := obj["speaker"].(string)
+			subs.add(key, &Subtitle{text, speaker})
+		}
+	default:
+		return errors.New("expected an object or an array of lines")
+	}
+	return nil
+}
+
+// readCSV reads the subtitles of a CSV file with a header row.
+func (subs Subtitles) readCSV(r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	keyCol, textCol, speakerCol := -1, -1, -1
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "text":
+			textCol = i
+		case "speaker":
+			speakerCol = i
+		}
+		for _, k := range subtitleKeys {
+			if name == k && (keyCol < 0 || rank(subtitleKeys, name) < rank(subtitleKeys, header[keyCol])) {
+				keyCol = i
+			}
+		}
+	}
+	if keyCol < 0 || textCol < 0 {
+		return fmt.Errorf("the header row must name a key column (%s) and a text column",
+			strings.Join(subtitleKeys, ", "))
+	}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s := &Subtitle{Text: field(row, textCol), Speaker: field(row, speakerCol)}
+		subs.add(field(row, keyCol), s)
+	}
+}
+
+// rank returns the position of name, in any case, in names.
+func rank(names []string, name string) int {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i, n := range names {
+		if n == name {
+			return i
+		}
+	}
+	return len(names)
+}
+
+// field returns the trimmed field i of row, or "" if row has no such field.
+func field(row []string, i int) string {
+	if i < 0 || i >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[i])
+}
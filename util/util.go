@@ -68,3 +68,63 @@ func contains(sources []string, target string) bool {
 	}
 	return false
 }
+
+// ParseByteSize parses a number of bytes, optionally followed by a K, M or G
+// suffix denoting kibibytes, mebibytes or gibibytes respectively, e.g. "20M".
+func ParseByteSize(s string) (int64, error) {
+	multiplier, digits := int64(1), s
+	if s != "" {
+		switch s[len(s)-1] {
+		case 'K', 'k':
+			multiplier = 1 << 10
+		case 'M', 'm':
+			multiplier = 1 << 20
+		case 'G', 'g':
+			multiplier = 1 << 30
+		}
+	}
+	if multiplier != 1 {
+		digits = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	return n * multiplier, nil
+}
+
+// FormatByteSize formats a number of bytes for display, using the largest of
+// the K, M and G units accepted by ParseByteSize that it is at least one of,
+// e.g. "1.5M".
+func FormatByteSize(n int64) string {
+	switch {
+	case n >= 1<<30:
+		return fmt.Sprintf("%.1fG", float64(n)/(1<<30))
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fM", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fK", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%dB", n)
+}
+
+// ParseID parses a Wwise ID given either in decimal or, with a 0x prefix, in
+// hexadecimal.
+func ParseID(s string) (uint32, error) {
+	s = strings.TrimSpace(s)
+	base := 10
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s, base = s[2:], 16
+	}
+	id, err := strconv.ParseUint(s, base, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ID %q: must be a decimal or 0x-prefixed hexadecimal 32-bit number", s)
+	}
+	return uint32(id), nil
+}
+
+// FormatID formats a Wwise ID in both decimal and hexadecimal, since both
+// forms are commonly used to refer to IDs.
+func FormatID(id uint32) string {
+	return fmt.Sprintf("%d (0x%08X)", id, id)
+}
@@ -0,0 +1,32 @@
+// Package util implements common utility functions.
+package util
+
+// The build tag enabling the cgo accelerated implementations of this module,
+// which only zlib has: Compress and Decompress link the system's zlib, and the
+// packages of this module compress and decompress through them. There is no
+// native Vorbis decoder; programs linking one register it with
+// wem.RegisterDecoder. Builds without the tag, or with cgo disabled, are pure
+// Go and can be cross compiled and linked statically.
+const CgoBuildTag = "wwiseutil_cgo"
+
+// ZlibImplementation returns the implementation of Compress and Decompress
+// linked into this build: "pure Go", or "cgo (zlib <version>)" for builds with
+// the CgoBuildTag build tag.
+func ZlibImplementation() string {
+	return zlibImplementation()
+}
+
+// The compression level Compress uses by default, as defined by compress/zlib.
+const DefaultCompression = -1
+
+// Compress returns data compressed in the zlib format at the given level, as
+// defined by compress/zlib.
+func Compress(data []byte, level int) ([]byte, error) {
+	return compress(data, level)
+}
+
+// Decompress returns the data compressed in the zlib format in data. sizeHint
+// is the expected length of the decompressed data, or 0 if it is unknown.
+func Decompress(data []byte, sizeHint int) ([]byte, error) {
+	return decompress(data, sizeHint)
+}
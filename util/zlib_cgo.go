@@ -0,0 +1,85 @@
+//go:build cgo && wwiseutil_cgo
+
+// Package util implements common utility functions.
+package util
+
+/*
+#cgo LDFLAGS: -lz
+#include <stdlib.h>
+#include <zlib.h>
+
+static int inflate_init(z_stream *s) {
+	return inflateInit(s);
+}
+*/
+import "C"
+
+import (
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+func zlibImplementation() string {
+	return "cgo (zlib " + C.GoString(C.zlibVersion()) + ")"
+}
+
+func compress(data []byte, level int) ([]byte, error) {
+	if level < zlib.HuffmanOnly || level > zlib.BestCompression {
+		return nil, fmt.Errorf("zlib: invalid compression level: %d", level)
+	}
+	if level == zlib.HuffmanOnly {
+		// compress2 cannot select the strategy; leave it to the default.
+		level = zlib.DefaultCompression
+	}
+	bound := C.compressBound(C.uLong(len(data)))
+	out := C.malloc(C.size_t(bound))
+	defer C.free(out)
+	in := C.CBytes(data)
+	defer C.free(in)
+	n := bound
+	if rc := C.compress2((*C.Bytef)(out), &n, (*C.Bytef)(in), C.uLong(len(data)), C.int(level)); rc != C.Z_OK {
+		return nil, fmt.Errorf("zlib: compress2 failed with code %d", int(rc))
+	}
+	return C.GoBytes(out, C.int(n)), nil
+}
+
+func decompress(data []byte, sizeHint int) ([]byte, error) {
+	s := (*C.z_stream)(C.calloc(1, C.sizeof_z_stream))
+	defer C.free(unsafe.Pointer(s))
+	if rc := C.inflate_init(s); rc != C.Z_OK {
+		return nil, fmt.Errorf("zlib: inflateInit failed with code %d", int(rc))
+	}
+	defer C.inflateEnd(s)
+
+	in := C.CBytes(data)
+	defer C.free(in)
+	s.next_in = (*C.Bytef)(in)
+	s.avail_in = C.uInt(len(data))
+
+	size := sizeHint
+	if size < 4096 {
+		size = 4096
+	}
+	buf := (*C.Bytef)(C.malloc(C.size_t(size)))
+	defer func() { C.free(unsafe.Pointer(buf)) }()
+	var out []byte
+	for {
+		s.next_out = buf
+		s.avail_out = C.uInt(size)
+		rc := C.inflate(s, C.Z_NO_FLUSH)
+		out = append(out, C.GoBytes(unsafe.Pointer(buf), C.int(size-int(s.avail_out)))...)
+		switch rc {
+		case C.Z_STREAM_END:
+			return out, nil
+		case C.Z_OK:
+		case C.Z_BUF_ERROR:
+			if s.avail_in == 0 {
+				return nil, errors.New("zlib: unexpected EOF")
+			}
+		default:
+			return nil, fmt.Errorf("zlib: inflate failed with code %d", int(rc))
+		}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build cgo && wwiseutil_cgo
+
+// Package util implements common utility functions.
+package util
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestZlibImplementationIsCgo(t *testing.T) {
+	if impl := ZlibImplementation(); !strings.HasPrefix(impl, "cgo (zlib ") {
+		t.Errorf("built with %s, the zlib implementation is %q", CgoBuildTag, impl)
+	}
+}
@@ -0,0 +1,42 @@
+//go:build !cgo || !wwiseutil_cgo
+
+// Package util implements common utility functions.
+package util
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+)
+
+func zlibImplementation() string {
+	return "pure Go"
+}
+
+func compress(data []byte, level int) ([]byte, error) {
+	var b bytes.Buffer
+	w, err := zlib.NewWriterLevel(&b, level)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func decompress(data []byte, sizeHint int) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	b := bytes.NewBuffer(make([]byte, 0, sizeHint))
+	if _, err := io.Copy(b, r); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
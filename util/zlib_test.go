@@ -0,0 +1,66 @@
+// Package util implements common utility functions.
+package util
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"testing"
+)
+
+func TestZlibRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("RIFF hello wwise "), 5000)
+	for _, level := range []int{zlib.NoCompression, zlib.BestSpeed, DefaultCompression,
+		zlib.BestCompression} {
+		z, err := Compress(data, level)
+		if err != nil {
+			t.Fatalf("level %d: %v", level, err)
+		}
+		// Decompress grows its buffer past a hint that is too small.
+		for _, hint := range []int{0, 16, len(data)} {
+			if got, err := Decompress(z, hint); err != nil || !bytes.Equal(got, data) {
+				t.Errorf("level %d, hint %d: decompressed %d bytes (%v), want %d", level, hint,
+					len(got), err, len(data))
+			}
+		}
+	}
+	if _, err := Compress(data, 42); err == nil {
+		t.Error("compressed at an invalid level")
+	}
+}
+
+func TestZlibInteroperates(t *testing.T) {
+	data := []byte("BKHD written by compress/zlib")
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write(data)
+	w.Close()
+	if got, err := Decompress(b.Bytes(), 0); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("decompressed %q (%v), want %q", got, err, data)
+	}
+
+	z, err := Compress(data, DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := zlib.NewReader(bytes.NewReader(z))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("compress/zlib read %q (%v), want %q", got, err, data)
+	}
+}
+
+func TestDecompressRejectsCorruptData(t *testing.T) {
+	z, err := Compress([]byte("RIFF corrupt"), DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decompress(z[:len(z)-6], 0); err == nil {
+		t.Error("a truncated stream was decompressed")
+	}
+	if _, err := Decompress([]byte("RIFF not zlib"), 0); err == nil {
+		t.Error("data that is not zlib was decompressed")
+	}
+}
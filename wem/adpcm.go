@@ -0,0 +1,104 @@
+// Package wem implements access to the Wwise encoded media (.wem) file format.
+package wem
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// The steps of IMA ADPCM, by step index.
+var imaSteps = [89]int{
+	7, 8, 9, 10, 11, 12, 13, 14, 16, 17, 19, 21, 23, 25, 28, 31, 34, 37, 41, 45, 50, 55, 60, 66,
+	73, 80, 88, 97, 107, 118, 130, 143, 157, 173, 190, 209, 230, 253, 279, 307, 337, 371, 408,
+	449, 494, 544, 598, 658, 724, 796, 876, 963, 1060, 1166, 1282, 1411, 1552, 1707, 1878, 2066,
+	2272, 2499, 2749, 3024, 3327, 3660, 4026, 4428, 4871, 5358, 5894, 6484, 7132, 7845, 8630,
+	9493, 10442, 11487, 12635, 13899, 15289, 16818, 18500, 20350, 22385, 24623, 27086, 29794,
+	32767,
+}
+
+// imaIndexSteps are the changes to the step index of IMA ADPCM, by the
+// magnitude of a nibble.
+var imaIndexSteps = [8]int{-1, -1, -1, -1, 2, 4, 6, 8}
+
+// The size of the header of the audio of each channel in a block of Wwise IMA
+// ADPCM: the first sample and the step index, followed by a reserved byte.
+const adpcmHeaderSize = 4
+
+// supportsADPCM reports whether decodeADPCM can decode audio of the format f.
+func supportsADPCM(f *Format) bool {
+	return f.BitsPerSample == 4 && f.Channels > 0 && f.BlockAlign%f.Channels == 0 &&
+		int(f.BlockAlign/f.Channels) > adpcmHeaderSize
+}
+
+// decodeADPCM decodes the Wwise IMA ADPCM audio of f. Unlike Microsoft IMA
+// ADPCM, each block holds the audio of every channel one after the other: a
+// header holding the first sample, followed by the nibbles of the others, low
+// nibble first. The last nibble of each channel is not used, so that each
+// block holds an even number of samples per channel.
+func decodeADPCM(f *File) (*PCM, error) {
+	data := make([]byte, f.dataLength)
+	if _, err := f.Data().ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading data chunk: %w", err)
+	}
+
+	channels := int(f.Channels)
+	size := int(f.BlockAlign) / channels
+	frames := (size - adpcmHeaderSize) * 2
+	// Drop any incomplete trailing block.
+	blocks := len(data) / int(f.BlockAlign)
+	samples := make([]int16, blocks*frames*channels)
+	for b := 0; b < blocks; b++ {
+		for c := 0; c < channels; c++ {
+			block := data[(b*channels+c)*size : (b*channels+c+1)*size]
+			sample := int(int16(f.order.Uint16(block)))
+			index := int(block[2])
+			if index >= len(imaSteps) {
+				index = len(imaSteps) - 1
+			}
+			out := samples[b*frames*channels+c:]
+			out[0] = int16(sample)
+			for i := 1; i < frames; i++ {
+				nibble := block[adpcmHeaderSize+(i-1)/2] >> (4 * ((i - 1) % 2)) & 0xF
+				sample, index = expandIMA(nibble, sample, index)
+				out[i*channels] = int16(sample)
+			}
+		}
+	}
+	return &PCM{SampleRate: int(f.SampleRate), Channels: channels, Samples: samples}, nil
+}
+
+// expandIMA decodes an IMA ADPCM nibble following the given sample, at the
+// given step index, returning the sample and the step index that follow it.
+func expandIMA(nibble byte, sample, index int) (int, int) {
+	step := imaSteps[index]
+	delta := step >> 3
+	if nibble&1 != 0 {
+		delta += step >> 2
+	}
+	if nibble&2 != 0 {
+		delta += step >> 1
+	}
+	if nibble&4 != 0 {
+		delta += step
+	}
+	if nibble&8 != 0 {
+		delta = -delta
+	}
+
+	sample += delta
+	switch {
+	case sample > math.MaxInt16:
+		sample = math.MaxInt16
+	case sample < math.MinInt16:
+		sample = math.MinInt16
+	}
+	index += imaIndexSteps[nibble&7]
+	switch {
+	case index < 0:
+		index = 0
+	case index >= len(imaSteps):
+		index = len(imaSteps) - 1
+	}
+	return sample, index
+}
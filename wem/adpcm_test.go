@@ -0,0 +1,79 @@
+// Package wem implements access to the Wwise encoded media (.wem) file format.
+package wem
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// adpcmBlock returns the audio of a channel in a block of Wwise IMA ADPCM of
+// 36 bytes, starting with sample at the step index, followed by nibbles.
+func adpcmBlock(sample int16, index byte, nibbles ...byte) []byte {
+	b := make([]byte, 36)
+	b[0], b[1], b[2] = byte(sample), byte(uint16(sample)>>8), index
+	for i, n := range nibbles {
+		b[adpcmHeaderSize+i/2] |= n << (4 * (i % 2))
+	}
+	return b
+}
+
+func TestDecodeADPCM(t *testing.T) {
+	format := Format{Codec: CodecADPCM, Channels: 2, SampleRate: 24000, BytesPerSec: 24000,
+		BlockAlign: 72, BitsPerSample: 4}
+	var data []byte
+	data = append(data, adpcmBlock(1000, 0, 7, 0, 15)...)
+	data = append(data, adpcmBlock(-200, 88, 8)...)
+	data = append(data, adpcmBlock(32000, 88, 7)...)
+	data = append(data, adpcmBlock(0, 0)...)
+	// An incomplete trailing block is dropped.
+	data = append(data, 1, 2, 3)
+	wem := buildWem(format, data)
+	f, err := NewFile(bytes.NewReader(wem), int64(len(wem)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !f.Decodable() {
+		t.Fatal("IMA ADPCM cannot be decoded")
+	}
+	pcm, err := f.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pcm.SampleRate != 24000 || pcm.Channels != 2 || pcm.Frames() != 2*64 {
+		t.Fatalf("decoded %d frames of %d Hz audio with %d channels, want 128 frames of 24000 Hz "+
+			"audio with 2 channels", pcm.Frames(), pcm.SampleRate, pcm.Channels)
+	}
+
+	left := make([]int16, 4)
+	right := make([]int16, 2)
+	for i := range left {
+		left[i] = pcm.Samples[2*i]
+	}
+	for i := range right {
+		right[i] = pcm.Samples[2*i+1]
+	}
+	// 1000 + 11/8 of step 7, then + 1/8 of step 16, then - 15/8 of step 14,
+	// each rounded down.
+	if want := []int16{1000, 1011, 1013, 988}; !reflect.DeepEqual(left, want) {
+		t.Errorf("decoded the left channel to %v, want %v", left, want)
+	}
+	// -200 - 1/8 of the largest step.
+	if want := []int16{-200, -4295}; !reflect.DeepEqual(right, want) {
+		t.Errorf("decoded the right channel to %v, want %v", right, want)
+	}
+	// The second block starts again from its header, and clamps to 16 bits.
+	if got := pcm.Samples[2*64 : 2*64+4]; !reflect.DeepEqual(got, []int16{32000, 0, 32767, 0}) {
+		t.Errorf("decoded the start of the second block to %v", got)
+	}
+
+	format.BitsPerSample = 16
+	wem = buildWem(format, data)
+	if f, err = NewFile(bytes.NewReader(wem), int64(len(wem))); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Decode(); !errors.Is(err, ErrUnsupportedCodec) {
+		t.Errorf("decoding 16 bit IMA ADPCM: got %v, want ErrUnsupportedCodec", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Package wem implements access to the Wwise encoded media (.wem) file format.
+package wem
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// A Decoder decodes the audio of wems encoded with a particular codec.
+type Decoder struct {
+	Codec uint16
+	// The implementation of the decoder, such as "pure Go" or "cgo", reported
+	// to users so that they can tell which one is active.
+	Implementation string
+	// Supports reports whether the decoder can decode audio of the given
+	// format, such as at its bit depth. A nil Supports supports every format.
+	Supports func(f *Format) bool
+	Decode   func(f *File) (*PCM, error)
+}
+
+// decoders are the registered decoders by the format tag of their codec.
+var decoders = make(map[uint16]*Decoder)
+
+func init() {
+	for _, tag := range []uint16{CodecPCM, CodecPCMExtensible} {
+		RegisterDecoder(&Decoder{
+			Codec:          tag,
+			Implementation: "pure Go",
+			Supports:       supportsPCM,
+			Decode:         decodePCM,
+		})
+	}
+	RegisterDecoder(&Decoder{
+		Codec:          CodecADPCM,
+		Implementation: "pure Go",
+		Supports:       supportsADPCM,
+		Decode:         decodeADPCM,
+	})
+}
+
+// RegisterDecoder registers d as the decoder of the audio of wems encoded with
+// its codec, replacing any decoder registered before. Builds that link a codec
+// library through cgo, or programs with a decoder of their own, register their
+// decoders with it; only PCM and IMA ADPCM are decoded by default, in pure Go.
+func RegisterDecoder(d *Decoder) {
+	decoders[d.Codec] = d
+}
+
+// Decoders returns the registered decoders, by increasing format tag.
+func Decoders() []*Decoder {
+	ds := make([]*Decoder, 0, len(decoders))
+	for _, d := range decoders {
+		ds = append(ds, d)
+	}
+	sort.Slice(ds, func(i, j int) bool { return ds[i].Codec < ds[j].Codec })
+	return ds
+}
+
+// supportsPCM reports whether decodePCM can decode audio of the format f.
+func supportsPCM(f *Format) bool {
+	return f.BitsPerSample == 8 || f.BitsPerSample == 16 || f.BitsPerSample == 24
+}
+
+// decodePCM decodes the PCM audio of f.
+func decodePCM(f *File) (*PCM, error) {
+	data := make([]byte, f.dataLength)
+	if _, err := f.Data().ReadAt(data, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading data chunk: %w", err)
+	}
+
+	width := int(f.BitsPerSample / 8)
+	samples := make([]int16, len(data)/width)
+	for i := range samples {
+		b := data[i*width : (i+1)*width]
+		switch width {
+		case 1:
+			// 8 bit samples are unsigned.
+			samples[i] = int16(int(b[0])-128) << 8
+		case 2:
+			samples[i] = int16(f.order.Uint16(b))
+		case 3:
+			// Keep the most significant 16 bits.
+			if f.order == binary.LittleEndian {
+				samples[i] = int16(uint16(b[1]) | uint16(b[2])<<8)
+			} else {
+				samples[i] = int16(uint16(b[1]) | uint16(b[0])<<8)
+			}
+		}
+	}
+	// Drop any incomplete trailing frame.
+	samples = samples[:len(samples)-len(samples)%int(f.Channels)]
+	return &PCM{SampleRate: int(f.SampleRate), Channels: int(f.Channels), Samples: samples}, nil
+}
@@ -0,0 +1,333 @@
+// Package wem implements access to the Wwise encoded media (.wem) file format.
+package wem
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// The number of samples per channel of each FLAC frame but the last.
+const flacBlockSize = 4096
+
+// The largest Rice parameter of a FLAC residual with a 4 bit parameter, the
+// next one being the escape code.
+const flacMaxRiceParameter = 14
+
+// The types of FLAC metadata blocks written by writeFLAC.
+const (
+	flacStreamInfo    = 0
+	flacVorbisComment = 4
+)
+
+// The vendor string of the Vorbis comments written by writeFLAC.
+const flacVendor = "wwiseutil"
+
+// flacSampleRates are the codes of the sample rates that the header of a FLAC
+// frame can give directly.
+var flacSampleRates = map[int]uint64{
+	88200: 1, 176400: 2, 192000: 3, 8000: 4, 16000: 5, 22050: 6, 24000: 7,
+	32000: 8, 44100: 9, 48000: 10, 96000: 11,
+}
+
+// WriteFLAC writes p to w as a FLAC file, compressed losslessly.
+func (p *PCM) WriteFLAC(w io.Writer) (int64, error) {
+	return writeFLAC(w, p, nil)
+}
+
+// writeFLAC writes p to w as a FLAC file with the given Vorbis comments, each
+// of the form NAME=value. Each channel of each frame is stored as a constant,
+// a fixed linear prediction with Rice coded residuals, or verbatim, whichever
+// is the shortest.
+func writeFLAC(w io.Writer, p *PCM, comments []string) (int64, error) {
+	if p.Channels < 1 || p.Channels > 8 {
+		return 0, fmt.Errorf("FLAC supports 1 to 8 channels, not %d", p.Channels)
+	}
+	if p.SampleRate <= 0 || p.SampleRate >= 1<<20 {
+		return 0, fmt.Errorf("FLAC does not support a sample rate of %d Hz", p.SampleRate)
+	}
+
+	frames := new(bytes.Buffer)
+	minFrame, maxFrame := 0, 0
+	channel := make([]int64, flacBlockSize)
+	for n, start := uint64(0), 0; start < p.Frames(); n, start = n+1, start+flacBlockSize {
+		size := p.Frames() - start
+		if size > flacBlockSize {
+			size = flacBlockSize
+		}
+		f := new(bitWriter)
+		f.writeBits(0x3FFE, 14)
+		f.writeBits(0, 2)
+		sizeCode := uint64(7)
+		if size == flacBlockSize {
+			sizeCode = 12
+		}
+		f.writeBits(sizeCode, 4)
+		rateCode, ok := flacSampleRates[p.SampleRate]
+		switch {
+		case ok:
+		case p.SampleRate%1000 == 0 && p.SampleRate/1000 < 256:
+			rateCode = 12
+		case p.SampleRate < 1<<16:
+			rateCode = 13
+		case p.SampleRate%10 == 0:
+			rateCode = 14
+		default:
+			// The sample rate is only given by the STREAMINFO block.
+			rateCode = 0
+		}
+		f.writeBits(rateCode, 4)
+		// Independent channels of 16 bit samples.
+		f.writeBits(uint64(p.Channels-1), 4)
+		f.writeBits(4, 3)
+		f.writeBits(0, 1)
+		f.writeUTF8(n)
+		if sizeCode == 7 {
+			f.writeBits(uint64(size-1), 16)
+		}
+		switch rateCode {
+		case 12:
+			f.writeBits(uint64(p.SampleRate/1000), 8)
+		case 13:
+			f.writeBits(uint64(p.SampleRate), 16)
+		case 14:
+			f.writeBits(uint64(p.SampleRate/10), 16)
+		}
+		f.writeBits(uint64(crc8(f.bytes())), 8)
+
+		for c := 0; c < p.Channels; c++ {
+			for i := 0; i < size; i++ {
+				channel[i] = int64(p.Samples[(start+i)*p.Channels+c])
+			}
+			writeSubframe(f, channel[:size])
+		}
+		f.align()
+		f.writeBits(uint64(crc16(f.bytes())), 16)
+
+		frame := f.bytes()
+		if minFrame == 0 || len(frame) < minFrame {
+			minFrame = len(frame)
+		}
+		if len(frame) > maxFrame {
+			maxFrame = len(frame)
+		}
+		frames.Write(frame)
+	}
+
+	sum := md5.New()
+	binary.Write(sum, binary.LittleEndian, p.Samples[:p.Frames()*p.Channels])
+	info := new(bitWriter)
+	info.writeBits(flacBlockSize, 16)
+	info.writeBits(flacBlockSize, 16)
+	info.writeBits(uint64(minFrame), 24)
+	info.writeBits(uint64(maxFrame), 24)
+	info.writeBits(uint64(p.SampleRate), 20)
+	info.writeBits(uint64(p.Channels-1), 3)
+	info.writeBits(15, 5)
+	info.writeBits(uint64(p.Frames()), 36)
+	info.buf = append(info.buf, sum.Sum(nil)...)
+
+	le := binary.LittleEndian
+	tags := new(bytes.Buffer)
+	binary.Write(tags, le, uint32(len(flacVendor)))
+	tags.WriteString(flacVendor)
+	binary.Write(tags, le, uint32(len(comments)))
+	for _, c := range comments {
+		binary.Write(tags, le, uint32(len(c)))
+		tags.WriteString(c)
+	}
+
+	b := bytes.NewBufferString("fLaC")
+	for i, block := range [][]byte{info.bytes(), tags.Bytes()} {
+		typ := []byte{flacStreamInfo, flacVorbisComment}[i]
+		if i == 1 {
+			// The last metadata block is flagged as such.
+			typ |= 0x80
+		}
+		b.WriteByte(typ)
+		b.Write([]byte{byte(len(block) >> 16), byte(len(block) >> 8), byte(len(block))})
+		b.Write(block)
+	}
+	n, err := b.WriteTo(w)
+	if err != nil {
+		return n, err
+	}
+	m, err := frames.WriteTo(w)
+	return n + m, err
+}
+
+// writeSubframe writes the samples of a channel of a FLAC frame to f as a
+// subframe.
+func writeSubframe(f *bitWriter, samples []int64) {
+	constant := true
+	for _, s := range samples {
+		if s != samples[0] {
+			constant = false
+			break
+		}
+	}
+	if constant {
+		f.writeBits(0, 8)
+		f.writeBits(uint64(samples[0]), 16)
+		return
+	}
+
+	// Pick the order of the fixed predictor, and the Rice parameter of its
+	// residual, taking the fewest bits.
+	best, bestParam, bestBits := -1, 0, 16*len(samples)
+	var residuals [5][]int64
+	for order := 0; order <= 4 && order < len(samples); order++ {
+		residuals[order] = fixedResidual(samples, order)
+		param, bits := riceParameter(residuals[order])
+		// The warm-up samples, the coding method and parameter, and the
+		// partition order.
+		if bits += 16*order + 2 + 4 + 4; bits < bestBits {
+			best, bestParam, bestBits = order, param, bits
+		}
+	}
+	if best < 0 {
+		f.writeBits(1<<1, 8)
+		for _, s := range samples {
+			f.writeBits(uint64(s), 16)
+		}
+		return
+	}
+	f.writeBits(uint64(8|best)<<1, 8)
+	for _, s := range samples[:best] {
+		f.writeBits(uint64(s), 16)
+	}
+	f.writeBits(0, 2)
+	f.writeBits(0, 4)
+	f.writeBits(uint64(bestParam), 4)
+	for _, r := range residuals[best] {
+		u := uint64(r<<1) ^ uint64(r>>63)
+		for q := u >> bestParam; q > 0; q-- {
+			f.writeBits(0, 1)
+		}
+		f.writeBits(1, 1)
+		f.writeBits(u, bestParam)
+	}
+}
+
+// fixedResidual returns the residual of the samples following the first order
+// ones, predicted by the fixed polynomial predictor of the given order.
+func fixedResidual(samples []int64, order int) []int64 {
+	r := make([]int64, len(samples)-order)
+	for i := range r {
+		s := samples[i+order:]
+		switch order {
+		case 0:
+			r[i] = s[0]
+		case 1:
+			r[i] = s[0] - samples[i]
+		case 2:
+			r[i] = s[0] - 2*samples[i+1] + samples[i]
+		case 3:
+			r[i] = s[0] - 3*samples[i+2] + 3*samples[i+1] - samples[i]
+		case 4:
+			r[i] = s[0] - 4*samples[i+3] + 6*samples[i+2] - 4*samples[i+1] + samples[i]
+		}
+	}
+	return r
+}
+
+// riceParameter returns the Rice parameter coding residual in the fewest bits,
+// and the number of bits.
+func riceParameter(residual []int64) (param, bits int) {
+	bits = -1
+	for k := 0; k <= flacMaxRiceParameter; k++ {
+		n := 0
+		for _, r := range residual {
+			u := uint64(r<<1) ^ uint64(r>>63)
+			n += int(u>>k) + 1 + k
+		}
+		if bits < 0 || n < bits {
+			param, bits = k, n
+		}
+	}
+	return param, bits
+}
+
+// A bitWriter writes values of any number of bits, most significant bit
+// first.
+type bitWriter struct {
+	buf []byte
+	// The number of bits of the last byte of buf written, or 0 if it is full.
+	used uint
+}
+
+// writeBits writes the low n bits of v.
+func (w *bitWriter) writeBits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		if w.used == 0 {
+			w.buf = append(w.buf, 0)
+		}
+		w.buf[len(w.buf)-1] |= byte(v>>uint(i)&1) << (7 - w.used)
+		w.used = (w.used + 1) % 8
+	}
+}
+
+// writeUTF8 writes v as a FLAC frame number, coded as UTF-8 is, extended to
+// 36 bits.
+func (w *bitWriter) writeUTF8(v uint64) {
+	if v < 0x80 {
+		w.writeBits(v, 8)
+		return
+	}
+	// The number of continuation bytes.
+	n := 1
+	for v >= 1<<(6*n+6-n) {
+		n++
+	}
+	lead := uint64(0xFF00>>(n+1)) & 0xFF
+	w.writeBits(lead|v>>(6*n), 8)
+	for i := n - 1; i >= 0; i-- {
+		w.writeBits(0x80|v>>(6*i)&0x3F, 8)
+	}
+}
+
+// align pads the written bits with zeros to a whole number of bytes.
+func (w *bitWriter) align() {
+	w.used = 0
+}
+
+// bytes returns the bytes written so far, the last one padded with zeros.
+func (w *bitWriter) bytes() []byte {
+	return w.buf
+}
+
+// crc8 returns the CRC-8 of b with the polynomial x^8 + x^2 + x + 1, which
+// protects the header of a FLAC frame.
+func crc8(b []byte) byte {
+	var crc byte
+	for _, c := range b {
+		crc ^= c
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// crc16 returns the CRC-16 of b with the polynomial x^16 + x^15 + x^2 + 1,
+// which protects a whole FLAC frame.
+func crc16(b []byte) uint16 {
+	var crc uint16
+	for _, c := range b {
+		crc ^= uint16(c) << 8
+		for i := 0; i < 8; i++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x8005
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
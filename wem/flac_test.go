@@ -0,0 +1,277 @@
+// Package wem implements access to the Wwise encoded media (.wem) file format.
+package wem
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// A bitReader reads values of any number of bits, most significant bit first.
+type bitReader struct {
+	t   *testing.T
+	buf []byte
+	pos int
+}
+
+// readBits reads n bits as an unsigned value.
+func (r *bitReader) readBits(n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		if r.pos/8 >= len(r.buf) {
+			r.t.Fatalf("read past the end of the frame")
+		}
+		v = v<<1 | uint64(r.buf[r.pos/8]>>(7-r.pos%8)&1)
+		r.pos++
+	}
+	return v
+}
+
+// readSigned reads n bits as a two's complement value.
+func (r *bitReader) readSigned(n int) int64 {
+	return int64(r.readBits(n)<<(64-n)) >> (64 - n)
+}
+
+// A flacStream is a FLAC file read by readFLAC.
+type flacStream struct {
+	info struct {
+		minBlock, maxBlock, minFrame, maxFrame, rate, channels, bits int
+		samples                                                      uint64
+		md5                                                          []byte
+	}
+	comments []string
+	samples  []int16
+}
+
+// readFLAC decodes the FLAC files written by writeFLAC, checking the CRCs of
+// their frames.
+func readFLAC(t *testing.T, data []byte) *flacStream {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("fLaC")) {
+		t.Fatalf("the file starts with %q", data[:4])
+	}
+	s := new(flacStream)
+	pos := 4
+	for last := false; !last; {
+		last = data[pos]&0x80 != 0
+		typ, length := data[pos]&0x7F, int(data[pos+1])<<16|int(data[pos+2])<<8|int(data[pos+3])
+		block := data[pos+4 : pos+4+length]
+		pos += 4 + length
+		switch typ {
+		case flacStreamInfo:
+			r := &bitReader{t: t, buf: block}
+			s.info.minBlock, s.info.maxBlock = int(r.readBits(16)), int(r.readBits(16))
+			s.info.minFrame, s.info.maxFrame = int(r.readBits(24)), int(r.readBits(24))
+			s.info.rate = int(r.readBits(20))
+			s.info.channels, s.info.bits = int(r.readBits(3))+1, int(r.readBits(5))+1
+			s.info.samples = r.readBits(36)
+			s.info.md5 = block[18:]
+		case flacVorbisComment:
+			le := binary.LittleEndian
+			vendor := le.Uint32(block)
+			block = block[4+vendor:]
+			count := le.Uint32(block)
+			block = block[4:]
+			for i := uint32(0); i < count; i++ {
+				n := le.Uint32(block)
+				s.comments = append(s.comments, string(block[4:4+n]))
+				block = block[4+n:]
+			}
+		}
+	}
+
+	for pos < len(data) {
+		r := &bitReader{t: t, buf: data[pos:]}
+		if sync := r.readBits(16); sync != 0xFFF8 {
+			t.Fatalf("frame at %d starts with %#x", pos, sync)
+		}
+		sizeCode, rateCode := r.readBits(4), r.readBits(4)
+		channels, sampleSize := int(r.readBits(4))+1, r.readBits(4)
+		if channels != s.info.channels || sampleSize != 4<<1 {
+			t.Fatalf("frame at %d has %d channels and sample size code %d", pos, channels,
+				sampleSize>>1)
+		}
+		for lead := r.readBits(8); lead&0xC0 == 0xC0; lead <<= 1 {
+			r.readBits(8)
+		}
+		size := flacBlockSize
+		if sizeCode == 7 {
+			size = int(r.readBits(16)) + 1
+		}
+		switch rateCode {
+		case 12:
+			r.readBits(8)
+		case 13, 14:
+			r.readBits(16)
+		}
+		if crc := crc8(r.buf[:r.pos/8]); byte(r.readBits(8)) != crc {
+			t.Fatalf("the header of the frame at %d has a wrong CRC-8", pos)
+		}
+
+		block := make([][]int64, channels)
+		for c := range block {
+			block[c] = readSubframe(r, size)
+		}
+		r.pos = (r.pos + 7) / 8 * 8
+		if crc := crc16(r.buf[:r.pos/8]); uint16(r.readBits(16)) != crc {
+			t.Fatalf("the frame at %d has a wrong CRC-16", pos)
+		}
+		for i := 0; i < size; i++ {
+			for c := range block {
+				s.samples = append(s.samples, int16(block[c][i]))
+			}
+		}
+		pos += r.pos / 8
+	}
+	return s
+}
+
+// readSubframe decodes a subframe of size samples.
+func readSubframe(r *bitReader, size int) []int64 {
+	header := r.readBits(8)
+	samples := make([]int64, size)
+	switch typ := header >> 1; {
+	case typ == 0:
+		v := r.readSigned(16)
+		for i := range samples {
+			samples[i] = v
+		}
+	case typ == 1:
+		for i := range samples {
+			samples[i] = r.readSigned(16)
+		}
+	case typ&0x38 == 8:
+		order := int(typ & 7)
+		for i := 0; i < order; i++ {
+			samples[i] = r.readSigned(16)
+		}
+		if method, partitions := r.readBits(2), r.readBits(4); method != 0 || partitions != 0 {
+			r.t.Fatalf("read the residual coding method %d with partition order %d", method,
+				partitions)
+		}
+		k := int(r.readBits(4))
+		coefficients := [][]int64{{}, {1}, {2, -1}, {3, -3, 1}, {4, -6, 4, -1}}[order]
+		for i := order; i < size; i++ {
+			q := uint64(0)
+			for r.readBits(1) == 0 {
+				q++
+			}
+			u := q<<k | r.readBits(k)
+			residual := int64(u>>1) ^ -int64(u&1)
+			for j, c := range coefficients {
+				residual += c * samples[i-1-j]
+			}
+			samples[i] = residual
+		}
+	default:
+		r.t.Fatalf("read a subframe of type %#x", typ)
+	}
+	return samples
+}
+
+func TestCRC(t *testing.T) {
+	// The check values of CRC-8 and CRC-16/UMTS.
+	if crc := crc8([]byte("123456789")); crc != 0xF4 {
+		t.Errorf("got the CRC-8 %#x, want 0xf4", crc)
+	}
+	if crc := crc16([]byte("123456789")); crc != 0xFEE8 {
+		t.Errorf("got the CRC-16 %#x, want 0xfee8", crc)
+	}
+}
+
+func TestWriteFLAC(t *testing.T) {
+	tone := func(frames, channels int) []int16 {
+		samples := make([]int16, frames*channels)
+		for i := range samples {
+			v := 20000 * math.Sin(float64(i/channels)/10+float64(i%channels))
+			// Noise keeps some blocks from being predicted well.
+			samples[i] = int16(v) ^ int16(i*7919%251)
+		}
+		return samples
+	}
+	noise := make([]int16, 5000)
+	for i := range noise {
+		noise[i] = int16(i * 40503)
+	}
+	for _, p := range []*PCM{
+		{SampleRate: 22050, Channels: 1, Samples: tone(3*flacBlockSize+17, 1)},
+		{SampleRate: 48000, Channels: 2, Samples: tone(flacBlockSize, 2)},
+		{SampleRate: 11025, Channels: 1, Samples: make([]int16, 9000)},
+		{SampleRate: 12000, Channels: 1, Samples: noise},
+		{SampleRate: 44100, Channels: 1, Samples: []int16{math.MinInt16, math.MaxInt16, 5}},
+		{SampleRate: 44100, Channels: 1, Samples: []int16{-7}},
+		{SampleRate: 44100, Channels: 3},
+	} {
+		b := new(bytes.Buffer)
+		n, err := p.WriteFLAC(b)
+		if err != nil || n != int64(b.Len()) {
+			t.Fatalf("wrote %d bytes, reporting %d (%v)", b.Len(), n, err)
+		}
+		s := readFLAC(t, b.Bytes())
+		if !reflect.DeepEqual(s.samples, p.Samples) {
+			t.Errorf("%d Hz, %d channels: the samples are not decoded as they were written",
+				p.SampleRate, p.Channels)
+		}
+		sum := md5.New()
+		binary.Write(sum, binary.LittleEndian, p.Samples)
+		if s.info.rate != p.SampleRate || s.info.channels != p.Channels || s.info.bits != 16 ||
+			s.info.samples != uint64(p.Frames()) || !bytes.Equal(s.info.md5, sum.Sum(nil)) ||
+			s.info.minBlock != flacBlockSize || s.info.maxFrame < s.info.minFrame {
+			t.Errorf("%d Hz, %d channels: read the stream info %+v", p.SampleRate, p.Channels, s.info)
+		}
+	}
+
+	// Silence is stored as constant subframes.
+	b := new(bytes.Buffer)
+	silence := &PCM{SampleRate: 22050, Channels: 1, Samples: make([]int16, 10*flacBlockSize)}
+	if _, err := silence.WriteFLAC(b); err != nil || b.Len() > 200 {
+		t.Errorf("%d samples of silence are written as %d bytes (%v)", len(silence.Samples), b.Len(),
+			err)
+	}
+	if _, err := (&PCM{SampleRate: 44100, Channels: 9}).WriteFLAC(b); err == nil {
+		t.Error("wrote a FLAC file with 9 channels")
+	}
+}
+
+func TestContactSheetFLAC(t *testing.T) {
+	sheet := NewContactSheet(22050, 500*time.Millisecond)
+	for _, label := range []string{"100", "200"} {
+		if err := sheet.Add(label, &PCM{SampleRate: 48000, Channels: 2,
+			Samples: make([]int16, 2*48000)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b := new(bytes.Buffer)
+	if _, err := sheet.WriteFLAC(b); err != nil {
+		t.Fatal(err)
+	}
+	s := readFLAC(t, b.Bytes())
+	if !reflect.DeepEqual(s.samples, sheet.samples) {
+		t.Error("the samples of the contact sheet are not decoded as they were written")
+	}
+	want := []string{"CHAPTER001=00:00:00.250", "CHAPTER001NAME=100", "CHAPTER002=00:00:01.300",
+		"CHAPTER002NAME=200"}
+	if !reflect.DeepEqual(s.comments, want) {
+		t.Errorf("got the comments %q, want %q", s.comments, want)
+	}
+}
+
+func TestContactSheetRejectsZeroSampleRate(t *testing.T) {
+	sheet := NewContactSheet(22050, 500*time.Millisecond)
+	for _, p := range []*PCM{
+		{SampleRate: 0, Channels: 1, Samples: make([]int16, 100)},
+		{SampleRate: 48000, Channels: 0},
+	} {
+		if err := sheet.Add("100", p); err == nil {
+			t.Errorf("previewed audio of %d Hz with %d channels", p.SampleRate, p.Channels)
+		}
+	}
+	if len(sheet.Cues) != 0 || len(sheet.samples) != 0 {
+		t.Errorf("audio that cannot be previewed was added as %d cues and %d samples",
+			len(sheet.Cues), len(sheet.samples))
+	}
+}
@@ -0,0 +1,135 @@
+// Package wem implements access to the Wwise encoded media (.wem) file format.
+package wem
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+// PCM is decoded audio: 16 bit samples, with the samples of each channel
+// interleaved.
+type PCM struct {
+	SampleRate int
+	Channels   int
+	Samples    []int16
+}
+
+// Frames returns the number of samples per channel.
+func (p *PCM) Frames() int {
+	return len(p.Samples) / p.Channels
+}
+
+// Duration returns the length of the audio.
+func (p *PCM) Duration() time.Duration {
+	return time.Duration(p.Frames()) * time.Second / time.Duration(p.SampleRate)
+}
+
+// Mono returns the audio mixed down to a single channel and resampled to
+// sampleRate, keeping at most the first max of audio.
+func (p *PCM) Mono(sampleRate int, max time.Duration) []int16 {
+	frames := int(int64(p.Frames()) * int64(sampleRate) / int64(p.SampleRate))
+	if limit := int(max * time.Duration(sampleRate) / time.Second); frames > limit {
+		frames = limit
+	}
+	mono := make([]int16, frames)
+	for i := range mono {
+		// Nearest neighbour resampling is good enough for previews.
+		src := int(int64(i)*int64(p.SampleRate)/int64(sampleRate)) * p.Channels
+		sum := 0
+		for c := 0; c < p.Channels; c++ {
+			sum += int(p.Samples[src+c])
+		}
+		mono[i] = int16(sum / p.Channels)
+	}
+	return mono
+}
+
+// Downmix returns the audio mixed down to a single channel, or p itself if it
+// already has one.
+func (p *PCM) Downmix() *PCM {
+	if p.Channels == 1 {
+		return p
+	}
+	out := &PCM{SampleRate: p.SampleRate, Channels: 1, Samples: make([]int16, p.Frames())}
+	for i := range out.Samples {
+		sum := 0
+		for c := 0; c < p.Channels; c++ {
+			sum += int(p.Samples[i*p.Channels+c])
+		}
+		out.Samples[i] = int16(sum / p.Channels)
+	}
+	return out
+}
+
+// Resample returns the audio resampled to sampleRate by linear interpolation,
+// or p itself if it already has that sample rate.
+func (p *PCM) Resample(sampleRate int) *PCM {
+	if sampleRate == p.SampleRate || p.Frames() == 0 {
+		return p
+	}
+	frames := int(int64(p.Frames()) * int64(sampleRate) / int64(p.SampleRate))
+	out := &PCM{SampleRate: sampleRate, Channels: p.Channels, Samples: make([]int16, frames*p.Channels)}
+	last := p.Frames() - 1
+	for i := 0; i < frames; i++ {
+		// The position of the output frame in the input, in 1/sampleRate of
+		// an input frame.
+		pos := int64(i) * int64(p.SampleRate)
+		src, frac := int(pos/int64(sampleRate)), pos%int64(sampleRate)
+		next := src + 1
+		if next > last {
+			next = last
+		}
+		for c := 0; c < p.Channels; c++ {
+			a, b := int64(p.Samples[src*p.Channels+c]), int64(p.Samples[next*p.Channels+c])
+			out.Samples[i*p.Channels+c] = int16(a + (b-a)*frac/int64(sampleRate))
+		}
+	}
+	return out
+}
+
+// WriteWAV writes p to w as a WAVE file.
+func (p *PCM) WriteWAV(w io.Writer) (int64, error) {
+	return writeWAV(w, p, nil)
+}
+
+// wavFormat is the fmt chunk of a 16 bit PCM WAVE file.
+type wavFormat struct {
+	Codec         uint16
+	Channels      uint16
+	SampleRate    uint32
+	BytesPerSec   uint32
+	BlockAlign    uint16
+	BitsPerSample uint16
+}
+
+// writeWAV writes p to w as a WAVE file, followed by the given extra chunks,
+// which must already be padded to an even length.
+func writeWAV(w io.Writer, p *PCM, extra []byte) (int64, error) {
+	bw := bufio.NewWriter(w)
+	le := binary.LittleEndian
+	blockAlign := uint16(p.Channels * 2)
+	dataLength := uint32(len(p.Samples) * 2)
+	fields := []interface{}{
+		[]byte("RIFF"), uint32(4 + 8 + 16 + 8 + dataLength + uint32(len(extra))), []byte("WAVE"),
+		[]byte("fmt "), uint32(16), wavFormat{
+			Codec:         CodecPCM,
+			Channels:      uint16(p.Channels),
+			SampleRate:    uint32(p.SampleRate),
+			BytesPerSec:   uint32(p.SampleRate) * uint32(blockAlign),
+			BlockAlign:    blockAlign,
+			BitsPerSample: 16,
+		},
+		[]byte("data"), dataLength, p.Samples,
+	}
+	for _, field := range fields {
+		if err := binary.Write(bw, le, field); err != nil {
+			return 0, err
+		}
+	}
+	if _, err := bw.Write(extra); err != nil {
+		return 0, err
+	}
+	return int64(12 + 8 + 16 + 8 + dataLength + uint32(len(extra))), bw.Flush()
+}
@@ -0,0 +1,154 @@
+// Package wem implements access to the Wwise encoded media (.wem) file format.
+package wem
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// The marker played before each preview of a contact sheet.
+const (
+	beepFrequency = 1000 // Hz
+	beepLength    = 150 * time.Millisecond
+	beepGap       = 100 * time.Millisecond
+	previewGap    = 300 * time.Millisecond
+)
+
+// A ContactSheet concatenates short previews of many sounds into a single mono
+// FLAC or WAVE file, so that the contents of a whole package can be skimmed by
+// listening to one file. Each preview is preceded by a beep, and its start is
+// recorded as a chapter of the FLAC file, or as a labelled cue point of the
+// WAVE file, which players and audio editors show as a marker.
+type ContactSheet struct {
+	SampleRate int
+	// The maximum length of each preview.
+	Preview time.Duration
+	// The previews added so far, in order.
+	Cues    []Cue
+	samples []int16
+}
+
+// A Cue marks the start of a preview in a contact sheet.
+type Cue struct {
+	Label string
+	Start time.Duration
+}
+
+// NewContactSheet creates an empty ContactSheet with the given sample rate and
+// maximum preview length.
+func NewContactSheet(sampleRate int, preview time.Duration) *ContactSheet {
+	return &ContactSheet{SampleRate: sampleRate, Preview: preview}
+}
+
+// Add appends a beep followed by a preview of p, labelled with label. Audio
+// without a sample rate or channels cannot be previewed, and results in an
+// error.
+func (s *ContactSheet) Add(label string, p *PCM) error {
+	if p.SampleRate <= 0 || p.Channels <= 0 {
+		return fmt.Errorf("cannot preview audio of %d Hz with %d channels", p.SampleRate,
+			p.Channels)
+	}
+	beep := s.frames(beepLength)
+	for i := 0; i < beep; i++ {
+		t := float64(i) / float64(s.SampleRate)
+		s.samples = append(s.samples, int16(0.3*math.MaxInt16*math.Sin(2*math.Pi*beepFrequency*t)))
+	}
+	s.silence(beepGap)
+
+	start := len(s.samples)
+	s.Cues = append(s.Cues, Cue{label, s.duration(start)})
+	s.samples = append(s.samples, p.Mono(s.SampleRate, s.Preview)...)
+	s.silence(previewGap)
+	return nil
+}
+
+// WriteTo writes the contact sheet to w as a WAVE file with a cue chunk and a
+// label for each preview.
+func (s *ContactSheet) WriteTo(w io.Writer) (int64, error) {
+	p := &PCM{SampleRate: s.SampleRate, Channels: 1, Samples: s.samples}
+	return writeWAV(w, p, s.cueChunks())
+}
+
+// WriteFLAC writes the contact sheet to w as a FLAC file, with a chapter for
+// each preview given by CHAPTERxxx and CHAPTERxxxNAME Vorbis comments.
+func (s *ContactSheet) WriteFLAC(w io.Writer) (int64, error) {
+	p := &PCM{SampleRate: s.SampleRate, Channels: 1, Samples: s.samples}
+	var comments []string
+	for i, c := range s.Cues {
+		start := c.Start.Round(time.Millisecond)
+		comments = append(comments,
+			fmt.Sprintf("CHAPTER%03d=%02d:%02d:%02d.%03d", i+1, int(start.Hours()),
+				int(start.Minutes())%60, int(start.Seconds())%60, start.Milliseconds()%1000),
+			fmt.Sprintf("CHAPTER%03dNAME=%s", i+1, c.Label))
+	}
+	return writeFLAC(w, p, comments)
+}
+
+// cuePoint is a single entry of a cue chunk.
+type cuePoint struct {
+	ID           uint32
+	Position     uint32
+	ChunkID      [4]byte
+	ChunkStart   uint32
+	BlockStart   uint32
+	SampleOffset uint32
+}
+
+// cueChunks returns the cue chunk and associated data list chunk describing the
+// cues of the contact sheet.
+func (s *ContactSheet) cueChunks() []byte {
+	if len(s.Cues) == 0 {
+		return nil
+	}
+	le := binary.LittleEndian
+	cues := new(bytes.Buffer)
+	binary.Write(cues, le, uint32(len(s.Cues)))
+	labels := bytes.NewBufferString("adtl")
+	for i, c := range s.Cues {
+		id := uint32(i + 1)
+		position := uint32(s.frames(c.Start))
+		binary.Write(cues, le, cuePoint{
+			ID:           id,
+			Position:     position,
+			ChunkID:      [4]byte{'d', 'a', 't', 'a'},
+			SampleOffset: position,
+		})
+
+		text := append([]byte(c.Label), 0)
+		labels.WriteString("labl")
+		binary.Write(labels, le, uint32(4+len(text)))
+		binary.Write(labels, le, id)
+		labels.Write(text)
+		if len(text)%2 == 1 {
+			labels.WriteByte(0)
+		}
+	}
+
+	chunks := new(bytes.Buffer)
+	chunks.WriteString("cue ")
+	binary.Write(chunks, le, uint32(cues.Len()))
+	chunks.Write(cues.Bytes())
+	chunks.WriteString("LIST")
+	binary.Write(chunks, le, uint32(labels.Len()))
+	chunks.Write(labels.Bytes())
+	return chunks.Bytes()
+}
+
+// silence appends d of silence.
+func (s *ContactSheet) silence(d time.Duration) {
+	s.samples = append(s.samples, make([]int16, s.frames(d))...)
+}
+
+// frames returns the number of samples in d, rounded to the nearest sample.
+func (s *ContactSheet) frames(d time.Duration) int {
+	return int((d*time.Duration(s.SampleRate) + time.Second/2) / time.Second)
+}
+
+// duration returns the time taken to play the given number of samples.
+func (s *ContactSheet) duration(frames int) time.Duration {
+	return time.Duration(frames) * time.Second / time.Duration(s.SampleRate)
+}
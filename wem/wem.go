@@ -0,0 +1,190 @@
+// Package wem implements access to the Wwise encoded media (.wem) file format.
+package wem
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// The codecs that can be found in the format tag of a wem.
+const (
+	CodecPCM           = 0x0001
+	CodecADPCM         = 0x0002
+	CodecXMA2          = 0x0166
+	CodecAAC           = 0xAAC0
+	CodecOpusNX        = 0x3039
+	CodecOpus          = 0x3040
+	CodecPTADPCM       = 0x8311
+	CodecPCMExtensible = 0xFFFE
+	CodecVorbis        = 0xFFFF
+)
+
+// codecNames are the names of the codecs in human readable form.
+var codecNames = map[uint16]string{
+	CodecPCM:           "PCM",
+	CodecADPCM:         "IMA ADPCM",
+	CodecXMA2:          "XMA2",
+	CodecAAC:           "AAC",
+	CodecOpusNX:        "Opus (NX)",
+	CodecOpus:          "Opus",
+	CodecPTADPCM:       "PTADPCM",
+	CodecPCMExtensible: "PCM",
+	CodecVorbis:        "Vorbis",
+}
+
+// CodecName returns the name of the codec with the given format tag.
+func CodecName(tag uint16) string {
+	if name, ok := codecNames[tag]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown (0x%04X)", tag)
+}
+
+// Codecs returns the format tags of the codecs known to this package, in
+// increasing order.
+func Codecs() []uint16 {
+	tags := make([]uint16, 0, len(codecNames))
+	for tag := range codecNames {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+	return tags
+}
+
+// CanDecode reports whether Decode supports audio encoded with the codec with
+// the given format tag, at least at some bit depths: whether a Decoder is
+// registered for it.
+func CanDecode(tag uint16) bool {
+	_, ok := decoders[tag]
+	return ok
+}
+
+// ErrUnsupportedCodec is returned when decoding a wem whose codec cannot be
+// decoded by this package.
+var ErrUnsupportedCodec = errors.New("unsupported codec")
+
+// A File represents a parsed wem.
+type File struct {
+	reader io.ReaderAt
+	// The byte order of the file: little endian for RIFF files and big endian
+	// for RIFX files.
+	order binary.ByteOrder
+	Format
+	// The offset and length of the audio data.
+	dataOffset int64
+	dataLength int64
+}
+
+// Format is the contents of the fmt chunk of a wem.
+type Format struct {
+	Codec         uint16
+	Channels      uint16
+	SampleRate    uint32
+	BytesPerSec   uint32
+	BlockAlign    uint16
+	BitsPerSample uint16
+}
+
+// NewFile parses the first size bytes of r as a wem.
+func NewFile(r io.ReaderAt, size int64) (*File, error) {
+	var riff [12]byte
+	if _, err := r.ReadAt(riff[:], 0); err != nil {
+		return nil, fmt.Errorf("reading RIFF header: %w", err)
+	}
+	f := &File{reader: r}
+	switch {
+	case bytes.Equal(riff[0:4], []byte("RIFF")):
+		f.order = binary.LittleEndian
+	case bytes.Equal(riff[0:4], []byte("RIFX")):
+		f.order = binary.BigEndian
+	default:
+		return nil, errors.New("not a RIFF file")
+	}
+	if !bytes.Equal(riff[8:12], []byte("WAVE")) {
+		return nil, errors.New("not a WAVE file")
+	}
+
+	var haveFmt, haveData bool
+	var chunk [8]byte
+	for offset := int64(len(riff)); offset+8 <= size; {
+		if _, err := r.ReadAt(chunk[:], offset); err != nil {
+			return nil, fmt.Errorf("reading chunk header at %d: %w", offset, err)
+		}
+		length := int64(f.order.Uint32(chunk[4:]))
+		body := offset + 8
+		switch string(chunk[0:4]) {
+		case "fmt ":
+			sr := io.NewSectionReader(r, body, length)
+			if err := binary.Read(sr, f.order, &f.Format); err != nil {
+				return nil, fmt.Errorf("reading fmt chunk: %w", err)
+			}
+			haveFmt = true
+		case "data":
+			f.dataOffset, f.dataLength = body, length
+			if body+length > size {
+				f.dataLength = size - body
+			}
+			haveData = true
+		}
+		// Chunks are padded to an even length.
+		offset = body + length + length%2
+	}
+	if !haveFmt || !haveData {
+		return nil, errors.New("missing fmt or data chunk")
+	}
+	// The length of the audio, and any resampling of it, depend on the sample
+	// rate.
+	if f.SampleRate == 0 {
+		return nil, errors.New("invalid sample rate of 0 Hz")
+	}
+	return f, nil
+}
+
+// CodecName returns the name of the codec the audio of this file is encoded
+// with.
+func (f *File) CodecName() string {
+	return CodecName(f.Codec)
+}
+
+// ByteOrder returns the byte order of this file: little endian for RIFF files
+// and big endian for RIFX files.
+func (f *File) ByteOrder() binary.ByteOrder {
+	return f.order
+}
+
+// Data returns a reader of the audio data of this file.
+func (f *File) Data() *io.SectionReader {
+	return io.NewSectionReader(f.reader, f.dataOffset, f.dataLength)
+}
+
+// Decodable reports whether the audio of this file can be decoded by Decode.
+func (f *File) Decodable() bool {
+	d, ok := decoders[f.Codec]
+	return ok && f.Channels > 0 && (d.Supports == nil || d.Supports(&f.Format))
+}
+
+// Decode decodes the audio of this file with the Decoder registered for its
+// codec. Audio that no registered Decoder supports results in an error
+// wrapping ErrUnsupportedCodec.
+func (f *File) Decode() (*PCM, error) {
+	if !f.Decodable() {
+		return nil, fmt.Errorf("%w: %s, %d bits", ErrUnsupportedCodec, f.CodecName(),
+			f.BitsPerSample)
+	}
+	return decoders[f.Codec].Decode(f)
+}
+
+// WriteWAV decodes the audio of this file and writes it to w as a 16 bit PCM
+// WAVE file with the given sample rate, resampling it if needed, as Decode
+// does.
+func (f *File) WriteWAV(w io.Writer, sampleRate int) (int64, error) {
+	pcm, err := f.Decode()
+	if err != nil {
+		return 0, err
+	}
+	return pcm.Resample(sampleRate).WriteWAV(w)
+}
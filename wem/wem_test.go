@@ -0,0 +1,41 @@
+// Package wem implements access to the Wwise encoded media (.wem) file format.
+package wem
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// buildWem returns a RIFF wem of the given format holding data.
+func buildWem(format Format, data []byte) []byte {
+	b := new(bytes.Buffer)
+	b.WriteString("RIFF")
+	binary.Write(b, binary.LittleEndian, uint32(4+8+16+8+len(data)))
+	b.WriteString("WAVEfmt ")
+	binary.Write(b, binary.LittleEndian, uint32(16))
+	binary.Write(b, binary.LittleEndian, format)
+	b.WriteString("data")
+	binary.Write(b, binary.LittleEndian, uint32(len(data)))
+	b.Write(data)
+	return b.Bytes()
+}
+
+func TestNewFileRejectsZeroSampleRate(t *testing.T) {
+	format := Format{Codec: CodecPCM, Channels: 1, SampleRate: 48000, BytesPerSec: 96000,
+		BlockAlign: 2, BitsPerSample: 16}
+	data := buildWem(format, make([]byte, 8))
+	f, err := NewFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pcm, err := f.Decode(); err != nil || pcm.Duration() == 0 {
+		t.Errorf("decoded the PCM wem to %+v (%v)", pcm, err)
+	}
+
+	format.SampleRate = 0
+	data = buildWem(format, make([]byte, 8))
+	if _, err := NewFile(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Error("parsed a PCM wem with a sample rate of 0 Hz")
+	}
+}